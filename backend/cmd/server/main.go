@@ -1,11 +1,16 @@
 package main
 
 import (
+	"fmt"
 	"os"
+	"time"
 
+	"whotakesshowers/internal/app"
+	"whotakesshowers/internal/backup"
+	"whotakesshowers/internal/cli"
 	"whotakesshowers/internal/config"
-	"whotakesshowers/internal/handler"
 	"whotakesshowers/internal/logger"
+	"whotakesshowers/internal/service"
 	"whotakesshowers/internal/store"
 
 	"github.com/gin-gonic/gin"
@@ -20,6 +25,24 @@ func main() {
 		cfg = config.Get()
 	}
 
+	// 子命令：migrate up | down [n] | status
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := cli.Migrate(cfg, os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// 子命令：backup create | list | prune | restore <archive>
+	if len(os.Args) > 1 && os.Args[1] == "backup" {
+		if err := cli.Backup(cfg, os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// 初始化日志系统
 	if err := logger.Init(cfg); err != nil {
 		panic("Failed to initialize logger: " + err.Error())
@@ -36,14 +59,55 @@ func main() {
 	gin.SetMode(cfg.Server.Mode)
 
 	// 初始化数据库
-	db, err := store.InitDB()
+	db, err := store.InitDB(cfg.Database)
 	if err != nil {
 		logger.Fatal("Failed to initialize database", zap.Error(err))
 	}
 	logger.Info("Database initialized successfully", zap.String("path", cfg.Database.Path))
 
-	// 初始化存储
-	store.Init(db)
+	// 组装应用：存储、服务和处理器
+	application := app.New(cfg, store.New(db))
+
+	// 创建默认用户（如果不存在）
+	if err := application.Services.Users.EnsureDefaultUser(); err != nil {
+		logger.Warn("Failed to create default user", zap.Error(err))
+	}
+
+	// 启动回收站清理任务
+	retention := time.Duration(cfg.Trash.RetentionDays) * 24 * time.Hour
+	purgeInterval := time.Duration(cfg.Trash.PurgeInterval) * time.Minute
+	application.Services.Trash.StartPurger(retention, purgeInterval)
+	logger.Info("Trash purger started",
+		zap.Int("retention_days", cfg.Trash.RetentionDays),
+		zap.Int("purge_interval_minutes", cfg.Trash.PurgeInterval),
+	)
+
+	// 启动账号删除任务
+	accountPurgeInterval := time.Duration(cfg.Account.PurgeInterval) * time.Minute
+	application.Services.Accounts.StartPurger(accountPurgeInterval)
+	logger.Info("Account purger started",
+		zap.Int("deletion_grace_days", cfg.Account.DeletionGraceDays),
+		zap.Int("purge_interval_minutes", cfg.Account.PurgeInterval),
+	)
+
+	// 启动过期幂等键清理任务
+	idempotencyPurgeInterval := time.Duration(cfg.Idempotency.PurgeInterval) * time.Minute
+	application.Services.Idempotency.StartPurger(idempotencyPurgeInterval)
+	logger.Info("Idempotency key purger started",
+		zap.Int("ttl_minutes", cfg.Idempotency.TTL),
+		zap.Int("purge_interval_minutes", cfg.Idempotency.PurgeInterval),
+	)
+
+	// 启用备份管理和定时备份（仅 SQLite）
+	if db.Dialector.Name() == config.DriverSQLite {
+		backups := backup.NewManager(db, cfg.Backup, service.UploadDir)
+		application.EnableBackups(backups)
+		backups.StartScheduler(time.Duration(cfg.Backup.Interval) * time.Minute)
+		logger.Info("Backup scheduler started",
+			zap.String("dir", cfg.Backup.Dir),
+			zap.Int("interval_minutes", cfg.Backup.Interval),
+		)
+	}
 
 	// 创建 Gin 路由
 	r := gin.New()
@@ -58,7 +122,7 @@ func main() {
 	// 注册路由
 	api := r.Group("/api")
 	{
-		handler.RegisterRoutes(api, store.DB)
+		application.RegisterRoutes(api)
 	}
 	logger.Info("API routes registered")
 
@@ -123,8 +187,9 @@ func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, Idempotency-Key")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "Idempotent-Replayed")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, PATCH, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -0,0 +1,44 @@
+// Package app 组装应用：创建存储、服务和处理器，并显式注入依赖
+package app
+
+import (
+	"whotakesshowers/internal/backup"
+	"whotakesshowers/internal/config"
+	"whotakesshowers/internal/handler"
+	"whotakesshowers/internal/service"
+	"whotakesshowers/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// App 应用容器
+type App struct {
+	Config   *config.Config
+	Store    store.Store
+	Services *service.Services
+	Handlers *handler.Handlers
+	Backups  *backup.Manager
+}
+
+// New 基于配置和数据存储创建应用容器
+// 生产环境传入 store.New(db)，测试可以传入 memory.New() 等内存实现
+func New(cfg *config.Config, s store.Store) *App {
+	services := service.New(cfg, s)
+	return &App{
+		Config:   cfg,
+		Store:    s,
+		Services: services,
+		Handlers: handler.New(s, services),
+	}
+}
+
+// EnableBackups 启用备份管理，注册路由前调用
+func (a *App) EnableBackups(backups *backup.Manager) {
+	a.Backups = backups
+	a.Handlers.Backups = handler.NewBackupHandler(backups)
+}
+
+// RegisterRoutes 在路由组上注册所有 API 路由
+func (a *App) RegisterRoutes(r *gin.RouterGroup) {
+	handler.RegisterRoutes(r, a.Handlers)
+}
@@ -0,0 +1,356 @@
+// Package backup 提供 SQLite 数据库和上传文件的在线备份与恢复
+//
+// 备份归档是一个 tar.gz 文件，依次包含：
+//
+//	manifest.json   归档格式、创建时间和数据库版本
+//	database.db     使用 VACUUM INTO 生成的数据库一致性快照
+//	uploads/...     上传的照片
+//
+// 备份可以在服务运行时进行；恢复会替换数据库文件和上传目录，必须在服务停止时执行。
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"whotakesshowers/internal/config"
+	"whotakesshowers/internal/logger"
+	"whotakesshowers/internal/migrate"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// FormatVersion 当前的归档格式版本
+const FormatVersion = 1
+
+// 归档中的文件名
+const (
+	manifestName = "manifest.json"
+	databaseName = "database.db"
+	uploadsDir   = "uploads"
+)
+
+var (
+	// ErrUnsupportedDriver 当前数据库驱动不支持备份
+	ErrUnsupportedDriver = errors.New("backup is only supported on sqlite, use pg_dump for postgres")
+	// ErrBackupNotFound 备份归档不存在
+	ErrBackupNotFound = errors.New("backup not found")
+	// ErrInvalidArchive 归档损坏或不是本程序生成的备份
+	ErrInvalidArchive = errors.New("invalid backup archive")
+)
+
+// archiveNamePattern 备份归档文件名，只有匹配的文件才会被列出和清理
+var archiveNamePattern = regexp.MustCompile(`^whotakesshowers-(\d{8}-\d{6}\.\d{3})\.tar\.gz$`)
+
+// archiveTimeLayout 归档文件名中的时间格式
+const archiveTimeLayout = "20060102-150405.000"
+
+// Manifest 归档清单
+type Manifest struct {
+	Format        int       `json:"format"`
+	CreatedAt     time.Time `json:"created_at"`
+	Driver        string    `json:"driver"`
+	SchemaVersion int       `json:"schema_version"`
+	Uploads       int       `json:"uploads"`
+}
+
+// Info 备份归档信息
+type Info struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Manager 备份管理器
+type Manager struct {
+	db        *gorm.DB
+	cfg       config.BackupConfig
+	uploadDir string
+
+	mu sync.Mutex // 同一时间只执行一个备份
+}
+
+// NewManager 创建备份管理器，uploadDir 为上传文件目录
+func NewManager(db *gorm.DB, cfg config.BackupConfig, uploadDir string) *Manager {
+	return &Manager{db: db, cfg: cfg, uploadDir: uploadDir}
+}
+
+// Create 生成一个新的备份归档，完成后按保留规则清理旧备份
+func (m *Manager) Create() (*Info, error) {
+	if m.db.Dialector.Name() != config.DriverSQLite {
+		return nil, ErrUnsupportedDriver
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := os.MkdirAll(m.cfg.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	migrator, err := migrate.New(m.db)
+	if err != nil {
+		return nil, err
+	}
+	version, err := migrator.Current()
+	if err != nil {
+		return nil, err
+	}
+
+	uploads, err := listUploads(m.uploadDir)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	name := "whotakesshowers-" + now.Format(archiveTimeLayout) + ".tar.gz"
+	archivePath := filepath.Join(m.cfg.Dir, name)
+
+	// 数据库快照先写到备份目录下的临时文件，打包后删除
+	snapshot := filepath.Join(m.cfg.Dir, "."+name+".db")
+	defer os.Remove(snapshot)
+	// 只读连接不能执行 VACUUM INTO，快照在写连接上生成，期间的写操作会短暂排队
+	if err := m.db.Exec("VACUUM INTO ?", snapshot).Error; err != nil {
+		return nil, fmt.Errorf("failed to snapshot database: %w", err)
+	}
+
+	manifest := Manifest{
+		Format:        FormatVersion,
+		CreatedAt:     now,
+		Driver:        config.DriverSQLite,
+		SchemaVersion: version,
+		Uploads:       len(uploads),
+	}
+	if err := writeArchive(archivePath, manifest, snapshot, m.uploadDir, uploads); err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	info := &Info{Name: name, Size: stat.Size(), CreatedAt: now}
+	logger.Info("Backup created",
+		zap.String("name", name),
+		zap.Int64("size", info.Size),
+		zap.Int("schema_version", version),
+		zap.Int("uploads", len(uploads)),
+	)
+
+	if _, err := m.Prune(); err != nil {
+		logger.Warn("Failed to prune backups", zap.Error(err))
+	}
+	return info, nil
+}
+
+// List 列出备份目录中的归档，按创建时间从新到旧排序
+func (m *Manager) List() ([]Info, error) {
+	entries, err := os.ReadDir(m.cfg.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Info{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]Info, 0)
+	for _, entry := range entries {
+		matches := archiveNamePattern.FindStringSubmatch(entry.Name())
+		if matches == nil || !entry.Type().IsRegular() {
+			continue
+		}
+		createdAt, err := time.ParseInLocation(archiveTimeLayout, matches[1], time.Local)
+		if err != nil {
+			continue
+		}
+		stat, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, Info{Name: entry.Name(), Size: stat.Size(), CreatedAt: createdAt})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].CreatedAt.After(infos[j].CreatedAt)
+	})
+	return infos, nil
+}
+
+// Path 返回备份归档的完整路径
+func (m *Manager) Path(name string) (string, error) {
+	if !archiveNamePattern.MatchString(name) {
+		return "", ErrBackupNotFound
+	}
+	archivePath := filepath.Join(m.cfg.Dir, name)
+	if _, err := os.Stat(archivePath); errors.Is(err, os.ErrNotExist) {
+		return "", ErrBackupNotFound
+	} else if err != nil {
+		return "", err
+	}
+	return archivePath, nil
+}
+
+// Prune 按保留规则删除旧备份，返回被删除的归档
+// 最近的 KeepLast 个备份和 KeepDays 天内的备份都会保留；两者都为 0 时不删除任何备份
+func (m *Manager) Prune() ([]Info, error) {
+	if m.cfg.KeepLast <= 0 && m.cfg.KeepDays <= 0 {
+		return nil, nil
+	}
+
+	infos, err := m.List()
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -m.cfg.KeepDays)
+	var removed []Info
+	for i, info := range infos {
+		if i < m.cfg.KeepLast || (m.cfg.KeepDays > 0 && info.CreatedAt.After(cutoff)) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(m.cfg.Dir, info.Name)); err != nil {
+			return removed, err
+		}
+		logger.Info("Backup pruned", zap.String("name", info.Name))
+		removed = append(removed, info)
+	}
+	return removed, nil
+}
+
+// StartScheduler 启动后台任务，每隔 interval 生成一个备份
+// interval 不大于 0 或数据库不是 SQLite 时不启动
+func (m *Manager) StartScheduler(interval time.Duration) {
+	if interval <= 0 || m.db.Dialector.Name() != config.DriverSQLite {
+		logger.Warn("Backup scheduler disabled",
+			zap.Duration("interval", interval),
+			zap.String("driver", m.db.Dialector.Name()),
+		)
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, err := m.Create(); err != nil {
+				logger.Error("Scheduled backup failed", zap.Error(err))
+			}
+		}
+	}()
+}
+
+// listUploads 返回上传目录中的文件名（不含隐藏文件）；目录不存在时返回空列表
+func listUploads(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload directory: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && !strings.HasPrefix(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// writeArchive 将清单、数据库快照和上传文件写入归档
+// 先写入临时文件，完成后再重命名，避免留下不完整的归档
+func writeArchive(archivePath string, manifest Manifest, snapshot, uploadDir string, uploads []string) (err error) {
+	tmpPath := archivePath + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create backup archive: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    manifestName,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: manifest.CreatedAt,
+	}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return err
+	}
+
+	if err := addFile(tw, databaseName, snapshot); err != nil {
+		return err
+	}
+	for _, name := range uploads {
+		if err := addFile(tw, uploadsDir+"/"+name, filepath.Join(uploadDir, name)); err != nil {
+			// 照片可能在备份期间被删除
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, archivePath)
+}
+
+// addFile 将文件 src 以 name 写入归档
+func addFile(tw *tar.Writer, name, src string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    stat.Size(),
+		ModTime: stat.ModTime(),
+	}); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
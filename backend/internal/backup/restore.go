@@ -0,0 +1,238 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"whotakesshowers/internal/config"
+	"whotakesshowers/internal/migrate"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// Staged 已解压并通过校验、等待替换现有数据的备份
+// 数据库和照片分别解压到目标位置所在的目录中，替换时只需重命名
+type Staged struct {
+	Manifest Manifest
+
+	dbPath     string
+	uploadDir  string
+	dbStage    string // 解压出的数据库所在的临时目录
+	filesStage string // 解压出的照片所在的临时目录
+}
+
+// Stage 解压归档并校验：清单格式、文件列表、数据库完整性以及数据库版本
+// 校验失败时清理临时文件并返回错误；成功后调用 Apply 替换现有数据，或调用 Discard 放弃
+func Stage(archivePath, dbPath, uploadDir string) (*Staged, error) {
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(uploadDir, 0755); err != nil {
+		return nil, err
+	}
+	dbStage, err := os.MkdirTemp(filepath.Dir(dbPath), ".restore-")
+	if err != nil {
+		return nil, err
+	}
+	filesStage, err := os.MkdirTemp(uploadDir, ".restore-")
+	if err != nil {
+		os.RemoveAll(dbStage)
+		return nil, err
+	}
+	staged := &Staged{
+		dbPath:     dbPath,
+		uploadDir:  uploadDir,
+		dbStage:    dbStage,
+		filesStage: filesStage,
+	}
+
+	if err := staged.extract(archivePath); err != nil {
+		staged.Discard()
+		return nil, err
+	}
+	if err := staged.verifyDatabase(); err != nil {
+		staged.Discard()
+		return nil, err
+	}
+	return staged, nil
+}
+
+// Apply 用解压出的数据库和照片替换现有数据，调用前必须停止服务并关闭数据库连接
+func (s *Staged) Apply() error {
+	defer s.Discard()
+
+	// 旧数据库的 WAL 文件不能留给新数据库
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(s.dbPath + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if err := os.Rename(filepath.Join(s.dbStage, databaseName), s.dbPath); err != nil {
+		return fmt.Errorf("failed to replace database: %w", err)
+	}
+
+	// 上传目录可能是挂载点，只替换其中的文件
+	entries, err := os.ReadDir(s.uploadDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			if err := os.Remove(filepath.Join(s.uploadDir, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	staged, err := os.ReadDir(s.filesStage)
+	if err != nil {
+		return err
+	}
+	for _, entry := range staged {
+		if err := os.Rename(filepath.Join(s.filesStage, entry.Name()), filepath.Join(s.uploadDir, entry.Name())); err != nil {
+			return fmt.Errorf("failed to restore upload %s: %w", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
+// Discard 删除解压出的临时文件
+func (s *Staged) Discard() {
+	os.RemoveAll(s.dbStage)
+	os.RemoveAll(s.filesStage)
+}
+
+// extract 解压归档，只接受本程序生成的文件
+func (s *Staged) extract(archivePath string) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	hasManifest, hasDatabase := false, false
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		if header.Typeflag == tar.TypeDir && path.Clean(header.Name) == uploadsDir {
+			continue
+		}
+		if header.Typeflag != tar.TypeReg {
+			return fmt.Errorf("%w: unexpected entry %q", ErrInvalidArchive, header.Name)
+		}
+
+		switch {
+		case header.Name == manifestName:
+			if err := json.NewDecoder(tr).Decode(&s.Manifest); err != nil {
+				return fmt.Errorf("%w: bad manifest: %v", ErrInvalidArchive, err)
+			}
+			if s.Manifest.Format < 1 || s.Manifest.Format > FormatVersion {
+				return fmt.Errorf("%w: unsupported format version %d", ErrInvalidArchive, s.Manifest.Format)
+			}
+			if s.Manifest.Driver != config.DriverSQLite {
+				return fmt.Errorf("%w: archive was made from a %s database", ErrInvalidArchive, s.Manifest.Driver)
+			}
+			hasManifest = true
+		case header.Name == databaseName:
+			if err := extractFile(tr, filepath.Join(s.dbStage, databaseName)); err != nil {
+				return err
+			}
+			hasDatabase = true
+		case strings.HasPrefix(header.Name, uploadsDir+"/"):
+			name := strings.TrimPrefix(header.Name, uploadsDir+"/")
+			if name == "" || name != path.Base(name) || name == "." || name == ".." || strings.HasPrefix(name, ".") {
+				return fmt.Errorf("%w: unexpected entry %q", ErrInvalidArchive, header.Name)
+			}
+			if err := extractFile(tr, filepath.Join(s.filesStage, name)); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%w: unexpected entry %q", ErrInvalidArchive, header.Name)
+		}
+	}
+
+	if !hasManifest {
+		return fmt.Errorf("%w: missing %s", ErrInvalidArchive, manifestName)
+	}
+	if !hasDatabase {
+		return fmt.Errorf("%w: missing %s", ErrInvalidArchive, databaseName)
+	}
+	return nil
+}
+
+// verifyDatabase 检查解压出的数据库：完整性检查通过，迁移记录与程序一致，版本与清单一致
+func (s *Staged) verifyDatabase() error {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(s.dbStage, databaseName)), &gorm.Config{
+		Logger: gormlogger.Discard,
+	})
+	if err != nil {
+		return err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	defer sqlDB.Close()
+
+	var result string
+	if err := db.Raw("PRAGMA integrity_check").Scan(&result).Error; err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("%w: integrity check failed: %s", ErrInvalidArchive, result)
+	}
+
+	if !db.Migrator().HasTable(&migrate.Record{}) {
+		return fmt.Errorf("%w: database has no schema_migrations table", ErrInvalidArchive)
+	}
+	migrator, err := migrate.New(db)
+	if err != nil {
+		return err
+	}
+	// 比当前程序新的版本或被修改过的迁移都无法使用
+	if err := migrator.Verify(); err != nil {
+		return err
+	}
+	version, err := migrator.Current()
+	if err != nil {
+		return err
+	}
+	if version != s.Manifest.SchemaVersion {
+		return fmt.Errorf("%w: manifest says schema version %d, database is at %d",
+			ErrInvalidArchive, s.Manifest.SchemaVersion, version)
+	}
+	return nil
+}
+
+// extractFile 将归档中的当前文件写入 dst
+func extractFile(r io.Reader, dst string) error {
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
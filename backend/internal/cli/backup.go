@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"whotakesshowers/internal/backup"
+	"whotakesshowers/internal/config"
+	"whotakesshowers/internal/service"
+	"whotakesshowers/internal/store"
+)
+
+// BackupUsage backup 子命令用法
+const BackupUsage = `usage: whotakesshowers backup <command>
+
+commands:
+  create             立即生成一个备份
+  list               列出备份
+  prune              按保留规则删除旧备份
+  restore <archive>  从备份恢复数据库和照片（需先停止服务）`
+
+// Backup 执行 backup 子命令
+func Backup(cfg *config.Config, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(BackupUsage)
+	}
+	if cfg.Database.Driver != config.DriverSQLite && cfg.Database.Driver != "" {
+		return backup.ErrUnsupportedDriver
+	}
+
+	switch args[0] {
+	case "create":
+		manager, closeDB, err := openManager(cfg)
+		if err != nil {
+			return err
+		}
+		defer closeDB()
+		info, err := manager.Create()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "created  %s (%d bytes)\n", info.Name, info.Size)
+		return nil
+
+	case "list":
+		manager := backup.NewManager(nil, cfg.Backup, service.UploadDir)
+		infos, err := manager.List()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSIZE\tCREATED AT")
+		for _, info := range infos {
+			fmt.Fprintf(w, "%s\t%d\t%s\n", info.Name, info.Size, info.CreatedAt.Format("2006-01-02 15:04:05"))
+		}
+		return w.Flush()
+
+	case "prune":
+		manager := backup.NewManager(nil, cfg.Backup, service.UploadDir)
+		removed, err := manager.Prune()
+		for _, info := range removed {
+			fmt.Fprintf(out, "removed  %s\n", info.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(removed) == 0 {
+			fmt.Fprintln(out, "nothing to prune")
+		}
+		return nil
+
+	case "restore":
+		if len(args) < 2 {
+			return errors.New(BackupUsage)
+		}
+		return restore(cfg, args[1], out)
+
+	default:
+		return errors.New(BackupUsage)
+	}
+}
+
+// restore 校验归档后先备份现有数据，再用归档替换数据库和照片
+func restore(cfg *config.Config, archivePath string, out io.Writer) error {
+	staged, err := backup.Stage(archivePath, cfg.Database.Path, service.UploadDir)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "archive ok: created at %s, schema version %d, %d uploads\n",
+		staged.Manifest.CreatedAt.Format("2006-01-02 15:04:05"), staged.Manifest.SchemaVersion, staged.Manifest.Uploads)
+
+	// 现有数据先备份一份，恢复错了还能找回
+	if _, err := os.Stat(cfg.Database.Path); err == nil {
+		manager, closeDB, err := openManager(cfg)
+		if err != nil {
+			staged.Discard()
+			return err
+		}
+		info, err := manager.Create()
+		closeDB()
+		if err != nil {
+			staged.Discard()
+			return fmt.Errorf("failed to back up current data: %w", err)
+		}
+		fmt.Fprintf(out, "current data backed up to %s\n", info.Name)
+	}
+
+	if err := staged.Apply(); err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "restore complete")
+	return nil
+}
+
+// openManager 打开数据库并创建备份管理器，返回关闭数据库连接的函数
+func openManager(cfg *config.Config) (*backup.Manager, func(), error) {
+	db, err := store.OpenDB(cfg.Database)
+	if err != nil {
+		return nil, nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, nil, err
+	}
+	return backup.NewManager(db, cfg.Backup, service.UploadDir), func() { sqlDB.Close() }, nil
+}
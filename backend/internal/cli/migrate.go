@@ -0,0 +1,105 @@
+// Package cli 实现服务器二进制的命令行子命令
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"whotakesshowers/internal/config"
+	"whotakesshowers/internal/store"
+)
+
+// MigrateUsage migrate 子命令用法
+const MigrateUsage = `usage: whotakesshowers migrate <command>
+
+commands:
+  up          执行所有未执行的迁移
+  down [n]    回滚最近的 n 个迁移（默认 1）
+  status      查看迁移状态`
+
+// Migrate 执行 migrate 子命令
+func Migrate(cfg *config.Config, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(MigrateUsage)
+	}
+
+	db, err := store.OpenDB(cfg.Database)
+	if err != nil {
+		return err
+	}
+	migrator, err := store.NewMigrator(db, cfg.Database)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		for _, migration := range applied {
+			fmt.Fprintf(out, "applied  %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Fprintln(out, "database is up to date")
+		}
+		return nil
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				return fmt.Errorf("invalid number of steps: %q", args[1])
+			}
+		}
+		reverted, err := migrator.Down(steps)
+		for _, migration := range reverted {
+			fmt.Fprintf(out, "reverted %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(reverted) == 0 {
+			fmt.Fprintln(out, "nothing to roll back")
+		}
+		return nil
+
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		current, err := migrator.Current()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "current version: %d, latest version: %d\n\n", current, migrator.Latest())
+
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, status := range statuses {
+			state := "pending"
+			switch {
+			case status.Unknown:
+				state = "unknown"
+			case status.ChecksumMismatch:
+				state = "modified"
+			case status.Applied:
+				state = "applied"
+			}
+			appliedAt := ""
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+		}
+		return w.Flush()
+
+	default:
+		return errors.New(MigrateUsage)
+	}
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"whotakesshowers/internal/logger"
+	"whotakesshowers/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// DeletionRequest 账号删除请求，需要重新输入密码
+type DeletionRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// DeletionResponse 账号删除申请结果
+type DeletionResponse struct {
+	*service.DeletionSchedule
+	ExportURL string `json:"export_url,omitempty"` // 冷静期内可以通过该地址导出全部数据
+}
+
+// AccountHandler 账号删除处理器
+type AccountHandler struct {
+	accounts *service.AccountService
+}
+
+// NewAccountHandler 创建账号删除处理器
+func NewAccountHandler(accounts *service.AccountService) *AccountHandler {
+	return &AccountHandler{accounts: accounts}
+}
+
+// RequestDeletion 申请删除当前账号，冷静期结束后彻底删除所有数据
+// POST /api/auth/me/deletion
+func (h *AccountHandler) RequestDeletion(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+
+	var req DeletionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
+		return
+	}
+
+	schedule, err := h.accounts.RequestDeletion(userID, req.Password)
+	if errors.Is(err, service.ErrInvalidCredentials) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "密码错误"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to request account deletion",
+			zap.String("user_id", userID.String()),
+			zap.Error(err),
+		)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if schedule.Deleted {
+		c.JSON(http.StatusOK, DeletionResponse{DeletionSchedule: schedule})
+		return
+	}
+	c.JSON(http.StatusAccepted, DeletionResponse{
+		DeletionSchedule: schedule,
+		ExportURL:        "/api/export",
+	})
+}
+
+// CancelDeletion 在冷静期内撤销账号删除申请
+// DELETE /api/auth/me/deletion
+func (h *AccountHandler) CancelDeletion(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+
+	err := h.accounts.CancelDeletion(userID)
+	if errors.Is(err, service.ErrDeletionNotRequested) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
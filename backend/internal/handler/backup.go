@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"whotakesshowers/internal/backup"
+	"whotakesshowers/internal/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// BackupHandler 备份管理处理器
+type BackupHandler struct {
+	backups *backup.Manager
+}
+
+// NewBackupHandler 创建备份管理处理器
+func NewBackupHandler(backups *backup.Manager) *BackupHandler {
+	return &BackupHandler{backups: backups}
+}
+
+// List 列出备份
+// GET /api/admin/backups
+func (h *BackupHandler) List(c *gin.Context) {
+	infos, err := h.backups.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, infos)
+}
+
+// Create 立即生成一个备份
+// POST /api/admin/backups
+func (h *BackupHandler) Create(c *gin.Context) {
+	info, err := h.backups.Create()
+	if errors.Is(err, backup.ErrUnsupportedDriver) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to create backup", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, info)
+}
+
+// Download 下载备份归档
+// GET /api/admin/backups/:name
+func (h *BackupHandler) Download(c *gin.Context) {
+	name := c.Param("name")
+	archivePath, err := h.backups.Path(name)
+	if errors.Is(err, backup.ErrBackupNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "backup not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.FileAttachment(archivePath, name)
+}
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"whotakesshowers/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// BadgeHandler 徽章处理器
+type BadgeHandler struct {
+	service *service.BadgeService
+}
+
+// NewBadgeHandler 创建徽章处理器
+func NewBadgeHandler(service *service.BadgeService) *BadgeHandler {
+	return &BadgeHandler{service: service}
+}
+
+// List 获取已获得和进行中的徽章，可按候选人过滤
+// GET /api/badges?candidate_id=
+func (h *BadgeHandler) List(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+	candidateID, err := parseUUIDQuery(c, "candidate_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	badges, err := h.service.List(userID, candidateID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, badges)
+}
+
+// Backfill 按已有的历史记录补发徽章
+// POST /api/badges/backfill
+func (h *BadgeHandler) Backfill(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+	awarded, err := h.service.Backfill(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"awarded": awarded})
+}
+
+// ListRules 获取徽章规则
+// GET /api/badges/rules
+func (h *BadgeHandler) ListRules(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+	rules, err := h.service.ListRules(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+// CreateRule 创建徽章规则
+// POST /api/badges/rules
+func (h *BadgeHandler) CreateRule(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+	var req service.BadgeRuleInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, err := h.service.CreateRule(userID, req)
+	if err != nil {
+		respondBadgeError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, rule)
+}
+
+// UpdateRule 修改徽章规则
+// PUT /api/badges/rules/:id
+func (h *BadgeHandler) UpdateRule(c *gin.Context) {
+	userID, ruleID, ok := badgeRuleRequestIDs(c)
+	if !ok {
+		return
+	}
+	var req service.BadgeRuleInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, err := h.service.UpdateRule(userID, ruleID, req)
+	if err != nil {
+		respondBadgeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteRule 删除徽章规则及其授予的徽章
+// DELETE /api/badges/rules/:id
+func (h *BadgeHandler) DeleteRule(c *gin.Context) {
+	userID, ruleID, ok := badgeRuleRequestIDs(c)
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteRule(userID, ruleID); err != nil {
+		respondBadgeError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// badgeRuleRequestIDs 解析当前用户 ID 和路径中的规则 ID，失败时写入错误响应
+func badgeRuleRequestIDs(c *gin.Context) (userID, ruleID uuid.UUID, ok bool) {
+	if userID, ok = requestUserID(c); !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+	ruleID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid badge rule id"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, ruleID, true
+}
+
+// respondBadgeError 返回徽章规则错误，无效的规则为 400
+func respondBadgeError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrInvalidBadgeRule) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	writeServiceError(c, err)
+}
@@ -2,9 +2,11 @@ package handler
 
 import (
 	"net/http"
+	"strings"
+	"time"
 	"whotakesshowers/internal/logger"
-	"whotakesshowers/internal/middleware"
 	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/service"
 	"whotakesshowers/internal/store"
 
 	"github.com/gin-gonic/gin"
@@ -12,32 +14,50 @@ import (
 	"go.uber.org/zap"
 )
 
-// ListCandidates 获取候选人列表
-// GET /api/candidates
-func ListCandidates(c *gin.Context) {
-	userIDStr, exists := middleware.GetUserID(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未认证"})
+// CandidateHandler 候选人处理器
+type CandidateHandler struct {
+	candidates store.CandidateRepository
+	service    *service.CandidateService
+	photos     *service.CandidatePhotoService
+	uploads    *service.PhotoUploads
+}
+
+// NewCandidateHandler 创建候选人处理器
+func NewCandidateHandler(candidates store.CandidateRepository, service *service.CandidateService, photos *service.CandidatePhotoService, uploads *service.PhotoUploads) *CandidateHandler {
+	return &CandidateHandler{candidates: candidates, service: service, photos: photos, uploads: uploads}
+}
+
+// List 分页获取候选人列表，q 按名称搜索，tag 按标签过滤
+// GET /api/candidates?q=&tag=&sort=-created_at&limit=20&cursor=
+func (h *CandidateHandler) List(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
 		return
 	}
-	userID, err := uuid.Parse(userIDStr)
+
+	page, err := parsePageRequest(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	candidates, err := store.Candidates.List(userID)
+	filter := store.CandidateFilter{
+		Query: c.Query("q"),
+		Tag:   strings.ToLower(strings.TrimSpace(c.Query("tag"))),
+	}
+	candidates, err := h.candidates.Find(userID, filter, page)
 	if err != nil {
 		logger.Error("Failed to list candidates",
 			zap.String("user_id", userID.String()),
 			zap.Error(err),
 		)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondListError(c, err)
 		return
 	}
 
 	logger.Info("Listed candidates successfully",
-		zap.Int("count", len(candidates)),
+		zap.Int("count", len(candidates.Items)),
+		zap.Int64("total", candidates.Total),
 	)
 	c.JSON(http.StatusOK, candidates)
 }
@@ -46,19 +66,14 @@ func ListCandidates(c *gin.Context) {
 type CreateCandidateRequest struct {
 	Name     string `json:"name" binding:"required"`
 	PhotoURL string `json:"photo_url"`
+	service.CandidateProfile
 }
 
-// CreateCandidate 创建候选人
+// Create 创建候选人
 // POST /api/candidates
-func CreateCandidate(c *gin.Context) {
-	userIDStr, exists := middleware.GetUserID(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未认证"})
-		return
-	}
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+func (h *CandidateHandler) Create(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
 		return
 	}
 
@@ -77,8 +92,12 @@ func CreateCandidate(c *gin.Context) {
 		PhotoURL: req.PhotoURL,
 		UserID:   userID,
 	}
+	if err := service.ApplyCandidateProfile(candidate, req.CandidateProfile, time.Now()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	if err := store.Candidates.Create(candidate); err != nil {
+	if err := h.candidates.Create(candidate); err != nil {
 		logger.Error("Failed to create candidate",
 			zap.String("user_id", userID.String()),
 			zap.String("name", req.Name),
@@ -99,19 +118,14 @@ func CreateCandidate(c *gin.Context) {
 type UpdateCandidateRequest struct {
 	Name     string `json:"name"`
 	PhotoURL string `json:"photo_url"`
+	service.CandidateProfile
 }
 
-// UpdateCandidate 更新候选人
+// Update 更新候选人
 // PUT /api/candidates/:id
-func UpdateCandidate(c *gin.Context) {
-	userIDStr, exists := middleware.GetUserID(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未认证"})
-		return
-	}
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+func (h *CandidateHandler) Update(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
 		return
 	}
 
@@ -125,7 +139,7 @@ func UpdateCandidate(c *gin.Context) {
 		return
 	}
 
-	candidate, err := store.Candidates.Get(id, userID)
+	candidate, err := h.candidates.Get(id, userID)
 	if err != nil {
 		logger.Warn("Candidate not found for update",
 			zap.String("candidate_id", id.String()),
@@ -151,8 +165,12 @@ func UpdateCandidate(c *gin.Context) {
 	if req.PhotoURL != "" {
 		candidate.PhotoURL = req.PhotoURL
 	}
+	if err := service.ApplyCandidateProfile(candidate, req.CandidateProfile, time.Now()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	if err := store.Candidates.Update(candidate); err != nil {
+	if err := h.candidates.Update(candidate); err != nil {
 		logger.Error("Failed to update candidate",
 			zap.String("candidate_id", id.String()),
 			zap.Error(err),
@@ -168,17 +186,11 @@ func UpdateCandidate(c *gin.Context) {
 	c.JSON(http.StatusOK, candidate)
 }
 
-// GetCandidate 获取候选人详情
+// Get 获取候选人详情
 // GET /api/candidates/:id
-func GetCandidate(c *gin.Context) {
-	userIDStr, exists := middleware.GetUserID(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未认证"})
-		return
-	}
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+func (h *CandidateHandler) Get(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
 		return
 	}
 
@@ -188,7 +200,7 @@ func GetCandidate(c *gin.Context) {
 		return
 	}
 
-	candidate, err := store.Candidates.Get(id, userID)
+	candidate, err := h.candidates.Get(id, userID)
 	if err != nil {
 		logger.Warn("Candidate not found",
 			zap.String("candidate_id", id.String()),
@@ -201,17 +213,11 @@ func GetCandidate(c *gin.Context) {
 	c.JSON(http.StatusOK, candidate)
 }
 
-// DeleteCandidate 删除候选人
+// Delete 删除候选人
 // DELETE /api/candidates/:id
-func DeleteCandidate(c *gin.Context) {
-	userIDStr, exists := middleware.GetUserID(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未认证"})
-		return
-	}
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+func (h *CandidateHandler) Delete(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
 		return
 	}
 
@@ -221,13 +227,14 @@ func DeleteCandidate(c *gin.Context) {
 		return
 	}
 
-	if err := store.Candidates.Delete(id, userID); err != nil {
+	// 将候选人及其照片移入回收站
+	if err := h.service.Delete(id, userID); err != nil {
 		logger.Error("Failed to delete candidate",
 			zap.String("candidate_id", id.String()),
 			zap.String("user_id", userID.String()),
 			zap.Error(err),
 		)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeServiceError(c, err)
 		return
 	}
 
@@ -237,17 +244,11 @@ func DeleteCandidate(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
-// UploadCandidatePhoto 上传候选人照片
+// UploadPhoto 上传候选人照片
 // POST /api/candidates/:id/photo
-func UploadCandidatePhoto(c *gin.Context) {
-	userIDStr, exists := middleware.GetUserID(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未认证"})
-		return
-	}
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+func (h *CandidateHandler) UploadPhoto(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
 		return
 	}
 
@@ -262,7 +263,7 @@ func UploadCandidatePhoto(c *gin.Context) {
 	}
 
 	// 检查候选人是否存在
-	_, err = store.Candidates.Get(id, userID)
+	_, err = h.candidates.Get(id, userID)
 	if err != nil {
 		logger.Warn("Candidate not found for photo upload",
 			zap.String("candidate_id", id.String()),
@@ -283,36 +284,33 @@ func UploadCandidatePhoto(c *gin.Context) {
 		return
 	}
 
-	// 生成文件名
-	filename := uuid.New().String() + "_" + file.Filename
-	filepath := "./uploads/" + filename
-
 	logger.Info("Saving uploaded photo",
 		zap.String("candidate_id", id.String()),
-		zap.String("filename", filename),
+		zap.String("filename", file.Filename),
 		zap.Int64("size", file.Size),
 	)
 
-	// 保存文件
-	if err := c.SaveUploadedFile(file, filepath); err != nil {
-		logger.Error("Failed to save uploaded file",
+	// 校验大小和类型后保存文件
+	photoURL, err := savePhotoFile(h.uploads, file)
+	if err != nil {
+		logger.Warn("Failed to save uploaded file",
 			zap.String("candidate_id", id.String()),
-			zap.String("filepath", filepath),
+			zap.String("filename", file.Filename),
 			zap.Error(err),
 		)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeServiceError(c, err)
 		return
 	}
 
-	// 更新候选人照片URL
-	photoURL := "/uploads/" + filename
-	if err := store.Candidates.UpdatePhoto(id, userID, photoURL); err != nil {
+	// 记录照片并设为头像（同步候选人照片URL）
+	if _, err := h.photos.ReplaceAvatar(id, userID, photoURL); err != nil {
+		h.uploads.Remove(photoURL)
 		logger.Error("Failed to update candidate photo URL",
 			zap.String("candidate_id", id.String()),
 			zap.String("photo_url", photoURL),
 			zap.Error(err),
 		)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeServiceError(c, err)
 		return
 	}
 
@@ -322,3 +320,23 @@ func UploadCandidatePhoto(c *gin.Context) {
 	)
 	c.JSON(http.StatusOK, gin.H{"photo_url": photoURL})
 }
+
+// Tags 列出候选人使用的所有标签及每个标签的候选人数量
+// GET /api/candidates/tags
+func (h *CandidateHandler) Tags(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+
+	tags, err := h.service.Tags(userID)
+	if err != nil {
+		logger.Error("Failed to list candidate tags",
+			zap.String("user_id", userID.String()),
+			zap.Error(err),
+		)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tags)
+}
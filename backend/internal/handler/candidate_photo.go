@@ -1,10 +1,11 @@
 package handler
 
 import (
+	"mime/multipart"
 	"net/http"
 	"whotakesshowers/internal/logger"
-	"whotakesshowers/internal/middleware"
 	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/service"
 	"whotakesshowers/internal/store"
 
 	"github.com/gin-gonic/gin"
@@ -12,17 +13,34 @@ import (
 	"go.uber.org/zap"
 )
 
-// ListCandidatePhotos 获取候选人的所有照片
-// GET /api/candidates/:id/photos
-func ListCandidatePhotos(c *gin.Context) {
-	userIDStr, exists := middleware.GetUserID(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未认证"})
-		return
-	}
-	userID, err := uuid.Parse(userIDStr)
+// CandidatePhotoHandler 候选人照片处理器
+type CandidatePhotoHandler struct {
+	candidates store.CandidateRepository
+	photos     store.CandidatePhotoRepository
+	service    *service.CandidatePhotoService
+	uploads    *service.PhotoUploads
+}
+
+// NewCandidatePhotoHandler 创建候选人照片处理器
+func NewCandidatePhotoHandler(candidates store.CandidateRepository, photos store.CandidatePhotoRepository, service *service.CandidatePhotoService, uploads *service.PhotoUploads) *CandidatePhotoHandler {
+	return &CandidatePhotoHandler{candidates: candidates, photos: photos, service: service, uploads: uploads}
+}
+
+// savePhotoFile 校验上传照片的大小和类型后保存，返回照片 URL
+func savePhotoFile(uploads *service.PhotoUploads, file *multipart.FileHeader) (string, error) {
+	f, err := file.Open()
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return "", err
+	}
+	defer f.Close()
+	return uploads.Save(f)
+}
+
+// List 获取候选人的所有照片
+// GET /api/candidates/:id/photos
+func (h *CandidatePhotoHandler) List(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
 		return
 	}
 
@@ -37,7 +55,7 @@ func ListCandidatePhotos(c *gin.Context) {
 	}
 
 	// 检查候选人是否存在
-	_, err = store.Candidates.Get(id, userID)
+	_, err = h.candidates.Get(id, userID)
 	if err != nil {
 		logger.Warn("Candidate not found when listing photos",
 			zap.String("candidate_id", id.String()),
@@ -47,7 +65,7 @@ func ListCandidatePhotos(c *gin.Context) {
 		return
 	}
 
-	photos, err := store.CandidatePhotos.List(id)
+	photos, err := h.photos.List(id)
 	if err != nil {
 		logger.Error("Failed to list candidate photos",
 			zap.String("candidate_id", id.String()),
@@ -64,17 +82,11 @@ func ListCandidatePhotos(c *gin.Context) {
 	c.JSON(http.StatusOK, photos)
 }
 
-// UploadCandidatePhotos 上传候选人的多张照片
+// Upload 上传候选人的多张照片
 // POST /api/candidates/:id/photos
-func UploadCandidatePhotos(c *gin.Context) {
-	userIDStr, exists := middleware.GetUserID(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未认证"})
-		return
-	}
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+func (h *CandidatePhotoHandler) Upload(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
 		return
 	}
 
@@ -89,7 +101,7 @@ func UploadCandidatePhotos(c *gin.Context) {
 	}
 
 	// 检查候选人是否存在
-	candidate, err := store.Candidates.Get(id, userID)
+	_, err = h.candidates.Get(id, userID)
 	if err != nil {
 		logger.Warn("Candidate not found for photo upload",
 			zap.String("candidate_id", id.String()),
@@ -124,26 +136,27 @@ func UploadCandidatePhotos(c *gin.Context) {
 		zap.Int("file_count", len(files)),
 	)
 
-	// 保存所有文件并创建照片记录
+	// 保存所有文件并创建照片记录；任何一张不合格时删除已保存的文件
 	photos := make([]model.CandidatePhoto, 0, len(files))
+	removeSaved := func() {
+		for _, photo := range photos {
+			h.uploads.Remove(photo.PhotoURL)
+		}
+	}
 	for _, file := range files {
-		// 生成文件名
-		filename := uuid.New().String() + "_" + file.Filename
-		filepath := "./uploads/" + filename
-
-		// 保存文件
-		if err := c.SaveUploadedFile(file, filepath); err != nil {
-			logger.Error("Failed to save uploaded photo",
+		photoURL, err := savePhotoFile(h.uploads, file)
+		if err != nil {
+			logger.Warn("Failed to save uploaded photo",
 				zap.String("candidate_id", id.String()),
-				zap.String("filename", filename),
+				zap.String("filename", file.Filename),
 				zap.Error(err),
 			)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			removeSaved()
+			writeServiceError(c, err)
 			return
 		}
 
 		// 创建照片记录
-		photoURL := "/uploads/" + filename
 		photos = append(photos, model.CandidatePhoto{
 			CandidateID: id,
 			PhotoURL:    photoURL,
@@ -152,47 +165,29 @@ func UploadCandidatePhotos(c *gin.Context) {
 
 		logger.Debug("Saved photo file",
 			zap.String("candidate_id", id.String()),
-			zap.String("filename", filename),
+			zap.String("photo_url", photoURL),
 			zap.Int64("size", file.Size),
 		)
 	}
 
-	// 批量保存照片记录
-	if err := store.CandidatePhotos.CreateBatch(photos); err != nil {
+	// 在事务中保存照片记录（候选人没有头像时第一张自动成为头像）
+	saved, err := h.service.AddPhotos(id, userID, photos)
+	if err != nil {
+		removeSaved()
 		logger.Error("Failed to create photo records",
 			zap.String("candidate_id", id.String()),
-			zap.Int("photo_count", len(photos)),
+			zap.Int("photo_count", len(files)),
 			zap.Error(err),
 		)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeServiceError(c, err)
 		return
 	}
 
-	// 如果候选人还没有头像，设置第一张照片为头像
-	if candidate.PhotoURL == "" && len(photos) > 0 {
-		avatarID := photos[0].ID
-		if err := store.CandidatePhotos.SetAvatar(id, avatarID); err == nil {
-			// 更新候选人的头像URL
-			if err := store.Candidates.UpdatePhoto(id, userID, photos[0].PhotoURL); err != nil {
-				logger.Error("Failed to update candidate avatar URL",
-					zap.String("candidate_id", id.String()),
-					zap.String("photo_url", photos[0].PhotoURL),
-					zap.Error(err),
-				)
-			} else {
-				logger.Info("Set first photo as avatar",
-					zap.String("candidate_id", id.String()),
-					zap.String("photo_url", photos[0].PhotoURL),
-				)
-			}
-		}
-	}
-
 	logger.Info("Photos uploaded successfully",
 		zap.String("candidate_id", id.String()),
-		zap.Int("count", len(photos)),
+		zap.Int("count", len(saved)),
 	)
-	c.JSON(http.StatusCreated, photos)
+	c.JSON(http.StatusCreated, saved)
 }
 
 // SetAvatarRequest 设置头像请求
@@ -200,17 +195,11 @@ type SetAvatarRequest struct {
 	PhotoID string `json:"photo_id" binding:"required"`
 }
 
-// SetCandidateAvatar 设置候选人的头像
+// SetAvatar 设置候选人的头像
 // PUT /api/candidates/:id/avatar
-func SetCandidateAvatar(c *gin.Context) {
-	userIDStr, exists := middleware.GetUserID(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未认证"})
-		return
-	}
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+func (h *CandidatePhotoHandler) SetAvatar(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
 		return
 	}
 
@@ -225,7 +214,7 @@ func SetCandidateAvatar(c *gin.Context) {
 	}
 
 	// 检查候选人是否存在
-	_, err = store.Candidates.Get(id, userID)
+	_, err = h.candidates.Get(id, userID)
 	if err != nil {
 		logger.Warn("Candidate not found for setting avatar",
 			zap.String("candidate_id", id.String()),
@@ -255,36 +244,15 @@ func SetCandidateAvatar(c *gin.Context) {
 		return
 	}
 
-	// 设置头像
-	if err := store.CandidatePhotos.SetAvatar(id, photoID); err != nil {
+	// 设置头像并同步候选人的photo_url
+	photo, err := h.service.SetAvatar(id, userID, photoID)
+	if err != nil {
 		logger.Error("Failed to set avatar",
 			zap.String("candidate_id", id.String()),
 			zap.String("photo_id", photoID.String()),
 			zap.Error(err),
 		)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	// 获取照片信息并更新候选人的photo_url
-	photo, err := store.CandidatePhotos.GetAvatar(id)
-	if err != nil {
-		logger.Error("Failed to get avatar photo",
-			zap.String("candidate_id", id.String()),
-			zap.Error(err),
-		)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	// 更新候选人的头像URL
-	if err := store.Candidates.UpdatePhoto(id, userID, photo.PhotoURL); err != nil {
-		logger.Error("Failed to update candidate photo URL",
-			zap.String("candidate_id", id.String()),
-			zap.String("photo_url", photo.PhotoURL),
-			zap.Error(err),
-		)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeServiceError(c, err)
 		return
 	}
 
@@ -295,17 +263,11 @@ func SetCandidateAvatar(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"photo_url": photo.PhotoURL})
 }
 
-// DeleteCandidatePhoto 删除候选人的照片
+// Delete 删除候选人的照片
 // DELETE /api/candidates/:id/photos/:photo_id
-func DeleteCandidatePhoto(c *gin.Context) {
-	userIDStr, exists := middleware.GetUserID(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未认证"})
-		return
-	}
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+func (h *CandidatePhotoHandler) Delete(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
 		return
 	}
 
@@ -330,7 +292,7 @@ func DeleteCandidatePhoto(c *gin.Context) {
 	}
 
 	// 检查候选人是否存在
-	_, err = store.Candidates.Get(id, userID)
+	_, err = h.candidates.Get(id, userID)
 	if err != nil {
 		logger.Warn("Candidate not found for photo deletion",
 			zap.String("candidate_id", id.String()),
@@ -340,52 +302,18 @@ func DeleteCandidatePhoto(c *gin.Context) {
 		return
 	}
 
-	// 检查要删除的照片是否是当前头像
-	photoToDelete, err := store.CandidatePhotos.Get(photoID, id)
+	// 删除照片；如果删除的是头像，最新的剩余照片自动成为新头像
+	deleted, err := h.service.DeletePhoto(id, userID, photoID)
 	if err != nil {
-		logger.Warn("Photo not found for deletion",
-			zap.String("photo_id", photoID.String()),
-			zap.String("candidate_id", id.String()),
-		)
-		c.JSON(http.StatusNotFound, gin.H{"error": "photo not found"})
-		return
-	}
-
-	isAvatar := photoToDelete.IsAvatar
-
-	// 删除照片
-	if err := store.CandidatePhotos.Delete(photoID); err != nil {
 		logger.Error("Failed to delete photo",
 			zap.String("candidate_id", id.String()),
 			zap.String("photo_id", photoID.String()),
 			zap.Error(err),
 		)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeServiceError(c, err)
 		return
 	}
-
-	// 如果删除的是头像，需要更新候选人的photo_url
-	if isAvatar {
-		// 尝试获取新的头像（其他照片中的第一张）
-		avatarPhoto, err := store.CandidatePhotos.GetAvatar(id)
-		if err == nil {
-			// 找到了新的头像，更新Candidate.PhotoURL
-			if err := store.Candidates.UpdatePhoto(id, userID, avatarPhoto.PhotoURL); err != nil {
-				logger.Warn("Failed to update candidate photo URL after avatar deletion",
-					zap.String("candidate_id", id.String()),
-					zap.Error(err),
-				)
-			}
-		} else {
-			// 没有其他照片了，清空Candidate.PhotoURL
-			if err := store.Candidates.UpdatePhoto(id, userID, ""); err != nil {
-				logger.Warn("Failed to clear candidate photo URL after avatar deletion",
-					zap.String("candidate_id", id.String()),
-					zap.Error(err),
-				)
-			}
-		}
-	}
+	isAvatar := deleted.IsAvatar
 
 	logger.Info("Photo deleted successfully",
 		zap.String("candidate_id", id.String()),
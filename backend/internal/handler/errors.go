@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"whotakesshowers/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// writeServiceError 将服务层的错误映射为HTTP响应
+func writeServiceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrProjectNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+	case errors.Is(err, service.ErrCandidateNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "candidate not found"})
+	case errors.Is(err, service.ErrPhotoNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "photo not found"})
+	case errors.Is(err, service.ErrHistoryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "history entry not found"})
+	case errors.Is(err, service.ErrRewardNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "reward not found"})
+	case errors.Is(err, service.ErrBadgeRuleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "badge rule not found"})
+	case errors.Is(err, service.ErrRoutineNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "routine not found"})
+	case errors.Is(err, service.ErrRoutineRunNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "routine run not found"})
+	case errors.Is(err, service.ErrProjectConstraintNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "project constraint not found"})
+	case errors.Is(err, service.ErrPhotoTooLarge), errors.Is(err, service.ErrPhotoType):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"whotakesshowers/internal/logger"
+	"whotakesshowers/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ExportHandler 数据导出与导入处理器
+type ExportHandler struct {
+	service *service.ExportService
+}
+
+// NewExportHandler 创建数据导出与导入处理器
+func NewExportHandler(service *service.ExportService) *ExportHandler {
+	return &ExportHandler{service: service}
+}
+
+// Export 导出当前用户的全部数据为 zip 归档
+// GET /api/export
+func (h *ExportHandler) Export(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+
+	export, err := h.service.Export(userID)
+	if err != nil {
+		logger.Error("Failed to export user data",
+			zap.String("user_id", userID.String()),
+			zap.Error(err),
+		)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("whotakesshowers-export-%s.zip", time.Now().Format("20060102-150405"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+	if _, err := export.WriteTo(c.Writer); err != nil {
+		// 响应已经开始发送，只能记录错误
+		logger.Error("Failed to write export archive",
+			zap.String("user_id", userID.String()),
+			zap.Error(err),
+		)
+		return
+	}
+
+	logger.Info("User data exported",
+		zap.String("user_id", userID.String()),
+		zap.Int("candidates", len(export.Document.Candidates)),
+		zap.Int("projects", len(export.Document.Projects)),
+		zap.Int("histories", len(export.Document.Histories)),
+		zap.Int("splits", len(export.Document.Splits)),
+	)
+}
+
+// Import 将导出归档导入当前用户的账号
+// POST /api/import?mode=merge|replace&dry_run=true，表单字段 file 为导出的 zip 归档
+func (h *ExportHandler) Import(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+
+	mode := service.ImportMode(c.DefaultQuery("mode", string(service.ImportMerge)))
+	dryRun := false
+	if dryRunStr := c.Query("dry_run"); dryRunStr != "" {
+		var err error
+		dryRun, err = strconv.ParseBool(dryRunStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dry_run"})
+			return
+		}
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no file uploaded"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	report, err := h.service.Import(userID, file, fileHeader.Size, mode, dryRun)
+	if errors.Is(err, service.ErrInvalidExport) || errors.Is(err, service.ErrInvalidImportMode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to import user data",
+			zap.String("user_id", userID.String()),
+			zap.Error(err),
+		)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("User data imported",
+		zap.String("user_id", userID.String()),
+		zap.String("mode", string(report.Mode)),
+		zap.Bool("dry_run", report.DryRun),
+		zap.Int("candidates", report.Candidates.Created),
+		zap.Int("projects", report.Projects.Created),
+		zap.Int("histories", report.Histories.Created),
+		zap.Int("splits", report.Splits.Created),
+	)
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,239 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportedHistory 导入后的历史记录中与导出相关的字段
+type exportedHistory struct {
+	ID            string `json:"id"`
+	CandidateName string `json:"candidate_name"`
+	PickPoints    int    `json:"pick_points"`
+	OnTimePoints  int    `json:"on_time_points"`
+	TargetMinutes *int   `json:"target_minutes"`
+
+	PoolCandidateIDs string `json:"pool_candidate_ids"`
+	TagQuery         string `json:"tag_query"`
+
+	Overridden     bool   `json:"overridden"`
+	OverrideReason string `json:"override_reason"`
+
+	SupersededByID *string `json:"superseded_by_id"`
+	RerollOfID     *string `json:"reroll_of_id"`
+}
+
+// importArchive 把 archive 导入到 token 的账号
+func (s *testServer) importArchive(token string, archive []byte) {
+	s.t.Helper()
+	rec := s.upload("/api/import", token, uploadFile{"file", "export.zip", archive})
+	if rec.Code != http.StatusOK {
+		s.t.Fatalf("import = %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestExportImportPoints(t *testing.T) {
+	t.Chdir(t.TempDir())
+	s := newTestServer(t)
+	token := s.register("alice")
+	ann := s.createCandidate(token, "Ann")
+	var project struct {
+		ID string `json:"id"`
+	}
+	s.expect(http.StatusCreated, "POST", "/api/projects", token, gin.H{
+		"name": "Shower", "candidate_ids": []string{ann}, "target_minutes": 15, "pick_points": 5, "on_time_points": 3,
+	}, &project)
+	var draw struct {
+		HistoryID string `json:"history_id"`
+	}
+	s.expect(http.StatusOK, "POST", "/api/randomize", token, gin.H{"project_id": project.ID}, &draw)
+	s.expect(http.StatusOK, "POST", "/api/history/"+draw.HistoryID+"/complete", token, nil, nil)
+
+	// 之后修改项目不影响已有记录的快照
+	s.expect(http.StatusOK, "PUT", "/api/projects/"+project.ID, token, gin.H{"target_minutes": 1, "pick_points": 1}, nil)
+
+	archive := s.expect(http.StatusOK, "GET", "/api/export", token, nil, nil).Body.Bytes()
+	other := s.register("bob")
+	s.importArchive(other, archive)
+
+	var projects struct {
+		Items []struct {
+			PickPoints   int `json:"pick_points"`
+			OnTimePoints int `json:"on_time_points"`
+		} `json:"items"`
+	}
+	s.expect(http.StatusOK, "GET", "/api/projects", other, nil, &projects)
+	if len(projects.Items) != 1 || projects.Items[0].PickPoints != 1 || projects.Items[0].OnTimePoints != 3 {
+		t.Errorf("imported projects = %+v, want pick_points 1 and on_time_points 3", projects.Items)
+	}
+
+	var history struct {
+		Items []exportedHistory `json:"items"`
+	}
+	s.expect(http.StatusOK, "GET", "/api/history", other, nil, &history)
+	if len(history.Items) != 1 {
+		t.Fatalf("imported history = %+v", history.Items)
+	}
+	got := history.Items[0]
+	if got.PickPoints != 5 || got.OnTimePoints != 3 || got.TargetMinutes == nil || *got.TargetMinutes != 15 {
+		t.Errorf("imported history = %+v, want the snapshot 5/3 points and 15 minutes", got)
+	}
+
+	// 导入的记录按快照记入积分
+	var board []struct {
+		CandidateName string `json:"candidate_name"`
+		Points        int    `json:"points"`
+	}
+	s.expect(http.StatusOK, "GET", "/api/points/leaderboard", other, nil, &board)
+	if len(board) != 1 || board[0].Points != 8 {
+		t.Errorf("leaderboard after import = %+v, want 8 points", board)
+	}
+
+	// 重复导入不重复记入积分
+	s.importArchive(other, archive)
+	s.expect(http.StatusOK, "GET", "/api/points/leaderboard", other, nil, &board)
+	if len(board) != 1 || board[0].Points != 8 {
+		t.Errorf("leaderboard after a second import = %+v, want 8 points", board)
+	}
+}
+
+func TestExportImportPoolSnapshot(t *testing.T) {
+	t.Chdir(t.TempDir())
+	s := newTestServer(t)
+	token := s.register("alice")
+	for _, name := range []string{"Ann", "Ben"} {
+		s.expect(http.StatusCreated, "POST", "/api/candidates", token, gin.H{"name": name, "tags": []string{"kids"}}, nil)
+	}
+	var project struct {
+		ID string `json:"id"`
+	}
+	s.expect(http.StatusCreated, "POST", "/api/projects", token, gin.H{"name": "Shower", "candidate_ids": []string{}, "tag_query": "kids"}, &project)
+	s.expect(http.StatusOK, "POST", "/api/randomize", token, gin.H{"project_id": project.ID}, nil)
+
+	archive := s.expect(http.StatusOK, "GET", "/api/export", token, nil, nil).Body.Bytes()
+	other := s.register("bob")
+	s.importArchive(other, archive)
+
+	var candidates struct {
+		Items []struct {
+			ID string `json:"id"`
+		} `json:"items"`
+	}
+	s.expect(http.StatusOK, "GET", "/api/candidates", other, nil, &candidates)
+	var want []string
+	for _, candidate := range candidates.Items {
+		want = append(want, candidate.ID)
+	}
+	var history struct {
+		Items []exportedHistory `json:"items"`
+	}
+	s.expect(http.StatusOK, "GET", "/api/history", other, nil, &history)
+	if len(history.Items) != 1 {
+		t.Fatalf("imported history = %+v", history.Items)
+	}
+	var pool []string
+	if err := json.Unmarshal([]byte(history.Items[0].PoolCandidateIDs), &pool); err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(pool)
+	slices.Sort(want)
+	if !slices.Equal(pool, want) || history.Items[0].TagQuery != "kids" {
+		t.Errorf("imported snapshot = %v %q, want the imported candidates %v and query kids", pool, history.Items[0].TagQuery, want)
+	}
+}
+
+func TestExportImportOverride(t *testing.T) {
+	t.Chdir(t.TempDir())
+	s := newTestServer(t)
+	token := s.register("alice")
+	ann := s.createCandidate(token, "Ann")
+	ben := s.createCandidate(token, "Ben")
+	today := time.Now().Format("2006-01-02")
+	var project struct {
+		ID string `json:"id"`
+	}
+	s.expect(http.StatusCreated, "POST", "/api/projects", token, gin.H{
+		"name": "Shower", "candidate_ids": []string{ann, ben},
+		"draw_overrides": []gin.H{{"type": "dates", "action": "force", "candidate_id": ann, "from": today, "to": today, "message": "Ann's turn"}},
+	}, &project)
+	s.expect(http.StatusOK, "POST", "/api/randomize", token, gin.H{"project_id": project.ID}, nil)
+
+	archive := s.expect(http.StatusOK, "GET", "/api/export", token, nil, nil).Body.Bytes()
+	other := s.register("bob")
+	s.importArchive(other, archive)
+
+	var history struct {
+		Items []exportedHistory `json:"items"`
+	}
+	s.expect(http.StatusOK, "GET", "/api/history", other, nil, &history)
+	if len(history.Items) != 1 {
+		t.Fatalf("imported history = %+v", history.Items)
+	}
+	if got := history.Items[0]; !got.Overridden || got.OverrideReason != "Ann's turn" || got.CandidateName != "Ann" {
+		t.Errorf("imported history = %+v, want the draw overridden for Ann", got)
+	}
+}
+
+func TestExportImportReroll(t *testing.T) {
+	t.Chdir(t.TempDir())
+	s := newTestServer(t)
+	token := s.register("alice")
+	ann := s.createCandidate(token, "Ann")
+	ben := s.createCandidate(token, "Ben")
+	var project struct {
+		ID string `json:"id"`
+	}
+	s.expect(http.StatusCreated, "POST", "/api/projects", token, gin.H{
+		"name": "Shower", "candidate_ids": []string{ann, ben}, "reroll_tokens": 1,
+	}, &project)
+	var draw struct {
+		HistoryID string `json:"history_id"`
+	}
+	s.expect(http.StatusOK, "POST", "/api/randomize", token, gin.H{"project_id": project.ID}, &draw)
+	s.expect(http.StatusOK, "POST", "/api/history/"+draw.HistoryID+"/reroll", token, nil, nil)
+
+	archive := s.expect(http.StatusOK, "GET", "/api/export", token, nil, nil).Body.Bytes()
+	other := s.register("bob")
+	s.importArchive(other, archive)
+	// 重复合并导入时跳过已有记录，关联保持不变
+	s.importArchive(other, archive)
+
+	var projects struct {
+		Items []struct {
+			RerollTokens int `json:"reroll_tokens"`
+		} `json:"items"`
+	}
+	s.expect(http.StatusOK, "GET", "/api/projects", other, nil, &projects)
+	if len(projects.Items) != 1 || projects.Items[0].RerollTokens != 1 {
+		t.Errorf("imported projects = %+v, want reroll_tokens 1", projects.Items)
+	}
+
+	var history struct {
+		Items []exportedHistory `json:"items"`
+	}
+	s.expect(http.StatusOK, "GET", "/api/history", other, nil, &history)
+	if len(history.Items) != 2 {
+		t.Fatalf("imported history = %+v", history.Items)
+	}
+	var original, rerolled exportedHistory
+	for _, item := range history.Items {
+		if item.SupersededByID != nil {
+			original = item
+		}
+		if item.RerollOfID != nil {
+			rerolled = item
+		}
+	}
+	if original.ID == "" || rerolled.ID == "" {
+		t.Fatalf("imported history = %+v, want the reroll links", history.Items)
+	}
+	if *original.SupersededByID != rerolled.ID || *rerolled.RerollOfID != original.ID {
+		t.Errorf("superseded_by_id = %s, reroll_of_id = %s, want %s and %s",
+			*original.SupersededByID, *rerolled.RerollOfID, rerolled.ID, original.ID)
+	}
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"net/http"
+	"whotakesshowers/internal/middleware"
+	"whotakesshowers/internal/service"
+	"whotakesshowers/internal/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Handlers 所有 HTTP 处理器
+type Handlers struct {
+	Auth            *AuthHandler
+	Projects        *ProjectHandler
+	Candidates      *CandidateHandler
+	CandidatePhotos *CandidatePhotoHandler
+	Histories       *HistoryHandler
+	Trash           *TrashHandler
+	Export          *ExportHandler
+	Accounts        *AccountHandler
+	Stats           *StatsHandler
+	HistoryExport   *HistoryExportHandler
+	Points          *PointsHandler
+	Badges          *BadgeHandler
+	Routines        *RoutineHandler
+	Constraints     *ProjectConstraintHandler
+
+	// Backups 备份管理处理器，未启用备份时为 nil
+	Backups *BackupHandler
+
+	users       store.UserRepository        // 认证中间件用于确认 token 对应的用户仍然存在
+	idempotency *service.IdempotencyService // 幂等键中间件用于保存和返回写请求的响应
+}
+
+// New 基于数据存储和服务创建所有处理器
+func New(s store.Store, services *service.Services) *Handlers {
+	return &Handlers{
+		Auth:            NewAuthHandler(services.Users),
+		Projects:        NewProjectHandler(s.Projects(), services.Projects),
+		Candidates:      NewCandidateHandler(s.Candidates(), services.Candidates, services.CandidatePhotos, services.Uploads),
+		CandidatePhotos: NewCandidatePhotoHandler(s.Candidates(), s.CandidatePhotos(), services.CandidatePhotos, services.Uploads),
+		Histories:       NewHistoryHandler(s.Histories(), services.Randomizer, services.Histories),
+		Trash:           NewTrashHandler(services.Trash),
+		Export:          NewExportHandler(services.Export),
+		Accounts:        NewAccountHandler(services.Accounts),
+		Stats:           NewStatsHandler(services.Stats),
+		HistoryExport:   NewHistoryExportHandler(services.HistoryExport),
+		Points:          NewPointsHandler(services.Points),
+		Badges:          NewBadgeHandler(services.Badges),
+		Routines:        NewRoutineHandler(services.Routines),
+		Constraints:     NewProjectConstraintHandler(services.Constraints),
+		users:           s.Users(),
+		idempotency:     services.Idempotency,
+	}
+}
+
+// requestUserID 解析当前用户 ID，失败时写入错误响应
+func requestUserID(c *gin.Context) (uuid.UUID, bool) {
+	userIDStr, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未认证"})
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return uuid.Nil, false
+	}
+	return userID, true
+}
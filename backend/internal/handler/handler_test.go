@@ -0,0 +1,313 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"whotakesshowers/internal/app"
+	"whotakesshowers/internal/config"
+	"whotakesshowers/internal/store/memory"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testServer 基于内存存储的完整 API，路由与 cmd/server 一致挂载在 /api 下
+type testServer struct {
+	t      *testing.T
+	app    *app.App
+	router *gin.Engine
+}
+
+// newTestServer 创建使用默认配置和内存存储的 API
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	a := app.New(config.Default(), memory.New())
+	router := gin.New()
+	a.RegisterRoutes(router.Group("/api"))
+	return &testServer{t: t, app: a, router: router}
+}
+
+// request 发送请求，body 不为 nil 时编码为 JSON；token 为空时不带认证
+func (s *testServer) request(method, path, token string, body any, headers ...string) *httptest.ResponseRecorder {
+	s.t.Helper()
+	var reader bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reader).Encode(body); err != nil {
+			s.t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// expect 发送请求并检查状态码，把响应体解码到 out（out 为 nil 时不解码）
+func (s *testServer) expect(status int, method, path, token string, body any, out any) *httptest.ResponseRecorder {
+	s.t.Helper()
+	rec := s.request(method, path, token, body)
+	if rec.Code != status {
+		s.t.Fatalf("%s %s = %d %s, want %d", method, path, rec.Code, rec.Body.String(), status)
+	}
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			s.t.Fatalf("%s %s: decode %s: %v", method, path, rec.Body.String(), err)
+		}
+	}
+	return rec
+}
+
+// register 注册用户并返回令牌
+func (s *testServer) register(username string) string {
+	s.t.Helper()
+	var auth struct {
+		Token string `json:"token"`
+	}
+	s.expect(http.StatusOK, "POST", "/api/auth/register", "", gin.H{
+		"username": username, "email": username + "@example.com", "password": "secret1",
+	}, &auth)
+	if auth.Token == "" {
+		s.t.Fatal("register returned no token")
+	}
+	return auth.Token
+}
+
+// createCandidate 创建候选人并返回其 ID
+func (s *testServer) createCandidate(token, name string) string {
+	s.t.Helper()
+	var candidate struct {
+		ID string `json:"id"`
+	}
+	s.expect(http.StatusCreated, "POST", "/api/candidates", token, gin.H{"name": name}, &candidate)
+	return candidate.ID
+}
+
+// createProject 创建项目并返回其 ID
+func (s *testServer) createProject(token, name string, candidateIDs ...string) string {
+	s.t.Helper()
+	var project struct {
+		ID string `json:"id"`
+	}
+	s.expect(http.StatusCreated, "POST", "/api/projects", token, gin.H{"name": name, "candidate_ids": candidateIDs}, &project)
+	return project.ID
+}
+
+func TestAuth(t *testing.T) {
+	s := newTestServer(t)
+	token := s.register("alice")
+
+	var me struct {
+		Username string `json:"username"`
+	}
+	s.expect(http.StatusOK, "GET", "/api/auth/me", token, nil, &me)
+	if me.Username != "alice" {
+		t.Errorf("me = %+v, want alice", me)
+	}
+
+	s.expect(http.StatusOK, "POST", "/api/auth/login", "", gin.H{"username": "alice", "password": "secret1"}, nil)
+	s.expect(http.StatusUnauthorized, "POST", "/api/auth/login", "", gin.H{"username": "alice", "password": "wrong!"}, nil)
+	s.expect(http.StatusBadRequest, "POST", "/api/auth/register", "", gin.H{
+		"username": "alice", "email": "other@example.com", "password": "secret1",
+	}, nil)
+	s.expect(http.StatusBadRequest, "POST", "/api/auth/register", "", gin.H{"username": "al"}, nil)
+
+	s.expect(http.StatusUnauthorized, "GET", "/api/projects", "", nil, nil)
+	s.expect(http.StatusUnauthorized, "GET", "/api/projects", "not-a-token", nil, nil)
+}
+
+func TestRandomizeRecordsHistory(t *testing.T) {
+	s := newTestServer(t)
+	token := s.register("alice")
+	ann := s.createCandidate(token, "Ann")
+	ben := s.createCandidate(token, "Ben")
+	project := s.createProject(token, "Shower", ann, ben)
+
+	var result struct {
+		CandidateID string `json:"candidate_id"`
+	}
+	s.expect(http.StatusOK, "POST", "/api/randomize", token, gin.H{"project_id": project}, &result)
+	if result.CandidateID != ann && result.CandidateID != ben {
+		t.Fatalf("picked %q, want one of the project's candidates", result.CandidateID)
+	}
+
+	var history struct {
+		Items []struct {
+			ProjectID   string `json:"project_id"`
+			CandidateID string `json:"candidate_id"`
+			Source      string `json:"source"`
+			Status      string `json:"status"`
+		} `json:"items"`
+		Total int `json:"total"`
+	}
+	s.expect(http.StatusOK, "GET", "/api/history?project_id="+project, token, nil, &history)
+	if history.Total != 1 || history.Items[0].CandidateID != result.CandidateID || history.Items[0].Source != "draw" {
+		t.Errorf("history = %+v, want the draw for %s", history, result.CandidateID)
+	}
+
+	// 其他用户看不到该项目
+	other := s.register("bob")
+	s.expect(http.StatusNotFound, "POST", "/api/randomize", other, gin.H{"project_id": project}, nil)
+	s.expect(http.StatusNotFound, "GET", "/api/projects/"+project, other, nil, nil)
+}
+
+func TestCandidateListPagination(t *testing.T) {
+	s := newTestServer(t)
+	token := s.register("alice")
+	for _, name := range []string{"Ann", "Ben", "Cid", "Dee", "Eve"} {
+		s.createCandidate(token, name)
+	}
+
+	var names []string
+	cursor := ""
+	for pages := 0; pages < 10; pages++ {
+		var page struct {
+			Items []struct {
+				Name string `json:"name"`
+			} `json:"items"`
+			Total      int    `json:"total"`
+			NextCursor string `json:"next_cursor"`
+		}
+		s.expect(http.StatusOK, "GET", "/api/candidates?sort=name&limit=2&cursor="+cursor, token, nil, &page)
+		if page.Total != 5 {
+			t.Fatalf("total = %d, want 5", page.Total)
+		}
+		for _, item := range page.Items {
+			names = append(names, item.Name)
+		}
+		if cursor = page.NextCursor; cursor == "" {
+			break
+		}
+	}
+	if want := []string{"Ann", "Ben", "Cid", "Dee", "Eve"}; !slices.Equal(names, want) {
+		t.Errorf("names = %v, want %v", names, want)
+	}
+
+	s.expect(http.StatusBadRequest, "GET", "/api/candidates?sort=password", token, nil, nil)
+	s.expect(http.StatusBadRequest, "GET", "/api/candidates?cursor=garbage", token, nil, nil)
+}
+
+func TestTrashAndRestoreProject(t *testing.T) {
+	s := newTestServer(t)
+	token := s.register("alice")
+	ann := s.createCandidate(token, "Ann")
+	project := s.createProject(token, "Shower", ann)
+	s.expect(http.StatusOK, "POST", "/api/randomize", token, gin.H{"project_id": project}, nil)
+
+	s.expect(http.StatusNoContent, "DELETE", "/api/projects/"+project, token, nil, nil)
+	s.expect(http.StatusNotFound, "GET", "/api/projects/"+project, token, nil, nil)
+
+	// 回收站中项目的历史记录被隐藏，恢复后重新出现
+	var history struct {
+		Total int `json:"total"`
+	}
+	s.expect(http.StatusOK, "GET", "/api/history", token, nil, &history)
+	if history.Total != 0 {
+		t.Errorf("history with the project in the trash = %d, want 0", history.Total)
+	}
+	s.expect(http.StatusNoContent, "POST", "/api/trash/projects/"+project+"/restore", token, nil, nil)
+	s.expect(http.StatusOK, "GET", "/api/history", token, nil, &history)
+	if history.Total != 1 {
+		t.Errorf("history after restoring the project = %d, want 1", history.Total)
+	}
+}
+
+func TestIdempotentRandomize(t *testing.T) {
+	s := newTestServer(t)
+	token := s.register("alice")
+	ann := s.createCandidate(token, "Ann")
+	ben := s.createCandidate(token, "Ben")
+	project := s.createProject(token, "Shower", ann, ben)
+
+	body := gin.H{"project_id": project}
+	first := s.request("POST", "/api/randomize", token, body, "Idempotency-Key", "draw-1")
+	if first.Code != http.StatusOK {
+		t.Fatalf("first request = %d %s", first.Code, first.Body.String())
+	}
+	retry := s.request("POST", "/api/randomize", token, body, "Idempotency-Key", "draw-1")
+	if retry.Code != http.StatusOK || retry.Body.String() != first.Body.String() {
+		t.Errorf("retry = %d %s, want the stored response %s", retry.Code, retry.Body.String(), first.Body.String())
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("retry is not marked as replayed")
+	}
+
+	// 同一个键用于不同的请求体
+	other := s.request("POST", "/api/randomize", token, gin.H{"project_id": ann}, "Idempotency-Key", "draw-1")
+	if other.Code != http.StatusUnprocessableEntity {
+		t.Errorf("reused key with another body = %d %s, want 422", other.Code, other.Body.String())
+	}
+
+	var history struct {
+		Total int `json:"total"`
+	}
+	s.expect(http.StatusOK, "GET", "/api/history", token, nil, &history)
+	if history.Total != 1 {
+		t.Errorf("history entries = %d, want 1 (the retry must not draw again)", history.Total)
+	}
+}
+
+func TestIdempotentFeedDoesNotStoreToken(t *testing.T) {
+	s := newTestServer(t)
+	token := s.register("alice")
+
+	body := gin.H{"name": "Family"}
+	first := s.request("POST", "/api/history/feeds", token, body, "Idempotency-Key", "feed-1")
+	var created map[string]any
+	if err := json.Unmarshal(first.Body.Bytes(), &created); err != nil || first.Code != http.StatusCreated {
+		t.Fatalf("first request = %d %s", first.Code, first.Body.String())
+	}
+	if created["token"] == "" || created["url"] == "" {
+		t.Fatalf("first response %s has no token", first.Body.String())
+	}
+
+	retry := s.request("POST", "/api/history/feeds", token, body, "Idempotency-Key", "feed-1")
+	var replayed map[string]any
+	if err := json.Unmarshal(retry.Body.Bytes(), &replayed); err != nil || retry.Code != http.StatusCreated {
+		t.Fatalf("retry = %d %s", retry.Code, retry.Body.String())
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("retry is not marked as replayed")
+	}
+	if _, ok := replayed["token"]; ok {
+		t.Errorf("replayed response %s contains the token", retry.Body.String())
+	}
+	if _, ok := replayed["url"]; ok {
+		t.Errorf("replayed response %s contains the feed url", retry.Body.String())
+	}
+	if replayed["id"] != created["id"] {
+		t.Errorf("replayed feed %v, want %v", replayed["id"], created["id"])
+	}
+
+	var feeds []map[string]any
+	s.expect(http.StatusOK, "GET", "/api/history/feeds", token, nil, &feeds)
+	if len(feeds) != 1 {
+		t.Errorf("feeds = %d, want 1 (the retry must not create another feed)", len(feeds))
+	}
+}
+
+func TestIdempotencyKeyRejectedWithoutUser(t *testing.T) {
+	s := newTestServer(t)
+	rec := s.request("POST", "/api/auth/register", "", gin.H{
+		"username": "alice", "email": "alice@example.com", "password": "secret1",
+	}, "Idempotency-Key", "register-1")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("register with an idempotency key = %d %s, want 400", rec.Code, rec.Body.String())
+	}
+	rec = s.request("POST", "/api/auth/login", "", gin.H{"username": "alice", "password": "secret1"}, "Idempotency-Key", "login-1")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("login with an idempotency key = %d %s, want 400", rec.Code, rec.Body.String())
+	}
+}
@@ -1,9 +1,11 @@
 package handler
 
 import (
-	"fmt"
+	"errors"
+	"io"
 	"net/http"
-	"whotakesshowers/internal/middleware"
+	"time"
+	"whotakesshowers/internal/model"
 	"whotakesshowers/internal/service"
 	"whotakesshowers/internal/store"
 
@@ -11,57 +13,228 @@ import (
 	"github.com/google/uuid"
 )
 
-// ListHistory 获取历史记录
-// GET /api/history
-func ListHistory(c *gin.Context) {
-	userIDStr, exists := middleware.GetUserID(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未认证"})
+// HistoryHandler 历史记录与随机选择处理器
+type HistoryHandler struct {
+	histories  store.HistoryRepository
+	randomizer *service.RandomizeService
+	service    *service.HistoryService
+}
+
+// NewHistoryHandler 创建历史记录处理器
+func NewHistoryHandler(histories store.HistoryRepository, randomizer *service.RandomizeService, service *service.HistoryService) *HistoryHandler {
+	return &HistoryHandler{histories: histories, randomizer: randomizer, service: service}
+}
+
+// List 分页获取历史记录
+// GET /api/history?project_id=&candidate_id=&from=&to=&source=&voided=&sort=-selected_at&limit=20&cursor=
+func (h *HistoryHandler) List(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+
+	filter, err := parseHistoryFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := parsePageRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	userID, err := uuid.Parse(userIDStr)
+
+	histories, err := h.histories.Find(userID, filter, page)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		respondListError(c, err)
 		return
 	}
 
-	var projectID *uuid.UUID
-	if projectIDStr := c.Query("project_id"); projectIDStr != "" {
-		id, err := uuid.Parse(projectIDStr)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project_id"})
-			return
-		}
-		projectID = &id
+	c.JSON(http.StatusOK, histories)
+}
+
+// Get 获取单条历史记录
+// GET /api/history/:id
+func (h *HistoryHandler) Get(c *gin.Context) {
+	userID, historyID, ok := historyRequestIDs(c)
+	if !ok {
+		return
 	}
+	history, err := h.service.Get(userID, historyID)
+	if err != nil {
+		respondHistoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
 
-	limit := 20
-	if limitStr := c.Query("limit"); limitStr != "" {
-		if l, err := parseInt(limitStr); err == nil && l > 0 {
-			limit = l
-		}
+// Update 修改备注或完成状态
+// PATCH /api/history/:id
+func (h *HistoryHandler) Update(c *gin.Context) {
+	userID, historyID, ok := historyRequestIDs(c)
+	if !ok {
+		return
+	}
+	var req service.HistoryUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	histories, err := store.Histories.List(userID, projectID, limit)
+	history, err := h.service.Update(userID, historyID, req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondHistoryError(c, err)
 		return
 	}
+	c.JSON(http.StatusOK, history)
+}
 
-	c.JSON(http.StatusOK, histories)
+// VoidRequest 作废历史记录请求
+type VoidRequest struct {
+	Reason string `json:"reason" binding:"required"`
 }
 
-// Randomize 执行随机选择
-// POST /api/randomize
-func Randomize(c *gin.Context) {
-	userIDStr, exists := middleware.GetUserID(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未认证"})
+// Void 作废历史记录
+// POST /api/history/:id/void
+func (h *HistoryHandler) Void(c *gin.Context) {
+	userID, historyID, ok := historyRequestIDs(c)
+	if !ok {
+		return
+	}
+	var req VoidRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	history, err := h.service.Void(userID, historyID, req.Reason)
+	if err != nil {
+		respondHistoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
+
+// Unvoid 撤销作废
+// DELETE /api/history/:id/void
+func (h *HistoryHandler) Unvoid(c *gin.Context) {
+	userID, historyID, ok := historyRequestIDs(c)
+	if !ok {
 		return
 	}
-	userID, err := uuid.Parse(userIDStr)
+	history, err := h.service.Unvoid(userID, historyID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		respondHistoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
+
+// Changes 获取历史记录的修改记录
+// GET /api/history/:id/changes
+func (h *HistoryHandler) Changes(c *gin.Context) {
+	userID, historyID, ok := historyRequestIDs(c)
+	if !ok {
+		return
+	}
+	changes, err := h.service.Changes(userID, historyID)
+	if err != nil {
+		respondHistoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, changes)
+}
+
+// TaskRequest 任务状态变化请求，请求体可以为空
+type TaskRequest struct {
+	At *time.Time `json:"at"` // 补记状态变化的时间，为空时为当前时间
+}
+
+// Start 开始任务
+// POST /api/history/:id/start
+func (h *HistoryHandler) Start(c *gin.Context) {
+	h.taskAction(c, h.service.Start)
+}
+
+// Complete 完成任务
+// POST /api/history/:id/complete
+func (h *HistoryHandler) Complete(c *gin.Context) {
+	h.taskAction(c, h.service.Complete)
+}
+
+// Skip 跳过任务
+// POST /api/history/:id/skip
+func (h *HistoryHandler) Skip(c *gin.Context) {
+	h.taskAction(c, h.service.Skip)
+}
+
+// Reopen 撤销完成或跳过
+// POST /api/history/:id/reopen
+func (h *HistoryHandler) Reopen(c *gin.Context) {
+	userID, historyID, ok := historyRequestIDs(c)
+	if !ok {
+		return
+	}
+	history, err := h.service.Reopen(userID, historyID)
+	if err != nil {
+		respondHistoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
+
+// taskAction 解析任务状态变化请求并调用 fn
+func (h *HistoryHandler) taskAction(c *gin.Context, fn func(userID, historyID uuid.UUID, at *time.Time) (*model.History, error)) {
+	userID, historyID, ok := historyRequestIDs(c)
+	if !ok {
+		return
+	}
+	var req TaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	history, err := fn(userID, historyID, req.At)
+	if err != nil {
+		respondHistoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
+
+// historyRequestIDs 解析当前用户和路径中的历史记录 ID，失败时已写入响应
+func historyRequestIDs(c *gin.Context) (userID, historyID uuid.UUID, ok bool) {
+	if userID, ok = requestUserID(c); !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+	historyID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid history id"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, historyID, true
+}
+
+// respondHistoryError 返回历史记录修改错误，无效的修改为 400，与当前状态冲突为 409
+func respondHistoryError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidHistoryUpdate):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrHistoryVoided), errors.Is(err, service.ErrHistoryNotVoided),
+		errors.Is(err, service.ErrInvalidTaskTransition), errors.Is(err, service.ErrHistorySuperseded),
+		errors.Is(err, service.ErrHistoryNotRerollable), errors.Is(err, service.ErrNoRerollTokens):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		writeServiceError(c, err)
+	}
+}
+
+// Randomize 执行随机选择
+// POST /api/randomize
+func (h *HistoryHandler) Randomize(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
 		return
 	}
 
@@ -71,9 +244,14 @@ func Randomize(c *gin.Context) {
 		return
 	}
 
-	result, err := service.Randomizer.Execute(&req, userID)
+	result, err := h.randomizer.Execute(&req, userID)
+	var noEligible *service.NoEligibleCandidatesError
+	if errors.As(err, &noEligible) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "ineligible": noEligible.Ineligible})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeServiceError(c, err)
 		return
 	}
 
@@ -84,10 +262,3 @@ func Randomize(c *gin.Context) {
 
 	c.JSON(http.StatusOK, result)
 }
-
-// parseInt 辅助函数：解析整数
-func parseInt(s string) (int, error) {
-	var result int
-	_, err := fmt.Sscanf(s, "%d", &result)
-	return result, err
-}
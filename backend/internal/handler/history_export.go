@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"whotakesshowers/internal/logger"
+	"whotakesshowers/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// HistoryExportHandler 历史记录导出与日历订阅处理器
+type HistoryExportHandler struct {
+	service *service.HistoryExportService
+}
+
+// NewHistoryExportHandler 创建历史记录导出处理器
+func NewHistoryExportHandler(service *service.HistoryExportService) *HistoryExportHandler {
+	return &HistoryExportHandler{service: service}
+}
+
+// Export 导出历史记录，过滤和排序参数与列表接口相同
+// GET /api/history/export?format=csv|ndjson|ics&project_id=&candidate_id=&from=&to=&source=&sort=
+func (h *HistoryExportHandler) Export(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+
+	format, err := service.ParseHistoryFormat(c.Query("format"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter, err := parseHistoryFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	export, err := h.service.Export(userID, filter, c.Query("sort"), format)
+	if err != nil {
+		respondListError(c, err)
+		return
+	}
+
+	filename := fmt.Sprintf("whotakesshowers-history-%s.%s", time.Now().Format("20060102-150405"), format)
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	writeHistoryExport(c, export)
+}
+
+// Feed 日历应用拉取订阅，地址中的令牌代替登录
+// GET /api/calendar/:token（:token 可以带 .ics 后缀）
+func (h *HistoryExportHandler) Feed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+	export, err := h.service.Feed(token)
+	if errors.Is(err, service.ErrFeedNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "calendar feed not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	writeHistoryExport(c, export)
+}
+
+// CreateFeedRequest 创建日历订阅请求
+type CreateFeedRequest struct {
+	Name        string     `json:"name" binding:"max=100"`
+	ProjectID   *uuid.UUID `json:"project_id"`
+	CandidateID *uuid.UUID `json:"candidate_id"`
+}
+
+// FeedResponse 新建的日历订阅，url 为订阅地址
+type FeedResponse struct {
+	*service.CreatedFeed
+	URL string `json:"url"`
+}
+
+// ListFeeds 获取日历订阅列表（不包含令牌）
+// GET /api/history/feeds
+func (h *HistoryExportHandler) ListFeeds(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+
+	feeds, err := h.service.ListFeeds(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, feeds)
+}
+
+// CreateFeed 创建日历订阅，返回的订阅地址只显示一次
+// POST /api/history/feeds
+func (h *HistoryExportHandler) CreateFeed(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+
+	var req CreateFeedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	feed, err := h.service.CreateFeed(userID, req.Name, req.ProjectID, req.CandidateID)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, FeedResponse{
+		CreatedFeed: feed,
+		URL:         requestBaseURL(c) + "/api/calendar/" + feed.Token + ".ics",
+	})
+}
+
+// DeleteFeed 删除日历订阅
+// DELETE /api/history/feeds/:id
+func (h *HistoryExportHandler) DeleteFeed(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid feed id"})
+		return
+	}
+
+	err = h.service.DeleteFeed(userID, id)
+	if errors.Is(err, service.ErrFeedNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "calendar feed not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// writeHistoryExport 以流的方式写出历史记录
+func writeHistoryExport(c *gin.Context, export *service.HistoryExport) {
+	c.Header("Content-Type", export.Format.ContentType())
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+	if _, err := export.WriteTo(c.Writer); err != nil {
+		// 响应已经开始发送，只能记录错误
+		logger.Error("Failed to write history export",
+			zap.String("format", string(export.Format)),
+			zap.Error(err),
+		)
+	}
+}
+
+// requestBaseURL 返回客户端访问本服务使用的地址，考虑反向代理设置的请求头
+func requestBaseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	host := c.Request.Host
+	if forwarded := c.GetHeader("X-Forwarded-Host"); forwarded != "" {
+		host = forwarded
+	}
+	return scheme + "://" + host
+}
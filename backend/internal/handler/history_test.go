@@ -0,0 +1,65 @@
+package handler_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRerollRecordsSupersededChanges(t *testing.T) {
+	s := newTestServer(t)
+	token := s.register("alice")
+	ann := s.createCandidate(token, "Ann")
+	ben := s.createCandidate(token, "Ben")
+	var project struct {
+		ID string `json:"id"`
+	}
+	s.expect(http.StatusCreated, "POST", "/api/projects", token, gin.H{
+		"name": "Shower", "candidate_ids": []string{ann, ben}, "reroll_tokens": 1,
+	}, &project)
+
+	var draw struct {
+		HistoryID   string `json:"history_id"`
+		CandidateID string `json:"candidate_id"`
+	}
+	s.expect(http.StatusOK, "POST", "/api/randomize", token, gin.H{"project_id": project.ID}, &draw)
+	var history struct {
+		Items []struct {
+			ID string `json:"id"`
+		} `json:"items"`
+	}
+	s.expect(http.StatusOK, "GET", "/api/history", token, nil, &history)
+	if draw.HistoryID == "" || len(history.Items) != 1 || history.Items[0].ID != draw.HistoryID {
+		t.Fatalf("randomize history_id = %q, history = %+v", draw.HistoryID, history.Items)
+	}
+
+	var reroll struct {
+		HistoryID string `json:"history_id"`
+		History   struct {
+			ID string `json:"id"`
+		} `json:"history"`
+	}
+	s.expect(http.StatusOK, "POST", "/api/history/"+draw.HistoryID+"/reroll", token, nil, &reroll)
+	if reroll.HistoryID == "" || reroll.HistoryID != reroll.History.ID {
+		t.Errorf("reroll history_id = %q, want the new entry %q", reroll.HistoryID, reroll.History.ID)
+	}
+
+	// 原记录的修改记录包括指向新记录的字段
+	var changes []struct {
+		Field    string `json:"field"`
+		OldValue string `json:"old_value"`
+		NewValue string `json:"new_value"`
+	}
+	s.expect(http.StatusOK, "GET", "/api/history/"+draw.HistoryID+"/changes", token, nil, &changes)
+	fields := make(map[string]string)
+	for _, change := range changes {
+		fields[change.Field] = change.NewValue
+	}
+	if fields["superseded_by_id"] != reroll.History.ID {
+		t.Errorf("superseded_by_id change = %q, want %q (changes %+v)", fields["superseded_by_id"], reroll.History.ID, changes)
+	}
+	if fields["superseded_at"] == "" || fields["voided_at"] == "" || fields["void_reason"] == "" {
+		t.Errorf("changes = %+v, want superseded_at, voided_at and void_reason", changes)
+	}
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"whotakesshowers/internal/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// parsePageRequest 解析分页参数 limit、cursor、sort
+func parsePageRequest(c *gin.Context) (store.PageRequest, error) {
+	page := store.PageRequest{
+		Cursor: c.Query("cursor"),
+		Sort:   c.Query("sort"),
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			return page, fmt.Errorf("invalid limit %q", limitStr)
+		}
+		page.Limit = limit
+	}
+	return page, nil
+}
+
+// parseHistoryFilter 解析历史记录过滤参数 project_id、candidate_id、from、to、source、voided
+func parseHistoryFilter(c *gin.Context) (store.HistoryFilter, error) {
+	var filter store.HistoryFilter
+	var err error
+	if filter.ProjectID, err = parseUUIDQuery(c, "project_id"); err != nil {
+		return filter, err
+	}
+	if filter.CandidateID, err = parseUUIDQuery(c, "candidate_id"); err != nil {
+		return filter, err
+	}
+	if filter.From, err = parseTimeQuery(c, "from", false); err != nil {
+		return filter, err
+	}
+	if filter.To, err = parseTimeQuery(c, "to", true); err != nil {
+		return filter, err
+	}
+	filter.Source = c.Query("source")
+	if voided := c.Query("voided"); voided != "" {
+		v, err := strconv.ParseBool(voided)
+		if err != nil {
+			return filter, fmt.Errorf("invalid voided %q", voided)
+		}
+		filter.Voided = &v
+	}
+	return filter, nil
+}
+
+// parseUUIDQuery 解析可选的 UUID 查询参数
+func parseUUIDQuery(c *gin.Context, name string) (*uuid.UUID, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s", name)
+	}
+	return &id, nil
+}
+
+// parseTimeQuery 解析可选的时间查询参数，支持 RFC3339 和 YYYY-MM-DD（本地时区）
+// endOfDay 为 true 时只有日期的参数包含当天，即取次日零点作为上界
+func parseTimeQuery(c *gin.Context, name string, endOfDay bool) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		// 与数据库中的时间使用相同时区，SQLite 按文本比较时间
+		t = t.Local()
+		return &t, nil
+	}
+	t, err := time.ParseInLocation(time.DateOnly, value, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: use RFC3339 or YYYY-MM-DD", name)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
+// respondListError 返回列表查询错误，无效的游标或排序方式为 400
+func respondListError(c *gin.Context, err error) {
+	if errors.Is(err, store.ErrInvalidCursor) || errors.Is(err, store.ErrInvalidSort) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,197 @@
+package handler_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"whotakesshowers/internal/config"
+	"whotakesshowers/internal/service"
+
+	"github.com/google/uuid"
+)
+
+// pngHeader PNG 文件头，足以让内容检测识别为 image/png
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+// uploadFile 上传的文件
+type uploadFile struct {
+	field, name string
+	data        []byte
+}
+
+// upload 以 multipart 表单发送文件
+func (s *testServer) upload(path, token string, files ...uploadFile) *httptest.ResponseRecorder {
+	s.t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for _, file := range files {
+		w, err := form.CreateFormFile(file.field, file.name)
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		w.Write(file.data)
+	}
+	if err := form.Close(); err != nil {
+		s.t.Fatal(err)
+	}
+	req := httptest.NewRequest("POST", path, &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// decode 解码响应体
+func decode(t *testing.T, rec *httptest.ResponseRecorder, out any) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body.String(), err)
+	}
+}
+
+// uploadedFiles 返回上传目录中的文件名
+func uploadedFiles(t *testing.T) []string {
+	t.Helper()
+	entries, err := os.ReadDir(service.UploadDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestPhotoUploadValidation(t *testing.T) {
+	t.Chdir(t.TempDir())
+	s := newTestServer(t)
+	token := s.register("alice")
+	ann := s.createCandidate(token, "Ann")
+
+	html := []byte("<html><script>alert(1)</script></html>")
+	svg := []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`)
+	oversized := append(bytes.Clone(pngHeader), make([]byte, config.Default().Upload.MaxSize)...)
+	rejected := []struct {
+		name string
+		file uploadFile
+	}{
+		{"html avatar", uploadFile{"photo", "a.html", html}},
+		{"svg avatar", uploadFile{"photo", "a.svg", svg}},
+		{"html named as an image", uploadFile{"photo", "a.png", html}},
+		{"oversized avatar", uploadFile{"photo", "a.png", oversized}},
+	}
+	for _, tc := range rejected {
+		if rec := s.upload("/api/candidates/"+ann+"/photo", token, tc.file); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s = %d %s, want 400", tc.name, rec.Code, rec.Body.String())
+		}
+	}
+
+	// 图片使用内容对应的扩展名保存，不沿用上传的文件名
+	var avatar struct {
+		PhotoURL string `json:"photo_url"`
+	}
+	rec := s.upload("/api/candidates/"+ann+"/photo", token, uploadFile{"photo", "a.html", pngHeader})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("png avatar = %d %s", rec.Code, rec.Body.String())
+	}
+	decode(t, rec, &avatar)
+	if !strings.HasSuffix(avatar.PhotoURL, ".png") {
+		t.Errorf("photo url = %q, want a .png file", avatar.PhotoURL)
+	}
+
+	// 批量上传中有一张不合格时整批拒绝，已保存的文件被删除
+	rec = s.upload("/api/candidates/"+ann+"/photos", token,
+		uploadFile{"photos", "b.png", pngHeader},
+		uploadFile{"photos", "c.svg", svg},
+	)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("batch with an svg = %d %s, want 400", rec.Code, rec.Body.String())
+	}
+	if files := uploadedFiles(t); len(files) != 1 || "/uploads/"+files[0] != avatar.PhotoURL {
+		t.Errorf("uploaded files = %v, want only %s", files, avatar.PhotoURL)
+	}
+}
+
+// exportArchive 生成包含一个候选人和一张照片的导出归档
+func exportArchive(t *testing.T, photoName string, photo []byte) []byte {
+	t.Helper()
+	doc := service.ExportDocument{
+		Format:     service.ExportFormat,
+		Version:    service.ExportVersion,
+		ExportedAt: time.Now(),
+		Candidates: []service.ExportCandidate{{
+			ID:        uuid.New(),
+			Name:      "Ann",
+			CreatedAt: time.Now(),
+			Photos:    []service.ExportPhoto{{File: "photos/" + photoName, IsAvatar: true, CreatedAt: time.Now()}},
+		}},
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	w, err := archive.Create("export.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.NewEncoder(w).Encode(doc); err != nil {
+		t.Fatal(err)
+	}
+	if w, err = archive.Create("photos/" + photoName); err != nil {
+		t.Fatal(err)
+	}
+	w.Write(photo)
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestImportValidatesPhotos(t *testing.T) {
+	t.Chdir(t.TempDir())
+	s := newTestServer(t)
+	token := s.register("alice")
+
+	html := []byte("<html><script>alert(1)</script></html>")
+	oversized := append(bytes.Clone(pngHeader), make([]byte, config.Default().Upload.MaxSize)...)
+	for _, tc := range []struct {
+		name    string
+		archive []byte
+	}{
+		{"html photo", exportArchive(t, "a.html", html)},
+		{"html photo named as an image", exportArchive(t, "a.jpg", html)},
+		{"oversized photo", exportArchive(t, "a.png", oversized)},
+	} {
+		for _, query := range []string{"?dry_run=true", ""} {
+			rec := s.upload("/api/import"+query, token, uploadFile{"file", "export.zip", tc.archive})
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("import %s%s = %d %s, want 400", tc.name, query, rec.Code, rec.Body.String())
+			}
+		}
+	}
+	if files := uploadedFiles(t); len(files) != 0 {
+		t.Errorf("rejected imports left files %v", files)
+	}
+
+	// 合格的图片按内容类型确定扩展名
+	rec := s.upload("/api/import", token, uploadFile{"file", "export.zip", exportArchive(t, "a.html", pngHeader)})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("import png = %d %s", rec.Code, rec.Body.String())
+	}
+	files := uploadedFiles(t)
+	if len(files) != 1 || filepath.Ext(files[0]) != ".png" {
+		t.Errorf("uploaded files = %v, want one .png file", files)
+	}
+}
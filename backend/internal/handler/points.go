@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/service"
+	"whotakesshowers/internal/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// PointsHandler 积分、奖励与排行榜处理器
+type PointsHandler struct {
+	service *service.PointsService
+}
+
+// NewPointsHandler 创建积分处理器
+func NewPointsHandler(service *service.PointsService) *PointsHandler {
+	return &PointsHandler{service: service}
+}
+
+// ListRewards 获取奖励列表
+// GET /api/rewards
+func (h *PointsHandler) ListRewards(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+	rewards, err := h.service.ListRewards(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rewards)
+}
+
+// CreateReward 创建奖励
+// POST /api/rewards
+func (h *PointsHandler) CreateReward(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+	var req service.RewardInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reward, err := h.service.CreateReward(userID, req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, reward)
+}
+
+// UpdateReward 修改奖励
+// PUT /api/rewards/:id
+func (h *PointsHandler) UpdateReward(c *gin.Context) {
+	userID, rewardID, ok := rewardRequestIDs(c)
+	if !ok {
+		return
+	}
+	var req service.RewardInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reward, err := h.service.UpdateReward(userID, rewardID, req)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, reward)
+}
+
+// DeleteReward 删除奖励
+// DELETE /api/rewards/:id
+func (h *PointsHandler) DeleteReward(c *gin.Context) {
+	userID, rewardID, ok := rewardRequestIDs(c)
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteReward(userID, rewardID); err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RedeemRequest 兑换奖励请求
+type RedeemRequest struct {
+	CandidateID uuid.UUID `json:"candidate_id" binding:"required"`
+}
+
+// Redeem 为候选人兑换奖励，返回兑换的积分流水
+// POST /api/rewards/:id/redeem
+func (h *PointsHandler) Redeem(c *gin.Context) {
+	userID, rewardID, ok := rewardRequestIDs(c)
+	if !ok {
+		return
+	}
+	var req RedeemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry, err := h.service.Redeem(userID, rewardID, req.CandidateID)
+	if err != nil {
+		respondPointsError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, entry)
+}
+
+// AdjustPointsRequest 手动调整积分请求
+type AdjustPointsRequest struct {
+	Amount int    `json:"amount"`
+	Note   string `json:"note"`
+}
+
+// Adjust 手动增减候选人的积分，返回调整的积分流水
+// POST /api/candidates/:id/points
+func (h *PointsHandler) Adjust(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+	candidateID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid candidate id"})
+		return
+	}
+	var req AdjustPointsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry, err := h.service.Adjust(userID, candidateID, req.Amount, req.Note)
+	if err != nil {
+		respondPointsError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, entry)
+}
+
+// Ledger 分页获取积分流水，可按候选人和类型过滤
+// GET /api/points/ledger?candidate_id=&kind=&limit=20&cursor=
+func (h *PointsHandler) Ledger(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+	page, err := parsePageRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter := store.PointEntryFilter{Kind: c.Query("kind")}
+	switch filter.Kind {
+	case "", model.PointKindPick, model.PointKindOnTime, model.PointKindRedeem, model.PointKindAdjust:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be pick, on_time, redeem or adjust"})
+		return
+	}
+	if filter.CandidateID, err = parseUUIDQuery(c, "candidate_id"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entries, err := h.service.Ledger(userID, filter, page)
+	if err != nil {
+		respondListError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// Leaderboard 按积分余额排列的候选人
+// GET /api/points/leaderboard
+func (h *PointsHandler) Leaderboard(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+	board, err := h.service.Leaderboard(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, board)
+}
+
+// rewardRequestIDs 解析当前用户 ID 和路径中的奖励 ID，失败时写入错误响应
+func rewardRequestIDs(c *gin.Context) (userID, rewardID uuid.UUID, ok bool) {
+	if userID, ok = requestUserID(c); !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+	rewardID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid reward id"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, rewardID, true
+}
+
+// respondPointsError 返回积分操作错误，无效的调整为 400，积分不足为 409
+func respondPointsError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidPointsAdjustment):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInsufficientPoints):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		writeServiceError(c, err)
+	}
+}
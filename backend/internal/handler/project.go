@@ -2,8 +2,8 @@ package handler
 
 import (
 	"net/http"
-	"whotakesshowers/internal/middleware"
 	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/service"
 	"whotakesshowers/internal/store"
 
 	"github.com/gin-gonic/gin"
@@ -11,27 +11,33 @@ import (
 )
 
 // ProjectHandler 项目处理器
-type ProjectHandler struct{}
+type ProjectHandler struct {
+	projects store.ProjectRepository
+	service  *service.ProjectService
+}
 
-var projectHandler = &ProjectHandler{}
+// NewProjectHandler 创建项目处理器
+func NewProjectHandler(projects store.ProjectRepository, service *service.ProjectService) *ProjectHandler {
+	return &ProjectHandler{projects: projects, service: service}
+}
 
-// ListProjects 获取项目列表
-// GET /api/projects
-func ListProjects(c *gin.Context) {
-	userIDStr, exists := middleware.GetUserID(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未认证"})
+// List 分页获取项目列表，q 按名称搜索
+// GET /api/projects?q=&sort=-created_at&limit=20&cursor=
+func (h *ProjectHandler) List(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
 		return
 	}
-	userID, err := uuid.Parse(userIDStr)
+
+	page, err := parsePageRequest(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	projects, err := store.Projects.List(userID)
+	projects, err := h.projects.Find(userID, store.ProjectFilter{Query: c.Query("q")}, page)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondListError(c, err)
 		return
 	}
 
@@ -40,21 +46,23 @@ func ListProjects(c *gin.Context) {
 
 // CreateProjectRequest 创建项目请求
 type CreateProjectRequest struct {
-	Name         string    `json:"name" binding:"required"`
-	CandidateIDs []uuid.UUID `json:"candidate_ids"`
+	Name          string      `json:"name" binding:"required"`
+	CandidateIDs  []uuid.UUID `json:"candidate_ids"`
+	TargetMinutes *int        `json:"target_minutes" binding:"omitempty,min=1,max=1440"`
+	PickPoints    int         `json:"pick_points" binding:"min=0,max=1000"`
+	OnTimePoints  int         `json:"on_time_points" binding:"min=0,max=1000"`
+
+	EligibilityRules []service.EligibilityRule `json:"eligibility_rules"`
+	TagQuery         string                    `json:"tag_query"` // 例如 kids AND NOT away，设置后忽略 candidate_ids
+	DrawOverrides    []service.DrawOverride    `json:"draw_overrides"`
+	RerollTokens     int                       `json:"reroll_tokens" binding:"min=0,max=10"` // 每个候选人每周可以重新选择的次数
 }
 
-// CreateProject 创建项目
+// Create 创建项目
 // POST /api/projects
-func CreateProject(c *gin.Context) {
-	userIDStr, exists := middleware.GetUserID(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未认证"})
-		return
-	}
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+func (h *ProjectHandler) Create(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
 		return
 	}
 
@@ -65,24 +73,42 @@ func CreateProject(c *gin.Context) {
 	}
 
 	project := &model.Project{
-		Name:   req.Name,
-		UserID: userID,
+		Name:          req.Name,
+		UserID:        userID,
+		TargetMinutes: req.TargetMinutes,
+		PickPoints:    req.PickPoints,
+		OnTimePoints:  req.OnTimePoints,
+		RerollTokens:  req.RerollTokens,
+	}
+	rules, err := service.EncodeEligibilityRules(req.EligibilityRules)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	project.EligibilityRules = rules
+	if project.TagQuery, err = service.EncodeTagQuery(req.TagQuery); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if project.DrawOverrides, err = service.EncodeDrawOverrides(req.DrawOverrides); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
 	// 保存项目
-	if err := store.Projects.Create(project); err != nil {
+	if err := h.projects.Create(project); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	// 设置候选人
 	if len(req.CandidateIDs) > 0 {
-		if err := store.Projects.SetCandidateIDs(project.ID, req.CandidateIDs); err != nil {
+		if err := h.projects.SetCandidateIDs(project.ID, req.CandidateIDs); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 		// 重新获取以更新 candidate_ids 字段
-		project, _ = store.Projects.Get(project.ID, userID)
+		project, _ = h.projects.Get(project.ID, userID)
 	}
 
 	c.JSON(http.StatusCreated, project)
@@ -90,21 +116,23 @@ func CreateProject(c *gin.Context) {
 
 // UpdateProjectRequest 更新项目请求
 type UpdateProjectRequest struct {
-	Name         string      `json:"name"`
-	CandidateIDs []uuid.UUID `json:"candidate_ids"`
+	Name          string      `json:"name"`
+	CandidateIDs  []uuid.UUID `json:"candidate_ids"`
+	TargetMinutes *int        `json:"target_minutes" binding:"omitempty,min=0,max=1440"` // 0 表示取消目标时长
+	PickPoints    *int        `json:"pick_points" binding:"omitempty,min=0,max=1000"`
+	OnTimePoints  *int        `json:"on_time_points" binding:"omitempty,min=0,max=1000"`
+
+	EligibilityRules []service.EligibilityRule `json:"eligibility_rules"` // 为空数组时清除所有规则
+	TagQuery         *string                   `json:"tag_query"`         // 为空字符串时改回使用 candidate_ids
+	DrawOverrides    []service.DrawOverride    `json:"draw_overrides"`    // 为空数组时清除所有特殊日规则
+	RerollTokens     *int                      `json:"reroll_tokens" binding:"omitempty,min=0,max=10"`
 }
 
-// UpdateProject 更新项目
+// Update 更新项目
 // PUT /api/projects/:id
-func UpdateProject(c *gin.Context) {
-	userIDStr, exists := middleware.GetUserID(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未认证"})
-		return
-	}
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+func (h *ProjectHandler) Update(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
 		return
 	}
 
@@ -115,7 +143,7 @@ func UpdateProject(c *gin.Context) {
 	}
 
 	// 获取现有项目
-	project, err := store.Projects.Get(id, userID)
+	project, err := h.projects.Get(id, userID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
 		return
@@ -131,34 +159,66 @@ func UpdateProject(c *gin.Context) {
 	if req.Name != "" {
 		project.Name = req.Name
 	}
-	if req.CandidateIDs != nil {
-		if err := store.Projects.SetCandidateIDs(id, req.CandidateIDs); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if req.TargetMinutes != nil {
+		project.TargetMinutes = req.TargetMinutes
+		if *req.TargetMinutes == 0 {
+			project.TargetMinutes = nil
+		}
+	}
+	if req.PickPoints != nil {
+		project.PickPoints = *req.PickPoints
+	}
+	if req.OnTimePoints != nil {
+		project.OnTimePoints = *req.OnTimePoints
+	}
+	if req.RerollTokens != nil {
+		project.RerollTokens = *req.RerollTokens
+	}
+	if req.EligibilityRules != nil {
+		rules, err := service.EncodeEligibilityRules(req.EligibilityRules)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		// 重新获取以更新 candidate_ids 字段
-		project, _ = store.Projects.Get(id, userID)
-	} else {
-		if err := store.Projects.Update(project); err != nil {
+		project.EligibilityRules = rules
+	}
+	if req.TagQuery != nil {
+		query, err := service.EncodeTagQuery(*req.TagQuery)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		project.TagQuery = query
+	}
+	if req.DrawOverrides != nil {
+		overrides, err := service.EncodeDrawOverrides(req.DrawOverrides)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		project.DrawOverrides = overrides
+	}
+	if err := h.projects.Update(project); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if req.CandidateIDs != nil {
+		if err := h.projects.SetCandidateIDs(id, req.CandidateIDs); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
+		// 重新获取以更新 candidate_ids 字段
+		project, _ = h.projects.Get(id, userID)
 	}
 
 	c.JSON(http.StatusOK, project)
 }
 
-// GetProject 获取项目详情
+// Get 获取项目详情
 // GET /api/projects/:id
-func GetProject(c *gin.Context) {
-	userIDStr, exists := middleware.GetUserID(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未认证"})
-		return
-	}
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+func (h *ProjectHandler) Get(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
 		return
 	}
 
@@ -168,7 +228,7 @@ func GetProject(c *gin.Context) {
 		return
 	}
 
-	project, err := store.Projects.Get(id, userID)
+	project, err := h.projects.Get(id, userID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
 		return
@@ -177,17 +237,11 @@ func GetProject(c *gin.Context) {
 	c.JSON(http.StatusOK, project)
 }
 
-// DeleteProject 删除项目
+// Delete 删除项目
 // DELETE /api/projects/:id
-func DeleteProject(c *gin.Context) {
-	userIDStr, exists := middleware.GetUserID(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未认证"})
-		return
-	}
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+func (h *ProjectHandler) Delete(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
 		return
 	}
 
@@ -197,13 +251,11 @@ func DeleteProject(c *gin.Context) {
 		return
 	}
 
-	if err := store.Projects.Delete(id, userID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	// 移入回收站（历史记录在彻底删除时一并删除）
+	if err := h.service.Delete(id, userID); err != nil {
+		writeServiceError(c, err)
 		return
 	}
 
-	// 删除相关历史记录
-	store.Histories.DeleteByProject(id, userID)
-
 	c.Status(http.StatusNoContent)
 }
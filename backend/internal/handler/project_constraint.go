@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"whotakesshowers/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ProjectConstraintHandler 项目间约束处理器
+type ProjectConstraintHandler struct {
+	service *service.ProjectConstraintService
+}
+
+// NewProjectConstraintHandler 创建项目间约束处理器
+func NewProjectConstraintHandler(service *service.ProjectConstraintService) *ProjectConstraintHandler {
+	return &ProjectConstraintHandler{service: service}
+}
+
+// List 获取项目间约束
+// GET /api/constraints
+func (h *ProjectConstraintHandler) List(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+	constraints, err := h.service.List(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, constraints)
+}
+
+// Create 创建项目间约束
+// POST /api/constraints
+func (h *ProjectConstraintHandler) Create(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+	var req service.ProjectConstraintInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	constraint, err := h.service.Create(userID, req)
+	if err != nil {
+		respondConstraintError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, constraint)
+}
+
+// Update 修改项目间约束
+// PUT /api/constraints/:id
+func (h *ProjectConstraintHandler) Update(c *gin.Context) {
+	userID, constraintID, ok := constraintRequestIDs(c)
+	if !ok {
+		return
+	}
+	var req service.ProjectConstraintInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	constraint, err := h.service.Update(userID, constraintID, req)
+	if err != nil {
+		respondConstraintError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, constraint)
+}
+
+// Delete 删除项目间约束
+// DELETE /api/constraints/:id
+func (h *ProjectConstraintHandler) Delete(c *gin.Context) {
+	userID, constraintID, ok := constraintRequestIDs(c)
+	if !ok {
+		return
+	}
+	if err := h.service.Delete(userID, constraintID); err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// constraintRequestIDs 解析当前用户 ID 和路径中的约束 ID，失败时写入错误响应
+func constraintRequestIDs(c *gin.Context) (userID, constraintID uuid.UUID, ok bool) {
+	if userID, ok = requestUserID(c); !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+	constraintID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid constraint id"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, constraintID, true
+}
+
+// respondConstraintError 返回项目间约束错误，无效的约束为 400，重复的约束为 409
+func respondConstraintError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidProjectConstraint):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrProjectConstraintExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		writeServiceError(c, err)
+	}
+}
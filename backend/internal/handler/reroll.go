@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Reroll 被选中的候选人使用一次重新选择，返回新的记录和被取代的原记录
+// POST /api/history/:id/reroll
+func (h *HistoryHandler) Reroll(c *gin.Context) {
+	userID, historyID, ok := historyRequestIDs(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.randomizer.Reroll(userID, historyID)
+	if err != nil {
+		respondHistoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// RerollBalances 列出项目的候选人本周已使用和剩余的重新选择次数
+// GET /api/projects/:id/rerolls
+func (h *HistoryHandler) RerollBalances(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+
+	balances, err := h.randomizer.RerollBalances(userID, projectID)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, balances)
+}
@@ -3,47 +3,129 @@ package handler
 import (
 	"github.com/gin-gonic/gin"
 	"whotakesshowers/internal/middleware"
-	"gorm.io/gorm"
 )
 
 // RegisterRoutes 注册所有路由
-func RegisterRoutes(r *gin.RouterGroup, db *gorm.DB) {
-	// 认证相关（不需要token）
-	r.POST("/auth/register", RegisterHandler(db))
-	r.POST("/auth/login", LoginHandler(db))
+func RegisterRoutes(r *gin.RouterGroup, h *Handlers) {
+	// 认证相关（不需要token）；幂等键按用户保存，这里还没有用户，且响应中的 token 不能保存，不支持幂等键
+	r.POST("/auth/register", middleware.RejectIdempotencyKey(), h.Auth.Register)
+	r.POST("/auth/login", middleware.RejectIdempotencyKey(), h.Auth.Login)
 
-	// 需要认证的路由
+	// 日历订阅（地址中的令牌代替登录）
+	r.GET("/calendar/:token", h.HistoryExport.Feed)
+
+	// 需要认证的路由；写请求可以带 Idempotency-Key 请求头
 	auth := r.Group("")
-	auth.Use(middleware.AuthMiddleware())
+	auth.Use(middleware.AuthMiddleware(h.users), middleware.IdempotencyMiddleware(h.idempotency))
 	{
 		// 用户信息
-		auth.GET("/auth/me", MeHandler(db))
+		auth.GET("/auth/me", h.Auth.Me)
+
+		// 账号删除
+		auth.POST("/auth/me/deletion", h.Accounts.RequestDeletion)
+		auth.DELETE("/auth/me/deletion", h.Accounts.CancelDeletion)
 
 		// 项目相关
-		auth.GET("/projects", ListProjects)
-		auth.POST("/projects", CreateProject)
-		auth.GET("/projects/:id", GetProject)
-		auth.PUT("/projects/:id", UpdateProject)
-		auth.DELETE("/projects/:id", DeleteProject)
+		auth.GET("/projects", h.Projects.List)
+		auth.POST("/projects", h.Projects.Create)
+		auth.GET("/projects/:id", h.Projects.Get)
+		auth.PUT("/projects/:id", h.Projects.Update)
+		auth.DELETE("/projects/:id", h.Projects.Delete)
+		auth.GET("/projects/:id/stats", h.Stats.Project)
+		auth.GET("/projects/:id/eligibility", h.Histories.Eligibility)
+		auth.GET("/projects/:id/rerolls", h.Histories.RerollBalances)
 
 		// 候选人相关
-		auth.GET("/candidates", ListCandidates)
-		auth.POST("/candidates", CreateCandidate)
-		auth.GET("/candidates/:id", GetCandidate)
-		auth.PUT("/candidates/:id", UpdateCandidate)
-		auth.DELETE("/candidates/:id", DeleteCandidate)
-		auth.POST("/candidates/:id/photo", UploadCandidatePhoto)
+		auth.GET("/candidates", h.Candidates.List)
+		auth.POST("/candidates", h.Candidates.Create)
+		auth.GET("/candidates/tags", h.Candidates.Tags)
+		auth.GET("/candidates/:id", h.Candidates.Get)
+		auth.PUT("/candidates/:id", h.Candidates.Update)
+		auth.DELETE("/candidates/:id", h.Candidates.Delete)
+		auth.POST("/candidates/:id/photo", h.Candidates.UploadPhoto)
+		auth.POST("/candidates/:id/points", h.Points.Adjust)
 
 		// 候选人照片相关
-		auth.GET("/candidates/:id/photos", ListCandidatePhotos)
-		auth.POST("/candidates/:id/photos", UploadCandidatePhotos)
-		auth.PUT("/candidates/:id/avatar", SetCandidateAvatar)
-		auth.DELETE("/candidates/:id/photos/:photo_id", DeleteCandidatePhoto)
+		auth.GET("/candidates/:id/photos", h.CandidatePhotos.List)
+		auth.POST("/candidates/:id/photos", h.CandidatePhotos.Upload)
+		auth.PUT("/candidates/:id/avatar", h.CandidatePhotos.SetAvatar)
+		auth.DELETE("/candidates/:id/photos/:photo_id", h.CandidatePhotos.Delete)
 
 		// 历史记录相关
-		auth.GET("/history", ListHistory)
+		auth.GET("/history", h.Histories.List)
+		auth.GET("/history/export", h.HistoryExport.Export)
+		auth.GET("/history/feeds", h.HistoryExport.ListFeeds)
+		auth.POST("/history/feeds", middleware.RedactIdempotentResponse("token", "url"), h.HistoryExport.CreateFeed)
+		auth.DELETE("/history/feeds/:id", h.HistoryExport.DeleteFeed)
+		auth.GET("/history/splits", h.Histories.ListSplits)
+		auth.GET("/history/:id", h.Histories.Get)
+		auth.PATCH("/history/:id", h.Histories.Update)
+		auth.POST("/history/:id/void", h.Histories.Void)
+		auth.DELETE("/history/:id/void", h.Histories.Unvoid)
+		auth.POST("/history/:id/start", h.Histories.Start)
+		auth.POST("/history/:id/complete", h.Histories.Complete)
+		auth.POST("/history/:id/skip", h.Histories.Skip)
+		auth.POST("/history/:id/reopen", h.Histories.Reopen)
+		auth.POST("/history/:id/reroll", h.Histories.Reroll)
+		auth.GET("/history/:id/changes", h.Histories.Changes)
+
+		// 公平性统计
+		auth.GET("/stats", h.Stats.Overall)
+
+		// 积分与奖励
+		auth.GET("/rewards", h.Points.ListRewards)
+		auth.POST("/rewards", h.Points.CreateReward)
+		auth.PUT("/rewards/:id", h.Points.UpdateReward)
+		auth.DELETE("/rewards/:id", h.Points.DeleteReward)
+		auth.POST("/rewards/:id/redeem", h.Points.Redeem)
+		auth.GET("/points/ledger", h.Points.Ledger)
+		auth.GET("/points/leaderboard", h.Points.Leaderboard)
+
+		// 徽章
+		auth.GET("/badges", h.Badges.List)
+		auth.POST("/badges/backfill", h.Badges.Backfill)
+		auth.GET("/badges/rules", h.Badges.ListRules)
+		auth.POST("/badges/rules", h.Badges.CreateRule)
+		auth.PUT("/badges/rules/:id", h.Badges.UpdateRule)
+		auth.DELETE("/badges/rules/:id", h.Badges.DeleteRule)
+		auth.GET("/routines", h.Routines.List)
+		auth.POST("/routines", h.Routines.Create)
+		auth.GET("/routines/runs/:id", h.Routines.GetRun)
+		auth.POST("/routines/runs/:id/advance", h.Routines.Advance)
+		auth.GET("/routines/:id", h.Routines.Get)
+		auth.PUT("/routines/:id", h.Routines.Update)
+		auth.DELETE("/routines/:id", h.Routines.Delete)
+		auth.POST("/routines/:id/runs", h.Routines.Run)
+		auth.GET("/routines/:id/runs", h.Routines.ListRuns)
+		auth.GET("/constraints", h.Constraints.List)
+		auth.POST("/constraints", h.Constraints.Create)
+		auth.PUT("/constraints/:id", h.Constraints.Update)
+		auth.DELETE("/constraints/:id", h.Constraints.Delete)
 
 		// 随机选择
-		auth.POST("/randomize", Randomize)
+		auth.POST("/randomize", h.Histories.Randomize)
+		auth.POST("/randomize/teams", h.Histories.Split)
+
+		// 回收站
+		auth.GET("/trash", h.Trash.List)
+		auth.POST("/trash/projects/:id/restore", h.Trash.RestoreProject)
+		auth.POST("/trash/candidates/:id/restore", h.Trash.RestoreCandidate)
+		auth.POST("/trash/photos/:id/restore", h.Trash.RestorePhoto)
+		auth.DELETE("/trash/projects/:id", h.Trash.PurgeProject)
+		auth.DELETE("/trash/candidates/:id", h.Trash.PurgeCandidate)
+		auth.DELETE("/trash/photos/:id", h.Trash.PurgePhoto)
+
+		// 数据导出与导入
+		auth.GET("/export", h.Export.Export)
+		auth.POST("/import", h.Export.Import)
+	}
+
+	// 管理接口（需要 X-Admin-Token）；不属于任何用户，不支持幂等键
+	admin := r.Group("/admin")
+	admin.Use(middleware.AdminMiddleware(), middleware.RejectIdempotencyKey())
+	if h.Backups != nil {
+		admin.GET("/backups", h.Backups.List)
+		admin.POST("/backups", h.Backups.Create)
+		admin.GET("/backups/:name", h.Backups.Download)
 	}
 }
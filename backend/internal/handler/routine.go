@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"whotakesshowers/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RoutineHandler 例程处理器
+type RoutineHandler struct {
+	service *service.RoutineService
+}
+
+// NewRoutineHandler 创建例程处理器
+func NewRoutineHandler(service *service.RoutineService) *RoutineHandler {
+	return &RoutineHandler{service: service}
+}
+
+// AdvanceRoutineRequest 推进例程执行的请求
+type AdvanceRoutineRequest struct {
+	Skip bool `json:"skip"` // 为 true 时跳过当前步骤而不是完成
+}
+
+// List 获取例程列表
+// GET /api/routines
+func (h *RoutineHandler) List(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+	routines, err := h.service.List(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, routines)
+}
+
+// Get 获取例程
+// GET /api/routines/:id
+func (h *RoutineHandler) Get(c *gin.Context) {
+	userID, routineID, ok := routineRequestIDs(c, "invalid routine id")
+	if !ok {
+		return
+	}
+	routine, err := h.service.Get(userID, routineID)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, routine)
+}
+
+// Create 创建例程
+// POST /api/routines
+func (h *RoutineHandler) Create(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+	var req service.RoutineInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	routine, err := h.service.Create(userID, req)
+	if err != nil {
+		respondRoutineError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, routine)
+}
+
+// Update 修改例程
+// PUT /api/routines/:id
+func (h *RoutineHandler) Update(c *gin.Context) {
+	userID, routineID, ok := routineRequestIDs(c, "invalid routine id")
+	if !ok {
+		return
+	}
+	var req service.RoutineInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	routine, err := h.service.Update(userID, routineID, req)
+	if err != nil {
+		respondRoutineError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, routine)
+}
+
+// Delete 删除例程，执行记录保留
+// DELETE /api/routines/:id
+func (h *RoutineHandler) Delete(c *gin.Context) {
+	userID, routineID, ok := routineRequestIDs(c, "invalid routine id")
+	if !ok {
+		return
+	}
+	if err := h.service.Delete(userID, routineID); err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Run 执行例程，为每个步骤随机选择候选人
+// POST /api/routines/:id/runs
+func (h *RoutineHandler) Run(c *gin.Context) {
+	userID, routineID, ok := routineRequestIDs(c, "invalid routine id")
+	if !ok {
+		return
+	}
+	run, err := h.service.Run(userID, routineID)
+	if err != nil {
+		respondRoutineError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, run)
+}
+
+// ListRuns 获取例程最近的执行记录
+// GET /api/routines/:id/runs
+func (h *RoutineHandler) ListRuns(c *gin.Context) {
+	userID, routineID, ok := routineRequestIDs(c, "invalid routine id")
+	if !ok {
+		return
+	}
+	runs, err := h.service.ListRuns(userID, routineID)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, runs)
+}
+
+// GetRun 获取例程执行记录及其各步骤的历史记录
+// GET /api/routines/runs/:id
+func (h *RoutineHandler) GetRun(c *gin.Context) {
+	userID, runID, ok := routineRequestIDs(c, "invalid routine run id")
+	if !ok {
+		return
+	}
+	run, err := h.service.GetRun(userID, runID)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
+
+// Advance 完成或跳过当前步骤并进入下一步骤
+// POST /api/routines/runs/:id/advance
+func (h *RoutineHandler) Advance(c *gin.Context) {
+	userID, runID, ok := routineRequestIDs(c, "invalid routine run id")
+	if !ok {
+		return
+	}
+	var req AdvanceRoutineRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	run, err := h.service.Advance(userID, runID, req.Skip)
+	if err != nil {
+		respondRoutineError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
+
+// routineRequestIDs 解析当前用户 ID 和路径中的 ID，失败时写入错误响应
+func routineRequestIDs(c *gin.Context, invalidMessage string) (userID, id uuid.UUID, ok bool) {
+	if userID, ok = requestUserID(c); !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": invalidMessage})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, id, true
+}
+
+// respondRoutineError 返回例程错误，无效的例程或无法选择的步骤为 400，已结束的执行为 409
+func respondRoutineError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidRoutine), errors.Is(err, service.ErrRoutineStepUnavailable):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrRoutineRunFinished):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		writeServiceError(c, err)
+	}
+}
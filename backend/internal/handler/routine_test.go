@@ -0,0 +1,121 @@
+package handler_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// routineRun 执行例程的响应
+type routineRun struct {
+	ID        string `json:"id"`
+	Histories []struct {
+		ID             string `json:"id"`
+		ProjectID      string `json:"project_id"`
+		CandidateID    string `json:"candidate_id"`
+		Overridden     bool   `json:"overridden"`
+		OverrideReason string `json:"override_reason"`
+	} `json:"histories"`
+	Draws []struct {
+		Step        int    `json:"step"`
+		ProjectID   string `json:"project_id"`
+		HistoryID   string `json:"history_id"`
+		Constraints []struct {
+			SourceProjectID string   `json:"source_project_id"`
+			CandidateIDs    []string `json:"candidate_ids"`
+			Relaxed         bool     `json:"relaxed"`
+		} `json:"constraints"`
+		Overrides []struct {
+			Action      string `json:"action"`
+			CandidateID string `json:"candidate_id"`
+			Reason      string `json:"reason"`
+		} `json:"overrides"`
+	} `json:"draws"`
+}
+
+// createRoutine 创建例程并返回其 ID
+func (s *testServer) createRoutine(token, name string, avoidRepeats bool, projectIDs ...string) string {
+	s.t.Helper()
+	var routine struct {
+		ID string `json:"id"`
+	}
+	s.expect(http.StatusCreated, "POST", "/api/routines", token, gin.H{
+		"name": name, "project_ids": projectIDs, "avoid_repeats": avoidRepeats,
+	}, &routine)
+	return routine.ID
+}
+
+func TestRoutineRunAppliesConstraints(t *testing.T) {
+	s := newTestServer(t)
+	token := s.register("alice")
+	ann := s.createCandidate(token, "Ann")
+	ben := s.createCandidate(token, "Ben")
+	shower := s.createProject(token, "Shower", ann, ben)
+	dishes := s.createProject(token, "Dishes", ann, ben)
+	s.expect(http.StatusCreated, "POST", "/api/constraints", token, gin.H{
+		"source_project_id": shower, "target_project_id": dishes, "mode": "exclude",
+	}, nil)
+
+	// 不避免重复时，第二步仍然不能选择第一步选中的候选人
+	routine := s.createRoutine(token, "Evening", false, shower, dishes)
+	var run routineRun
+	s.expect(http.StatusCreated, "POST", "/api/routines/"+routine+"/runs", token, nil, &run)
+	if len(run.Histories) != 2 || len(run.Draws) != 2 {
+		t.Fatalf("run = %+v, want two steps", run)
+	}
+	for i, draw := range run.Draws {
+		if draw.HistoryID != run.Histories[i].ID {
+			t.Errorf("step %d history_id = %q, want %q", i+1, draw.HistoryID, run.Histories[i].ID)
+		}
+	}
+	first, second := run.Histories[0].CandidateID, run.Histories[1].CandidateID
+	if first == second {
+		t.Errorf("step 2 picked %s again despite the exclude constraint", second)
+	}
+
+	if len(run.Draws[0].Constraints) != 0 {
+		t.Errorf("step 1 constraints = %+v, want none", run.Draws[0].Constraints)
+	}
+	draw := run.Draws[1]
+	if draw.Step != 1 || draw.ProjectID != dishes || len(draw.Constraints) != 1 {
+		t.Fatalf("step 2 draw = %+v, want the constraint from the shower project", draw)
+	}
+	if c := draw.Constraints[0]; c.SourceProjectID != shower || len(c.CandidateIDs) != 1 || c.CandidateIDs[0] != first || c.Relaxed {
+		t.Errorf("step 2 constraint = %+v, want %s excluded", c, first)
+	}
+}
+
+func TestRoutineRunAppliesDrawOverrides(t *testing.T) {
+	s := newTestServer(t)
+	token := s.register("alice")
+	ann := s.createCandidate(token, "Ann")
+	ben := s.createCandidate(token, "Ben")
+	shower := s.createProject(token, "Shower", ann, ben)
+	dishes := s.createProject(token, "Dishes", ann, ben)
+	today := time.Now().Format(time.DateOnly)
+	s.expect(http.StatusOK, "PUT", "/api/projects/"+shower, token, gin.H{"draw_overrides": []gin.H{{
+		"type": "dates", "action": "force", "candidate_id": ann, "from": today, "to": today, "message": "Ann's turn",
+	}}}, nil)
+
+	// 避免重复不能推翻一定选择的规则
+	routine := s.createRoutine(token, "Evening", true, dishes, shower)
+	for i := 0; i < 5; i++ {
+		var run routineRun
+		s.expect(http.StatusCreated, "POST", "/api/routines/"+routine+"/runs", token, nil, &run)
+		if len(run.Histories) != 2 || len(run.Draws) != 2 {
+			t.Fatalf("run = %+v, want two steps", run)
+		}
+		plain, forced := run.Histories[0], run.Histories[1]
+		if plain.Overridden || plain.OverrideReason != "" || len(run.Draws[0].Overrides) != 0 {
+			t.Errorf("step 1 = %+v %+v, want no override", plain, run.Draws[0])
+		}
+		if forced.CandidateID != ann || !forced.Overridden || forced.OverrideReason != "Ann's turn" {
+			t.Errorf("step 2 = %+v, want Ann picked by the override", forced)
+		}
+		if o := run.Draws[1].Overrides; len(o) != 1 || o[0].CandidateID != ann || o[0].Action != "force" {
+			t.Errorf("step 2 overrides = %+v, want the force rule for Ann", o)
+		}
+	}
+}
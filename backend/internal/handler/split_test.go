@@ -0,0 +1,146 @@
+package handler_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// splitHistory 分组产生的历史记录
+type splitHistory struct {
+	ID          string  `json:"id"`
+	CandidateID string  `json:"candidate_id"`
+	Source      string  `json:"source"`
+	SplitID     *string `json:"split_id"`
+	SplitGroup  *int    `json:"split_group"`
+}
+
+func TestSplitRecordsHistory(t *testing.T) {
+	s := newTestServer(t)
+	token := s.register("alice")
+	var candidates []string
+	for _, name := range []string{"Ann", "Ben", "Cid", "Dee"} {
+		candidates = append(candidates, s.createCandidate(token, name))
+	}
+	project := s.createProject(token, "Games", candidates...)
+
+	var split struct {
+		ID         string   `json:"id"`
+		HistoryIDs []string `json:"history_ids"`
+	}
+	s.expect(http.StatusOK, "POST", "/api/randomize/teams", token, gin.H{"project_id": project, "group_count": 2}, &split)
+	if len(split.HistoryIDs) != 4 {
+		t.Fatalf("split history_ids = %v, want one per candidate", split.HistoryIDs)
+	}
+
+	// 每个候选人一条来源为 split 的历史记录
+	var history struct {
+		Items []splitHistory `json:"items"`
+		Total int            `json:"total"`
+	}
+	s.expect(http.StatusOK, "GET", "/api/history?source=split", token, nil, &history)
+	if history.Total != 4 {
+		t.Fatalf("split history entries = %d, want 4", history.Total)
+	}
+	groups := make(map[int]int)
+	for _, item := range history.Items {
+		if item.SplitID == nil || *item.SplitID != split.ID || item.SplitGroup == nil {
+			t.Errorf("history = %+v, want it linked to split %s", item, split.ID)
+			continue
+		}
+		groups[*item.SplitGroup]++
+	}
+	if groups[0] != 2 || groups[1] != 2 {
+		t.Errorf("group sizes = %v, want 2 and 2", groups)
+	}
+
+	// 分组不计入被选中次数，单独统计
+	var stats struct {
+		TotalDraws  int `json:"total_draws"`
+		TotalSplits int `json:"total_splits"`
+		Candidates  []struct {
+			Draws  int `json:"draws"`
+			Splits int `json:"splits"`
+		} `json:"candidates"`
+	}
+	s.expect(http.StatusOK, "GET", "/api/stats", token, nil, &stats)
+	if stats.TotalDraws != 0 || stats.TotalSplits != 1 || len(stats.Candidates) != 4 {
+		t.Fatalf("stats = %+v, want no draws and one split", stats)
+	}
+	for _, candidate := range stats.Candidates {
+		if candidate.Draws != 0 || candidate.Splits != 1 {
+			t.Errorf("candidate stats = %+v, want 0 draws and 1 split", candidate)
+		}
+	}
+
+	// 分组记录不能重新选择
+	s.expect(http.StatusConflict, "POST", "/api/history/"+split.HistoryIDs[0]+"/reroll", token, nil, nil)
+
+	// 日历中每个候选人一个事件，标明所在的组
+	rec := s.expect(http.StatusOK, "GET", "/api/history/export?format=ics", token, nil, nil)
+	if events := strings.Count(rec.Body.String(), "BEGIN:VEVENT"); events != 4 {
+		t.Errorf("calendar events = %d, want 4", events)
+	}
+	if !strings.Contains(rec.Body.String(), "第 2 组") {
+		t.Errorf("calendar does not name the groups:\n%s", rec.Body.String())
+	}
+}
+
+func TestSplitExportImport(t *testing.T) {
+	t.Chdir(t.TempDir())
+	s := newTestServer(t)
+	token := s.register("alice")
+	ann := s.createCandidate(token, "Ann")
+	ben := s.createCandidate(token, "Ben")
+	project := s.createProject(token, "Games", ann, ben)
+	s.expect(http.StatusOK, "POST", "/api/randomize/teams", token, gin.H{"project_id": project, "group_count": 2}, nil)
+
+	archive := s.expect(http.StatusOK, "GET", "/api/export", token, nil, nil).Body.Bytes()
+
+	other := s.register("bob")
+	var report struct {
+		Histories struct {
+			Created int `json:"created"`
+		} `json:"histories"`
+		Splits struct {
+			Created int `json:"created"`
+			Skipped int `json:"skipped"`
+		} `json:"splits"`
+	}
+	rec := s.upload("/api/import", other, uploadFile{"file", "export.zip", archive})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("import = %d %s", rec.Code, rec.Body.String())
+	}
+	decode(t, rec, &report)
+	if report.Splits.Created != 1 || report.Histories.Created != 2 {
+		t.Errorf("import report = %+v, want 1 split and 2 histories", report)
+	}
+
+	var splits struct {
+		Items []struct {
+			ID string `json:"id"`
+		} `json:"items"`
+	}
+	s.expect(http.StatusOK, "GET", "/api/history/splits", other, nil, &splits)
+	var history struct {
+		Items []splitHistory `json:"items"`
+	}
+	s.expect(http.StatusOK, "GET", "/api/history", other, nil, &history)
+	if len(splits.Items) != 1 || len(history.Items) != 2 {
+		t.Fatalf("imported splits = %+v, history = %+v", splits.Items, history.Items)
+	}
+	for _, item := range history.Items {
+		if item.Source != "split" || item.SplitID == nil || *item.SplitID != splits.Items[0].ID {
+			t.Errorf("imported history = %+v, want it linked to split %s", item, splits.Items[0].ID)
+		}
+	}
+
+	// 再次导入时分组已存在
+	rec = s.upload("/api/import", other, uploadFile{"file", "export.zip", archive})
+	decode(t, rec, &report)
+	if report.Splits.Created != 0 || report.Splits.Skipped != 1 || report.Histories.Created != 0 {
+		t.Errorf("second import report = %+v, want everything skipped", report)
+	}
+}
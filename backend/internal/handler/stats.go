@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"whotakesshowers/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// StatsHandler 公平性统计处理器
+type StatsHandler struct {
+	stats *service.StatsService
+}
+
+// NewStatsHandler 创建统计处理器
+func NewStatsHandler(stats *service.StatsService) *StatsHandler {
+	return &StatsHandler{stats: stats}
+}
+
+// Overall 获取所有项目的统计
+// GET /api/stats?windows=7d,30d,all&bucket=day|week
+func (h *StatsHandler) Overall(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.stats.Overall(userID, statsQuery(c))
+	if err != nil {
+		writeStatsError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// Project 获取单个项目的统计
+// GET /api/projects/:id/stats?windows=7d,30d,all&bucket=day|week
+func (h *StatsHandler) Project(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+
+	stats, err := h.stats.Project(userID, id, statsQuery(c))
+	if err != nil {
+		writeStatsError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// statsQuery 读取统计参数
+func statsQuery(c *gin.Context) service.StatsQuery {
+	return service.StatsQuery{
+		Windows: c.Query("windows"),
+		Bucket:  c.Query("bucket"),
+	}
+}
+
+// writeStatsError 统计参数无效时返回 400，其余按服务层错误处理
+func writeStatsError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrInvalidStatsQuery) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	writeServiceError(c, err)
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"whotakesshowers/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Split 把项目的候选人随机分成若干组
+// POST /api/randomize/teams
+func (h *HistoryHandler) Split(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+	var req service.TeamSplitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	split, err := h.randomizer.Split(&req, userID)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidTeamSplit) || errors.Is(err, service.ErrTeamSplitUnsatisfiable) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		writeServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, split)
+}
+
+// ListSplits 分页获取分组记录，可按项目过滤
+// GET /api/history/splits?project_id=&limit=20&cursor=
+func (h *HistoryHandler) ListSplits(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+	projectID, err := parseUUIDQuery(c, "project_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := parsePageRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	splits, err := h.randomizer.Splits(userID, projectID, page)
+	if err != nil {
+		respondListError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, splits)
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"net/http"
+	"whotakesshowers/internal/logger"
+	"whotakesshowers/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// TrashHandler 回收站处理器
+type TrashHandler struct {
+	service *service.TrashService
+}
+
+// NewTrashHandler 创建回收站处理器
+func NewTrashHandler(service *service.TrashService) *TrashHandler {
+	return &TrashHandler{service: service}
+}
+
+// List 获取回收站内容
+// GET /api/trash
+func (h *TrashHandler) List(c *gin.Context) {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+
+	listing, err := h.service.List(userID)
+	if err != nil {
+		logger.Error("Failed to list trash",
+			zap.String("user_id", userID.String()),
+			zap.Error(err),
+		)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, listing)
+}
+
+// RestoreProject 恢复回收站中的项目
+// POST /api/trash/projects/:id/restore
+func (h *TrashHandler) RestoreProject(c *gin.Context) {
+	trashAction(c, "restore project", h.service.RestoreProject)
+}
+
+// RestoreCandidate 恢复回收站中的候选人
+// POST /api/trash/candidates/:id/restore
+func (h *TrashHandler) RestoreCandidate(c *gin.Context) {
+	trashAction(c, "restore candidate", h.service.RestoreCandidate)
+}
+
+// RestorePhoto 恢复回收站中的照片
+// POST /api/trash/photos/:id/restore
+func (h *TrashHandler) RestorePhoto(c *gin.Context) {
+	trashAction(c, "restore photo", h.service.RestorePhoto)
+}
+
+// PurgeProject 彻底删除回收站中的项目
+// DELETE /api/trash/projects/:id
+func (h *TrashHandler) PurgeProject(c *gin.Context) {
+	trashAction(c, "purge project", h.service.PurgeProject)
+}
+
+// PurgeCandidate 彻底删除回收站中的候选人
+// DELETE /api/trash/candidates/:id
+func (h *TrashHandler) PurgeCandidate(c *gin.Context) {
+	trashAction(c, "purge candidate", h.service.PurgeCandidate)
+}
+
+// PurgePhoto 彻底删除回收站中的照片
+// DELETE /api/trash/photos/:id
+func (h *TrashHandler) PurgePhoto(c *gin.Context) {
+	trashAction(c, "purge photo", h.service.PurgePhoto)
+}
+
+// trashAction 解析用户和资源ID后执行回收站操作
+func trashAction(c *gin.Context, action string, fn func(id, userID uuid.UUID) error) {
+	userID, ok := requestUserID(c)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	if err := fn(id, userID); err != nil {
+		logger.Warn("Trash action failed",
+			zap.String("action", action),
+			zap.String("id", id.String()),
+			zap.String("user_id", userID.String()),
+			zap.Error(err),
+		)
+		writeServiceError(c, err)
+		return
+	}
+
+	logger.Info("Trash action completed",
+		zap.String("action", action),
+		zap.String("id", id.String()),
+	)
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,130 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+	"whotakesshowers/internal/config"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// GormLogger 将 GORM 的 SQL 日志输出到 zap
+// 出错的 SQL 记为 error，超过阈值的慢查询记为 warn，info 级别下其余 SQL 记为 debug
+type GormLogger struct {
+	level         gormlogger.LogLevel
+	slowThreshold time.Duration
+	redactParams  bool
+}
+
+// NewGormLogger 根据数据库日志配置创建 GORM 日志器
+func NewGormLogger(cfg config.DatabaseLogConfig) *GormLogger {
+	level := gormlogger.Warn
+	switch cfg.Level {
+	case "silent":
+		level = gormlogger.Silent
+	case "error":
+		level = gormlogger.Error
+	case "warn":
+		level = gormlogger.Warn
+	case "info":
+		level = gormlogger.Info
+	}
+
+	return &GormLogger{
+		level:         level,
+		slowThreshold: time.Duration(cfg.SlowThreshold) * time.Millisecond,
+		redactParams:  cfg.RedactParams,
+	}
+}
+
+// LogMode 返回指定级别的日志器副本
+func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	copied := *l
+	copied.level = level
+	return &copied
+}
+
+// Info 输出 GORM 的 info 消息
+func (l *GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Info {
+		l.zap().Info(fmt.Sprintf(msg, args...), zap.String("source", sourceLine()))
+	}
+}
+
+// Warn 输出 GORM 的 warn 消息
+func (l *GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		l.zap().Warn(fmt.Sprintf(msg, args...), zap.String("source", sourceLine()))
+	}
+}
+
+// Error 输出 GORM 的 error 消息
+func (l *GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Error {
+		l.zap().Error(fmt.Sprintf(msg, args...), zap.String("source", sourceLine()))
+	}
+}
+
+// Trace 记录一条 SQL 的执行结果
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	fields := func() []zap.Field {
+		sql, rows := fc()
+		return []zap.Field{
+			zap.String("sql", sql),
+			zap.Int64("rows", rows),
+			zap.Duration("elapsed", elapsed),
+			zap.String("source", sourceLine()),
+		}
+	}
+
+	switch {
+	case err != nil && l.level >= gormlogger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		l.zap().Error("Database query failed", append(fields(), zap.Error(err))...)
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.level >= gormlogger.Warn:
+		l.zap().Warn("Slow database query", append(fields(), zap.Duration("threshold", l.slowThreshold))...)
+	case l.level >= gormlogger.Info:
+		l.zap().Debug("Database query", fields()...)
+	}
+}
+
+// ParamsFilter 开启参数隐藏时，日志中的 SQL 只保留占位符
+func (l *GormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if l.redactParams {
+		return sql, nil
+	}
+	return sql, params
+}
+
+// zap 返回用于 SQL 日志的 zap logger；调用位置由 source 字段记录，不再附加调用栈
+func (l *GormLogger) zap() *zap.Logger {
+	return Get().WithOptions(zap.WithCaller(false), zap.AddStacktrace(zapcore.FatalLevel))
+}
+
+// sourceLine 返回触发 SQL 的业务代码位置，跳过 GORM 和本文件的调用栈
+func sourceLine() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.Contains(frame.File, "gorm.io/") && !strings.HasSuffix(frame.File, "/logger/gorm.go") {
+			return frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
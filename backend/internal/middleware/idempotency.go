@@ -0,0 +1,205 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"whotakesshowers/internal/logger"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/service"
+)
+
+const (
+	// IdempotencyKeyHeader 客户端为写请求指定幂等键的请求头
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader 响应是保存的第一次响应时设置的响应头
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	// redactedFieldsKey 上下文中需要从保存的响应中去掉的字段
+	redactedFieldsKey = "idempotency_redacted_fields"
+)
+
+// RedactIdempotentResponse 用于响应中包含令牌等秘密的路由：保存响应前去掉 JSON 响应体中的这些顶层字段，
+// 秘密不写入数据库，用同一个键重试时返回的响应不包含这些字段
+func RedactIdempotentResponse(fields ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(redactedFieldsKey, fields)
+		c.Next()
+	}
+}
+
+// RejectIdempotencyKey 用于不支持幂等键的路由：带 Idempotency-Key 请求头的写请求返回 400，
+// 避免客户端误以为重试是安全的
+func RejectIdempotencyKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader(IdempotencyKeyHeader) != "" && isMutating(c.Request.Method) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is not supported for this endpoint"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// responseRecorder 在写出响应的同时保存响应体
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// IdempotencyMiddleware 幂等键中间件，需要在 AuthMiddleware 之后使用
+// 带 Idempotency-Key 请求头的 POST、PUT、PATCH、DELETE 请求：第一次请求的响应被保存，
+// 有效期内用同一个键重试时直接返回保存的响应（带 Idempotent-Replayed: true）；
+// 同一个键用于不同的请求（方法、路径或请求体不同）时返回 422，第一次请求仍在处理中时返回 409；
+// 响应中的秘密字段由 RedactIdempotentResponse 指定，不会保存
+func IdempotencyMiddleware(keys *service.IdempotencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" || !keys.Enabled() || !isMutating(c.Request.Method) {
+			c.Next()
+			return
+		}
+
+		id, _ := GetUserID(c)
+		userID, err := uuid.Parse(id)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "无效或过期的token"})
+			c.Abort()
+			return
+		}
+		// 请求体需要整个读入内存计算哈希，限制其大小
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, keys.MaxBodySize()))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("request body larger than %d bytes cannot use Idempotency-Key", tooLarge.Limit)})
+			} else {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			}
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		record, replay, err := keys.Begin(&service.IdempotentRequest{
+			UserID: userID,
+			Key:    key,
+			Method: c.Request.Method,
+			Path:   c.Request.URL.RequestURI(),
+			Body:   stripMultipartBoundary(c.GetHeader("Content-Type"), body),
+		})
+		if err != nil {
+			switch {
+			case errors.Is(err, service.ErrInvalidIdempotencyKey):
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			case errors.Is(err, service.ErrIdempotencyKeyReused):
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			case errors.Is(err, service.ErrIdempotencyKeyInProgress):
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			c.Abort()
+			return
+		}
+		if replay {
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Data(record.StatusCode, record.ContentType, []byte(record.ResponseBody))
+			c.Abort()
+			return
+		}
+
+		// 处理中 panic 时释放该键，否则重试会一直返回 409 直到过期
+		finished := false
+		defer func() {
+			if !finished {
+				releaseKey(keys, record, "handler panicked")
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+		finished = true
+
+		body = recorder.body.Bytes()
+		if fields := c.GetStringSlice(redactedFieldsKey); len(fields) > 0 {
+			var ok bool
+			if body, ok = redactFields(body, fields); !ok {
+				releaseKey(keys, record, "response cannot be redacted")
+				return
+			}
+		}
+		if err := keys.Finish(record, recorder.Status(), recorder.Header().Get("Content-Type"), body); err != nil {
+			logger.Error("Failed to save idempotent response",
+				zap.String("user_id", userID.String()),
+				zap.String("path", record.Path),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// releaseKey 不保存响应并释放幂等键，客户端可以用同一个键重试
+func releaseKey(keys *service.IdempotencyService, record *model.IdempotencyKey, reason string) {
+	err := keys.Release(record)
+	logger.Warn("Idempotent response not saved",
+		zap.String("user_id", record.UserID.String()),
+		zap.String("path", record.Path),
+		zap.String("reason", reason),
+		zap.Error(err),
+	)
+}
+
+// redactFields 去掉 JSON 对象中的顶层字段（其余字段按字段名排序）；响应体不是 JSON 对象时返回 false
+func redactFields(body []byte, fields []string) ([]byte, bool) {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(body, &object); err != nil || object == nil {
+		return nil, false
+	}
+	for _, field := range fields {
+		delete(object, field)
+	}
+	redacted, err := json.Marshal(object)
+	if err != nil {
+		return nil, false
+	}
+	return redacted, true
+}
+
+// isMutating 判断请求方法是否会修改数据
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// stripMultipartBoundary 去掉 multipart 请求体中的分隔符：客户端重试上传时每次生成的分隔符不同，
+// 去掉后同样的内容得到同样的哈希
+func stripMultipartBoundary(contentType string, body []byte) []byte {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+		return body
+	}
+	return bytes.ReplaceAll(body, []byte(params["boundary"]), nil)
+}
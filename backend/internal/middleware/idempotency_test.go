@@ -0,0 +1,90 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"whotakesshowers/internal/middleware"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/service"
+	"whotakesshowers/internal/store/memory"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newIdempotentRouter 使用幂等键中间件的路由，请求体最大 100 字节，所有请求以同一个用户身份执行
+func newIdempotentRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	s := memory.New()
+	user := &model.User{Username: "alice", Email: "alice@example.com", Password: "x"}
+	if err := s.Users().Create(user); err != nil {
+		t.Fatal(err)
+	}
+	router := gin.New()
+	router.Use(gin.Recovery(), func(c *gin.Context) {
+		c.Set("user_id", user.ID.String())
+	}, middleware.IdempotencyMiddleware(service.NewIdempotencyService(s, time.Hour, 100)))
+	return router
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	router := newIdempotentRouter(t)
+	calls := map[string]int{}
+	router.POST("/echo", func(c *gin.Context) {
+		calls["echo"]++
+		c.JSON(http.StatusOK, gin.H{"calls": calls["echo"]})
+	})
+	router.POST("/panic", func(c *gin.Context) {
+		calls["panic"]++
+		if calls["panic"] == 1 {
+			panic("boom")
+		}
+		c.JSON(http.StatusOK, gin.H{"calls": calls["panic"]})
+	})
+	router.POST("/text", middleware.RedactIdempotentResponse("token"), func(c *gin.Context) {
+		calls["text"]++
+		c.String(http.StatusOK, "plain")
+	})
+
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		wantFirst  int
+		wantRetry  int
+		wantCalls  int
+		wantReplay bool
+	}{
+		{name: "replay", path: "/echo", body: "{}", wantFirst: http.StatusOK, wantRetry: http.StatusOK, wantCalls: 1, wantReplay: true},
+		{name: "body too large", path: "/echo", body: strings.Repeat("x", 101), wantFirst: http.StatusRequestEntityTooLarge, wantRetry: http.StatusRequestEntityTooLarge, wantCalls: 1},
+		{name: "panic releases the key", path: "/panic", body: "{}", wantFirst: http.StatusInternalServerError, wantRetry: http.StatusOK, wantCalls: 2},
+		{name: "unredactable response is not stored", path: "/text", body: "{}", wantFirst: http.StatusOK, wantRetry: http.StatusOK, wantCalls: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			send := func() *httptest.ResponseRecorder {
+				req := httptest.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+				req.Header.Set(middleware.IdempotencyKeyHeader, tt.name)
+				rec := httptest.NewRecorder()
+				router.ServeHTTP(rec, req)
+				return rec
+			}
+			if first := send(); first.Code != tt.wantFirst {
+				t.Errorf("first request = %d %s, want %d", first.Code, first.Body.String(), tt.wantFirst)
+			}
+			retry := send()
+			if retry.Code != tt.wantRetry {
+				t.Errorf("retry = %d %s, want %d", retry.Code, retry.Body.String(), tt.wantRetry)
+			}
+			if replayed := retry.Header().Get(middleware.IdempotentReplayedHeader) == "true"; replayed != tt.wantReplay {
+				t.Errorf("retry replayed = %v, want %v", replayed, tt.wantReplay)
+			}
+			if got := calls[strings.TrimPrefix(tt.path, "/")]; got != tt.wantCalls {
+				t.Errorf("handler calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
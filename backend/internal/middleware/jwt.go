@@ -1,12 +1,15 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"errors"
 	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"whotakesshowers/internal/store"
 )
 
 // JWTClaims JWT声明
@@ -59,7 +62,8 @@ func ParseToken(tokenString string) (*JWTClaims, error) {
 }
 
 // AuthMiddleware JWT认证中间件
-func AuthMiddleware() gin.HandlerFunc {
+// token 对应的用户已被删除时拒绝请求，账号删除后已签发的 token 随之失效
+func AuthMiddleware(users store.UserRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 从Authorization header获取token
 		authHeader := c.GetHeader("Authorization")
@@ -84,12 +88,50 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		userID, err := uuid.Parse(claims.UserID)
+		if err != nil {
+			c.JSON(401, gin.H{"error": "无效或过期的token"})
+			c.Abort()
+			return
+		}
+		if _, err := users.Get(userID); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				c.JSON(401, gin.H{"error": "用户不存在"})
+			} else {
+				c.JSON(500, gin.H{"error": err.Error()})
+			}
+			c.Abort()
+			return
+		}
+
 		// 将用户ID存入上下文
 		c.Set("user_id", claims.UserID)
 		c.Next()
 	}
 }
 
+// AdminMiddleware 管理接口认证中间件
+// 请求头 X-Admin-Token 必须与环境变量 ADMIN_TOKEN 一致；未设置 ADMIN_TOKEN 时管理接口不可用
+func AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminToken := os.Getenv("ADMIN_TOKEN")
+		if adminToken == "" {
+			c.JSON(403, gin.H{"error": "管理接口未启用"})
+			c.Abort()
+			return
+		}
+
+		token := c.GetHeader("X-Admin-Token")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+			c.JSON(401, gin.H{"error": "无效的管理token"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // GetUserID 从上下文中获取用户ID
 func GetUserID(c *gin.Context) (string, bool) {
 	userID, exists := c.Get("user_id")
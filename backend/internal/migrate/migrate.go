@@ -0,0 +1,387 @@
+// Package migrate 提供版本化的数据库迁移
+//
+// 迁移脚本以 sql/<dialect>/NNNN_name.up.sql 与 NNNN_name.down.sql 的形式嵌入二进制，
+// 按版本号顺序执行；已执行的版本及其校验和记录在 schema_migrations 表中。
+// 表结构只由迁移脚本决定，model 中的 gorm 标签需与之保持一致。
+package migrate
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed sql
+var files embed.FS
+
+var (
+	// ErrChecksumMismatch 已执行的迁移脚本被修改
+	ErrChecksumMismatch = errors.New("migration checksum mismatch")
+	// ErrUnknownVersion 数据库版本比当前程序支持的版本新
+	ErrUnknownVersion = errors.New("database schema is newer than this binary")
+	// ErrPendingMigrations 存在未执行的迁移
+	ErrPendingMigrations = errors.New("database schema has pending migrations")
+)
+
+// Migration 一个版本化迁移
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // Up 脚本的 SHA-256
+}
+
+// Record schema_migrations 表中的一条记录
+type Record struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"type:varchar(200);not null"`
+	Checksum  string    `gorm:"type:varchar(64);not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// TableName 指定表名
+func (Record) TableName() string {
+	return "schema_migrations"
+}
+
+// Status 迁移状态
+type Status struct {
+	Version          int        `json:"version"`
+	Name             string     `json:"name"`
+	Applied          bool       `json:"applied"`
+	AppliedAt        *time.Time `json:"applied_at,omitempty"`
+	ChecksumMismatch bool       `json:"checksum_mismatch"`
+	Unknown          bool       `json:"unknown"` // 数据库中存在但程序中没有的版本
+}
+
+var fileNamePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Load 加载指定方言的所有迁移，按版本号升序返回
+func Load(dialect string) ([]Migration, error) {
+	dir := path.Join("sql", dialect)
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for dialect %q: %w", dialect, err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		matches := fileNamePattern.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(matches[1])
+		content, err := files.ReadFile(path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: matches[2]}
+			byVersion[version] = m
+		} else if m.Name != matches[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, m.Name, matches[2])
+		}
+		if matches[3] == "up" {
+			m.Up = string(content)
+			sum := sha256.Sum256(content)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down scripts", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration versions must be contiguous from 1, got %d at position %d", m.Version, i+1)
+		}
+	}
+	return migrations, nil
+}
+
+// Migrator 执行迁移
+type Migrator struct {
+	db         *gorm.DB
+	dialect    string
+	migrations []Migration
+
+	// BeforeMigrate 在执行任何迁移（up 或 down）之前调用，用于备份数据库
+	BeforeMigrate func(fromVersion int) error
+}
+
+// New 创建迁移器，方言由数据库驱动决定
+func New(db *gorm.DB) (*Migrator, error) {
+	dialect := db.Dialector.Name()
+	migrations, err := Load(dialect)
+	if err != nil {
+		return nil, err
+	}
+	m := &Migrator{db: db, dialect: dialect, migrations: migrations}
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Latest 返回程序支持的最新版本
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Current 返回数据库当前版本（0 表示尚未执行任何迁移）
+func (m *Migrator) Current() (int, error) {
+	var version int
+	err := m.db.Model(&Record{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return version, err
+}
+
+// Status 返回所有迁移的状态
+func (m *Migrator) Status() ([]Status, error) {
+	records, err := m.records()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if record, ok := records[migration.Version]; ok {
+			appliedAt := record.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			status.ChecksumMismatch = record.Checksum != migration.Checksum
+			delete(records, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+
+	// 数据库中存在、程序中不存在的版本
+	unknown := make([]int, 0, len(records))
+	for version := range records {
+		unknown = append(unknown, version)
+	}
+	sort.Ints(unknown)
+	for _, version := range unknown {
+		record := records[version]
+		appliedAt := record.AppliedAt
+		statuses = append(statuses, Status{
+			Version:   version,
+			Name:      record.Name,
+			Applied:   true,
+			AppliedAt: &appliedAt,
+			Unknown:   true,
+		})
+	}
+	return statuses, nil
+}
+
+// Verify 校验数据库状态：已执行迁移的校验和必须一致，且不能存在未知的更新版本
+func (m *Migrator) Verify() error {
+	statuses, err := m.Status()
+	if err != nil {
+		return err
+	}
+	for _, status := range statuses {
+		if status.Unknown {
+			return fmt.Errorf("%w: database is at version %d, this binary supports up to %d",
+				ErrUnknownVersion, status.Version, m.Latest())
+		}
+		if status.ChecksumMismatch {
+			return fmt.Errorf("%w: version %d (%s)", ErrChecksumMismatch, status.Version, status.Name)
+		}
+	}
+	return nil
+}
+
+// Pending 返回尚未执行的迁移
+func (m *Migrator) Pending() ([]Migration, error) {
+	current, err := m.Current()
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if migration.Version > current {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up 执行所有未执行的迁移，返回本次执行的迁移
+func (m *Migrator) Up() ([]Migration, error) {
+	if err := m.Verify(); err != nil {
+		return nil, err
+	}
+	pending, err := m.Pending()
+	if err != nil || len(pending) == 0 {
+		return nil, err
+	}
+	if err := m.beforeMigrate(); err != nil {
+		return nil, err
+	}
+
+	applied := make([]Migration, 0, len(pending))
+	for _, migration := range pending {
+		migration := migration
+		err := m.run(func(tx *gorm.DB) error {
+			if err := tx.Exec(migration.Up).Error; err != nil {
+				return err
+			}
+			return tx.Create(&Record{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				Checksum:  migration.Checksum,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return applied, fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		applied = append(applied, migration)
+	}
+	return applied, nil
+}
+
+// Down 回滚最近执行的 steps 个迁移，返回本次回滚的迁移
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	if err := m.Verify(); err != nil {
+		return nil, err
+	}
+	current, err := m.Current()
+	if err != nil || current == 0 || steps <= 0 {
+		return nil, err
+	}
+	if err := m.beforeMigrate(); err != nil {
+		return nil, err
+	}
+
+	reverted := make([]Migration, 0, steps)
+	for version := current; version > 0 && len(reverted) < steps; version-- {
+		migration := m.migrations[version-1]
+		err := m.run(func(tx *gorm.DB) error {
+			if err := tx.Exec(migration.Down).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&Record{}, "version = ?", migration.Version).Error
+		})
+		if err != nil {
+			return reverted, fmt.Errorf("rollback of %d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		reverted = append(reverted, migration)
+	}
+	return reverted, nil
+}
+
+// Baseline 将 version 及之前的迁移标记为已执行而不运行脚本，
+// 用于接管版本化迁移之前由 AutoMigrate 创建的数据库
+func (m *Migrator) Baseline(version int) error {
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		for _, migration := range m.migrations {
+			if migration.Version > version {
+				break
+			}
+			if err := tx.Create(&Record{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				Checksum:  migration.Checksum,
+				AppliedAt: time.Now(),
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ensureTable 创建 schema_migrations 表
+func (m *Migrator) ensureTable() error {
+	return m.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name VARCHAR(200) NOT NULL,
+		checksum VARCHAR(64) NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`).Error
+}
+
+// records 读取已执行的迁移记录
+func (m *Migrator) records() (map[int]Record, error) {
+	var records []Record
+	if err := m.db.Order("version").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int]Record, len(records))
+	for _, record := range records {
+		byVersion[record.Version] = record
+	}
+	return byVersion, nil
+}
+
+// beforeMigrate 调用迁移前钩子
+func (m *Migrator) beforeMigrate() error {
+	if m.BeforeMigrate == nil {
+		return nil
+	}
+	current, err := m.Current()
+	if err != nil {
+		return err
+	}
+	if err := m.BeforeMigrate(current); err != nil {
+		return fmt.Errorf("pre-migration hook failed: %w", err)
+	}
+	return nil
+}
+
+// run 在事务中执行一个迁移
+// SQLite 上在固定连接中临时关闭外键（重建表时不能触发级联），提交前检查外键完整性
+func (m *Migrator) run(fn func(tx *gorm.DB) error) error {
+	if m.dialect != "sqlite" {
+		return m.db.Transaction(fn)
+	}
+
+	return m.db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("PRAGMA foreign_keys = OFF").Error; err != nil {
+			return err
+		}
+		defer conn.Exec("PRAGMA foreign_keys = ON")
+
+		return conn.Transaction(func(tx *gorm.DB) error {
+			if err := fn(tx); err != nil {
+				return err
+			}
+			var violations []map[string]interface{}
+			if err := tx.Raw("PRAGMA foreign_key_check").Scan(&violations).Error; err != nil {
+				return err
+			}
+			if len(violations) > 0 {
+				return fmt.Errorf("foreign key check failed: %v", violations)
+			}
+			return nil
+		})
+	})
+}
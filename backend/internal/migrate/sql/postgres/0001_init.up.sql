@@ -0,0 +1,65 @@
+-- 初始表结构（与 SQLite 的 0001_init 对应）
+CREATE TABLE users (
+    id uuid,
+    username varchar(50) NOT NULL,
+    email varchar(100),
+    password varchar(255),
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX idx_users_email ON users(email);
+CREATE UNIQUE INDEX idx_users_username ON users(username);
+
+CREATE TABLE projects (
+    id uuid,
+    name varchar(200) NOT NULL,
+    user_id uuid NOT NULL,
+    candidate_ids text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_users_projects FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_projects_deleted_at ON projects(deleted_at);
+
+CREATE TABLE candidates (
+    id uuid,
+    name varchar(100) NOT NULL,
+    photo_url varchar(500),
+    user_id uuid NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_users_candidates FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_candidates_deleted_at ON candidates(deleted_at);
+
+CREATE TABLE candidate_photos (
+    id uuid,
+    candidate_id uuid NOT NULL,
+    photo_url varchar(500) NOT NULL,
+    is_avatar boolean DEFAULT false,
+    created_at timestamptz,
+    deleted_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_candidates_photos FOREIGN KEY (candidate_id) REFERENCES candidates(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_candidate_photos_deleted_at ON candidate_photos(deleted_at);
+CREATE INDEX idx_candidate_photos_candidate_id ON candidate_photos(candidate_id);
+CREATE UNIQUE INDEX idx_candidate_photos_avatar ON candidate_photos(candidate_id) WHERE is_avatar;
+
+CREATE TABLE histories (
+    id uuid,
+    project_id uuid NOT NULL,
+    project_name varchar(200) NOT NULL,
+    candidate_id uuid NOT NULL,
+    candidate_name varchar(100) NOT NULL,
+    selected_at timestamptz NOT NULL,
+    user_id uuid NOT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_projects_histories FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+    CONSTRAINT fk_users_histories FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
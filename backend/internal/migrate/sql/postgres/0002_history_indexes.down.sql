@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_histories_project_selected;
+DROP INDEX IF EXISTS idx_histories_user_selected;
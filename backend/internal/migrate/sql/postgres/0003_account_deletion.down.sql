@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS account_deletions;
+DROP INDEX IF EXISTS idx_users_deletion_requested_at;
+ALTER TABLE users DROP COLUMN deletion_requested_at;
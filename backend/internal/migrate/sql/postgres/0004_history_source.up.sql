@@ -0,0 +1,5 @@
+-- 历史记录来源（draw 随机选择 / import 导入），已有记录均来自随机选择
+ALTER TABLE histories ADD COLUMN source varchar(20) NOT NULL DEFAULT 'draw';
+
+-- 历史记录按候选人过滤
+CREATE INDEX IF NOT EXISTS idx_histories_candidate_selected ON histories(candidate_id, selected_at);
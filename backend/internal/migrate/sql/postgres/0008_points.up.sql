@@ -0,0 +1,41 @@
+-- 项目的积分设置，历史记录保存选中时的积分设置
+ALTER TABLE projects ADD COLUMN pick_points integer NOT NULL DEFAULT 0;
+ALTER TABLE projects ADD COLUMN on_time_points integer NOT NULL DEFAULT 0;
+ALTER TABLE histories ADD COLUMN pick_points integer NOT NULL DEFAULT 0;
+ALTER TABLE histories ADD COLUMN on_time_points integer NOT NULL DEFAULT 0;
+
+-- 候选人的积分余额
+ALTER TABLE candidates ADD COLUMN points integer NOT NULL DEFAULT 0;
+
+-- 可兑换的奖励
+CREATE TABLE rewards (
+    id uuid,
+    user_id uuid NOT NULL,
+    name varchar(100) NOT NULL,
+    cost integer NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_users_rewards FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_rewards_user_id ON rewards(user_id);
+
+-- 积分流水
+CREATE TABLE point_entries (
+    id uuid,
+    user_id uuid NOT NULL,
+    candidate_id uuid NOT NULL,
+    kind varchar(20) NOT NULL,
+    amount integer NOT NULL,
+    balance integer NOT NULL,
+    history_id uuid,
+    reward_id uuid,
+    note varchar(200) NOT NULL DEFAULT '',
+    created_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_users_point_entries FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_candidates_point_entries FOREIGN KEY (candidate_id) REFERENCES candidates(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_point_entries_user_created ON point_entries(user_id, created_at);
+CREATE INDEX idx_point_entries_candidate_id ON point_entries(candidate_id);
+CREATE INDEX idx_point_entries_history_id ON point_entries(history_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS badges;
+DROP TABLE IF EXISTS badge_rules;
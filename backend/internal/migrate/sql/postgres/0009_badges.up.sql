@@ -0,0 +1,31 @@
+-- 徽章规则
+CREATE TABLE badge_rules (
+    id uuid,
+    user_id uuid NOT NULL,
+    name varchar(100) NOT NULL,
+    type varchar(30) NOT NULL,
+    threshold integer NOT NULL,
+    project_id uuid,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_users_badge_rules FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_badge_rules_user_id ON badge_rules(user_id);
+
+-- 候选人获得的徽章
+CREATE TABLE badges (
+    id uuid,
+    user_id uuid NOT NULL,
+    rule_id uuid NOT NULL,
+    candidate_id uuid NOT NULL,
+    period varchar(10) NOT NULL DEFAULT '',
+    history_id uuid,
+    earned_at timestamptz NOT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_users_badges FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_badge_rules_badges FOREIGN KEY (rule_id) REFERENCES badge_rules(id) ON DELETE CASCADE,
+    CONSTRAINT fk_candidates_badges FOREIGN KEY (candidate_id) REFERENCES candidates(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_badges_user_id ON badges(user_id);
+CREATE UNIQUE INDEX idx_badges_rule_candidate_period ON badges(rule_id, candidate_id, period);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_histories_routine_run_id;
+ALTER TABLE histories DROP COLUMN routine_step;
+ALTER TABLE histories DROP COLUMN routine_run_id;
+DROP TABLE IF EXISTS routine_runs;
+DROP TABLE IF EXISTS routines;
@@ -0,0 +1,34 @@
+-- 例程：按顺序执行的多个项目
+CREATE TABLE routines (
+    id uuid,
+    name varchar(100) NOT NULL,
+    user_id uuid NOT NULL,
+    project_ids text,
+    avoid_repeats boolean NOT NULL DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_users_routines FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_routines_user_id ON routines(user_id);
+
+-- 例程的执行记录，删除例程后保留
+CREATE TABLE routine_runs (
+    id uuid,
+    user_id uuid NOT NULL,
+    routine_id uuid NOT NULL,
+    routine_name varchar(100) NOT NULL,
+    steps integer NOT NULL,
+    current_step integer NOT NULL DEFAULT 0,
+    started_at timestamptz NOT NULL,
+    finished_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_users_routine_runs FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_routine_runs_user_id ON routine_runs(user_id);
+CREATE INDEX idx_routine_runs_routine_id ON routine_runs(routine_id);
+
+-- 例程执行产生的历史记录
+ALTER TABLE histories ADD COLUMN routine_run_id uuid;
+ALTER TABLE histories ADD COLUMN routine_step integer;
+CREATE INDEX idx_histories_routine_run_id ON histories(routine_run_id);
@@ -0,0 +1,4 @@
+ALTER TABLE histories DROP COLUMN tag_query;
+ALTER TABLE histories DROP COLUMN pool_candidate_ids;
+ALTER TABLE projects DROP COLUMN tag_query;
+ALTER TABLE candidates DROP COLUMN tags;
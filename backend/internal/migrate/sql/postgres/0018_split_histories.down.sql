@@ -0,0 +1,5 @@
+DELETE FROM history_changes WHERE history_id IN (SELECT id FROM histories WHERE split_id IS NOT NULL);
+DELETE FROM histories WHERE split_id IS NOT NULL;
+DROP INDEX IF EXISTS idx_histories_split_id;
+ALTER TABLE histories DROP COLUMN split_group;
+ALTER TABLE histories DROP COLUMN split_id;
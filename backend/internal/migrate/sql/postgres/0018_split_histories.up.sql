@@ -0,0 +1,13 @@
+-- 分组为每个候选人产生一条历史记录（source 为 split），关联分组记录和所在的组（从 0 开始）
+ALTER TABLE histories ADD COLUMN split_id uuid;
+ALTER TABLE histories ADD COLUMN split_group integer;
+CREATE INDEX idx_histories_split_id ON histories(split_id);
+
+-- 为已有的分组补充历史记录
+INSERT INTO histories (id, project_id, project_name, candidate_id, candidate_name, selected_at, user_id, source, split_id, split_group)
+SELECT gen_random_uuid(), s.project_id, s.project_name,
+       (m.value->>'candidate_id')::uuid, m.value->>'candidate_name',
+       COALESCE(s.created_at, CURRENT_TIMESTAMP), s.user_id, 'split', s.id, g.ordinality - 1
+FROM team_splits s
+CROSS JOIN LATERAL jsonb_array_elements(s.groups::jsonb) WITH ORDINALITY AS g(value, ordinality)
+CROSS JOIN LATERAL jsonb_array_elements(g.value) AS m(value);
@@ -0,0 +1,3 @@
+-- 路径超过 500 个字符的键无法保留，直接删除（客户端重试时重新执行）
+DELETE FROM idempotency_keys WHERE length(path) > 500;
+ALTER TABLE idempotency_keys ALTER COLUMN path TYPE varchar(500);
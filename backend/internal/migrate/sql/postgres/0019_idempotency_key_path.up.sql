@@ -0,0 +1,2 @@
+-- 幂等键的请求路径包括查询参数，长度不受限制
+ALTER TABLE idempotency_keys ALTER COLUMN path TYPE text;
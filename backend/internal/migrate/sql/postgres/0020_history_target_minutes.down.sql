@@ -0,0 +1 @@
+ALTER TABLE histories DROP COLUMN target_minutes;
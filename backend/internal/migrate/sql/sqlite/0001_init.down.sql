@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS `histories`;
+DROP TABLE IF EXISTS `candidate_photos`;
+DROP TABLE IF EXISTS `candidates`;
+DROP TABLE IF EXISTS `projects`;
+DROP TABLE IF EXISTS `users`;
@@ -0,0 +1,3 @@
+-- 历史记录按用户/项目按时间倒序查询
+CREATE INDEX IF NOT EXISTS `idx_histories_user_selected` ON `histories`(`user_id`, `selected_at`);
+CREATE INDEX IF NOT EXISTS `idx_histories_project_selected` ON `histories`(`project_id`, `selected_at`);
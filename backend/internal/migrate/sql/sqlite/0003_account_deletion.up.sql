@@ -0,0 +1,18 @@
+-- 账号删除：申请删除的时间（冷静期结束后彻底删除），以及删除操作的审计记录
+ALTER TABLE `users` ADD COLUMN `deletion_requested_at` datetime;
+CREATE INDEX `idx_users_deletion_requested_at` ON `users`(`deletion_requested_at`);
+
+-- 审计记录只保存统计数据，不保存被删除用户的个人信息；user_id 不加外键，用户删除后保留
+CREATE TABLE `account_deletions` (
+    `id` uuid,
+    `user_id` uuid NOT NULL,
+    `requested_at` datetime NOT NULL,
+    `purged_at` datetime NOT NULL,
+    `projects` integer NOT NULL DEFAULT 0,
+    `candidates` integer NOT NULL DEFAULT 0,
+    `photos` integer NOT NULL DEFAULT 0,
+    `files` integer NOT NULL DEFAULT 0,
+    `histories` integer NOT NULL DEFAULT 0,
+    PRIMARY KEY (`id`)
+);
+CREATE INDEX `idx_account_deletions_user_id` ON `account_deletions`(`user_id`);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS `idx_histories_candidate_selected`;
+ALTER TABLE `histories` DROP COLUMN `source`;
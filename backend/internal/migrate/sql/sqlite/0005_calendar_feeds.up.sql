@@ -0,0 +1,14 @@
+-- 历史记录的日历订阅，只保存令牌的 SHA-256 哈希
+CREATE TABLE `calendar_feeds` (
+    `id` uuid,
+    `user_id` uuid NOT NULL,
+    `name` varchar(100) NOT NULL,
+    `token_hash` varchar(64) NOT NULL,
+    `project_id` uuid,
+    `candidate_id` uuid,
+    `created_at` datetime,
+    PRIMARY KEY (`id`),
+    CONSTRAINT `fk_users_calendar_feeds` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`) ON DELETE CASCADE
+);
+CREATE INDEX `idx_calendar_feeds_user_id` ON `calendar_feeds`(`user_id`);
+CREATE UNIQUE INDEX `idx_calendar_feeds_token_hash` ON `calendar_feeds`(`token_hash`);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS `history_changes`;
+ALTER TABLE `histories` DROP COLUMN `void_reason`;
+ALTER TABLE `histories` DROP COLUMN `voided_at`;
+ALTER TABLE `histories` DROP COLUMN `completed_at`;
+ALTER TABLE `histories` DROP COLUMN `note`;
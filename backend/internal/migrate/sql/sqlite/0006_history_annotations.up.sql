@@ -0,0 +1,19 @@
+-- 历史记录的备注、完成时间和作废标记
+ALTER TABLE `histories` ADD COLUMN `note` text NOT NULL DEFAULT '';
+ALTER TABLE `histories` ADD COLUMN `completed_at` datetime;
+ALTER TABLE `histories` ADD COLUMN `voided_at` datetime;
+ALTER TABLE `histories` ADD COLUMN `void_reason` varchar(200) NOT NULL DEFAULT '';
+
+-- 历史记录的修改记录
+CREATE TABLE `history_changes` (
+    `id` uuid,
+    `history_id` uuid NOT NULL,
+    `user_id` uuid NOT NULL,
+    `field` varchar(30) NOT NULL,
+    `old_value` text NOT NULL DEFAULT '',
+    `new_value` text NOT NULL DEFAULT '',
+    `changed_at` datetime NOT NULL,
+    PRIMARY KEY (`id`),
+    CONSTRAINT `fk_histories_changes` FOREIGN KEY (`history_id`) REFERENCES `histories`(`id`) ON DELETE CASCADE
+);
+CREATE INDEX `idx_history_changes_history_id` ON `history_changes`(`history_id`);
@@ -0,0 +1,4 @@
+ALTER TABLE `projects` DROP COLUMN `target_minutes`;
+ALTER TABLE `histories` DROP COLUMN `skipped_at`;
+ALTER TABLE `histories` DROP COLUMN `started_at`;
+ALTER TABLE `histories` DROP COLUMN `status`;
@@ -0,0 +1,8 @@
+-- 任务状态：已记录完成时间的记录视为已完成，其余为已选中
+ALTER TABLE `histories` ADD COLUMN `status` varchar(20) NOT NULL DEFAULT 'picked';
+ALTER TABLE `histories` ADD COLUMN `started_at` datetime;
+ALTER TABLE `histories` ADD COLUMN `skipped_at` datetime;
+UPDATE `histories` SET `status` = 'completed' WHERE `completed_at` IS NOT NULL;
+
+-- 项目的目标时长（分钟）
+ALTER TABLE `projects` ADD COLUMN `target_minutes` integer;
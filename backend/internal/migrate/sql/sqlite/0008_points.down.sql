@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS `point_entries`;
+DROP TABLE IF EXISTS `rewards`;
+ALTER TABLE `candidates` DROP COLUMN `points`;
+ALTER TABLE `histories` DROP COLUMN `on_time_points`;
+ALTER TABLE `histories` DROP COLUMN `pick_points`;
+ALTER TABLE `projects` DROP COLUMN `on_time_points`;
+ALTER TABLE `projects` DROP COLUMN `pick_points`;
@@ -0,0 +1,18 @@
+-- 项目间约束
+CREATE TABLE `project_constraints` (
+    `id` uuid,
+    `user_id` uuid NOT NULL,
+    `source_project_id` uuid NOT NULL,
+    `target_project_id` uuid NOT NULL,
+    `mode` varchar(20) NOT NULL,
+    `weight` real NOT NULL,
+    `created_at` datetime,
+    `updated_at` datetime,
+    PRIMARY KEY (`id`),
+    CONSTRAINT `fk_users_project_constraints` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`) ON DELETE CASCADE,
+    CONSTRAINT `fk_projects_source_constraints` FOREIGN KEY (`source_project_id`) REFERENCES `projects`(`id`) ON DELETE CASCADE,
+    CONSTRAINT `fk_projects_target_constraints` FOREIGN KEY (`target_project_id`) REFERENCES `projects`(`id`) ON DELETE CASCADE
+);
+CREATE INDEX `idx_project_constraints_user_id` ON `project_constraints`(`user_id`);
+CREATE INDEX `idx_project_constraints_target_project_id` ON `project_constraints`(`target_project_id`);
+CREATE UNIQUE INDEX `idx_project_constraints_source_target` ON `project_constraints`(`source_project_id`, `target_project_id`);
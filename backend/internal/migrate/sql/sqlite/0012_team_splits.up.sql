@@ -0,0 +1,16 @@
+-- 分组记录
+CREATE TABLE `team_splits` (
+    `id` uuid,
+    `user_id` uuid NOT NULL,
+    `project_id` uuid NOT NULL,
+    `project_name` varchar(200) NOT NULL,
+    `group_count` integer NOT NULL,
+    `groups` text NOT NULL,
+    `keep_apart` text,
+    `created_at` datetime,
+    PRIMARY KEY (`id`),
+    CONSTRAINT `fk_users_team_splits` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`) ON DELETE CASCADE,
+    CONSTRAINT `fk_projects_team_splits` FOREIGN KEY (`project_id`) REFERENCES `projects`(`id`) ON DELETE CASCADE
+);
+CREATE INDEX `idx_team_splits_user_id` ON `team_splits`(`user_id`);
+CREATE INDEX `idx_team_splits_project_id` ON `team_splits`(`project_id`);
@@ -0,0 +1,5 @@
+ALTER TABLE `projects` DROP COLUMN `eligibility_rules`;
+ALTER TABLE `candidates` DROP COLUMN `custom_fields`;
+ALTER TABLE `candidates` DROP COLUMN `color`;
+ALTER TABLE `candidates` DROP COLUMN `birthday`;
+ALTER TABLE `candidates` DROP COLUMN `nickname`;
@@ -0,0 +1,8 @@
+-- 候选人资料
+ALTER TABLE `candidates` ADD COLUMN `nickname` varchar(100) NOT NULL DEFAULT '';
+ALTER TABLE `candidates` ADD COLUMN `birthday` varchar(10) NOT NULL DEFAULT '';
+ALTER TABLE `candidates` ADD COLUMN `color` varchar(7) NOT NULL DEFAULT '';
+ALTER TABLE `candidates` ADD COLUMN `custom_fields` text;
+
+-- 项目的候选人资格规则
+ALTER TABLE `projects` ADD COLUMN `eligibility_rules` text;
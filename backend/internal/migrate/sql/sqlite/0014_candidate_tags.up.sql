@@ -0,0 +1,9 @@
+-- 候选人标签
+ALTER TABLE `candidates` ADD COLUMN `tags` text;
+
+-- 项目按标签查询动态确定候选人
+ALTER TABLE `projects` ADD COLUMN `tag_query` varchar(500) NOT NULL DEFAULT '';
+
+-- 选择时项目的候选人池快照
+ALTER TABLE `histories` ADD COLUMN `pool_candidate_ids` text;
+ALTER TABLE `histories` ADD COLUMN `tag_query` varchar(500) NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+ALTER TABLE `histories` DROP COLUMN `override_reason`;
+ALTER TABLE `histories` DROP COLUMN `overridden`;
+ALTER TABLE `projects` DROP COLUMN `draw_overrides`;
@@ -0,0 +1,6 @@
+-- 项目的生日和特殊日期选择规则
+ALTER TABLE `projects` ADD COLUMN `draw_overrides` text;
+
+-- 特殊日规则改变了选择结果的历史记录
+ALTER TABLE `histories` ADD COLUMN `overridden` numeric NOT NULL DEFAULT false;
+ALTER TABLE `histories` ADD COLUMN `override_reason` text NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+ALTER TABLE `histories` DROP COLUMN `reroll_of_id`;
+ALTER TABLE `histories` DROP COLUMN `superseded_at`;
+ALTER TABLE `histories` DROP COLUMN `superseded_by_id`;
+ALTER TABLE `projects` DROP COLUMN `reroll_tokens`;
@@ -0,0 +1,7 @@
+-- 每个候选人每周可以重新选择的次数
+ALTER TABLE `projects` ADD COLUMN `reroll_tokens` integer NOT NULL DEFAULT 0;
+
+-- 重新选择的记录和被它取代的记录之间的关联
+ALTER TABLE `histories` ADD COLUMN `superseded_by_id` uuid;
+ALTER TABLE `histories` ADD COLUMN `superseded_at` datetime;
+ALTER TABLE `histories` ADD COLUMN `reroll_of_id` uuid;
@@ -0,0 +1,18 @@
+-- 写请求的幂等键
+CREATE TABLE `idempotency_keys` (
+    `id` uuid,
+    `user_id` uuid NOT NULL,
+    `key` varchar(255) NOT NULL,
+    `method` varchar(10) NOT NULL,
+    `path` varchar(500) NOT NULL,
+    `request_hash` varchar(64) NOT NULL,
+    `status_code` integer NOT NULL DEFAULT 0,
+    `content_type` varchar(100),
+    `response_body` text,
+    `created_at` datetime,
+    `expires_at` datetime NOT NULL,
+    PRIMARY KEY (`id`),
+    CONSTRAINT `fk_users_idempotency_keys` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX `idx_idempotency_keys_user_key` ON `idempotency_keys`(`user_id`, `key`);
+CREATE INDEX `idx_idempotency_keys_expires_at` ON `idempotency_keys`(`expires_at`);
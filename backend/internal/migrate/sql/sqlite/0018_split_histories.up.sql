@@ -0,0 +1,13 @@
+-- 分组为每个候选人产生一条历史记录（source 为 split），关联分组记录和所在的组（从 0 开始）
+ALTER TABLE `histories` ADD COLUMN `split_id` uuid;
+ALTER TABLE `histories` ADD COLUMN `split_group` integer;
+CREATE INDEX `idx_histories_split_id` ON `histories`(`split_id`);
+
+-- 为已有的分组补充历史记录
+INSERT INTO `histories` (`id`, `project_id`, `project_name`, `candidate_id`, `candidate_name`, `selected_at`, `user_id`, `source`, `split_id`, `split_group`)
+SELECT lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' ||
+             substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6))),
+       s.`project_id`, s.`project_name`,
+       json_extract(m.value, '$.candidate_id'), json_extract(m.value, '$.candidate_name'),
+       COALESCE(s.`created_at`, CURRENT_TIMESTAMP), s.`user_id`, 'split', s.`id`, CAST(g.key AS integer)
+FROM `team_splits` s, json_each(s.`groups`) g, json_each(g.value) m;
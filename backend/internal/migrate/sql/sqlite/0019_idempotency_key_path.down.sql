@@ -0,0 +1,22 @@
+-- 路径超过 500 个字符的键无法保留，直接删除（客户端重试时重新执行）
+DELETE FROM `idempotency_keys` WHERE length(`path`) > 500;
+CREATE TABLE `idempotency_keys_old` (
+    `id` uuid,
+    `user_id` uuid NOT NULL,
+    `key` varchar(255) NOT NULL,
+    `method` varchar(10) NOT NULL,
+    `path` varchar(500) NOT NULL,
+    `request_hash` varchar(64) NOT NULL,
+    `status_code` integer NOT NULL DEFAULT 0,
+    `content_type` varchar(100),
+    `response_body` text,
+    `created_at` datetime,
+    `expires_at` datetime NOT NULL,
+    PRIMARY KEY (`id`),
+    CONSTRAINT `fk_users_idempotency_keys` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`) ON DELETE CASCADE
+);
+INSERT INTO `idempotency_keys_old` SELECT `id`, `user_id`, `key`, `method`, `path`, `request_hash`, `status_code`, `content_type`, `response_body`, `created_at`, `expires_at` FROM `idempotency_keys`;
+DROP TABLE `idempotency_keys`;
+ALTER TABLE `idempotency_keys_old` RENAME TO `idempotency_keys`;
+CREATE UNIQUE INDEX `idx_idempotency_keys_user_key` ON `idempotency_keys`(`user_id`, `key`);
+CREATE INDEX `idx_idempotency_keys_expires_at` ON `idempotency_keys`(`expires_at`);
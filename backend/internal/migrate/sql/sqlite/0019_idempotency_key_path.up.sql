@@ -0,0 +1,21 @@
+-- 幂等键的请求路径包括查询参数，长度不受限制；SQLite 不能修改列类型，重建表
+CREATE TABLE `idempotency_keys_new` (
+    `id` uuid,
+    `user_id` uuid NOT NULL,
+    `key` varchar(255) NOT NULL,
+    `method` varchar(10) NOT NULL,
+    `path` text NOT NULL,
+    `request_hash` varchar(64) NOT NULL,
+    `status_code` integer NOT NULL DEFAULT 0,
+    `content_type` varchar(100),
+    `response_body` text,
+    `created_at` datetime,
+    `expires_at` datetime NOT NULL,
+    PRIMARY KEY (`id`),
+    CONSTRAINT `fk_users_idempotency_keys` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`) ON DELETE CASCADE
+);
+INSERT INTO `idempotency_keys_new` SELECT `id`, `user_id`, `key`, `method`, `path`, `request_hash`, `status_code`, `content_type`, `response_body`, `created_at`, `expires_at` FROM `idempotency_keys`;
+DROP TABLE `idempotency_keys`;
+ALTER TABLE `idempotency_keys_new` RENAME TO `idempotency_keys`;
+CREATE UNIQUE INDEX `idx_idempotency_keys_user_key` ON `idempotency_keys`(`user_id`, `key`);
+CREATE INDEX `idx_idempotency_keys_expires_at` ON `idempotency_keys`(`expires_at`);
@@ -0,0 +1,5 @@
+-- 历史记录保存选中时项目的目标时长，之后修改项目不影响已有记录是否按时完成
+ALTER TABLE `histories` ADD COLUMN `target_minutes` integer;
+
+-- 已有的记录使用项目当前的目标时长
+UPDATE `histories` SET `target_minutes` = (SELECT `target_minutes` FROM `projects` WHERE `projects`.`id` = `histories`.`project_id`);
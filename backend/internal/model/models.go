@@ -15,6 +15,26 @@ type User struct {
 	Password  string    `gorm:"type:varchar(255)" json:"-"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	// 申请删除账号的时间，冷静期结束后账号及所有数据被彻底删除
+	DeletionRequestedAt *time.Time `gorm:"index" json:"deletion_requested_at"`
+
+	// 用户拥有的数据随用户一起删除
+	Projects   []Project   `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+	Candidates []Candidate `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+	Histories  []History   `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+
+	CalendarFeeds []CalendarFeed `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+	Rewards       []Reward       `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+	PointEntries  []PointEntry   `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+	BadgeRules    []BadgeRule    `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+	Badges        []Badge        `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+	Routines      []Routine      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+	RoutineRuns   []RoutineRun   `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+
+	ProjectConstraints []ProjectConstraint `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+	TeamSplits         []TeamSplit         `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+	IdempotencyKeys    []IdempotencyKey    `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
 }
 
 // BeforeCreate GORM hook
@@ -27,12 +47,26 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 
 // Project 项目模型
 type Project struct {
-	ID           uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
-	Name         string    `gorm:"type:varchar(200);not null" json:"name"`
-	UserID       uuid.UUID `gorm:"type:uuid;not null" json:"user_id"`
-	CandidateIDs string    `gorm:"type:text" json:"candidate_ids"` // JSON array
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID               uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
+	Name             string         `gorm:"type:varchar(200);not null" json:"name"`
+	UserID           uuid.UUID      `gorm:"type:uuid;not null" json:"user_id"`
+	CandidateIDs     string         `gorm:"type:text" json:"candidate_ids"`                         // JSON array
+	TargetMinutes    *int           `json:"target_minutes"`                                         // 任务的目标时长（分钟），为空表示不设目标
+	PickPoints       int            `gorm:"not null;default:0" json:"pick_points"`                  // 被选中时获得的积分
+	OnTimePoints     int            `gorm:"not null;default:0" json:"on_time_points"`               // 按时完成时获得的积分
+	EligibilityRules string         `gorm:"type:text" json:"eligibility_rules"`                     // JSON array，候选人需满足的资格规则
+	TagQuery         string         `gorm:"type:varchar(500);not null;default:''" json:"tag_query"` // 标签查询，不为空时项目的候选人由查询在选择时确定，忽略 CandidateIDs
+	DrawOverrides    string         `gorm:"type:text" json:"draw_overrides"`                        // JSON array，生日和特殊日期的选择规则
+	RerollTokens     int            `gorm:"not null;default:0" json:"reroll_tokens"`                // 每个候选人每周可以重新选择的次数，0 表示不允许
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"deleted_at"` // 软删除（回收站）
+
+	// 彻底删除项目时一并删除其历史记录、分组记录和相关的项目间约束
+	Histories         []History           `gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE" json:"-"`
+	TeamSplits        []TeamSplit         `gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE" json:"-"`
+	SourceConstraints []ProjectConstraint `gorm:"foreignKey:SourceProjectID;constraint:OnDelete:CASCADE" json:"-"`
+	TargetConstraints []ProjectConstraint `gorm:"foreignKey:TargetProjectID;constraint:OnDelete:CASCADE" json:"-"`
 }
 
 // BeforeCreate GORM hook
@@ -45,12 +79,24 @@ func (p *Project) BeforeCreate(tx *gorm.DB) error {
 
 // Candidate 候选人模型
 type Candidate struct {
-	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
-	Name      string    `gorm:"type:varchar(100);not null" json:"name"`
-	PhotoURL  string    `gorm:"type:varchar(500)" json:"photo_url"`
-	UserID    uuid.UUID `gorm:"type:uuid;not null" json:"user_id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID           uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
+	Name         string         `gorm:"type:varchar(100);not null" json:"name"`
+	PhotoURL     string         `gorm:"type:varchar(500)" json:"photo_url"`
+	UserID       uuid.UUID      `gorm:"type:uuid;not null" json:"user_id"`
+	Points       int            `gorm:"not null;default:0" json:"points"` // 积分余额，等于其所有积分流水之和；作废记录扣回积分后可能为负数
+	Nickname     string         `gorm:"type:varchar(100);not null;default:''" json:"nickname"`
+	Birthday     string         `gorm:"type:varchar(10);not null;default:''" json:"birthday"` // YYYY-MM-DD，为空表示未设置
+	Color        string         `gorm:"type:varchar(7);not null;default:''" json:"color"`     // 喜欢的颜色 #rrggbb，用于转盘的扇区
+	CustomFields string         `gorm:"type:text" json:"custom_fields"`                       // JSON object，自定义字段名到值
+	Tags         string         `gorm:"type:text" json:"tags"`                                // JSON array，小写标签，已排序
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"deleted_at"` // 软删除（回收站）
+
+	// 彻底删除候选人时一并删除其照片记录、积分流水和徽章
+	Photos       []CandidatePhoto `gorm:"foreignKey:CandidateID;constraint:OnDelete:CASCADE" json:"-"`
+	PointEntries []PointEntry     `gorm:"foreignKey:CandidateID;constraint:OnDelete:CASCADE" json:"-"`
+	Badges       []Badge          `gorm:"foreignKey:CandidateID;constraint:OnDelete:CASCADE" json:"-"`
 }
 
 // BeforeCreate GORM hook
@@ -63,11 +109,12 @@ func (c *Candidate) BeforeCreate(tx *gorm.DB) error {
 
 // CandidatePhoto 候选人照片模型
 type CandidatePhoto struct {
-	ID           uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
-	CandidateID  uuid.UUID `gorm:"type:uuid;not null" json:"candidate_id"`
-	PhotoURL     string    `gorm:"type:varchar(500);not null" json:"photo_url"`
-	IsAvatar     bool      `gorm:"default:false" json:"is_avatar"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID          uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
+	CandidateID uuid.UUID      `gorm:"type:uuid;not null;index;uniqueIndex:idx_candidate_photos_avatar,where:is_avatar" json:"candidate_id"` // 每个候选人最多一张头像
+	PhotoURL    string         `gorm:"type:varchar(500);not null" json:"photo_url"`
+	IsAvatar    bool           `gorm:"default:false" json:"is_avatar"`
+	CreatedAt   time.Time      `json:"created_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deleted_at"` // 软删除（回收站）
 }
 
 // BeforeCreate GORM hook
@@ -79,20 +126,350 @@ func (cp *CandidatePhoto) BeforeCreate(tx *gorm.DB) error {
 }
 
 // History 历史记录模型
+// CandidateID 故意不加外键约束：历史记录保存了 CandidateName 快照，
+// 删除候选人后仍应保留其被选中的记录
 type History struct {
-	ID           uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
-	ProjectID    uuid.UUID `gorm:"type:uuid;not null" json:"project_id"`
-	ProjectName  string    `gorm:"type:varchar(200);not null" json:"project_name"`
-	CandidateID  uuid.UUID `gorm:"type:uuid;not null" json:"candidate_id"`
-	CandidateName string   `gorm:"type:varchar(100);not null" json:"candidate_name"`
-	SelectedAt   time.Time `gorm:"not null" json:"selected_at"`
-	UserID       uuid.UUID `gorm:"type:uuid;not null" json:"user_id"`
+	ID            uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	ProjectID     uuid.UUID  `gorm:"type:uuid;not null" json:"project_id"`
+	ProjectName   string     `gorm:"type:varchar(200);not null" json:"project_name"`
+	CandidateID   uuid.UUID  `gorm:"type:uuid;not null" json:"candidate_id"`
+	CandidateName string     `gorm:"type:varchar(100);not null" json:"candidate_name"`
+	SelectedAt    time.Time  `gorm:"not null" json:"selected_at"`
+	UserID        uuid.UUID  `gorm:"type:uuid;not null" json:"user_id"`
+	Source        string     `gorm:"type:varchar(20);not null;default:draw" json:"source"` // 记录来源，见 HistorySource* 常量
+	Note          string     `gorm:"type:text;not null;default:''" json:"note"`
+	Status        string     `gorm:"type:varchar(20);not null;default:picked" json:"status"` // 任务状态，见 HistoryStatus* 常量
+	StartedAt     *time.Time `json:"started_at"`
+	CompletedAt   *time.Time `json:"completed_at"`
+	SkippedAt     *time.Time `json:"skipped_at"`
+	VoidedAt      *time.Time `json:"voided_at"` // 作废时间，作废的记录保留但不计入统计
+	VoidReason    string     `gorm:"type:varchar(200);not null;default:''" json:"void_reason"`
+	// 选中时项目的积分设置和目标时长，之后修改项目不影响已有的记录
+	PickPoints    int  `gorm:"not null;default:0" json:"pick_points"`
+	OnTimePoints  int  `gorm:"not null;default:0" json:"on_time_points"`
+	TargetMinutes *int `json:"target_minutes"` // 为空表示选中时项目没有目标时长
+	// 例程执行产生的记录所属的执行和步骤（从 0 开始）
+	RoutineRunID *uuid.UUID `gorm:"type:uuid;index" json:"routine_run_id"`
+	RoutineStep  *int       `json:"routine_step"`
+	// 选择时项目的候选人池快照（JSON array）和当时的标签查询，之后修改项目或标签不影响已有的记录
+	PoolCandidateIDs string `gorm:"type:text" json:"pool_candidate_ids"`
+	TagQuery         string `gorm:"type:varchar(500);not null;default:''" json:"tag_query"`
+	// 特殊日规则改变了选择结果时为 true，OverrideReason 为生效规则的说明
+	Overridden     bool   `gorm:"not null;default:false" json:"overridden"`
+	OverrideReason string `gorm:"type:text;not null;default:''" json:"override_reason"`
+	// 重新选择：原记录被作废并指向取代它的记录，新记录指向原记录
+	SupersededByID *uuid.UUID `gorm:"type:uuid" json:"superseded_by_id"`
+	SupersededAt   *time.Time `json:"superseded_at"`
+	RerollOfID     *uuid.UUID `gorm:"type:uuid" json:"reroll_of_id"`
+	// 分组产生的记录所属的分组记录和所在的组（从 0 开始）
+	SplitID    *uuid.UUID `gorm:"type:uuid;index" json:"split_id"`
+	SplitGroup *int       `json:"split_group"`
+
+	Changes []HistoryChange `gorm:"foreignKey:HistoryID;constraint:OnDelete:CASCADE" json:"-"`
 }
 
+// 历史记录来源
+const (
+	HistorySourceDraw   = "draw"   // 随机选择产生
+	HistorySourceImport = "import" // 从导出归档导入
+	HistorySourceSplit  = "split"  // 分组产生，每个候选人一条；不是随机选中，不计入公平性统计、积分、徽章和项目间约束
+)
+
+// 任务状态：picked → started → completed / skipped，picked 也可以直接完成或跳过
+const (
+	HistoryStatusPicked    = "picked"    // 已选中，尚未开始
+	HistoryStatusStarted   = "started"   // 已开始
+	HistoryStatusCompleted = "completed" // 已完成
+	HistoryStatusSkipped   = "skipped"   // 已跳过
+)
+
 // BeforeCreate GORM hook
 func (h *History) BeforeCreate(tx *gorm.DB) error {
 	if h.ID == uuid.Nil {
 		h.ID = uuid.New()
 	}
+	if h.Source == "" {
+		h.Source = HistorySourceDraw
+	}
+	if h.Status == "" {
+		h.Status = HistoryStatusPicked
+	}
+	return nil
+}
+
+// Duration 任务从开始到完成的时长；没有记录开始或完成时间时 ok 为 false
+func (h *History) Duration() (d time.Duration, ok bool) {
+	if h.StartedAt == nil || h.CompletedAt == nil {
+		return 0, false
+	}
+	return h.CompletedAt.Sub(*h.StartedAt), true
+}
+
+// HistoryChange 历史记录的修改记录，每次修改的每个字段一条
+// 时间字段的值为 RFC3339 格式，空字符串表示未设置
+type HistoryChange struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	HistoryID uuid.UUID `gorm:"type:uuid;not null;index" json:"history_id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null" json:"user_id"` // 修改人
+	Field     string    `gorm:"type:varchar(30);not null" json:"field"`
+	OldValue  string    `gorm:"type:text;not null;default:''" json:"old_value"`
+	NewValue  string    `gorm:"type:text;not null;default:''" json:"new_value"`
+	ChangedAt time.Time `gorm:"not null" json:"changed_at"`
+}
+
+// BeforeCreate GORM hook
+func (hc *HistoryChange) BeforeCreate(tx *gorm.DB) error {
+	if hc.ID == uuid.Nil {
+		hc.ID = uuid.New()
+	}
+	return nil
+}
+
+// Reward 可以用积分兑换的奖励
+type Reward struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
+	Name      string    `gorm:"type:varchar(100);not null" json:"name"`
+	Cost      int       `gorm:"not null" json:"cost"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// BeforeCreate GORM hook
+func (r *Reward) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// PointEntry 积分流水，每次加减积分一条，只增不改
+type PointEntry struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	UserID      uuid.UUID  `gorm:"type:uuid;not null;index:idx_point_entries_user_created" json:"user_id"`
+	CandidateID uuid.UUID  `gorm:"type:uuid;not null;index" json:"candidate_id"`
+	Kind        string     `gorm:"type:varchar(20);not null" json:"kind"` // 见 PointKind* 常量
+	Amount      int        `gorm:"not null" json:"amount"`                // 正数为增加，负数为扣除
+	Balance     int        `gorm:"not null" json:"balance"`               // 记账后的余额
+	HistoryID   *uuid.UUID `gorm:"type:uuid;index" json:"history_id"`     // 来源历史记录
+	RewardID    *uuid.UUID `gorm:"type:uuid" json:"reward_id"`            // 兑换的奖励，奖励删除后保留
+	Note        string     `gorm:"type:varchar(200);not null;default:''" json:"note"`
+	CreatedAt   time.Time  `gorm:"index:idx_point_entries_user_created" json:"created_at"`
+}
+
+// 积分流水类型
+const (
+	PointKindPick   = "pick"    // 被选中
+	PointKindOnTime = "on_time" // 按时完成
+	PointKindRedeem = "redeem"  // 兑换奖励
+	PointKindAdjust = "adjust"  // 家长手动调整
+)
+
+// BeforeCreate GORM hook
+func (pe *PointEntry) BeforeCreate(tx *gorm.DB) error {
+	if pe.ID == uuid.Nil {
+		pe.ID = uuid.New()
+	}
+	return nil
+}
+
+// BadgeRule 徽章规则，每个家庭可以用内置的规则类型配置自己的徽章和门槛
+type BadgeRule struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	Name      string     `gorm:"type:varchar(100);not null" json:"name"`
+	Type      string     `gorm:"type:varchar(30);not null" json:"type"` // 见 BadgeRule* 常量
+	Threshold int        `gorm:"not null" json:"threshold"`
+	ProjectID *uuid.UUID `gorm:"type:uuid" json:"project_id"` // 只统计该项目的记录，为空表示所有项目
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+
+	Badges []Badge `gorm:"foreignKey:RuleID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// 徽章规则类型
+const (
+	BadgeRuleCompletedStreak = "completed_streak" // 连续完成 N 次，跳过会中断
+	BadgeRuleTotalCompleted  = "total_completed"  // 累计完成 N 次
+	BadgeRuleFirstTo         = "first_to"         // 第一个被选中 N 次的候选人，每条规则只授予一人
+	BadgeRuleCleanWeek       = "clean_week"       // 一周内完成 N 次且没有跳过，每周可以获得一次
+)
+
+// BeforeCreate GORM hook
+func (br *BadgeRule) BeforeCreate(tx *gorm.DB) error {
+	if br.ID == uuid.Nil {
+		br.ID = uuid.New()
+	}
+	return nil
+}
+
+// Badge 候选人获得的徽章
+type Badge struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	UserID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	RuleID      uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_badges_rule_candidate_period" json:"rule_id"`
+	CandidateID uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_badges_rule_candidate_period" json:"candidate_id"`
+	Period      string     `gorm:"type:varchar(10);not null;default:'';uniqueIndex:idx_badges_rule_candidate_period" json:"period"` // 按周的徽章为该周周一的日期，其他为空
+	HistoryID   *uuid.UUID `gorm:"type:uuid" json:"history_id"`                                                                     // 达成条件的历史记录
+	EarnedAt    time.Time  `gorm:"not null" json:"earned_at"`                                                                       // 达成条件的时间
+}
+
+// BeforeCreate GORM hook
+func (b *Badge) BeforeCreate(tx *gorm.DB) error {
+	if b.ID == uuid.Nil {
+		b.ID = uuid.New()
+	}
+	return nil
+}
+
+// Routine 例程：按顺序执行的多个项目，例如洗澡 → 刷牙 → 换睡衣 → 讲故事
+type Routine struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	Name         string    `gorm:"type:varchar(100);not null" json:"name"`
+	UserID       uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
+	ProjectIDs   string    `gorm:"type:text" json:"project_ids"`  // JSON array，按步骤顺序
+	AvoidRepeats bool      `gorm:"not null" json:"avoid_repeats"` // 一次执行中尽量不重复选中同一个候选人
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// BeforeCreate GORM hook
+func (r *Routine) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// RoutineRun 例程的一次执行，每个步骤对应一条历史记录
+// 删除例程后执行记录保留，RoutineName 保存执行时的名称
+type RoutineRun struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	UserID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	RoutineID   uuid.UUID  `gorm:"type:uuid;not null;index" json:"routine_id"`
+	RoutineName string     `gorm:"type:varchar(100);not null" json:"routine_name"`
+	Steps       int        `gorm:"not null" json:"steps"`
+	CurrentStep int        `gorm:"not null;default:0" json:"current_step"` // 当前步骤（从 0 开始），全部完成后等于 Steps
+	StartedAt   time.Time  `gorm:"not null" json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at"`
+}
+
+// BeforeCreate GORM hook
+func (rr *RoutineRun) BeforeCreate(tx *gorm.DB) error {
+	if rr.ID == uuid.Nil {
+		rr.ID = uuid.New()
+	}
+	return nil
+}
+
+// ProjectConstraint 项目间约束：今天在来源项目中被选中的候选人，在目标项目中被排除或降低权重
+type ProjectConstraint struct {
+	ID              uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	UserID          uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
+	SourceProjectID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_project_constraints_source_target" json:"source_project_id"`
+	TargetProjectID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_project_constraints_source_target;index" json:"target_project_id"`
+	Mode            string    `gorm:"type:varchar(20);not null" json:"mode"` // 见 ConstraintMode* 常量
+	Weight          float64   `gorm:"not null" json:"weight"`                // 降低后的权重（0–1，其他候选人为 1），排除时为 0
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// 项目间约束的方式
+const (
+	ConstraintModeExclude    = "exclude"     // 排除，所有候选人都被排除时不生效
+	ConstraintModeDownWeight = "down_weight" // 按 Weight 降低被选中的概率
+)
+
+// BeforeCreate GORM hook
+func (pc *ProjectConstraint) BeforeCreate(tx *gorm.DB) error {
+	if pc.ID == uuid.Nil {
+		pc.ID = uuid.New()
+	}
+	return nil
+}
+
+// TeamSplit 分组记录：项目的候选人被随机分成人数均衡的若干组
+type TeamSplit struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	UserID      uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
+	ProjectID   uuid.UUID `gorm:"type:uuid;not null;index" json:"project_id"`
+	ProjectName string    `gorm:"type:varchar(200);not null" json:"project_name"`
+	GroupCount  int       `gorm:"not null" json:"group_count"`
+	Groups      string    `gorm:"type:text;not null" json:"groups"` // JSON array，每组为 [{candidate_id, candidate_name}]
+	KeepApart   string    `gorm:"type:text" json:"keep_apart"`      // JSON array，不能分在同一组的候选人 ID 对
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// BeforeCreate GORM hook
+func (ts *TeamSplit) BeforeCreate(tx *gorm.DB) error {
+	if ts.ID == uuid.Nil {
+		ts.ID = uuid.New()
+	}
+	return nil
+}
+
+// CalendarFeed 历史记录的日历订阅
+// 日历应用通过带令牌的地址拉取 iCalendar 文件，不需要登录；只保存令牌的哈希
+type CalendarFeed struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	UserID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	Name        string     `gorm:"type:varchar(100);not null" json:"name"`
+	TokenHash   string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	ProjectID   *uuid.UUID `gorm:"type:uuid" json:"project_id"`   // 只包含该项目的记录，为空表示所有项目
+	CandidateID *uuid.UUID `gorm:"type:uuid" json:"candidate_id"` // 只包含该候选人的记录，为空表示所有候选人
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// BeforeCreate GORM hook
+func (f *CalendarFeed) BeforeCreate(tx *gorm.DB) error {
+	if f.ID == uuid.Nil {
+		f.ID = uuid.New()
+	}
+	return nil
+}
+
+// AccountDeletion 账号删除审计记录
+// 只记录删除了多少数据，不保存被删除用户的任何个人信息
+type AccountDeletion struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	UserID      uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
+	RequestedAt time.Time `gorm:"not null" json:"requested_at"`
+	PurgedAt    time.Time `gorm:"not null" json:"purged_at"`
+	Projects    int       `gorm:"not null;default:0" json:"projects"`
+	Candidates  int       `gorm:"not null;default:0" json:"candidates"`
+	Photos      int       `gorm:"not null;default:0" json:"photos"`
+	Files       int       `gorm:"not null;default:0" json:"files"`
+	Histories   int       `gorm:"not null;default:0" json:"histories"`
+}
+
+// BeforeCreate GORM hook
+func (d *AccountDeletion) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
+
+// IdempotencyKey 幂等键：保存带 Idempotency-Key 请求头的写请求的第一次响应，
+// 有效期内用同一个键重试时直接返回保存的响应
+type IdempotencyKey struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	UserID       uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_idempotency_keys_user_key" json:"user_id"`
+	Key          string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_idempotency_keys_user_key" json:"key"`
+	Method       string    `gorm:"type:varchar(10);not null" json:"method"`
+	Path         string    `gorm:"type:text;not null" json:"path"`
+	RequestHash  string    `gorm:"type:varchar(64);not null" json:"request_hash"` // 方法、路径和请求体的 SHA-256
+	StatusCode   int       `gorm:"not null;default:0" json:"status_code"`         // 0 表示第一次请求仍在处理中
+	ContentType  string    `gorm:"type:varchar(100)" json:"content_type"`
+	ResponseBody string    `gorm:"type:text" json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+	ExpiresAt    time.Time `gorm:"not null;index" json:"expires_at"`
+}
+
+// BeforeCreate GORM hook
+func (k *IdempotencyKey) BeforeCreate(tx *gorm.DB) error {
+	if k.ID == uuid.Nil {
+		k.ID = uuid.New()
+	}
 	return nil
 }
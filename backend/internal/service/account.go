@@ -0,0 +1,282 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"whotakesshowers/internal/logger"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// AccountService 账号删除服务
+// 申请删除后进入冷静期，期间可以导出数据或撤销；冷静期结束后账号及所有数据被彻底删除
+type AccountService struct {
+	store       store.Store
+	gracePeriod time.Duration
+}
+
+// NewAccountService 创建账号删除服务，gracePeriod 为申请删除后的冷静期
+func NewAccountService(s store.Store, gracePeriod time.Duration) *AccountService {
+	return &AccountService{store: s, gracePeriod: gracePeriod}
+}
+
+// DeletionSchedule 账号删除计划
+type DeletionSchedule struct {
+	RequestedAt time.Time `json:"requested_at"`
+	DeleteAfter time.Time `json:"delete_after"`
+	Deleted     bool      `json:"deleted"` // 没有冷静期时账号已被立即删除
+}
+
+// RequestDeletion 校验密码后申请删除账号；没有冷静期时立即删除
+func (s *AccountService) RequestDeletion(userID uuid.UUID, password string) (*DeletionSchedule, error) {
+	user, err := s.store.Users().Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Password == "" || bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if user.DeletionRequestedAt != nil {
+		return &DeletionSchedule{
+			RequestedAt: *user.DeletionRequestedAt,
+			DeleteAfter: user.DeletionRequestedAt.Add(s.gracePeriod),
+		}, nil
+	}
+
+	now := time.Now()
+	if err := s.store.Users().SetDeletionRequestedAt(userID, &now); err != nil {
+		return nil, err
+	}
+	user.DeletionRequestedAt = &now
+	logger.Info("Account deletion requested",
+		zap.String("user_id", userID.String()),
+		zap.Duration("grace_period", s.gracePeriod),
+	)
+
+	schedule := &DeletionSchedule{RequestedAt: now, DeleteAfter: now.Add(s.gracePeriod)}
+	if s.gracePeriod <= 0 {
+		if err := s.purgeUser(user); err != nil {
+			return nil, err
+		}
+		schedule.Deleted = true
+	}
+	return schedule, nil
+}
+
+// CancelDeletion 撤销账号删除申请
+func (s *AccountService) CancelDeletion(userID uuid.UUID) error {
+	user, err := s.store.Users().Get(userID)
+	if err != nil {
+		return err
+	}
+	if user.DeletionRequestedAt == nil {
+		return ErrDeletionNotRequested
+	}
+	if err := s.store.Users().SetDeletionRequestedAt(userID, nil); err != nil {
+		return err
+	}
+	logger.Info("Account deletion cancelled", zap.String("user_id", userID.String()))
+	return nil
+}
+
+// PurgeDue 彻底删除冷静期已结束的账号，返回删除的账号数
+func (s *AccountService) PurgeDue() (int, error) {
+	users, err := s.store.Users().ListDeletionDue(time.Now().Add(-s.gracePeriod))
+	if err != nil {
+		return 0, err
+	}
+	purged := 0
+	for i := range users {
+		err := s.purgeUser(&users[i])
+		if errors.Is(err, ErrDeletionNotRequested) || errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// StartPurger 启动后台任务，每隔 interval 删除冷静期已结束的账号
+// interval 不大于 0 时不启动
+func (s *AccountService) StartPurger(interval time.Duration) {
+	if interval <= 0 {
+		logger.Warn("Account purger disabled", zap.Duration("interval", interval))
+		return
+	}
+
+	run := func() {
+		purged, err := s.PurgeDue()
+		if err != nil {
+			logger.Error("Failed to purge deleted accounts", zap.Error(err))
+			return
+		}
+		if purged > 0 {
+			logger.Info("Purged deleted accounts", zap.Int("count", purged))
+		}
+	}
+
+	go func() {
+		run()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			run()
+		}
+	}()
+}
+
+// purgeUser 在一个事务中删除用户的历史记录、照片、积分流水、奖励、徽章、例程、项目间约束、分组记录、幂等键、候选人（包括回收站中的）、项目、日历订阅和账号本身，
+// 并写入审计记录；提交后删除照片文件。用户删除后其登录 token 随之失效
+func (s *AccountService) purgeUser(user *model.User) error {
+	deletion := &model.AccountDeletion{
+		UserID:      user.ID,
+		RequestedAt: *user.DeletionRequestedAt,
+	}
+	files := make(map[string]bool)
+
+	err := s.store.Transaction(func(tx store.Store) error {
+		// 申请可能在列出到期账号之后被撤销
+		current, err := tx.Users().Get(user.ID)
+		if err != nil {
+			return err
+		}
+		if current.DeletionRequestedAt == nil {
+			return ErrDeletionNotRequested
+		}
+
+		histories, err := tx.Histories().DeleteByUser(user.ID)
+		if err != nil {
+			return err
+		}
+		deletion.Histories = int(histories)
+
+		if err := tx.PointEntries().DeleteByUser(user.ID); err != nil {
+			return err
+		}
+		if err := tx.Rewards().DeleteByUser(user.ID); err != nil {
+			return err
+		}
+		if err := tx.Badges().DeleteByUser(user.ID); err != nil {
+			return err
+		}
+		if err := tx.BadgeRules().DeleteByUser(user.ID); err != nil {
+			return err
+		}
+		if err := tx.RoutineRuns().DeleteByUser(user.ID); err != nil {
+			return err
+		}
+		if err := tx.Routines().DeleteByUser(user.ID); err != nil {
+			return err
+		}
+		if err := tx.ProjectConstraints().DeleteByUser(user.ID); err != nil {
+			return err
+		}
+		if err := tx.TeamSplits().DeleteByUser(user.ID); err != nil {
+			return err
+		}
+		if err := tx.IdempotencyKeys().DeleteByUser(user.ID); err != nil {
+			return err
+		}
+
+		candidates, err := listAllCandidates(tx, user.ID)
+		if err != nil {
+			return err
+		}
+		for _, candidate := range candidates {
+			photos, err := tx.CandidatePhotos().ListAll(candidate.ID)
+			if err != nil {
+				return err
+			}
+			for _, photo := range photos {
+				files[photo.PhotoURL] = true
+			}
+			files[candidate.PhotoURL] = true
+			deletion.Photos += len(photos)
+
+			if err := tx.CandidatePhotos().PurgeByCandidateID(candidate.ID); err != nil {
+				return err
+			}
+			if err := tx.Candidates().Purge(candidate.ID, user.ID); err != nil {
+				return err
+			}
+		}
+		deletion.Candidates = len(candidates)
+
+		projects, err := listAllProjects(tx, user.ID)
+		if err != nil {
+			return err
+		}
+		for _, project := range projects {
+			if err := tx.Projects().Purge(project.ID, user.ID); err != nil {
+				return err
+			}
+		}
+		deletion.Projects = len(projects)
+
+		if err := tx.CalendarFeeds().DeleteByUser(user.ID); err != nil {
+			return err
+		}
+		if err := tx.Users().Delete(user.ID); err != nil {
+			return err
+		}
+
+		for photoURL := range files {
+			if strings.HasPrefix(photoURL, uploadURLPrefix) {
+				deletion.Files++
+			}
+		}
+		deletion.PurgedAt = time.Now()
+		return tx.AccountDeletions().Create(deletion)
+	})
+	if err != nil {
+		return err
+	}
+
+	for photoURL := range files {
+		removeUpload(photoURL)
+	}
+	logger.Info("Account deleted",
+		zap.String("user_id", user.ID.String()),
+		zap.String("audit_id", deletion.ID.String()),
+		zap.Int("projects", deletion.Projects),
+		zap.Int("candidates", deletion.Candidates),
+		zap.Int("photos", deletion.Photos),
+		zap.Int("files", deletion.Files),
+		zap.Int("histories", deletion.Histories),
+	)
+	return nil
+}
+
+// listAllCandidates 获取用户的所有候选人，包括回收站中的
+func listAllCandidates(tx store.Store, userID uuid.UUID) ([]model.Candidate, error) {
+	active, err := tx.Candidates().List(userID)
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := tx.Candidates().ListDeleted(userID)
+	if err != nil {
+		return nil, err
+	}
+	return append(active, deleted...), nil
+}
+
+// listAllProjects 获取用户的所有项目，包括回收站中的
+func listAllProjects(tx store.Store, userID uuid.UUID) ([]model.Project, error) {
+	active, err := tx.Projects().List(userID)
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := tx.Projects().ListDeleted(userID)
+	if err != nil {
+		return nil, err
+	}
+	return append(active, deleted...), nil
+}
@@ -0,0 +1,494 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"whotakesshowers/internal/logger"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// 徽章规则门槛的上限
+const maxBadgeThreshold = 1000
+
+// BadgeService 徽章规则与徽章
+// 记录随机选择或修改任务状态时只评估相关候选人的徽章；新建、修改规则或调用 Backfill 时评估全部已有记录。
+// 徽章一经获得不会因为之后作废或撤销记录而收回
+type BadgeService struct {
+	store store.Store
+}
+
+// NewBadgeService 创建徽章服务
+func NewBadgeService(s store.Store) *BadgeService {
+	return &BadgeService{store: s}
+}
+
+// BadgeRuleInput 创建或修改徽章规则的内容
+type BadgeRuleInput struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Type      string     `json:"type" binding:"required"`
+	Threshold int        `json:"threshold"`
+	ProjectID *uuid.UUID `json:"project_id"`
+}
+
+// ListRules 获取徽章规则
+func (s *BadgeService) ListRules(userID uuid.UUID) ([]model.BadgeRule, error) {
+	return s.store.BadgeRules().List(userID)
+}
+
+// CreateRule 创建徽章规则，并按已有的历史记录补发徽章
+func (s *BadgeService) CreateRule(userID uuid.UUID, input BadgeRuleInput) (*model.BadgeRule, error) {
+	rule := &model.BadgeRule{UserID: userID}
+	err := s.store.Transaction(func(tx store.Store) error {
+		if err := applyBadgeRuleInput(tx, rule, input); err != nil {
+			return err
+		}
+		if err := tx.BadgeRules().Create(rule); err != nil {
+			return err
+		}
+		_, err := awardBadges(tx, userID, []model.BadgeRule{*rule}, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// UpdateRule 修改徽章规则；规则授予的徽章按新的条件重新计算
+func (s *BadgeService) UpdateRule(userID, ruleID uuid.UUID, input BadgeRuleInput) (*model.BadgeRule, error) {
+	var rule *model.BadgeRule
+	err := s.store.Transaction(func(tx store.Store) error {
+		var err error
+		rule, err = tx.BadgeRules().Get(ruleID, userID)
+		if errors.Is(err, store.ErrNotFound) {
+			return ErrBadgeRuleNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := applyBadgeRuleInput(tx, rule, input); err != nil {
+			return err
+		}
+		if err := tx.BadgeRules().Update(rule); err != nil {
+			return err
+		}
+		if err := tx.Badges().DeleteByRule(rule.ID); err != nil {
+			return err
+		}
+		_, err = awardBadges(tx, userID, []model.BadgeRule{*rule}, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// DeleteRule 删除徽章规则及其授予的徽章
+func (s *BadgeService) DeleteRule(userID, ruleID uuid.UUID) error {
+	return s.store.Transaction(func(tx store.Store) error {
+		if _, err := tx.BadgeRules().Get(ruleID, userID); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return ErrBadgeRuleNotFound
+			}
+			return err
+		}
+		if err := tx.Badges().DeleteByRule(ruleID); err != nil {
+			return err
+		}
+		return tx.BadgeRules().Delete(ruleID, userID)
+	})
+}
+
+// Backfill 按所有已有的历史记录评估全部规则，补发缺少的徽章（例如导入历史记录之后），返回新授予的数量
+func (s *BadgeService) Backfill(userID uuid.UUID) (int, error) {
+	var awarded int
+	err := s.store.Transaction(func(tx store.Store) error {
+		rules, err := tx.BadgeRules().List(userID)
+		if err != nil {
+			return err
+		}
+		awarded, err = awardBadges(tx, userID, rules, nil)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	logger.Info("Badges backfilled", zap.String("user_id", userID.String()), zap.Int("awarded", awarded))
+	return awarded, nil
+}
+
+// EarnedBadge 已获得的徽章
+type EarnedBadge struct {
+	model.Badge
+	RuleName      string `json:"rule_name"`
+	RuleType      string `json:"rule_type"`
+	CandidateName string `json:"candidate_name"`
+}
+
+// BadgeProgress 尚未获得的徽章的进度
+type BadgeProgress struct {
+	RuleID        uuid.UUID `json:"rule_id"`
+	RuleName      string    `json:"rule_name"`
+	RuleType      string    `json:"rule_type"`
+	CandidateID   uuid.UUID `json:"candidate_id"`
+	CandidateName string    `json:"candidate_name"`
+	Current       int       `json:"current"`
+	Threshold     int       `json:"threshold"`
+}
+
+// BadgeList 已获得和进行中的徽章
+type BadgeList struct {
+	Earned     []EarnedBadge   `json:"earned"`
+	InProgress []BadgeProgress `json:"in_progress"`
+}
+
+// List 获取已获得的徽章和当前候选人进行中的徽章，candidateID 不为空时只返回该候选人的
+func (s *BadgeService) List(userID uuid.UUID, candidateID *uuid.UUID) (*BadgeList, error) {
+	rules, err := s.store.BadgeRules().List(userID)
+	if err != nil {
+		return nil, err
+	}
+	badges, err := s.store.Badges().List(userID, nil)
+	if err != nil {
+		return nil, err
+	}
+	candidates, err := listAllCandidates(s.store, userID)
+	if err != nil {
+		return nil, err
+	}
+	histories, err := badgeHistories(s.store, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[uuid.UUID]string, len(candidates))
+	for _, candidate := range candidates {
+		names[candidate.ID] = candidate.Name
+	}
+	rulesByID := make(map[uuid.UUID]*model.BadgeRule, len(rules))
+	for i := range rules {
+		rulesByID[rules[i].ID] = &rules[i]
+	}
+	earned := make(map[badgeKey]bool, len(badges))
+	claimed := make(map[uuid.UUID]bool) // 已经授予过的 first_to 规则
+
+	result := &BadgeList{Earned: make([]EarnedBadge, 0), InProgress: make([]BadgeProgress, 0)}
+	for _, badge := range badges {
+		earned[badgeKey{badge.RuleID, badge.CandidateID, badge.Period}] = true
+		claimed[badge.RuleID] = true
+		if candidateID != nil && badge.CandidateID != *candidateID {
+			continue
+		}
+		rule := rulesByID[badge.RuleID]
+		result.Earned = append(result.Earned, EarnedBadge{
+			Badge:         badge,
+			RuleName:      rule.Name,
+			RuleType:      rule.Type,
+			CandidateName: names[badge.CandidateID],
+		})
+	}
+
+	// 进行中的徽章只包括当前的候选人，不包括回收站中的
+	current, err := s.store.Candidates().List(userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, rule := range rules {
+		if rule.Type == model.BadgeRuleFirstTo && claimed[rule.ID] {
+			continue
+		}
+		_, progress := evaluateBadgeRule(&rule, ruleHistories(&rule, histories), now)
+		for _, candidate := range current {
+			if candidateID != nil && candidate.ID != *candidateID {
+				continue
+			}
+			if earned[badgeKey{rule.ID, candidate.ID, badgePeriod(&rule, now)}] {
+				continue
+			}
+			result.InProgress = append(result.InProgress, BadgeProgress{
+				RuleID:        rule.ID,
+				RuleName:      rule.Name,
+				RuleType:      rule.Type,
+				CandidateID:   candidate.ID,
+				CandidateName: candidate.Name,
+				Current:       min(progress[candidate.ID], rule.Threshold),
+				Threshold:     rule.Threshold,
+			})
+		}
+	}
+	return result, nil
+}
+
+// applyBadgeRuleInput 校验并写入徽章规则的内容
+func applyBadgeRuleInput(tx store.Store, rule *model.BadgeRule, input BadgeRuleInput) error {
+	switch input.Type {
+	case model.BadgeRuleCompletedStreak, model.BadgeRuleTotalCompleted, model.BadgeRuleFirstTo, model.BadgeRuleCleanWeek:
+	default:
+		return fmt.Errorf("%w: type must be %s, %s, %s or %s", ErrInvalidBadgeRule,
+			model.BadgeRuleCompletedStreak, model.BadgeRuleTotalCompleted, model.BadgeRuleFirstTo, model.BadgeRuleCleanWeek)
+	}
+	if input.Threshold < 1 || input.Threshold > maxBadgeThreshold {
+		return fmt.Errorf("%w: threshold must be between 1 and %d", ErrInvalidBadgeRule, maxBadgeThreshold)
+	}
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidBadgeRule)
+	}
+	if input.ProjectID != nil {
+		if _, err := tx.Projects().Get(*input.ProjectID, rule.UserID); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return ErrProjectNotFound
+			}
+			return err
+		}
+	}
+
+	rule.Name = name
+	rule.Type = input.Type
+	rule.Threshold = input.Threshold
+	rule.ProjectID = input.ProjectID
+	return nil
+}
+
+// badgeKey 徽章的唯一键
+type badgeKey struct {
+	ruleID      uuid.UUID
+	candidateID uuid.UUID
+	period      string
+}
+
+// badgeAward 评估规则得到的一次授予
+type badgeAward struct {
+	candidateID uuid.UUID
+	period      string
+	historyID   uuid.UUID
+	earnedAt    time.Time
+}
+
+// awardCandidateBadges 记录随机选择或修改任务状态后，评估该候选人的所有徽章
+func awardCandidateBadges(tx store.Store, userID, candidateID uuid.UUID) error {
+	rules, err := tx.BadgeRules().List(userID)
+	if err != nil || len(rules) == 0 {
+		return err
+	}
+	awarded, err := awardBadges(tx, userID, rules, &candidateID)
+	if err != nil {
+		return err
+	}
+	if awarded > 0 {
+		logger.Info("Badges awarded",
+			zap.String("user_id", userID.String()),
+			zap.String("candidate_id", candidateID.String()),
+			zap.Int("count", awarded),
+		)
+	}
+	return nil
+}
+
+// awardBadges 按历史记录评估 rules，授予尚未获得的徽章；candidateID 不为空时只授予该候选人，返回新授予的数量
+func awardBadges(tx store.Store, userID uuid.UUID, rules []model.BadgeRule, candidateID *uuid.UUID) (int, error) {
+	if len(rules) == 0 {
+		return 0, nil
+	}
+	histories, err := badgeHistories(tx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	awarded := 0
+	for i := range rules {
+		rule := &rules[i]
+		existing, err := tx.Badges().ListByRule(rule.ID)
+		if err != nil {
+			return awarded, err
+		}
+		if rule.Type == model.BadgeRuleFirstTo && len(existing) > 0 {
+			continue
+		}
+		earned := make(map[badgeKey]bool, len(existing))
+		for _, badge := range existing {
+			earned[badgeKey{badge.RuleID, badge.CandidateID, badge.Period}] = true
+		}
+
+		awards, _ := evaluateBadgeRule(rule, ruleHistories(rule, histories), now)
+		for _, award := range awards {
+			if candidateID != nil && award.candidateID != *candidateID {
+				continue
+			}
+			if earned[badgeKey{rule.ID, award.candidateID, award.period}] {
+				continue
+			}
+			historyID := award.historyID
+			badge := &model.Badge{
+				UserID:      userID,
+				RuleID:      rule.ID,
+				CandidateID: award.candidateID,
+				Period:      award.period,
+				HistoryID:   &historyID,
+				EarnedAt:    award.earnedAt,
+			}
+			if err := tx.Badges().Create(badge); err != nil {
+				return awarded, err
+			}
+			awarded++
+		}
+	}
+	return awarded, nil
+}
+
+// badgeHistories 按选中时间正序返回参与徽章评估的历史记录（不包括作废的、分组产生的和已删除候选人的）
+func badgeHistories(s store.Store, userID uuid.UUID) ([]model.History, error) {
+	histories, err := s.Histories().List(userID, nil, -1)
+	if err != nil {
+		return nil, err
+	}
+	candidates, err := listAllCandidates(s, userID)
+	if err != nil {
+		return nil, err
+	}
+	exists := make(map[uuid.UUID]bool, len(candidates))
+	for _, candidate := range candidates {
+		exists[candidate.ID] = true
+	}
+
+	histories = slices.DeleteFunc(histories, func(h model.History) bool {
+		return h.VoidedAt != nil || h.Source == model.HistorySourceSplit || !exists[h.CandidateID]
+	})
+	slices.Reverse(histories)
+	return histories, nil
+}
+
+// ruleHistories 返回规则统计范围内的历史记录
+func ruleHistories(rule *model.BadgeRule, histories []model.History) []model.History {
+	if rule.ProjectID == nil {
+		return histories
+	}
+	var scoped []model.History
+	for _, history := range histories {
+		if history.ProjectID == *rule.ProjectID {
+			scoped = append(scoped, history)
+		}
+	}
+	return scoped
+}
+
+// evaluateBadgeRule 按时间顺序回放历史记录，返回规则的所有授予和每个候选人当前的进度
+func evaluateBadgeRule(rule *model.BadgeRule, histories []model.History, now time.Time) ([]badgeAward, map[uuid.UUID]int) {
+	var awards []badgeAward
+	progress := make(map[uuid.UUID]int)
+	awarded := make(map[badgeKey]bool)
+	award := func(history *model.History, period string, at time.Time) {
+		key := badgeKey{rule.ID, history.CandidateID, period}
+		if awarded[key] {
+			return
+		}
+		awarded[key] = true
+		awards = append(awards, badgeAward{
+			candidateID: history.CandidateID,
+			period:      period,
+			historyID:   history.ID,
+			earnedAt:    at,
+		})
+	}
+
+	switch rule.Type {
+	case model.BadgeRuleCompletedStreak:
+		// 选中后尚未完成或跳过的记录不影响连续次数
+		for i := range histories {
+			history := &histories[i]
+			switch history.Status {
+			case model.HistoryStatusCompleted:
+				progress[history.CandidateID]++
+				if progress[history.CandidateID] >= rule.Threshold {
+					award(history, "", *history.CompletedAt)
+				}
+			case model.HistoryStatusSkipped:
+				progress[history.CandidateID] = 0
+			}
+		}
+
+	case model.BadgeRuleTotalCompleted:
+		for i := range histories {
+			history := &histories[i]
+			if history.Status != model.HistoryStatusCompleted {
+				continue
+			}
+			progress[history.CandidateID]++
+			if progress[history.CandidateID] >= rule.Threshold {
+				award(history, "", *history.CompletedAt)
+			}
+		}
+
+	case model.BadgeRuleFirstTo:
+		for i := range histories {
+			history := &histories[i]
+			progress[history.CandidateID]++
+			if len(awards) == 0 && progress[history.CandidateID] >= rule.Threshold {
+				award(history, "", history.SelectedAt)
+			}
+		}
+
+	case model.BadgeRuleCleanWeek:
+		// 按选中时间所在的周（周一开始）统计；一周结束后，完成次数达到门槛且没有跳过才授予
+		type week struct {
+			key       badgeKey
+			completed int
+			skipped   bool
+			last      *model.History // 本周最后一次完成的记录
+		}
+		var weeks []*week
+		byKey := make(map[badgeKey]*week)
+		for i := range histories {
+			history := &histories[i]
+			key := badgeKey{rule.ID, history.CandidateID, weekPeriod(history.SelectedAt)}
+			w := byKey[key]
+			if w == nil {
+				w = &week{key: key}
+				byKey[key] = w
+				weeks = append(weeks, w)
+			}
+			switch history.Status {
+			case model.HistoryStatusSkipped:
+				w.skipped = true
+			case model.HistoryStatusCompleted:
+				w.completed++
+				w.last = history
+			}
+		}
+		thisWeek := weekPeriod(now)
+		for _, w := range weeks {
+			switch {
+			case w.key.period == thisWeek:
+				if !w.skipped {
+					progress[w.key.candidateID] = w.completed
+				}
+			case !w.skipped && w.completed >= rule.Threshold:
+				award(w.last, w.key.period, *w.last.CompletedAt)
+			}
+		}
+	}
+	return awards, progress
+}
+
+// badgePeriod 规则当前的周期：按周的规则为本周，其他为空
+func badgePeriod(rule *model.BadgeRule, now time.Time) string {
+	if rule.Type == model.BadgeRuleCleanWeek {
+		return weekPeriod(now)
+	}
+	return ""
+}
+
+// weekPeriod t 所在周的周一日期（本地时区）
+func weekPeriod(t time.Time) string {
+	return bucketStart(t, BucketWeek).Format(time.DateOnly)
+}
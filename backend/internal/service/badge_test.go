@@ -0,0 +1,173 @@
+package service
+
+import (
+	"testing"
+	"time"
+	"whotakesshowers/internal/model"
+
+	"github.com/google/uuid"
+)
+
+func TestEvaluateBadgeRule(t *testing.T) {
+	// 2025-03-12 是周三，上一周从 2025-03-03 开始
+	now := time.Date(2025, 3, 12, 20, 0, 0, 0, time.Local)
+	lastWeek := time.Date(2025, 3, 4, 20, 0, 0, 0, time.Local)
+	ann, ben := uuid.New(), uuid.New()
+
+	// draw 按顺序生成历史记录，每条比上一条晚一小时
+	type draw struct {
+		candidate uuid.UUID
+		status    string
+		at        time.Time // 为零值时接着上一条
+	}
+	build := func(draws []draw) []model.History {
+		histories := make([]model.History, len(draws))
+		var at time.Time
+		for i, d := range draws {
+			if !d.at.IsZero() {
+				at = d.at
+			} else {
+				at = at.Add(time.Hour)
+			}
+			histories[i] = model.History{ID: uuid.New(), CandidateID: d.candidate, Status: d.status, SelectedAt: at}
+			if d.status == model.HistoryStatusCompleted {
+				completed := at.Add(15 * time.Minute)
+				histories[i].CompletedAt = &completed
+			}
+		}
+		return histories
+	}
+
+	const (
+		picked    = model.HistoryStatusPicked
+		completed = model.HistoryStatusCompleted
+		skipped   = model.HistoryStatusSkipped
+	)
+	type award struct {
+		index  int // 触发授予的记录下标
+		period string
+	}
+	tests := []struct {
+		name      string
+		ruleType  string
+		threshold int
+		draws     []draw
+		awards    []award
+		progress  map[uuid.UUID]int
+	}{
+		{
+			name:     "completed streak",
+			ruleType: model.BadgeRuleCompletedStreak, threshold: 2,
+			draws:    []draw{{ann, completed, now}, {ann, completed, time.Time{}}, {ann, completed, time.Time{}}},
+			awards:   []award{{index: 1}},
+			progress: map[uuid.UUID]int{ann: 3},
+		},
+		{
+			name:     "skip breaks the streak",
+			ruleType: model.BadgeRuleCompletedStreak, threshold: 2,
+			draws:    []draw{{ann, completed, now}, {ann, skipped, time.Time{}}, {ann, completed, time.Time{}}},
+			progress: map[uuid.UUID]int{ann: 1},
+		},
+		{
+			name:     "unfinished task does not break the streak",
+			ruleType: model.BadgeRuleCompletedStreak, threshold: 2,
+			draws:    []draw{{ann, completed, now}, {ann, picked, time.Time{}}, {ann, completed, time.Time{}}},
+			awards:   []award{{index: 2}},
+			progress: map[uuid.UUID]int{ann: 2},
+		},
+		{
+			name:     "streaks are per candidate",
+			ruleType: model.BadgeRuleCompletedStreak, threshold: 2,
+			draws:    []draw{{ann, completed, now}, {ben, skipped, time.Time{}}, {ann, completed, time.Time{}}},
+			awards:   []award{{index: 2}},
+			progress: map[uuid.UUID]int{ann: 2, ben: 0},
+		},
+		{
+			name:     "total completed ignores skips",
+			ruleType: model.BadgeRuleTotalCompleted, threshold: 2,
+			draws:    []draw{{ann, completed, now}, {ann, skipped, time.Time{}}, {ann, completed, time.Time{}}},
+			awards:   []award{{index: 2}},
+			progress: map[uuid.UUID]int{ann: 2},
+		},
+		{
+			name:     "total completed below threshold",
+			ruleType: model.BadgeRuleTotalCompleted, threshold: 3,
+			draws:    []draw{{ann, completed, now}, {ann, picked, time.Time{}}, {ben, completed, time.Time{}}},
+			progress: map[uuid.UUID]int{ann: 1, ben: 1},
+		},
+		{
+			name:     "first to counts every draw and awards once",
+			ruleType: model.BadgeRuleFirstTo, threshold: 2,
+			draws:    []draw{{ann, skipped, now}, {ben, picked, time.Time{}}, {ben, picked, time.Time{}}, {ann, completed, time.Time{}}},
+			awards:   []award{{index: 2}},
+			progress: map[uuid.UUID]int{ann: 2, ben: 2},
+		},
+		{
+			name:     "clean week",
+			ruleType: model.BadgeRuleCleanWeek, threshold: 2,
+			draws: []draw{
+				{ann, completed, lastWeek}, {ben, completed, time.Time{}}, {ann, completed, time.Time{}}, {ben, skipped, time.Time{}},
+				{ann, completed, now}, {ben, completed, time.Time{}},
+			},
+			awards:   []award{{index: 2, period: "2025-03-03"}},
+			progress: map[uuid.UUID]int{ann: 1, ben: 1},
+		},
+		{
+			name:     "clean week below threshold",
+			ruleType: model.BadgeRuleCleanWeek, threshold: 2,
+			draws:    []draw{{ann, completed, lastWeek}, {ann, picked, time.Time{}}},
+			progress: map[uuid.UUID]int{},
+		},
+		{
+			name:     "skip this week resets progress",
+			ruleType: model.BadgeRuleCleanWeek, threshold: 2,
+			draws:    []draw{{ann, completed, now}, {ann, skipped, time.Time{}}},
+			progress: map[uuid.UUID]int{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := &model.BadgeRule{ID: uuid.New(), Type: tt.ruleType, Threshold: tt.threshold}
+			histories := build(tt.draws)
+			awards, progress := evaluateBadgeRule(rule, histories, now)
+
+			if len(awards) != len(tt.awards) {
+				t.Fatalf("awards = %+v, want %+v", awards, tt.awards)
+			}
+			for i, want := range tt.awards {
+				history := histories[want.index]
+				earnedAt := history.SelectedAt
+				if history.CompletedAt != nil && tt.ruleType != model.BadgeRuleFirstTo {
+					earnedAt = *history.CompletedAt
+				}
+				got := awards[i]
+				if got.candidateID != history.CandidateID || got.historyID != history.ID ||
+					got.period != want.period || !got.earnedAt.Equal(earnedAt) {
+					t.Errorf("award %d = %+v, want history %d in period %q earned at %v", i, got, want.index, want.period, earnedAt)
+				}
+			}
+			if len(progress) != len(tt.progress) {
+				t.Errorf("progress = %v, want %v", progress, tt.progress)
+			}
+			for id, want := range tt.progress {
+				if progress[id] != want {
+					t.Errorf("progress = %v, want %v", progress, tt.progress)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestRuleHistories(t *testing.T) {
+	shower, dishes := uuid.New(), uuid.New()
+	histories := []model.History{{ProjectID: shower}, {ProjectID: dishes}, {ProjectID: shower}}
+
+	if got := ruleHistories(&model.BadgeRule{}, histories); len(got) != 3 {
+		t.Errorf("rule without project = %d histories, want 3", len(got))
+	}
+	got := ruleHistories(&model.BadgeRule{ProjectID: &shower}, histories)
+	if len(got) != 2 || got[0].ProjectID != shower || got[1].ProjectID != shower {
+		t.Errorf("rule for project = %+v, want the 2 shower histories", got)
+	}
+}
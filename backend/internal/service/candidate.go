@@ -0,0 +1,36 @@
+package service
+
+import (
+	"time"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// CandidateService 候选人服务
+type CandidateService struct {
+	store store.Store
+}
+
+// NewCandidateService 创建候选人服务
+func NewCandidateService(s store.Store) *CandidateService {
+	return &CandidateService{store: s}
+}
+
+// Delete 将候选人及其照片一起移入回收站
+// 照片与候选人使用相同的删除时间，恢复候选人时据此只恢复一起删除的照片；
+// 项目中的引用保留，彻底删除时才清理
+func (s *CandidateService) Delete(candidateID, userID uuid.UUID) error {
+	return s.store.Transaction(func(tx store.Store) error {
+		if _, err := getCandidate(tx, candidateID, userID); err != nil {
+			return err
+		}
+
+		// 截断到微秒：PostgreSQL 只保存到微秒，否则恢复时按时间匹配不到照片
+		now := time.Now().Truncate(time.Microsecond)
+		if err := tx.CandidatePhotos().TrashByCandidateID(candidateID, now); err != nil {
+			return err
+		}
+		return tx.Candidates().Trash(candidateID, userID, now)
+	})
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"errors"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// CandidatePhotoService 候选人照片服务
+// 所有操作都在事务中完成，保证 candidate_photos.is_avatar 与 Candidate.PhotoURL 始终一致
+type CandidatePhotoService struct {
+	store store.Store
+}
+
+// NewCandidatePhotoService 创建候选人照片服务
+func NewCandidatePhotoService(s store.Store) *CandidatePhotoService {
+	return &CandidatePhotoService{store: s}
+}
+
+// AddPhotos 为候选人添加照片；候选人还没有头像时，第一张照片自动成为头像
+func (s *CandidatePhotoService) AddPhotos(candidateID, userID uuid.UUID, photos []model.CandidatePhoto) ([]model.CandidatePhoto, error) {
+	err := s.store.Transaction(func(tx store.Store) error {
+		candidate, err := getCandidate(tx, candidateID, userID)
+		if err != nil {
+			return err
+		}
+
+		photoStore := tx.CandidatePhotos()
+		for i := range photos {
+			photos[i].CandidateID = candidateID
+			photos[i].IsAvatar = false
+		}
+		if err := photoStore.CreateBatch(photos); err != nil {
+			return err
+		}
+
+		if len(photos) == 0 || candidate.PhotoURL != "" {
+			return nil
+		}
+		if _, err := photoStore.GetAvatar(candidateID); err == nil {
+			return nil
+		} else if !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+
+		if err := promoteAvatar(tx, candidateID, userID, &photos[0]); err != nil {
+			return err
+		}
+		photos[0].IsAvatar = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return photos, nil
+}
+
+// ReplaceAvatar 新增一张照片并直接设为头像
+func (s *CandidatePhotoService) ReplaceAvatar(candidateID, userID uuid.UUID, photoURL string) (*model.CandidatePhoto, error) {
+	photo := &model.CandidatePhoto{
+		CandidateID: candidateID,
+		PhotoURL:    photoURL,
+	}
+	err := s.store.Transaction(func(tx store.Store) error {
+		if _, err := getCandidate(tx, candidateID, userID); err != nil {
+			return err
+		}
+		if err := tx.CandidatePhotos().Create(photo); err != nil {
+			return err
+		}
+		return promoteAvatar(tx, candidateID, userID, photo)
+	})
+	if err != nil {
+		return nil, err
+	}
+	photo.IsAvatar = true
+	return photo, nil
+}
+
+// SetAvatar 将指定照片设为候选人的头像
+func (s *CandidatePhotoService) SetAvatar(candidateID, userID, photoID uuid.UUID) (*model.CandidatePhoto, error) {
+	var photo *model.CandidatePhoto
+	err := s.store.Transaction(func(tx store.Store) error {
+		if _, err := getCandidate(tx, candidateID, userID); err != nil {
+			return err
+		}
+
+		var err error
+		photo, err = getPhoto(tx, photoID, candidateID)
+		if err != nil {
+			return err
+		}
+		return promoteAvatar(tx, candidateID, userID, photo)
+	})
+	if err != nil {
+		return nil, err
+	}
+	photo.IsAvatar = true
+	return photo, nil
+}
+
+// DeletePhoto 将候选人的照片移入回收站，返回被删除的照片记录
+// 如果删除的是头像，剩余照片中最新的一张自动成为新头像；没有剩余照片时清空 Candidate.PhotoURL
+func (s *CandidatePhotoService) DeletePhoto(candidateID, userID, photoID uuid.UUID) (*model.CandidatePhoto, error) {
+	var deleted *model.CandidatePhoto
+	err := s.store.Transaction(func(tx store.Store) error {
+		if _, err := getCandidate(tx, candidateID, userID); err != nil {
+			return err
+		}
+
+		var err error
+		deleted, err = getPhoto(tx, photoID, candidateID)
+		if err != nil {
+			return err
+		}
+
+		photoStore := tx.CandidatePhotos()
+		if !deleted.IsAvatar {
+			return photoStore.Delete(photoID)
+		}
+
+		// 回收站中的照片不保留头像标记
+		if err := photoStore.ClearAvatar(candidateID); err != nil {
+			return err
+		}
+		if err := photoStore.Delete(photoID); err != nil {
+			return err
+		}
+
+		// 删除的是头像：提升最新的剩余照片
+		latest, err := photoStore.GetLatest(candidateID)
+		if errors.Is(err, store.ErrNotFound) {
+			return tx.Candidates().UpdatePhoto(candidateID, userID, "")
+		}
+		if err != nil {
+			return err
+		}
+		return promoteAvatar(tx, candidateID, userID, latest)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// promoteAvatar 在事务内把照片设为头像并同步 Candidate.PhotoURL
+func promoteAvatar(tx store.Store, candidateID, userID uuid.UUID, photo *model.CandidatePhoto) error {
+	if err := tx.CandidatePhotos().SetAvatar(candidateID, photo.ID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return ErrPhotoNotFound
+		}
+		return err
+	}
+	return tx.Candidates().UpdatePhoto(candidateID, userID, photo.PhotoURL)
+}
+
+// getCandidate 在事务内获取候选人，不存在时返回 ErrCandidateNotFound
+func getCandidate(tx store.Store, candidateID, userID uuid.UUID) (*model.Candidate, error) {
+	candidate, err := tx.Candidates().Get(candidateID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrCandidateNotFound
+	}
+	return candidate, err
+}
+
+// getPhoto 在事务内获取照片，不存在时返回 ErrPhotoNotFound
+func getPhoto(tx store.Store, photoID, candidateID uuid.UUID) (*model.CandidatePhoto, error) {
+	photo, err := tx.CandidatePhotos().Get(photoID, candidateID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrPhotoNotFound
+	}
+	return photo, err
+}
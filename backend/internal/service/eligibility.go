@@ -0,0 +1,322 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// 每个候选人自定义字段的最大数量
+	maxCustomFields = 20
+	// 自定义字段名和值的最大长度
+	maxCustomFieldName  = 50
+	maxCustomFieldValue = 200
+	// 每个项目资格规则的最大数量
+	maxEligibilityRules = 10
+	// 资格规则中自定义字段的前缀
+	customFieldPrefix = "custom."
+)
+
+// 资格规则的字段
+const (
+	EligibilityFieldAge = "age" // 按生日计算的周岁
+)
+
+// 资格规则的比较方式
+const (
+	EligibilityOpGte     = "gte"     // 大于等于
+	EligibilityOpLte     = "lte"     // 小于等于
+	EligibilityOpEq      = "eq"      // 等于
+	EligibilityOpNeq     = "neq"     // 不等于
+	EligibilityOpExists  = "exists"  // 自定义字段已设置
+	EligibilityOpMissing = "missing" // 自定义字段未设置
+)
+
+var colorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// CandidateProfile 候选人资料；修改时为 nil 的字段保持不变，空字符串表示清除
+type CandidateProfile struct {
+	Nickname     *string           `json:"nickname" binding:"omitempty,max=100"`
+	Birthday     *string           `json:"birthday"`      // YYYY-MM-DD
+	Color        *string           `json:"color"`         // #rrggbb
+	CustomFields map[string]string `json:"custom_fields"` // 替换全部自定义字段
+	Tags         []string          `json:"tags"`          // 替换全部标签，不区分大小写
+}
+
+// ApplyCandidateProfile 校验并写入候选人资料，无效时返回 ErrInvalidCandidateProfile
+func ApplyCandidateProfile(candidate *model.Candidate, profile CandidateProfile, now time.Time) error {
+	if profile.Nickname != nil {
+		candidate.Nickname = strings.TrimSpace(*profile.Nickname)
+	}
+	if profile.Birthday != nil {
+		birthday := strings.TrimSpace(*profile.Birthday)
+		if birthday != "" {
+			date, err := time.ParseInLocation(time.DateOnly, birthday, time.Local)
+			if err != nil {
+				return fmt.Errorf("%w: birthday must be YYYY-MM-DD", ErrInvalidCandidateProfile)
+			}
+			if date.After(now) {
+				return fmt.Errorf("%w: birthday is in the future", ErrInvalidCandidateProfile)
+			}
+		}
+		candidate.Birthday = birthday
+	}
+	if profile.Color != nil {
+		color := strings.TrimSpace(*profile.Color)
+		if color != "" && !colorPattern.MatchString(color) {
+			return fmt.Errorf("%w: color must be #rrggbb", ErrInvalidCandidateProfile)
+		}
+		candidate.Color = strings.ToLower(color)
+	}
+	if profile.CustomFields != nil {
+		if len(profile.CustomFields) > maxCustomFields {
+			return fmt.Errorf("%w: at most %d custom fields", ErrInvalidCandidateProfile, maxCustomFields)
+		}
+		for name, value := range profile.CustomFields {
+			if name == "" || strings.TrimSpace(name) != name || len(name) > maxCustomFieldName {
+				return fmt.Errorf("%w: invalid custom field name %q", ErrInvalidCandidateProfile, name)
+			}
+			if len(value) > maxCustomFieldValue {
+				return fmt.Errorf("%w: custom field %q is longer than %d characters", ErrInvalidCandidateProfile, name, maxCustomFieldValue)
+			}
+		}
+		data, err := json.Marshal(profile.CustomFields)
+		if err != nil {
+			return err
+		}
+		candidate.CustomFields = string(data)
+	}
+	if profile.Tags != nil {
+		tags, err := encodeCandidateTags(profile.Tags)
+		if err != nil {
+			return err
+		}
+		candidate.Tags = tags
+	}
+	return nil
+}
+
+// EligibilityRule 项目的候选人资格规则，候选人需满足项目的所有规则才能被选中
+//
+//	{"field": "age", "op": "gte", "value": 8}
+//	{"field": "custom.school", "op": "eq", "value": "A"}
+//	{"field": "custom.away", "op": "missing"}
+type EligibilityRule struct {
+	Field string          `json:"field"` // age，或 custom.<名称> 表示自定义字段
+	Op    string          `json:"op"`    // age: gte、lte；自定义字段: eq、neq、exists、missing
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Ineligible 不符合项目资格规则的候选人及原因
+type Ineligible struct {
+	CandidateID   uuid.UUID `json:"candidate_id"`
+	CandidateName string    `json:"candidate_name"`
+	Reasons       []string  `json:"reasons"`
+}
+
+// NoEligibleCandidatesError 项目有候选人，但都不符合资格规则
+type NoEligibleCandidatesError struct {
+	Ineligible []Ineligible
+}
+
+func (e *NoEligibleCandidatesError) Error() string {
+	return ErrNoEligibleCandidates.Error()
+}
+
+func (e *NoEligibleCandidatesError) Unwrap() error {
+	return ErrNoEligibleCandidates
+}
+
+// eligibilityCheck 解析后的资格规则
+type eligibilityCheck struct {
+	field string // age 或自定义字段名
+	op    string
+	years int
+	value string
+}
+
+// EncodeEligibilityRules 校验资格规则并编码为 Project.EligibilityRules，无效时返回 ErrInvalidEligibilityRule
+func EncodeEligibilityRules(rules []EligibilityRule) (string, error) {
+	if len(rules) > maxEligibilityRules {
+		return "", fmt.Errorf("%w: at most %d rules", ErrInvalidEligibilityRule, maxEligibilityRules)
+	}
+	if _, err := parseEligibilityRules(rules); err != nil {
+		return "", err
+	}
+	if len(rules) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(rules)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// parseEligibilityRules 解析资格规则
+func parseEligibilityRules(rules []EligibilityRule) ([]eligibilityCheck, error) {
+	checks := make([]eligibilityCheck, 0, len(rules))
+	for _, rule := range rules {
+		check := eligibilityCheck{op: rule.Op}
+		switch {
+		case rule.Field == EligibilityFieldAge:
+			check.field = EligibilityFieldAge
+			if rule.Op != EligibilityOpGte && rule.Op != EligibilityOpLte {
+				return nil, fmt.Errorf("%w: age supports gte and lte", ErrInvalidEligibilityRule)
+			}
+			if err := json.Unmarshal(rule.Value, &check.years); err != nil || check.years < 0 || check.years > 150 {
+				return nil, fmt.Errorf("%w: age needs a value between 0 and 150", ErrInvalidEligibilityRule)
+			}
+		case strings.HasPrefix(rule.Field, customFieldPrefix) && len(rule.Field) > len(customFieldPrefix):
+			check.field = strings.TrimPrefix(rule.Field, customFieldPrefix)
+			switch rule.Op {
+			case EligibilityOpEq, EligibilityOpNeq:
+				if err := json.Unmarshal(rule.Value, &check.value); err != nil {
+					return nil, fmt.Errorf("%w: %s %s needs a string value", ErrInvalidEligibilityRule, rule.Field, rule.Op)
+				}
+			case EligibilityOpExists, EligibilityOpMissing:
+				if len(rule.Value) > 0 {
+					return nil, fmt.Errorf("%w: %s %s takes no value", ErrInvalidEligibilityRule, rule.Field, rule.Op)
+				}
+			default:
+				return nil, fmt.Errorf("%w: custom fields support eq, neq, exists and missing", ErrInvalidEligibilityRule)
+			}
+		default:
+			return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidEligibilityRule, rule.Field)
+		}
+		checks = append(checks, check)
+	}
+	return checks, nil
+}
+
+// eligibleCandidates 按项目的资格规则过滤候选人，返回符合的候选人和不符合的原因；年龄按 now 所在的日期计算
+func eligibleCandidates(project *model.Project, candidates []model.Candidate, now time.Time) ([]model.Candidate, []Ineligible, error) {
+	if project.EligibilityRules == "" {
+		return candidates, []Ineligible{}, nil
+	}
+	var rules []EligibilityRule
+	if err := json.Unmarshal([]byte(project.EligibilityRules), &rules); err != nil {
+		return nil, nil, err
+	}
+	checks, err := parseEligibilityRules(rules)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	eligible := make([]model.Candidate, 0, len(candidates))
+	ineligible := make([]Ineligible, 0)
+	for _, candidate := range candidates {
+		reasons, err := eligibilityReasons(&candidate, checks, now)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(reasons) == 0 {
+			eligible = append(eligible, candidate)
+			continue
+		}
+		ineligible = append(ineligible, Ineligible{
+			CandidateID:   candidate.ID,
+			CandidateName: candidate.Name,
+			Reasons:       reasons,
+		})
+	}
+	return eligible, ineligible, nil
+}
+
+// eligibilityReasons 返回候选人不满足的规则的说明，满足所有规则时为空
+func eligibilityReasons(candidate *model.Candidate, checks []eligibilityCheck, now time.Time) ([]string, error) {
+	fields := map[string]string{}
+	if candidate.CustomFields != "" {
+		if err := json.Unmarshal([]byte(candidate.CustomFields), &fields); err != nil {
+			return nil, err
+		}
+	}
+
+	var reasons []string
+	for _, check := range checks {
+		if check.field == EligibilityFieldAge {
+			age, ok := candidateAge(candidate, now)
+			switch {
+			case !ok:
+				reasons = append(reasons, "birthday is not set")
+			case check.op == EligibilityOpGte && age < check.years:
+				reasons = append(reasons, fmt.Sprintf("age %d is below the minimum of %d", age, check.years))
+			case check.op == EligibilityOpLte && age > check.years:
+				reasons = append(reasons, fmt.Sprintf("age %d is above the maximum of %d", age, check.years))
+			}
+			continue
+		}
+
+		value, set := fields[check.field]
+		switch {
+		case (check.op == EligibilityOpExists || check.op == EligibilityOpEq) && !set:
+			reasons = append(reasons, fmt.Sprintf("%s is not set", check.field))
+		case check.op == EligibilityOpMissing && set:
+			reasons = append(reasons, fmt.Sprintf("%s is set", check.field))
+		case check.op == EligibilityOpEq && value != check.value:
+			reasons = append(reasons, fmt.Sprintf("%s is %q, not %q", check.field, value, check.value))
+		case check.op == EligibilityOpNeq && value == check.value:
+			reasons = append(reasons, fmt.Sprintf("%s is %q", check.field, value))
+		}
+	}
+	return reasons, nil
+}
+
+// candidateAge 按生日计算候选人在 now 所在日期（本地时区）的周岁，未设置生日时 ok 为 false
+func candidateAge(candidate *model.Candidate, now time.Time) (age int, ok bool) {
+	if candidate.Birthday == "" {
+		return 0, false
+	}
+	birthday, err := time.ParseInLocation(time.DateOnly, candidate.Birthday, time.Local)
+	if err != nil {
+		return 0, false
+	}
+	now = now.Local()
+	age = now.Year() - birthday.Year()
+	if now.Month() < birthday.Month() || (now.Month() == birthday.Month() && now.Day() < birthday.Day()) {
+		age--
+	}
+	return age, true
+}
+
+// drawCandidates 获取项目内当前的候选人（标签查询在此时解析），并按资格规则过滤
+func drawCandidates(s store.Store, project *model.Project, now time.Time) (eligible []model.Candidate, ineligible []Ineligible, err error) {
+	candidates, err := projectCandidates(s, project)
+	if err != nil {
+		return nil, nil, err
+	}
+	return eligibleCandidates(project, candidates, now)
+}
+
+// EligibilityResult 项目候选人的资格
+type EligibilityResult struct {
+	Eligible   []model.Candidate `json:"eligible"`
+	Ineligible []Ineligible      `json:"ineligible"`
+}
+
+// Eligibility 按项目的资格规则列出现在可以和不能被选中的候选人
+func (s *RandomizeService) Eligibility(userID, projectID uuid.UUID) (*EligibilityResult, error) {
+	project, err := s.store.Projects().Get(projectID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrProjectNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	eligible, ineligible, err := drawCandidates(s.store, project, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if eligible == nil {
+		eligible = make([]model.Candidate, 0)
+	}
+	return &EligibilityResult{Eligible: eligible, Ineligible: ineligible}, nil
+}
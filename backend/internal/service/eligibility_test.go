@@ -0,0 +1,189 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+	"whotakesshowers/internal/model"
+
+	"github.com/google/uuid"
+)
+
+// testRule 构造一条资格规则，value 为空时不设置值
+func testRule(field, op string, value any) EligibilityRule {
+	r := EligibilityRule{Field: field, Op: op}
+	if value != nil {
+		r.Value, _ = json.Marshal(value)
+	}
+	return r
+}
+
+func TestEncodeEligibilityRules(t *testing.T) {
+	tooMany := make([]EligibilityRule, maxEligibilityRules+1)
+	for i := range tooMany {
+		tooMany[i] = testRule("age", "gte", 8)
+	}
+
+	tests := []struct {
+		name    string
+		rules   []EligibilityRule
+		want    string
+		wantErr bool
+	}{
+		{name: "none", want: ""},
+		{name: "age", rules: []EligibilityRule{testRule("age", "gte", 8)}, want: `[{"field":"age","op":"gte","value":8}]`},
+		{name: "custom field", rules: []EligibilityRule{testRule("custom.school", "eq", "A"), testRule("custom.away", "missing", nil)},
+			want: `[{"field":"custom.school","op":"eq","value":"A"},{"field":"custom.away","op":"missing"}]`},
+		{name: "age with custom op", rules: []EligibilityRule{testRule("age", "eq", 8)}, wantErr: true},
+		{name: "age without value", rules: []EligibilityRule{testRule("age", "lte", nil)}, wantErr: true},
+		{name: "negative age", rules: []EligibilityRule{testRule("age", "gte", -1)}, wantErr: true},
+		{name: "age too large", rules: []EligibilityRule{testRule("age", "lte", 151)}, wantErr: true},
+		{name: "age as string", rules: []EligibilityRule{testRule("age", "gte", "8")}, wantErr: true},
+		{name: "custom field with age op", rules: []EligibilityRule{testRule("custom.school", "gte", "A")}, wantErr: true},
+		{name: "eq without value", rules: []EligibilityRule{testRule("custom.school", "eq", nil)}, wantErr: true},
+		{name: "eq with number", rules: []EligibilityRule{testRule("custom.school", "eq", 1)}, wantErr: true},
+		{name: "exists with value", rules: []EligibilityRule{testRule("custom.away", "exists", "yes")}, wantErr: true},
+		{name: "empty custom field name", rules: []EligibilityRule{testRule("custom.", "exists", nil)}, wantErr: true},
+		{name: "unknown field", rules: []EligibilityRule{testRule("height", "gte", 120)}, wantErr: true},
+		{name: "too many rules", rules: tooMany, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EncodeEligibilityRules(tt.rules)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidEligibilityRule) {
+					t.Errorf("EncodeEligibilityRules() error = %v, want ErrInvalidEligibilityRule", err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("EncodeEligibilityRules() = %s, %v, want %s", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCandidateAge(t *testing.T) {
+	tests := []struct {
+		birthday string
+		now      time.Time
+		want     int
+		ok       bool
+	}{
+		{birthday: "2015-06-15", now: time.Date(2025, 6, 14, 23, 59, 0, 0, time.Local), want: 9, ok: true},
+		{birthday: "2015-06-15", now: time.Date(2025, 6, 15, 0, 0, 0, 0, time.Local), want: 10, ok: true},
+		{birthday: "2015-06-15", now: time.Date(2025, 5, 20, 0, 0, 0, 0, time.Local), want: 9, ok: true},
+		// 2 月 29 日出生的候选人在平年的 3 月 1 日满岁
+		{birthday: "2016-02-29", now: time.Date(2025, 2, 28, 12, 0, 0, 0, time.Local), want: 8, ok: true},
+		{birthday: "2016-02-29", now: time.Date(2025, 3, 1, 12, 0, 0, 0, time.Local), want: 9, ok: true},
+		{birthday: "", now: time.Date(2025, 3, 1, 12, 0, 0, 0, time.Local)},
+		{birthday: "not a date", now: time.Date(2025, 3, 1, 12, 0, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.birthday+" "+tt.now.Format(time.DateOnly), func(t *testing.T) {
+			age, ok := candidateAge(&model.Candidate{Birthday: tt.birthday}, tt.now)
+			if age != tt.want || ok != tt.ok {
+				t.Errorf("candidateAge() = %d, %v, want %d, %v", age, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestEligibleCandidates(t *testing.T) {
+	now := time.Date(2025, 6, 15, 20, 0, 0, 0, time.Local)
+	candidate := func(name, birthday, fields string) model.Candidate {
+		return model.Candidate{ID: uuid.New(), Name: name, Birthday: birthday, CustomFields: fields}
+	}
+	ann := candidate("Ann", "2015-06-15", `{"school":"A"}`)              // 10 岁
+	ben := candidate("Ben", "2019-01-01", `{"school":"B","away":"yes"}`) // 6 岁
+	cat := candidate("Cat", "", "")
+	candidates := []model.Candidate{ann, ben, cat}
+
+	tests := []struct {
+		name       string
+		rules      []EligibilityRule
+		eligible   []string
+		ineligible map[string][]string // 名字 -> 原因
+	}{
+		{name: "no rules", eligible: []string{"Ann", "Ben", "Cat"}},
+		{
+			name:       "minimum age includes the birthday",
+			rules:      []EligibilityRule{testRule("age", "gte", 10)},
+			eligible:   []string{"Ann"},
+			ineligible: map[string][]string{"Ben": {"age 6 is below the minimum of 10"}, "Cat": {"birthday is not set"}},
+		},
+		{
+			name:       "maximum age",
+			rules:      []EligibilityRule{testRule("age", "lte", 9)},
+			eligible:   []string{"Ben"},
+			ineligible: map[string][]string{"Ann": {"age 10 is above the maximum of 9"}, "Cat": {"birthday is not set"}},
+		},
+		{
+			name:       "eq",
+			rules:      []EligibilityRule{testRule("custom.school", "eq", "A")},
+			eligible:   []string{"Ann"},
+			ineligible: map[string][]string{"Ben": {`school is "B", not "A"`}, "Cat": {"school is not set"}},
+		},
+		{
+			name:       "neq passes when the field is not set",
+			rules:      []EligibilityRule{testRule("custom.school", "neq", "A")},
+			eligible:   []string{"Ben", "Cat"},
+			ineligible: map[string][]string{"Ann": {`school is "A"`}},
+		},
+		{
+			name:       "exists",
+			rules:      []EligibilityRule{testRule("custom.away", "exists", nil)},
+			eligible:   []string{"Ben"},
+			ineligible: map[string][]string{"Ann": {"away is not set"}, "Cat": {"away is not set"}},
+		},
+		{
+			name:       "missing",
+			rules:      []EligibilityRule{testRule("custom.away", "missing", nil)},
+			eligible:   []string{"Ann", "Cat"},
+			ineligible: map[string][]string{"Ben": {"away is set"}},
+		},
+		{
+			name:     "all rules must pass and every failure is reported",
+			rules:    []EligibilityRule{testRule("age", "gte", 8), testRule("custom.away", "missing", nil), testRule("custom.school", "eq", "A")},
+			eligible: []string{"Ann"},
+			ineligible: map[string][]string{
+				"Ben": {"age 6 is below the minimum of 8", "away is set", `school is "B", not "A"`},
+				"Cat": {"birthday is not set", "school is not set"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project := &model.Project{}
+			if tt.rules != nil {
+				encoded, err := EncodeEligibilityRules(tt.rules)
+				if err != nil {
+					t.Fatalf("EncodeEligibilityRules(): %v", err)
+				}
+				project.EligibilityRules = encoded
+			}
+			eligible, ineligible, err := eligibleCandidates(project, candidates, now)
+			if err != nil {
+				t.Fatalf("eligibleCandidates(): %v", err)
+			}
+
+			var names []string
+			for _, candidate := range eligible {
+				names = append(names, candidate.Name)
+			}
+			if !slices.Equal(names, tt.eligible) {
+				t.Errorf("eligible = %v, want %v", names, tt.eligible)
+			}
+			if len(ineligible) != len(tt.ineligible) {
+				t.Fatalf("ineligible = %+v, want %v", ineligible, tt.ineligible)
+			}
+			for _, item := range ineligible {
+				if !slices.Equal(item.Reasons, tt.ineligible[item.CandidateName]) {
+					t.Errorf("%s reasons = %q, want %q", item.CandidateName, item.Reasons, tt.ineligible[item.CandidateName])
+				}
+			}
+		})
+	}
+}
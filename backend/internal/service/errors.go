@@ -0,0 +1,92 @@
+package service
+
+import "errors"
+
+var (
+	// ErrProjectNotFound 项目不存在
+	ErrProjectNotFound = errors.New("project not found")
+	// ErrCandidateNotFound 候选人不存在
+	ErrCandidateNotFound = errors.New("candidate not found")
+	// ErrPhotoNotFound 照片不存在
+	ErrPhotoNotFound = errors.New("photo not found")
+	// ErrUsernameTaken 用户名已存在
+	ErrUsernameTaken = errors.New("用户名已存在")
+	// ErrEmailTaken 邮箱已被注册
+	ErrEmailTaken = errors.New("邮箱已被注册")
+	// ErrInvalidCredentials 用户名或密码错误
+	ErrInvalidCredentials = errors.New("用户名或密码错误")
+	// ErrInvalidExport 导入的归档无效
+	ErrInvalidExport = errors.New("invalid export archive")
+	// ErrDeletionNotRequested 账号没有待执行的删除申请
+	ErrDeletionNotRequested = errors.New("account deletion has not been requested")
+	// ErrInvalidStatsQuery 统计参数无效
+	ErrInvalidStatsQuery = errors.New("invalid stats query")
+	// ErrInvalidHistoryFormat 不支持的历史记录导出格式
+	ErrInvalidHistoryFormat = errors.New("history format must be csv, ndjson or ics")
+	// ErrFeedNotFound 日历订阅不存在
+	ErrFeedNotFound = errors.New("calendar feed not found")
+	// ErrHistoryNotFound 历史记录不存在
+	ErrHistoryNotFound = errors.New("history entry not found")
+	// ErrInvalidHistoryUpdate 历史记录的修改无效
+	ErrInvalidHistoryUpdate = errors.New("invalid history update")
+	// ErrHistoryVoided 历史记录已作废
+	ErrHistoryVoided = errors.New("history entry is already voided")
+	// ErrHistoryNotVoided 历史记录没有作废
+	ErrHistoryNotVoided = errors.New("history entry is not voided")
+	// ErrInvalidTaskTransition 任务当前的状态不能进行该操作
+	ErrInvalidTaskTransition = errors.New("invalid task transition")
+	// ErrRewardNotFound 奖励不存在
+	ErrRewardNotFound = errors.New("reward not found")
+	// ErrInsufficientPoints 候选人的积分不足
+	ErrInsufficientPoints = errors.New("insufficient points")
+	// ErrInvalidPointsAdjustment 积分调整无效
+	ErrInvalidPointsAdjustment = errors.New("invalid points adjustment")
+	// ErrBadgeRuleNotFound 徽章规则不存在
+	ErrBadgeRuleNotFound = errors.New("badge rule not found")
+	// ErrInvalidBadgeRule 徽章规则无效
+	ErrInvalidBadgeRule = errors.New("invalid badge rule")
+	// ErrRoutineNotFound 例程不存在
+	ErrRoutineNotFound = errors.New("routine not found")
+	// ErrInvalidRoutine 例程无效
+	ErrInvalidRoutine = errors.New("invalid routine")
+	// ErrRoutineStepUnavailable 例程的某个步骤无法随机选择
+	ErrRoutineStepUnavailable = errors.New("routine step unavailable")
+	// ErrRoutineRunNotFound 例程执行记录不存在
+	ErrRoutineRunNotFound = errors.New("routine run not found")
+	// ErrRoutineRunFinished 例程执行已经结束
+	ErrRoutineRunFinished = errors.New("routine run is already finished")
+	// ErrProjectConstraintNotFound 项目间约束不存在
+	ErrProjectConstraintNotFound = errors.New("project constraint not found")
+	// ErrInvalidProjectConstraint 项目间约束无效
+	ErrInvalidProjectConstraint = errors.New("invalid project constraint")
+	// ErrProjectConstraintExists 两个项目之间已经有约束
+	ErrProjectConstraintExists = errors.New("a constraint between these projects already exists")
+	// ErrInvalidTeamSplit 分组请求无效
+	ErrInvalidTeamSplit = errors.New("invalid team split")
+	// ErrTeamSplitUnsatisfiable 无法在人数均衡的前提下满足 keep_apart
+	ErrTeamSplitUnsatisfiable = errors.New("keep_apart pairs cannot be satisfied with balanced groups")
+	// ErrInvalidCandidateProfile 候选人资料无效
+	ErrInvalidCandidateProfile = errors.New("invalid candidate profile")
+	// ErrInvalidEligibilityRule 项目的资格规则无效
+	ErrInvalidEligibilityRule = errors.New("invalid eligibility rule")
+	// ErrNoEligibleCandidates 项目的候选人都不符合资格规则
+	ErrNoEligibleCandidates = errors.New("no eligible candidates")
+	// ErrInvalidTagQuery 项目的标签查询无效
+	ErrInvalidTagQuery = errors.New("invalid tag query")
+	// ErrInvalidDrawOverride 项目的特殊日规则无效
+	ErrInvalidDrawOverride = errors.New("invalid draw override")
+	// ErrHistoryNotRerollable 历史记录不能重新选择
+	ErrHistoryNotRerollable = errors.New("history entry cannot be re-rolled")
+	// ErrNoRerollTokens 候选人本周的重新选择次数已用完
+	ErrNoRerollTokens = errors.New("no re-roll tokens left")
+	// ErrHistorySuperseded 历史记录已被重新选择取代
+	ErrHistorySuperseded = errors.New("history entry has been superseded by a re-roll")
+	// ErrInvalidIdempotencyKey 幂等键无效
+	ErrInvalidIdempotencyKey = errors.New("invalid idempotency key")
+	// ErrIdempotencyKeyReused 幂等键已用于另一个请求
+	ErrIdempotencyKeyReused = errors.New("idempotency key was already used for a different request")
+	// ErrIdempotencyKeyInProgress 使用同一个幂等键的请求仍在处理中
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is still in progress")
+	// ErrInvalidImportMode 导入模式只能是 merge 或 replace
+	ErrInvalidImportMode = errors.New("import mode must be merge or replace")
+)
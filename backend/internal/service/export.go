@@ -0,0 +1,1140 @@
+package service
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"whotakesshowers/internal/logger"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// 导出文件格式，详见 EXPORT.md
+const (
+	ExportFormat  = "whotakesshowers-export"
+	ExportVersion = 8
+
+	exportDocumentName = "export.json"
+	exportPhotoDir     = "photos/"
+)
+
+// ExportDocument 导出归档中的 export.json
+// 其中的 ID 只用于文档内部的相互引用，导入时会重新生成
+type ExportDocument struct {
+	Format     string            `json:"format"`
+	Version    int               `json:"version"`
+	ExportedAt time.Time         `json:"exported_at"`
+	Username   string            `json:"username"`
+	Candidates []ExportCandidate `json:"candidates"`
+	Projects   []ExportProject   `json:"projects"`
+	Histories  []ExportHistory   `json:"histories"`
+
+	// 以下字段从版本 7 开始导出
+	Splits []ExportSplit `json:"splits,omitempty"`
+}
+
+// ExportCandidate 导出的候选人
+type ExportCandidate struct {
+	ID        uuid.UUID     `json:"id"`
+	Name      string        `json:"name"`
+	PhotoURL  string        `json:"photo_url,omitempty"` // 非本地上传的头像链接
+	CreatedAt time.Time     `json:"created_at"`
+	Photos    []ExportPhoto `json:"photos"`
+
+	// 以下字段从版本 4 开始导出
+	Nickname     string            `json:"nickname,omitempty"`
+	Birthday     string            `json:"birthday,omitempty"`
+	Color        string            `json:"color,omitempty"`
+	CustomFields map[string]string `json:"custom_fields,omitempty"`
+
+	// 以下字段从版本 5 开始导出
+	Tags []string `json:"tags,omitempty"`
+}
+
+// profile 导出的候选人资料
+func (c ExportCandidate) profile() CandidateProfile {
+	return CandidateProfile{
+		Nickname:     &c.Nickname,
+		Birthday:     &c.Birthday,
+		Color:        &c.Color,
+		CustomFields: c.CustomFields,
+		Tags:         c.Tags,
+	}
+}
+
+// ExportPhoto 导出的候选人照片
+type ExportPhoto struct {
+	File      string    `json:"file"` // 照片在归档中的路径 photos/<name>
+	IsAvatar  bool      `json:"is_avatar"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ExportProject 导出的项目
+type ExportProject struct {
+	ID           uuid.UUID   `json:"id"`
+	Name         string      `json:"name"`
+	CandidateIDs []uuid.UUID `json:"candidate_ids"`
+	CreatedAt    time.Time   `json:"created_at"`
+
+	// 以下字段从版本 3 开始导出
+	TargetMinutes *int `json:"target_minutes,omitempty"`
+
+	// 以下字段从版本 4 开始导出
+	EligibilityRules []EligibilityRule `json:"eligibility_rules,omitempty"`
+
+	// 以下字段从版本 5 开始导出
+	TagQuery string `json:"tag_query,omitempty"`
+
+	// 以下字段从版本 6 开始导出
+	DrawOverrides []DrawOverride `json:"draw_overrides,omitempty"`
+
+	// 以下字段从版本 8 开始导出
+	PickPoints   int `json:"pick_points,omitempty"`
+	OnTimePoints int `json:"on_time_points,omitempty"`
+	RerollTokens int `json:"reroll_tokens,omitempty"`
+}
+
+// ExportHistory 导出的历史记录
+// CandidateID 可能指向已删除、未导出的候选人
+type ExportHistory struct {
+	ProjectID     uuid.UUID `json:"project_id"`
+	ProjectName   string    `json:"project_name"`
+	CandidateID   uuid.UUID `json:"candidate_id"`
+	CandidateName string    `json:"candidate_name"`
+	SelectedAt    time.Time `json:"selected_at"`
+
+	// 以下字段从版本 2 开始导出
+	Note        string     `json:"note,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	VoidedAt    *time.Time `json:"voided_at,omitempty"`
+	VoidReason  string     `json:"void_reason,omitempty"`
+
+	// 以下字段从版本 3 开始导出；旧版本的记录有完成时间时视为已完成
+	Status    string     `json:"status,omitempty"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	SkippedAt *time.Time `json:"skipped_at,omitempty"`
+
+	// 以下字段从版本 7 开始导出；分组产生的记录引用 splits[].id
+	SplitID    *uuid.UUID `json:"split_id,omitempty"`
+	SplitGroup *int       `json:"split_group,omitempty"`
+
+	// 以下字段从版本 8 开始导出：选中时项目的积分设置和目标时长
+	PickPoints    int  `json:"pick_points,omitempty"`
+	OnTimePoints  int  `json:"on_time_points,omitempty"`
+	TargetMinutes *int `json:"target_minutes,omitempty"`
+
+	// 以下字段从版本 8 开始导出：选中时的候选人池和标签查询，候选人 ID 可能指向已删除、未导出的候选人
+	PoolCandidateIDs []uuid.UUID `json:"pool_candidate_ids,omitempty"`
+	TagQuery         string      `json:"tag_query,omitempty"`
+
+	// 以下字段从版本 8 开始导出：选中时生效的特殊日规则
+	Overridden     bool   `json:"overridden,omitempty"`
+	OverrideReason string `json:"override_reason,omitempty"`
+
+	// 以下字段从版本 8 开始导出：重新选择的关联，引用 histories[].id
+	ID             uuid.UUID  `json:"id"`
+	SupersededByID *uuid.UUID `json:"superseded_by_id,omitempty"`
+	SupersededAt   *time.Time `json:"superseded_at,omitempty"`
+	RerollOfID     *uuid.UUID `json:"reroll_of_id,omitempty"`
+}
+
+// ExportSplit 导出的分组记录，成员的候选人 ID 可能指向已删除、未导出的候选人
+type ExportSplit struct {
+	ID          uuid.UUID      `json:"id"`
+	ProjectID   uuid.UUID      `json:"project_id"`
+	ProjectName string         `json:"project_name"`
+	Groups      [][]TeamMember `json:"groups"`
+	KeepApart   [][2]uuid.UUID `json:"keep_apart,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+// Export 用户数据的导出结果，通过 WriteTo 写出 zip 归档
+type Export struct {
+	Document ExportDocument
+	files    map[string]string // 归档中的照片路径 -> 本地文件路径
+}
+
+// ImportMode 导入模式
+type ImportMode string
+
+const (
+	// ImportMerge 合并：保留现有数据，同名的候选人和项目直接复用
+	ImportMerge ImportMode = "merge"
+	// ImportReplace 替换：现有的项目和候选人移入回收站，再导入全部数据
+	ImportReplace ImportMode = "replace"
+)
+
+// ImportCounts 一类数据的导入统计
+type ImportCounts struct {
+	Created int `json:"created"`
+	Skipped int `json:"skipped"` // 已存在而未导入
+	Trashed int `json:"trashed"` // 替换模式下移入回收站的现有数据
+}
+
+// ImportReport 导入报告；DryRun 为 true 时只是预计的变更，数据没有被修改
+type ImportReport struct {
+	Mode       ImportMode   `json:"mode"`
+	DryRun     bool         `json:"dry_run"`
+	Projects   ImportCounts `json:"projects"`
+	Candidates ImportCounts `json:"candidates"`
+	Photos     ImportCounts `json:"photos"`
+	Histories  ImportCounts `json:"histories"`
+	Splits     ImportCounts `json:"splits"`
+}
+
+// errDryRun 试运行结束时返回，用于回滚事务
+var errDryRun = errors.New("dry run")
+
+// ExportService 用户数据导出与导入服务
+type ExportService struct {
+	store   store.Store
+	uploads *PhotoUploads
+}
+
+// NewExportService 创建导出服务，导入的照片与上传接口使用相同的大小和类型限制
+func NewExportService(s store.Store, uploads *PhotoUploads) *ExportService {
+	return &ExportService{store: s, uploads: uploads}
+}
+
+// Export 导出用户的项目、候选人（含照片）和历史记录；回收站中的数据不导出
+func (s *ExportService) Export(userID uuid.UUID) (*Export, error) {
+	user, err := s.store.Users().Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	candidates, err := s.store.Candidates().List(userID)
+	if err != nil {
+		return nil, err
+	}
+	projects, err := s.store.Projects().List(userID)
+	if err != nil {
+		return nil, err
+	}
+	histories, err := s.store.Histories().List(userID, nil, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &Export{
+		Document: ExportDocument{
+			Format:     ExportFormat,
+			Version:    ExportVersion,
+			ExportedAt: time.Now(),
+			Username:   user.Username,
+			Candidates: make([]ExportCandidate, 0, len(candidates)),
+			Projects:   make([]ExportProject, 0, len(projects)),
+			Histories:  make([]ExportHistory, 0, len(histories)),
+		},
+		files: make(map[string]string),
+	}
+
+	exported := make(map[uuid.UUID]bool, len(candidates))
+	for _, candidate := range candidates {
+		photos, err := s.store.CandidatePhotos().List(candidate.ID)
+		if err != nil {
+			return nil, err
+		}
+		item := ExportCandidate{
+			ID:        candidate.ID,
+			Name:      candidate.Name,
+			CreatedAt: candidate.CreatedAt,
+			Photos:    make([]ExportPhoto, 0, len(photos)),
+			Nickname:  candidate.Nickname,
+			Birthday:  candidate.Birthday,
+			Color:     candidate.Color,
+		}
+		if candidate.CustomFields != "" {
+			if err := json.Unmarshal([]byte(candidate.CustomFields), &item.CustomFields); err != nil {
+				return nil, err
+			}
+		}
+		if item.Tags, err = candidateTags(&candidate); err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(candidate.PhotoURL, uploadURLPrefix) {
+			item.PhotoURL = candidate.PhotoURL
+		}
+
+		hasAvatar := false
+		for _, photo := range photos {
+			file, ok := export.addFile(photo.PhotoURL)
+			if !ok {
+				continue
+			}
+			item.Photos = append(item.Photos, ExportPhoto{File: file, IsAvatar: photo.IsAvatar, CreatedAt: photo.CreatedAt})
+			hasAvatar = hasAvatar || photo.IsAvatar
+		}
+		// 早期上传的头像可能没有对应的照片记录
+		if !hasAvatar && item.PhotoURL == "" && candidate.PhotoURL != "" {
+			if file, ok := export.addFile(candidate.PhotoURL); ok {
+				item.Photos = append(item.Photos, ExportPhoto{File: file, IsAvatar: true, CreatedAt: candidate.UpdatedAt})
+			}
+		}
+
+		export.Document.Candidates = append(export.Document.Candidates, item)
+		exported[candidate.ID] = true
+	}
+
+	exportedProjects := make(map[uuid.UUID]bool, len(projects))
+	for _, project := range projects {
+		var candidateIDs []uuid.UUID
+		if project.CandidateIDs != "" {
+			if err := json.Unmarshal([]byte(project.CandidateIDs), &candidateIDs); err != nil {
+				return nil, err
+			}
+		}
+		// 回收站中的候选人不导出，项目中对它们的引用也一并去掉
+		kept := make([]uuid.UUID, 0, len(candidateIDs))
+		for _, id := range candidateIDs {
+			if exported[id] {
+				kept = append(kept, id)
+			}
+		}
+		item := ExportProject{
+			ID:            project.ID,
+			Name:          project.Name,
+			CandidateIDs:  kept,
+			CreatedAt:     project.CreatedAt,
+			TargetMinutes: project.TargetMinutes,
+			TagQuery:      project.TagQuery,
+			PickPoints:    project.PickPoints,
+			OnTimePoints:  project.OnTimePoints,
+			RerollTokens:  project.RerollTokens,
+		}
+		if project.EligibilityRules != "" {
+			if err := json.Unmarshal([]byte(project.EligibilityRules), &item.EligibilityRules); err != nil {
+				return nil, err
+			}
+		}
+		overrides, err := decodeDrawOverrides(&project)
+		if err != nil {
+			return nil, err
+		}
+		// 同样去掉针对未导出候选人的特殊日规则
+		for _, override := range overrides {
+			if override.CandidateID == nil || exported[*override.CandidateID] {
+				item.DrawOverrides = append(item.DrawOverrides, override)
+			}
+		}
+		export.Document.Projects = append(export.Document.Projects, item)
+		exportedProjects[project.ID] = true
+	}
+
+	splits, err := listAllSplits(s.store, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, split := range splits {
+		if !exportedProjects[split.ProjectID] {
+			continue
+		}
+		item := ExportSplit{
+			ID:          split.ID,
+			ProjectID:   split.ProjectID,
+			ProjectName: split.ProjectName,
+			CreatedAt:   split.CreatedAt,
+		}
+		if err := json.Unmarshal([]byte(split.Groups), &item.Groups); err != nil {
+			return nil, err
+		}
+		if split.KeepApart != "" {
+			if err := json.Unmarshal([]byte(split.KeepApart), &item.KeepApart); err != nil {
+				return nil, err
+			}
+		}
+		export.Document.Splits = append(export.Document.Splits, item)
+	}
+
+	for _, history := range histories {
+		if !exportedProjects[history.ProjectID] {
+			continue
+		}
+		var pool []uuid.UUID
+		if history.PoolCandidateIDs != "" {
+			if err := json.Unmarshal([]byte(history.PoolCandidateIDs), &pool); err != nil {
+				return nil, err
+			}
+		}
+		export.Document.Histories = append(export.Document.Histories, ExportHistory{
+			ProjectID:     history.ProjectID,
+			ProjectName:   history.ProjectName,
+			CandidateID:   history.CandidateID,
+			CandidateName: history.CandidateName,
+			SelectedAt:    history.SelectedAt,
+			Note:          history.Note,
+			CompletedAt:   history.CompletedAt,
+			VoidedAt:      history.VoidedAt,
+			VoidReason:    history.VoidReason,
+			Status:        history.Status,
+			StartedAt:     history.StartedAt,
+			SkippedAt:     history.SkippedAt,
+			SplitID:       history.SplitID,
+			SplitGroup:    history.SplitGroup,
+			PickPoints:    history.PickPoints,
+			OnTimePoints:  history.OnTimePoints,
+			TargetMinutes: history.TargetMinutes,
+
+			PoolCandidateIDs: pool,
+			TagQuery:         history.TagQuery,
+			Overridden:       history.Overridden,
+			OverrideReason:   history.OverrideReason,
+
+			ID:             history.ID,
+			SupersededByID: history.SupersededByID,
+			SupersededAt:   history.SupersededAt,
+			RerollOfID:     history.RerollOfID,
+		})
+	}
+
+	return export, nil
+}
+
+// listAllSplits 按时间倒序列出用户的所有分组记录
+func listAllSplits(s store.Store, userID uuid.UUID) ([]model.TeamSplit, error) {
+	var splits []model.TeamSplit
+	page := store.PageRequest{Limit: store.MaxPageLimit}
+	for {
+		result, err := s.TeamSplits().Find(userID, nil, page)
+		if err != nil {
+			return nil, err
+		}
+		splits = append(splits, result.Items...)
+		if result.NextCursor == "" {
+			return splits, nil
+		}
+		page.Cursor = result.NextCursor
+	}
+}
+
+// addFile 将本地上传的照片加入归档，返回照片在归档中的路径；文件不存在时跳过
+func (e *Export) addFile(photoURL string) (string, bool) {
+	if !strings.HasPrefix(photoURL, uploadURLPrefix) {
+		return "", false
+	}
+	name := path.Base(photoURL)
+	local := filepath.Join(UploadDir, name)
+	if _, err := os.Stat(local); err != nil {
+		logger.Warn("Skipping missing photo in export",
+			zap.String("photo_url", photoURL),
+			zap.Error(err),
+		)
+		return "", false
+	}
+	file := exportPhotoDir + name
+	e.files[file] = local
+	return file, true
+}
+
+// WriteTo 将导出结果写为 zip 归档
+func (e *Export) WriteTo(w io.Writer) (int64, error) {
+	counter := &countingWriter{w: w}
+	zw := zip.NewWriter(counter)
+
+	doc, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     exportDocumentName,
+		Method:   zip.Deflate,
+		Modified: e.Document.ExportedAt,
+	})
+	if err != nil {
+		return counter.n, err
+	}
+	encoder := json.NewEncoder(doc)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(e.Document); err != nil {
+		return counter.n, err
+	}
+
+	names := make([]string, 0, len(e.files))
+	for file := range e.files {
+		names = append(names, file)
+	}
+	sort.Strings(names)
+	for _, file := range names {
+		if err := copyToZip(zw, file, e.files[file]); err != nil {
+			return counter.n, err
+		}
+	}
+
+	err = zw.Close()
+	return counter.n, err
+}
+
+// copyToZip 将本地文件以 name 写入 zip（照片已压缩过，直接存储）
+func copyToZip(zw *zip.Writer, name, local string) error {
+	f, err := os.Open(local)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store, Modified: stat.ModTime()})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// countingWriter 统计写入的字节数
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Import 将导出归档导入到用户的账号中，所有 ID 重新生成
+// dryRun 为 true 时在事务中完整执行一遍后回滚，返回的报告即实际导入时的变更
+func (s *ExportService) Import(userID uuid.UUID, r io.ReaderAt, size int64, mode ImportMode, dryRun bool) (*ImportReport, error) {
+	if mode != ImportMerge && mode != ImportReplace {
+		return nil, ErrInvalidImportMode
+	}
+
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+	}
+	doc, files, err := readExport(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	importer := &importer{
+		userID:  userID,
+		doc:     doc,
+		files:   files,
+		uploads: s.uploads,
+		dryRun:  dryRun,
+		report:  &ImportReport{Mode: mode, DryRun: dryRun},
+	}
+	err = s.store.Transaction(func(tx store.Store) error {
+		importer.tx = tx
+		if mode == ImportReplace {
+			if err := importer.trashExisting(); err != nil {
+				return err
+			}
+		}
+		if err := importer.run(); err != nil {
+			return err
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		// 事务已回滚，删除已经写入的照片文件
+		for _, photoURL := range importer.written {
+			removeUpload(photoURL)
+		}
+		return nil, err
+	}
+	return importer.report, nil
+}
+
+// readExport 读取并校验 export.json：格式、版本以及文档内部的引用
+func readExport(archive *zip.Reader) (*ExportDocument, map[string]*zip.File, error) {
+	files := make(map[string]*zip.File)
+	var docFile *zip.File
+	for _, f := range archive.File {
+		switch {
+		case f.Name == exportDocumentName:
+			docFile = f
+		case strings.HasPrefix(f.Name, exportPhotoDir) && !f.FileInfo().IsDir():
+			files[f.Name] = f
+		}
+	}
+	if docFile == nil {
+		return nil, nil, fmt.Errorf("%w: missing %s", ErrInvalidExport, exportDocumentName)
+	}
+
+	rc, err := docFile.Open()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+	}
+	defer rc.Close()
+	var doc ExportDocument
+	if err := json.NewDecoder(rc).Decode(&doc); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+	}
+
+	if doc.Format != ExportFormat {
+		return nil, nil, fmt.Errorf("%w: unknown format %q", ErrInvalidExport, doc.Format)
+	}
+	if doc.Version < 1 || doc.Version > ExportVersion {
+		return nil, nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidExport, doc.Version)
+	}
+
+	candidates := make(map[uuid.UUID]bool, len(doc.Candidates))
+	for _, candidate := range doc.Candidates {
+		if candidate.ID == uuid.Nil || candidates[candidate.ID] {
+			return nil, nil, fmt.Errorf("%w: duplicate candidate id %s", ErrInvalidExport, candidate.ID)
+		}
+		if candidate.Name == "" {
+			return nil, nil, fmt.Errorf("%w: candidate %s has no name", ErrInvalidExport, candidate.ID)
+		}
+		candidates[candidate.ID] = true
+
+		avatars := 0
+		for _, photo := range candidate.Photos {
+			name := strings.TrimPrefix(photo.File, exportPhotoDir)
+			if name == "" || name != path.Base(name) || name == "." || name == ".." {
+				return nil, nil, fmt.Errorf("%w: invalid photo path %q", ErrInvalidExport, photo.File)
+			}
+			if files[photo.File] == nil {
+				return nil, nil, fmt.Errorf("%w: missing photo %q", ErrInvalidExport, photo.File)
+			}
+			if photo.IsAvatar {
+				avatars++
+			}
+		}
+		if avatars > 1 {
+			return nil, nil, fmt.Errorf("%w: candidate %s has %d avatars", ErrInvalidExport, candidate.ID, avatars)
+		}
+		if err := ApplyCandidateProfile(&model.Candidate{}, candidate.profile(), time.Now()); err != nil {
+			return nil, nil, fmt.Errorf("%w: candidate %s: %v", ErrInvalidExport, candidate.ID, err)
+		}
+	}
+
+	projects := make(map[uuid.UUID]bool, len(doc.Projects))
+	for _, project := range doc.Projects {
+		if project.ID == uuid.Nil || projects[project.ID] {
+			return nil, nil, fmt.Errorf("%w: duplicate project id %s", ErrInvalidExport, project.ID)
+		}
+		if project.Name == "" {
+			return nil, nil, fmt.Errorf("%w: project %s has no name", ErrInvalidExport, project.ID)
+		}
+		if project.TargetMinutes != nil && *project.TargetMinutes <= 0 {
+			return nil, nil, fmt.Errorf("%w: project %s has an invalid target_minutes", ErrInvalidExport, project.ID)
+		}
+		if err := validateProjectPoints(project.PickPoints, project.OnTimePoints); err != nil {
+			return nil, nil, fmt.Errorf("%w: project %s: %v", ErrInvalidExport, project.ID, err)
+		}
+		if project.RerollTokens < 0 || project.RerollTokens > maxRerollTokens {
+			return nil, nil, fmt.Errorf("%w: project %s has an invalid reroll_tokens", ErrInvalidExport, project.ID)
+		}
+		if _, err := EncodeEligibilityRules(project.EligibilityRules); err != nil {
+			return nil, nil, fmt.Errorf("%w: project %s: %v", ErrInvalidExport, project.ID, err)
+		}
+		if _, err := EncodeTagQuery(project.TagQuery); err != nil {
+			return nil, nil, fmt.Errorf("%w: project %s: %v", ErrInvalidExport, project.ID, err)
+		}
+		if _, err := EncodeDrawOverrides(project.DrawOverrides); err != nil {
+			return nil, nil, fmt.Errorf("%w: project %s: %v", ErrInvalidExport, project.ID, err)
+		}
+		for _, override := range project.DrawOverrides {
+			if override.CandidateID != nil && !candidates[*override.CandidateID] {
+				return nil, nil, fmt.Errorf("%w: project %s override references unknown candidate %s", ErrInvalidExport, project.ID, *override.CandidateID)
+			}
+		}
+		projects[project.ID] = true
+		for _, id := range project.CandidateIDs {
+			if !candidates[id] {
+				return nil, nil, fmt.Errorf("%w: project %s references unknown candidate %s", ErrInvalidExport, project.ID, id)
+			}
+		}
+	}
+
+	splits := make(map[uuid.UUID]bool, len(doc.Splits))
+	for _, split := range doc.Splits {
+		if split.ID == uuid.Nil || splits[split.ID] {
+			return nil, nil, fmt.Errorf("%w: duplicate split id %s", ErrInvalidExport, split.ID)
+		}
+		if !projects[split.ProjectID] {
+			return nil, nil, fmt.Errorf("%w: split references unknown project %s", ErrInvalidExport, split.ProjectID)
+		}
+		if len(split.Groups) < 2 {
+			return nil, nil, fmt.Errorf("%w: split %s has fewer than 2 groups", ErrInvalidExport, split.ID)
+		}
+		splits[split.ID] = true
+	}
+
+	historyIDs := make(map[uuid.UUID]bool, len(doc.Histories))
+	for _, history := range doc.Histories {
+		if history.ID == uuid.Nil {
+			continue
+		}
+		if historyIDs[history.ID] {
+			return nil, nil, fmt.Errorf("%w: duplicate history id %s", ErrInvalidExport, history.ID)
+		}
+		historyIDs[history.ID] = true
+	}
+
+	for _, history := range doc.Histories {
+		if history.SupersededByID != nil && !historyIDs[*history.SupersededByID] {
+			return nil, nil, fmt.Errorf("%w: history references unknown history %s", ErrInvalidExport, *history.SupersededByID)
+		}
+		if history.RerollOfID != nil && !historyIDs[*history.RerollOfID] {
+			return nil, nil, fmt.Errorf("%w: history references unknown history %s", ErrInvalidExport, *history.RerollOfID)
+		}
+		if !projects[history.ProjectID] {
+			return nil, nil, fmt.Errorf("%w: history references unknown project %s", ErrInvalidExport, history.ProjectID)
+		}
+		if history.SplitID != nil && !splits[*history.SplitID] {
+			return nil, nil, fmt.Errorf("%w: history references unknown split %s", ErrInvalidExport, *history.SplitID)
+		}
+		if err := validateProjectPoints(history.PickPoints, history.OnTimePoints); err != nil {
+			return nil, nil, fmt.Errorf("%w: history: %v", ErrInvalidExport, err)
+		}
+		if history.TargetMinutes != nil && *history.TargetMinutes <= 0 {
+			return nil, nil, fmt.Errorf("%w: history has an invalid target_minutes", ErrInvalidExport)
+		}
+		if _, err := EncodeTagQuery(history.TagQuery); err != nil {
+			return nil, nil, fmt.Errorf("%w: history: %v", ErrInvalidExport, err)
+		}
+		switch history.Status {
+		case "", model.HistoryStatusPicked, model.HistoryStatusStarted, model.HistoryStatusCompleted, model.HistoryStatusSkipped:
+		default:
+			return nil, nil, fmt.Errorf("%w: invalid history status %q", ErrInvalidExport, history.Status)
+		}
+	}
+	return &doc, files, nil
+}
+
+// importer 一次导入的状态
+type importer struct {
+	tx      store.Store
+	userID  uuid.UUID
+	doc     *ExportDocument
+	files   map[string]*zip.File
+	uploads *PhotoUploads
+	dryRun  bool
+	report  *ImportReport
+
+	ids     map[uuid.UUID]uuid.UUID // 导出文档中的 ID -> 新 ID
+	written []string                // 已写入的照片 URL，失败时删除
+}
+
+// trashExisting 替换模式：将现有的项目和候选人（连同照片）移入回收站
+func (im *importer) trashExisting() error {
+	projects, err := im.tx.Projects().List(im.userID)
+	if err != nil {
+		return err
+	}
+	for _, project := range projects {
+		if err := im.tx.Projects().Delete(project.ID, im.userID); err != nil {
+			return err
+		}
+		im.report.Projects.Trashed++
+	}
+
+	candidates, err := im.tx.Candidates().List(im.userID)
+	if err != nil {
+		return err
+	}
+	// 与 CandidateService.Delete 一致：照片与候选人使用相同的删除时间
+	now := time.Now().Truncate(time.Microsecond)
+	for _, candidate := range candidates {
+		if err := im.tx.CandidatePhotos().TrashByCandidateID(candidate.ID, now); err != nil {
+			return err
+		}
+		if err := im.tx.Candidates().Trash(candidate.ID, im.userID, now); err != nil {
+			return err
+		}
+		im.report.Candidates.Trashed++
+	}
+	return nil
+}
+
+// run 依次导入候选人、项目和历史记录
+func (im *importer) run() error {
+	im.ids = make(map[uuid.UUID]uuid.UUID)
+
+	existingCandidates, err := im.tx.Candidates().List(im.userID)
+	if err != nil {
+		return err
+	}
+	candidatesByName := make(map[string]uuid.UUID, len(existingCandidates))
+	for _, candidate := range existingCandidates {
+		if _, ok := candidatesByName[candidate.Name]; !ok {
+			candidatesByName[candidate.Name] = candidate.ID
+		}
+	}
+	for _, item := range im.doc.Candidates {
+		if id, ok := candidatesByName[item.Name]; ok {
+			im.ids[item.ID] = id
+			im.report.Candidates.Skipped++
+			im.report.Photos.Skipped += len(item.Photos)
+			continue
+		}
+		if err := im.importCandidate(item); err != nil {
+			return err
+		}
+	}
+
+	existingProjects, err := im.tx.Projects().List(im.userID)
+	if err != nil {
+		return err
+	}
+	projectsByName := make(map[string]uuid.UUID, len(existingProjects))
+	for _, project := range existingProjects {
+		if _, ok := projectsByName[project.Name]; !ok {
+			projectsByName[project.Name] = project.ID
+		}
+	}
+	for _, item := range im.doc.Projects {
+		if id, ok := projectsByName[item.Name]; ok {
+			im.ids[item.ID] = id
+			im.report.Projects.Skipped++
+			continue
+		}
+		if err := im.importProject(item); err != nil {
+			return err
+		}
+	}
+
+	if err := im.importSplits(); err != nil {
+		return err
+	}
+	return im.importHistories()
+}
+
+// importCandidate 创建候选人及其照片，头像与 Candidate.PhotoURL 保持一致
+func (im *importer) importCandidate(item ExportCandidate) error {
+	candidate := &model.Candidate{
+		Name:      item.Name,
+		PhotoURL:  item.PhotoURL,
+		UserID:    im.userID,
+		CreatedAt: item.CreatedAt,
+	}
+	if err := ApplyCandidateProfile(candidate, item.profile(), time.Now()); err != nil {
+		return err
+	}
+	if err := im.tx.Candidates().Create(candidate); err != nil {
+		return err
+	}
+	im.ids[item.ID] = candidate.ID
+	im.report.Candidates.Created++
+
+	var avatar *model.CandidatePhoto
+	for _, item := range item.Photos {
+		photoURL, err := im.savePhoto(item.File)
+		if err != nil {
+			return err
+		}
+		photo := &model.CandidatePhoto{
+			CandidateID: candidate.ID,
+			PhotoURL:    photoURL,
+			CreatedAt:   item.CreatedAt,
+		}
+		if err := im.tx.CandidatePhotos().Create(photo); err != nil {
+			return err
+		}
+		if item.IsAvatar {
+			avatar = photo
+		}
+		im.report.Photos.Created++
+	}
+	if avatar != nil {
+		return promoteAvatar(im.tx, candidate.ID, im.userID, avatar)
+	}
+	return nil
+}
+
+// savePhoto 校验归档中照片的大小和类型后写入上传目录，返回照片 URL；
+// 扩展名由图片内容决定，不使用归档中的文件名。试运行时只校验不写文件
+func (im *importer) savePhoto(file string) (string, error) {
+	rc, err := im.files[file].Open()
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidExport, err)
+	}
+	defer rc.Close()
+
+	data, ext, err := im.uploads.Read(rc)
+	if err != nil {
+		return "", fmt.Errorf("%w: photo %q: %v", ErrInvalidExport, file, err)
+	}
+	if im.dryRun {
+		return uploadURLPrefix + uuid.New().String() + ext, nil
+	}
+
+	photoURL, err := writeUpload(data, ext)
+	if err != nil {
+		return "", err
+	}
+	im.written = append(im.written, photoURL)
+	return photoURL, nil
+}
+
+// importProject 创建项目，候选人列表和特殊日规则使用新的候选人 ID
+func (im *importer) importProject(item ExportProject) error {
+	candidateIDs := make([]uuid.UUID, 0, len(item.CandidateIDs))
+	for _, id := range item.CandidateIDs {
+		candidateIDs = append(candidateIDs, im.ids[id])
+	}
+	data, err := json.Marshal(candidateIDs)
+	if err != nil {
+		return err
+	}
+	rules, err := EncodeEligibilityRules(item.EligibilityRules)
+	if err != nil {
+		return err
+	}
+	tagQuery, err := EncodeTagQuery(item.TagQuery)
+	if err != nil {
+		return err
+	}
+	remapped := make([]DrawOverride, 0, len(item.DrawOverrides))
+	for _, override := range item.DrawOverrides {
+		if override.CandidateID != nil {
+			id := im.ids[*override.CandidateID]
+			override.CandidateID = &id
+		}
+		remapped = append(remapped, override)
+	}
+	overrides, err := EncodeDrawOverrides(remapped)
+	if err != nil {
+		return err
+	}
+
+	project := &model.Project{
+		Name:             item.Name,
+		UserID:           im.userID,
+		CandidateIDs:     string(data),
+		CreatedAt:        item.CreatedAt,
+		TargetMinutes:    item.TargetMinutes,
+		PickPoints:       item.PickPoints,
+		OnTimePoints:     item.OnTimePoints,
+		RerollTokens:     item.RerollTokens,
+		EligibilityRules: rules,
+		TagQuery:         tagQuery,
+		DrawOverrides:    overrides,
+	}
+	if err := im.tx.Projects().Create(project); err != nil {
+		return err
+	}
+	im.ids[item.ID] = project.ID
+	im.report.Projects.Created++
+	return nil
+}
+
+// importHistories 创建历史记录并按记录的积分设置记入积分；项目中已有同一时间选中同一候选人的记录时跳过，
+// 因此同一份归档重复合并导入不会产生重复记录和积分。重新选择的关联在所有记录创建后再使用新的 ID 补上
+func (im *importer) importHistories() error {
+	// 版本 8 之前的记录没有目标时长快照，使用归档中项目的目标时长
+	targets := make(map[uuid.UUID]*int, len(im.doc.Projects))
+	if im.doc.Version < 8 {
+		for _, project := range im.doc.Projects {
+			targets[project.ID] = project.TargetMinutes
+		}
+	}
+
+	existing := make(map[uuid.UUID]map[string]uuid.UUID)
+	// 有重新选择关联的新记录，所有记录创建后再补上关联
+	type link struct {
+		history *model.History
+		item    ExportHistory
+	}
+	var links []link
+	for _, item := range im.doc.Histories {
+		projectID := im.ids[item.ProjectID]
+		seen, ok := existing[projectID]
+		if !ok {
+			histories, err := im.tx.Histories().List(im.userID, &projectID, -1)
+			if err != nil {
+				return err
+			}
+			seen = make(map[string]uuid.UUID, len(histories))
+			for _, history := range histories {
+				seen[historyKey(history.CandidateName, history.SelectedAt)] = history.ID
+			}
+			existing[projectID] = seen
+		}
+
+		key := historyKey(item.CandidateName, item.SelectedAt)
+		if id, ok := seen[key]; ok {
+			if item.ID != uuid.Nil {
+				im.ids[item.ID] = id
+			}
+			im.report.Histories.Skipped++
+			continue
+		}
+
+		candidateID := im.candidateID(item.CandidateID)
+		history := &model.History{
+			ProjectID:     projectID,
+			ProjectName:   item.ProjectName,
+			CandidateID:   candidateID,
+			CandidateName: item.CandidateName,
+			SelectedAt:    item.SelectedAt,
+			UserID:        im.userID,
+			Source:        model.HistorySourceImport,
+			Note:          item.Note,
+			CompletedAt:   item.CompletedAt,
+			VoidedAt:      item.VoidedAt,
+			VoidReason:    item.VoidReason,
+			Status:        importedStatus(&item),
+			StartedAt:     item.StartedAt,
+			SkippedAt:     item.SkippedAt,
+			PickPoints:    item.PickPoints,
+			OnTimePoints:  item.OnTimePoints,
+			TargetMinutes: item.TargetMinutes,
+			TagQuery:      item.TagQuery,
+
+			Overridden:     item.Overridden,
+			OverrideReason: item.OverrideReason,
+		}
+		if im.doc.Version < 8 {
+			history.TargetMinutes = targets[item.ProjectID]
+		}
+		if item.PoolCandidateIDs != nil {
+			pool := make([]uuid.UUID, len(item.PoolCandidateIDs))
+			for i, id := range item.PoolCandidateIDs {
+				pool[i] = im.candidateID(id)
+			}
+			data, err := json.Marshal(pool)
+			if err != nil {
+				return err
+			}
+			history.PoolCandidateIDs = string(data)
+		}
+		if item.SplitID != nil {
+			splitID := im.ids[*item.SplitID]
+			history.Source = model.HistorySourceSplit
+			history.SplitID = &splitID
+			history.SplitGroup = item.SplitGroup
+		}
+		if err := im.tx.Histories().Create(history); err != nil {
+			return err
+		}
+		if err := syncHistoryPoints(im.tx, history); err != nil {
+			return err
+		}
+		seen[key] = history.ID
+		if item.ID != uuid.Nil {
+			im.ids[item.ID] = history.ID
+		}
+		if item.SupersededByID != nil || item.RerollOfID != nil {
+			links = append(links, link{history, item})
+		}
+		im.report.Histories.Created++
+	}
+
+	for _, link := range links {
+		history, item := link.history, link.item
+		if item.SupersededByID != nil {
+			id := im.ids[*item.SupersededByID]
+			history.SupersededByID = &id
+			history.SupersededAt = item.SupersededAt
+		}
+		if item.RerollOfID != nil {
+			id := im.ids[*item.RerollOfID]
+			history.RerollOfID = &id
+		}
+		if err := im.tx.Histories().Update(history); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importSplits 创建分组记录，成员和不能分在同一组的候选人使用新的候选人 ID；
+// 项目中已有同一时间的分组时复用，不重复创建
+func (im *importer) importSplits() error {
+	existing := make(map[uuid.UUID]map[string]uuid.UUID)
+	for _, item := range im.doc.Splits {
+		projectID := im.ids[item.ProjectID]
+		seen, ok := existing[projectID]
+		if !ok {
+			histories, err := im.tx.Histories().List(im.userID, &projectID, -1)
+			if err != nil {
+				return err
+			}
+			seen = make(map[string]uuid.UUID)
+			for _, history := range histories {
+				if history.SplitID != nil {
+					seen[historyKey("", history.SelectedAt)] = *history.SplitID
+				}
+			}
+			existing[projectID] = seen
+		}
+		if splitID, ok := seen[historyKey("", item.CreatedAt)]; ok {
+			im.ids[item.ID] = splitID
+			im.report.Splits.Skipped++
+			continue
+		}
+
+		groups := make([][]TeamMember, len(item.Groups))
+		for i, group := range item.Groups {
+			groups[i] = make([]TeamMember, len(group))
+			for j, member := range group {
+				groups[i][j] = TeamMember{CandidateID: im.candidateID(member.CandidateID), CandidateName: member.CandidateName}
+			}
+		}
+		keepApart := make([][2]uuid.UUID, len(item.KeepApart))
+		for i, pair := range item.KeepApart {
+			keepApart[i] = [2]uuid.UUID{im.candidateID(pair[0]), im.candidateID(pair[1])}
+		}
+		groupsJSON, err := json.Marshal(groups)
+		if err != nil {
+			return err
+		}
+		keepApartJSON, err := json.Marshal(keepApart)
+		if err != nil {
+			return err
+		}
+		split := &model.TeamSplit{
+			UserID:      im.userID,
+			ProjectID:   projectID,
+			ProjectName: item.ProjectName,
+			GroupCount:  len(groups),
+			Groups:      string(groupsJSON),
+			KeepApart:   string(keepApartJSON),
+			CreatedAt:   item.CreatedAt,
+		}
+		if err := im.tx.TeamSplits().Create(split); err != nil {
+			return err
+		}
+		im.ids[item.ID] = split.ID
+		seen[historyKey("", item.CreatedAt)] = split.ID
+		im.report.Splits.Created++
+	}
+	return nil
+}
+
+// candidateID 导出文档中的候选人 ID 对应的新 ID；已删除的候选人没有被导出，为其分配一个新的 ID，
+// 保持同一候选人的记录相互关联
+func (im *importer) candidateID(id uuid.UUID) uuid.UUID {
+	newID, ok := im.ids[id]
+	if !ok {
+		newID = uuid.New()
+		im.ids[id] = newID
+	}
+	return newID
+}
+
+// importedStatus 导入记录的任务状态；版本 3 之前的归档没有状态，有完成时间时视为已完成
+func importedStatus(item *ExportHistory) string {
+	switch {
+	case item.Status != "":
+		return item.Status
+	case item.CompletedAt != nil:
+		return model.HistoryStatusCompleted
+	default:
+		return model.HistoryStatusPicked
+	}
+}
+
+// historyKey 用于识别重复历史记录的键；时间截断到微秒以兼容 PostgreSQL 的精度
+func historyKey(candidateName string, selectedAt time.Time) string {
+	return candidateName + "|" + selectedAt.Truncate(time.Microsecond).UTC().Format(time.RFC3339Nano)
+}
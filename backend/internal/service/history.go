@@ -0,0 +1,345 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"whotakesshowers/internal/logger"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// 备注的最大长度（字符数）
+	maxHistoryNoteLength = 500
+	// 作废原因的最大长度（字符数）
+	maxVoidReasonLength = 200
+	// 任务时间允许超前当前时间的误差，容忍客户端时钟偏差
+	taskClockSkew = time.Minute
+)
+
+// 修改记录中的字段名
+const (
+	HistoryFieldNote           = "note"
+	HistoryFieldStatus         = "status"
+	HistoryFieldStartedAt      = "started_at"
+	HistoryFieldCompletedAt    = "completed_at"
+	HistoryFieldSkippedAt      = "skipped_at"
+	HistoryFieldVoidedAt       = "voided_at"
+	HistoryFieldVoidReason     = "void_reason"
+	HistoryFieldSupersededByID = "superseded_by_id"
+	HistoryFieldSupersededAt   = "superseded_at"
+)
+
+// HistoryService 历史记录的备注、任务状态与作废，每次修改都留下修改记录，并同步记录产生的积分和徽章
+type HistoryService struct {
+	store store.Store
+}
+
+// NewHistoryService 创建历史记录服务
+func NewHistoryService(s store.Store) *HistoryService {
+	return &HistoryService{store: s}
+}
+
+// HistoryUpdate 历史记录的修改，为 nil 的字段保持不变
+type HistoryUpdate struct {
+	Note        *string    `json:"note"`
+	Completed   *bool      `json:"completed"`    // 标记完成（完成时间默认为当前时间）或撤销完成
+	CompletedAt *time.Time `json:"completed_at"` // 指定或更正完成时间，隐含已完成
+}
+
+// Get 获取单条历史记录
+func (s *HistoryService) Get(userID, historyID uuid.UUID) (*model.History, error) {
+	history, err := s.store.Histories().Get(historyID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrHistoryNotFound
+	}
+	return history, err
+}
+
+// Update 修改备注或完成状态
+func (s *HistoryService) Update(userID, historyID uuid.UUID, update HistoryUpdate) (*model.History, error) {
+	if update.Note != nil && utf8.RuneCountInString(*update.Note) > maxHistoryNoteLength {
+		return nil, fmt.Errorf("%w: note is longer than %d characters", ErrInvalidHistoryUpdate, maxHistoryNoteLength)
+	}
+	if update.Completed != nil && !*update.Completed && update.CompletedAt != nil {
+		return nil, fmt.Errorf("%w: completed_at requires completed to be true", ErrInvalidHistoryUpdate)
+	}
+
+	return s.modify(userID, historyID, func(history *model.History, now time.Time) error {
+		if update.Note != nil {
+			history.Note = *update.Note
+		}
+		completed := history.Status == model.HistoryStatusCompleted
+		switch {
+		case update.CompletedAt != nil && completed:
+			// 更正已完成任务的完成时间
+			completedAt, err := taskTime(HistoryFieldCompletedAt, update.CompletedAt, taskStartedAt(history), now)
+			if err != nil {
+				return err
+			}
+			history.CompletedAt = &completedAt
+		case update.CompletedAt != nil || (update.Completed != nil && *update.Completed && !completed):
+			return transitionTask(history, model.HistoryStatusCompleted, update.CompletedAt, now)
+		case update.Completed != nil && !*update.Completed && completed:
+			return reopenTask(history)
+		}
+		return nil
+	})
+}
+
+// Start 开始任务，at 为空时为当前时间
+func (s *HistoryService) Start(userID, historyID uuid.UUID, at *time.Time) (*model.History, error) {
+	return s.transition(userID, historyID, model.HistoryStatusStarted, at)
+}
+
+// Complete 完成任务，at 为空时为当前时间
+func (s *HistoryService) Complete(userID, historyID uuid.UUID, at *time.Time) (*model.History, error) {
+	return s.transition(userID, historyID, model.HistoryStatusCompleted, at)
+}
+
+// Skip 跳过任务，at 为空时为当前时间
+func (s *HistoryService) Skip(userID, historyID uuid.UUID, at *time.Time) (*model.History, error) {
+	return s.transition(userID, historyID, model.HistoryStatusSkipped, at)
+}
+
+// Reopen 撤销完成或跳过，任务回到开始或选中状态，用于更正误操作
+func (s *HistoryService) Reopen(userID, historyID uuid.UUID) (*model.History, error) {
+	return s.modify(userID, historyID, func(history *model.History, now time.Time) error {
+		return reopenTask(history)
+	})
+}
+
+// transition 将任务转换到 status
+func (s *HistoryService) transition(userID, historyID uuid.UUID, status string, at *time.Time) (*model.History, error) {
+	return s.modify(userID, historyID, func(history *model.History, now time.Time) error {
+		return transitionTask(history, status, at, now)
+	})
+}
+
+// transitionTask 将任务从 picked 转换到 started、completed 或 skipped，或从 started 转换到 completed 或 skipped
+func transitionTask(history *model.History, status string, at *time.Time, now time.Time) error {
+	if history.VoidedAt != nil {
+		return ErrHistoryVoided
+	}
+	switch {
+	case history.Status == model.HistoryStatusPicked:
+	case history.Status == model.HistoryStatusStarted && status != model.HistoryStatusStarted:
+	default:
+		return fmt.Errorf("%w: cannot change a %s task to %s", ErrInvalidTaskTransition, history.Status, status)
+	}
+
+	field := status + "_at"
+	t, err := taskTime(field, at, taskStartedAt(history), now)
+	if err != nil {
+		return err
+	}
+	switch status {
+	case model.HistoryStatusStarted:
+		history.StartedAt = &t
+	case model.HistoryStatusCompleted:
+		history.CompletedAt = &t
+	case model.HistoryStatusSkipped:
+		history.SkippedAt = &t
+	}
+	history.Status = status
+	return nil
+}
+
+// reopenTask 撤销完成或跳过；记录过开始时间的任务回到 started，否则回到 picked
+func reopenTask(history *model.History) error {
+	if history.VoidedAt != nil {
+		return ErrHistoryVoided
+	}
+	if history.Status != model.HistoryStatusCompleted && history.Status != model.HistoryStatusSkipped {
+		return fmt.Errorf("%w: cannot reopen a %s task", ErrInvalidTaskTransition, history.Status)
+	}
+	history.CompletedAt = nil
+	history.SkippedAt = nil
+	history.Status = model.HistoryStatusPicked
+	if history.StartedAt != nil {
+		history.Status = model.HistoryStatusStarted
+	}
+	return nil
+}
+
+// taskStartedAt 任务的开始时间，没有记录时为选中时间
+func taskStartedAt(history *model.History) time.Time {
+	if history.StartedAt != nil {
+		return *history.StartedAt
+	}
+	return history.SelectedAt
+}
+
+// taskTime 返回任务状态变化的时间：at 为空时为当前时间，否则不能早于 notBefore，也不能晚于当前时间
+func taskTime(field string, at *time.Time, notBefore, now time.Time) (time.Time, error) {
+	if at == nil {
+		return now, nil
+	}
+	t := at.Local()
+	if t.Before(notBefore) {
+		return time.Time{}, fmt.Errorf("%w: %s is before the task was picked or started", ErrInvalidHistoryUpdate, field)
+	}
+	if t.After(now.Add(taskClockSkew)) {
+		return time.Time{}, fmt.Errorf("%w: %s is in the future", ErrInvalidHistoryUpdate, field)
+	}
+	return t, nil
+}
+
+// Void 作废历史记录，作废的记录保留但不计入统计
+func (s *HistoryService) Void(userID, historyID uuid.UUID, reason string) (*model.History, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, fmt.Errorf("%w: reason is required", ErrInvalidHistoryUpdate)
+	}
+	if utf8.RuneCountInString(reason) > maxVoidReasonLength {
+		return nil, fmt.Errorf("%w: reason is longer than %d characters", ErrInvalidHistoryUpdate, maxVoidReasonLength)
+	}
+
+	return s.modify(userID, historyID, func(history *model.History, now time.Time) error {
+		if history.VoidedAt != nil {
+			return ErrHistoryVoided
+		}
+		history.VoidedAt = &now
+		history.VoidReason = reason
+		return nil
+	})
+}
+
+// Unvoid 撤销作废；被重新选择取代的记录不能撤销作废
+func (s *HistoryService) Unvoid(userID, historyID uuid.UUID) (*model.History, error) {
+	return s.modify(userID, historyID, func(history *model.History, now time.Time) error {
+		if history.VoidedAt == nil {
+			return ErrHistoryNotVoided
+		}
+		if history.SupersededByID != nil {
+			return ErrHistorySuperseded
+		}
+		history.VoidedAt = nil
+		history.VoidReason = ""
+		return nil
+	})
+}
+
+// Changes 按时间顺序获取历史记录的修改记录
+func (s *HistoryService) Changes(userID, historyID uuid.UUID) ([]model.HistoryChange, error) {
+	if _, err := s.Get(userID, historyID); err != nil {
+		return nil, err
+	}
+	return s.store.HistoryChanges().List(historyID)
+}
+
+// modify 在事务中读取历史记录、调用 apply 修改，并为每个变化的字段写入修改记录
+func (s *HistoryService) modify(userID, historyID uuid.UUID, apply func(history *model.History, now time.Time) error) (*model.History, error) {
+	var updated *model.History
+	err := s.store.Transaction(func(tx store.Store) error {
+		var err error
+		updated, err = modifyHistory(tx, userID, historyID, apply)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// modifyHistory 在事务 tx 中读取历史记录、调用 apply 修改，写入修改记录并同步积分和徽章
+func modifyHistory(tx store.Store, userID, historyID uuid.UUID, apply func(history *model.History, now time.Time) error) (*model.History, error) {
+	history, err := tx.Histories().Get(historyID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrHistoryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	before := *history
+	now := time.Now()
+	if err := apply(history, now); err != nil {
+		return nil, err
+	}
+
+	changes := diffHistory(&before, history, userID, now)
+	if len(changes) == 0 {
+		return history, nil
+	}
+	if err := tx.Histories().Update(history); err != nil {
+		return nil, err
+	}
+	if err := tx.HistoryChanges().Create(changes); err != nil {
+		return nil, err
+	}
+	// 完成状态或作废的变化可能改变这条记录应得的积分
+	if err := syncHistoryPoints(tx, history); err != nil {
+		return nil, err
+	}
+	if err := awardCandidateBadges(tx, userID, history.CandidateID); err != nil {
+		return nil, err
+	}
+
+	fields := make([]string, len(changes))
+	for i, change := range changes {
+		fields[i] = change.Field
+	}
+	logger.Info("History entry changed",
+		zap.String("user_id", userID.String()),
+		zap.String("history_id", historyID.String()),
+		zap.Strings("fields", fields),
+	)
+	return history, nil
+}
+
+// diffHistory 比较修改前后的可修改字段，返回变化字段的修改记录
+func diffHistory(before, after *model.History, userID uuid.UUID, at time.Time) []model.HistoryChange {
+	fields := []struct {
+		name          string
+		before, after string
+	}{
+		{HistoryFieldNote, before.Note, after.Note},
+		{HistoryFieldStatus, before.Status, after.Status},
+		{HistoryFieldStartedAt, formatOptionalTime(before.StartedAt, time.RFC3339Nano), formatOptionalTime(after.StartedAt, time.RFC3339Nano)},
+		{HistoryFieldCompletedAt, formatOptionalTime(before.CompletedAt, time.RFC3339Nano), formatOptionalTime(after.CompletedAt, time.RFC3339Nano)},
+		{HistoryFieldSkippedAt, formatOptionalTime(before.SkippedAt, time.RFC3339Nano), formatOptionalTime(after.SkippedAt, time.RFC3339Nano)},
+		{HistoryFieldVoidedAt, formatOptionalTime(before.VoidedAt, time.RFC3339Nano), formatOptionalTime(after.VoidedAt, time.RFC3339Nano)},
+		{HistoryFieldVoidReason, before.VoidReason, after.VoidReason},
+		{HistoryFieldSupersededByID, formatOptionalUUID(before.SupersededByID), formatOptionalUUID(after.SupersededByID)},
+		{HistoryFieldSupersededAt, formatOptionalTime(before.SupersededAt, time.RFC3339Nano), formatOptionalTime(after.SupersededAt, time.RFC3339Nano)},
+	}
+
+	var changes []model.HistoryChange
+	for _, field := range fields {
+		if field.before == field.after {
+			continue
+		}
+		changes = append(changes, model.HistoryChange{
+			HistoryID: after.ID,
+			UserID:    userID,
+			Field:     field.name,
+			OldValue:  field.before,
+			NewValue:  field.after,
+			ChangedAt: at,
+		})
+	}
+	return changes
+}
+
+// formatOptionalTime 按 layout 格式化可为空的时间，nil 为空字符串
+func formatOptionalTime(t *time.Time, layout string) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(layout)
+}
+
+// formatOptionalUUID 格式化可为空的 ID，nil 为空字符串
+func formatOptionalUUID(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
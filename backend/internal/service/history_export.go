@@ -0,0 +1,411 @@
+package service
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"whotakesshowers/internal/logger"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// HistoryFormat 历史记录导出格式
+type HistoryFormat string
+
+const (
+	HistoryFormatCSV    HistoryFormat = "csv"
+	HistoryFormatNDJSON HistoryFormat = "ndjson"
+	HistoryFormatICS    HistoryFormat = "ics"
+)
+
+// ParseHistoryFormat 解析导出格式，为空时为 csv
+func ParseHistoryFormat(s string) (HistoryFormat, error) {
+	switch format := HistoryFormat(s); format {
+	case "":
+		return HistoryFormatCSV, nil
+	case HistoryFormatCSV, HistoryFormatNDJSON, HistoryFormatICS:
+		return format, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrInvalidHistoryFormat, s)
+}
+
+// ContentType 返回格式对应的 MIME 类型
+func (f HistoryFormat) ContentType() string {
+	switch f {
+	case HistoryFormatNDJSON:
+		return "application/x-ndjson; charset=utf-8"
+	case HistoryFormatICS:
+		return "text/calendar; charset=utf-8"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+const (
+	// 日历事件的时长
+	calendarEventDuration = "PT15M"
+	// 默认日历名称
+	defaultCalendarName = "WhoTakesShowers 历史记录"
+)
+
+// HistoryExportService 历史记录导出与日历订阅服务
+type HistoryExportService struct {
+	store store.Store
+}
+
+// NewHistoryExportService 创建历史记录导出服务
+func NewHistoryExportService(s store.Store) *HistoryExportService {
+	return &HistoryExportService{store: s}
+}
+
+// HistoryExport 待写出的历史记录，第一页已经读取，参数错误在写出前返回
+type HistoryExport struct {
+	Format HistoryFormat
+
+	histories    store.HistoryRepository
+	userID       uuid.UUID
+	filter       store.HistoryFilter
+	page         store.PageRequest
+	first        *store.Page[model.History]
+	calendarName string
+}
+
+// Export 准备导出符合过滤条件的历史记录，sort 与列表接口相同
+func (s *HistoryExportService) Export(userID uuid.UUID, filter store.HistoryFilter, sort string, format HistoryFormat) (*HistoryExport, error) {
+	return s.open(userID, filter, sort, format, defaultCalendarName)
+}
+
+// open 读取第一页，确认过滤条件和排序方式有效
+func (s *HistoryExportService) open(userID uuid.UUID, filter store.HistoryFilter, sort string, format HistoryFormat, calendarName string) (*HistoryExport, error) {
+	page := store.PageRequest{Limit: store.MaxPageLimit, Sort: sort}
+	first, err := s.store.Histories().Find(userID, filter, page)
+	if err != nil {
+		return nil, err
+	}
+	return &HistoryExport{
+		Format:       format,
+		histories:    s.store.Histories(),
+		userID:       userID,
+		filter:       filter,
+		page:         page,
+		first:        first,
+		calendarName: calendarName,
+	}, nil
+}
+
+// WriteTo 逐页读取历史记录并写入 w
+func (e *HistoryExport) WriteTo(w io.Writer) (int64, error) {
+	counter := &countingWriter{w: w}
+	buffered := bufio.NewWriter(counter)
+
+	var out historyWriter
+	switch e.Format {
+	case HistoryFormatNDJSON:
+		out = &ndjsonHistoryWriter{encoder: json.NewEncoder(buffered)}
+	case HistoryFormatICS:
+		out = &icsHistoryWriter{w: buffered, name: e.calendarName, stamp: time.Now().UTC()}
+	default:
+		out = &csvHistoryWriter{w: buffered, csv: csv.NewWriter(buffered)}
+	}
+
+	if err := out.begin(); err != nil {
+		return counter.n, err
+	}
+	page := e.first
+	for {
+		for i := range page.Items {
+			if err := out.write(&page.Items[i]); err != nil {
+				return counter.n, err
+			}
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		// 每页写完后发送给客户端，避免整个文件积压在内存中
+		if err := buffered.Flush(); err != nil {
+			return counter.n, err
+		}
+
+		next := e.page
+		next.Cursor = page.NextCursor
+		var err error
+		if page, err = e.histories.Find(e.userID, e.filter, next); err != nil {
+			return counter.n, err
+		}
+	}
+	if err := out.end(); err != nil {
+		return counter.n, err
+	}
+	err := buffered.Flush()
+	return counter.n, err
+}
+
+// historyWriter 一种导出格式的写入器
+type historyWriter interface {
+	begin() error
+	write(h *model.History) error
+	end() error
+}
+
+// csvHistoryWriter 写出 CSV，带 UTF-8 BOM 以便电子表格软件正确识别中文
+type csvHistoryWriter struct {
+	w   io.Writer
+	csv *csv.Writer
+}
+
+func (cw *csvHistoryWriter) begin() error {
+	if _, err := io.WriteString(cw.w, "\ufeff"); err != nil {
+		return err
+	}
+	return cw.csv.Write([]string{
+		"id", "selected_at", "project_id", "project_name", "candidate_id", "candidate_name", "source",
+		"note", "status", "started_at", "completed_at", "skipped_at", "voided_at", "void_reason",
+		"split_id", "split_group",
+	})
+}
+
+func (cw *csvHistoryWriter) write(h *model.History) error {
+	return cw.csv.Write([]string{
+		h.ID.String(),
+		h.SelectedAt.Format(time.RFC3339),
+		h.ProjectID.String(),
+		h.ProjectName,
+		h.CandidateID.String(),
+		h.CandidateName,
+		h.Source,
+		h.Note,
+		h.Status,
+		formatOptionalTime(h.StartedAt, time.RFC3339),
+		formatOptionalTime(h.CompletedAt, time.RFC3339),
+		formatOptionalTime(h.SkippedAt, time.RFC3339),
+		formatOptionalTime(h.VoidedAt, time.RFC3339),
+		h.VoidReason,
+		formatOptionalUUID(h.SplitID),
+		formatOptionalInt(h.SplitGroup),
+	})
+}
+
+func (cw *csvHistoryWriter) end() error {
+	cw.csv.Flush()
+	return cw.csv.Error()
+}
+
+// ndjsonHistoryWriter 每行一条 JSON 格式的历史记录
+type ndjsonHistoryWriter struct {
+	encoder *json.Encoder
+}
+
+func (nw *ndjsonHistoryWriter) begin() error { return nil }
+
+func (nw *ndjsonHistoryWriter) write(h *model.History) error {
+	return nw.encoder.Encode(h)
+}
+
+func (nw *ndjsonHistoryWriter) end() error { return nil }
+
+// icsHistoryWriter 写出 iCalendar（RFC 5545），每条历史记录一个事件
+type icsHistoryWriter struct {
+	w     io.Writer
+	name  string
+	stamp time.Time
+}
+
+func (iw *icsHistoryWriter) begin() error {
+	return iw.lines(
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//WhoTakesShowers//History//ZH",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:"+icsEscape(iw.name),
+		"REFRESH-INTERVAL;VALUE=DURATION:PT1H",
+		"X-PUBLISHED-TTL:PT1H",
+	)
+}
+
+func (iw *icsHistoryWriter) write(h *model.History) error {
+	description := fmt.Sprintf("项目：%s\n选中：%s\n来源：%s", h.ProjectName, h.CandidateName, h.Source)
+	summary := h.ProjectName + "：" + h.CandidateName
+	if h.SplitGroup != nil {
+		description += "\n分组：第 " + splitGroupLabel(h.SplitGroup) + " 组"
+		summary += "（第 " + splitGroupLabel(h.SplitGroup) + " 组）"
+	}
+	if h.StartedAt != nil {
+		description += "\n开始：" + h.StartedAt.Local().Format(time.DateTime)
+	}
+	if h.CompletedAt != nil {
+		description += "\n完成：" + h.CompletedAt.Local().Format(time.DateTime)
+	}
+	if h.SkippedAt != nil {
+		description += "\n跳过：" + h.SkippedAt.Local().Format(time.DateTime)
+	}
+	if h.Note != "" {
+		description += "\n备注：" + h.Note
+	}
+	// 作废的记录保留为已取消的事件，日历应用会移除或划掉已同步的事件
+	status := "CONFIRMED"
+	if h.VoidedAt != nil {
+		status = "CANCELLED"
+		description += "\n已作废：" + h.VoidReason
+	}
+	return iw.lines(
+		"BEGIN:VEVENT",
+		"UID:"+h.ID.String()+"@whotakesshowers",
+		"DTSTAMP:"+icsTime(iw.stamp),
+		"DTSTART:"+icsTime(h.SelectedAt),
+		"DURATION:"+calendarEventDuration,
+		"STATUS:"+status,
+		"SUMMARY:"+icsEscape(summary),
+		"DESCRIPTION:"+icsEscape(description),
+		"END:VEVENT",
+	)
+}
+
+// splitGroupLabel 分组记录所在的组，从 1 开始编号，用于展示
+func splitGroupLabel(group *int) string {
+	return strconv.Itoa(*group + 1)
+}
+
+// formatOptionalInt 格式化可为空的整数，nil 为空字符串
+func formatOptionalInt(n *int) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.Itoa(*n)
+}
+
+func (iw *icsHistoryWriter) end() error {
+	return iw.lines("END:VCALENDAR")
+}
+
+// lines 写出内容行，超过 75 字节的行按 RFC 5545 折行，不拆分 UTF-8 字符
+func (iw *icsHistoryWriter) lines(lines ...string) error {
+	for _, line := range lines {
+		var b strings.Builder
+		width := 0
+		for _, r := range line {
+			size := utf8.RuneLen(r)
+			if width+size > 75 {
+				b.WriteString("\r\n ")
+				width = 1
+			}
+			b.WriteRune(r)
+			width += size
+		}
+		b.WriteString("\r\n")
+		if _, err := io.WriteString(iw.w, b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// icsTime 格式化为 UTC 时间
+func icsTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// icsEscape 转义文本值中的特殊字符
+func icsEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `;`, `\;`, `,`, `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// CreatedFeed 新建的日历订阅，令牌只在创建时返回一次
+type CreatedFeed struct {
+	model.CalendarFeed
+	Token string `json:"token"`
+}
+
+// CreateFeed 创建日历订阅，可以只包含指定项目或候选人的记录
+func (s *HistoryExportService) CreateFeed(userID uuid.UUID, name string, projectID, candidateID *uuid.UUID) (*CreatedFeed, error) {
+	if projectID != nil {
+		if _, err := s.store.Projects().Get(*projectID, userID); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return nil, ErrProjectNotFound
+			}
+			return nil, err
+		}
+	}
+	if candidateID != nil {
+		if _, err := s.store.Candidates().Get(*candidateID, userID); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return nil, ErrCandidateNotFound
+			}
+			return nil, err
+		}
+	}
+	if name == "" {
+		name = defaultCalendarName
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+	feed := &CreatedFeed{
+		CalendarFeed: model.CalendarFeed{
+			UserID:      userID,
+			Name:        name,
+			TokenHash:   hashFeedToken(token),
+			ProjectID:   projectID,
+			CandidateID: candidateID,
+		},
+		Token: token,
+	}
+	if err := s.store.CalendarFeeds().Create(&feed.CalendarFeed); err != nil {
+		return nil, err
+	}
+	logger.Info("Calendar feed created",
+		zap.String("user_id", userID.String()),
+		zap.String("feed_id", feed.ID.String()),
+	)
+	return feed, nil
+}
+
+// ListFeeds 获取用户的日历订阅
+func (s *HistoryExportService) ListFeeds(userID uuid.UUID) ([]model.CalendarFeed, error) {
+	return s.store.CalendarFeeds().List(userID)
+}
+
+// DeleteFeed 删除日历订阅，订阅地址随即失效
+func (s *HistoryExportService) DeleteFeed(userID, feedID uuid.UUID) error {
+	err := s.store.CalendarFeeds().Delete(feedID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrFeedNotFound
+	}
+	return err
+}
+
+// Feed 根据订阅令牌准备 iCalendar 文件
+func (s *HistoryExportService) Feed(token string) (*HistoryExport, error) {
+	feed, err := s.store.CalendarFeeds().GetByTokenHash(hashFeedToken(token))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrFeedNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	filter := store.HistoryFilter{ProjectID: feed.ProjectID, CandidateID: feed.CandidateID}
+	return s.open(feed.UserID, filter, "", HistoryFormatICS, feed.Name)
+}
+
+// hashFeedToken 返回订阅令牌的 SHA-256 哈希
+func hashFeedToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+	"whotakesshowers/internal/logger"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// 幂等键的最大长度
+	maxIdempotencyKeyLength = 255
+	// 第一次请求处理超过该时长仍未完成时（例如服务在处理中重启），视为已放弃，允许用同一个键重试
+	idempotencyLockTimeout = time.Minute
+)
+
+// IdempotencyService 幂等键服务
+// 带 Idempotency-Key 请求头的写请求第一次完成后保存响应，有效期内用同一个键重试时返回保存的响应而不再执行；
+// 同一个键用于不同的请求时拒绝
+type IdempotencyService struct {
+	store       store.Store
+	ttl         time.Duration
+	maxBodySize int64
+}
+
+// NewIdempotencyService 创建幂等键服务，ttl 为保存的响应的有效期，不大于 0 时不启用；
+// maxBodySize 为带幂等键的请求体的最大字节数，请求体需要整个读入内存计算哈希
+func NewIdempotencyService(s store.Store, ttl time.Duration, maxBodySize int64) *IdempotencyService {
+	return &IdempotencyService{store: s, ttl: ttl, maxBodySize: maxBodySize}
+}
+
+// MaxBodySize 带幂等键的请求体的最大字节数
+func (s *IdempotencyService) MaxBodySize() int64 {
+	return s.maxBodySize
+}
+
+// Enabled 是否启用幂等键
+func (s *IdempotencyService) Enabled() bool {
+	return s.ttl > 0
+}
+
+// IdempotentRequest 带幂等键的写请求
+type IdempotentRequest struct {
+	UserID uuid.UUID
+	Key    string
+	Method string
+	Path   string // 包括查询参数
+	Body   []byte
+}
+
+// hash 请求方法、路径和请求体的 SHA-256，用于判断重试的是否是同一个请求
+func (r *IdempotentRequest) hash() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", r.Method, r.Path)
+	h.Write(r.Body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Begin 开始处理带幂等键的请求
+// 键已有保存的响应时返回该记录且 replay 为 true，调用方直接返回保存的响应；
+// 否则占用该键并返回新的记录，调用方执行请求后调用 Finish
+func (s *IdempotencyService) Begin(req *IdempotentRequest) (record *model.IdempotencyKey, replay bool, err error) {
+	if len(req.Key) > maxIdempotencyKeyLength {
+		return nil, false, fmt.Errorf("%w: longer than %d characters", ErrInvalidIdempotencyKey, maxIdempotencyKeyLength)
+	}
+	hash := req.hash()
+	now := time.Now()
+
+	existing, err := s.store.IdempotencyKeys().Get(req.UserID, req.Key)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return nil, false, err
+	}
+	if existing != nil {
+		replay, err := s.reuse(existing, hash, now)
+		if err != nil || replay {
+			return existing, replay, err
+		}
+	}
+
+	record = &model.IdempotencyKey{
+		UserID:      req.UserID,
+		Key:         req.Key,
+		Method:      req.Method,
+		Path:        req.Path,
+		RequestHash: hash,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(s.ttl),
+	}
+	if err := s.store.IdempotencyKeys().Create(record); err != nil {
+		// 同一个键的另一个请求刚刚占用了它
+		existing, getErr := s.store.IdempotencyKeys().Get(req.UserID, req.Key)
+		if getErr != nil {
+			return nil, false, err
+		}
+		replay, err := s.reuse(existing, hash, now)
+		if err == nil && !replay {
+			err = ErrIdempotencyKeyInProgress
+		}
+		return existing, replay, err
+	}
+	return record, false, nil
+}
+
+// reuse 判断已有的键能否用于请求：返回 replay 为 true 表示返回保存的响应；
+// 键已过期或第一次请求已放弃时删除该键，返回 false 表示可以重新占用
+func (s *IdempotencyService) reuse(existing *model.IdempotencyKey, hash string, now time.Time) (bool, error) {
+	abandoned := existing.StatusCode == 0 && now.Sub(existing.CreatedAt) > idempotencyLockTimeout
+	if existing.ExpiresAt.Before(now) || abandoned {
+		return false, s.store.IdempotencyKeys().Delete(existing.ID)
+	}
+	if existing.RequestHash != hash {
+		return false, ErrIdempotencyKeyReused
+	}
+	if existing.StatusCode == 0 {
+		return false, ErrIdempotencyKeyInProgress
+	}
+	return true, nil
+}
+
+// Finish 保存请求的响应；服务端错误（5xx）不保存并释放该键，以便客户端重试
+func (s *IdempotencyService) Finish(record *model.IdempotencyKey, statusCode int, contentType string, body []byte) error {
+	if statusCode >= 500 {
+		return s.Release(record)
+	}
+	record.StatusCode = statusCode
+	record.ContentType = contentType
+	record.ResponseBody = string(body)
+	return s.store.IdempotencyKeys().Complete(record)
+}
+
+// Release 不保存响应并释放该键，客户端可以用同一个键重试
+func (s *IdempotencyService) Release(record *model.IdempotencyKey) error {
+	return s.store.IdempotencyKeys().Delete(record.ID)
+}
+
+// PurgeExpired 删除已过期的幂等键，返回删除的条数
+func (s *IdempotencyService) PurgeExpired() (int64, error) {
+	return s.store.IdempotencyKeys().DeleteExpired(time.Now())
+}
+
+// StartPurger 启动后台任务，每隔 interval 删除已过期的幂等键
+// 未启用幂等键或 interval 不大于 0 时不启动
+func (s *IdempotencyService) StartPurger(interval time.Duration) {
+	if !s.Enabled() || interval <= 0 {
+		logger.Warn("Idempotency key purger disabled",
+			zap.Duration("ttl", s.ttl),
+			zap.Duration("interval", interval),
+		)
+		return
+	}
+
+	run := func() {
+		purged, err := s.PurgeExpired()
+		if err != nil {
+			logger.Error("Failed to purge expired idempotency keys", zap.Error(err))
+			return
+		}
+		if purged > 0 {
+			logger.Info("Purged expired idempotency keys", zap.Int64("count", purged))
+		}
+	}
+
+	go func() {
+		run()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			run()
+		}
+	}()
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"whotakesshowers/internal/model"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// 每个项目特殊日规则的最大数量
+	maxDrawOverrides = 20
+	// 特殊日规则自定义说明的最大长度
+	maxDrawOverrideMessage = 200
+)
+
+// 特殊日规则的类型
+const (
+	DrawOverrideBirthday = "birthday" // 候选人生日当天
+	DrawOverrideDates    = "dates"    // 指定的日期范围内
+)
+
+// 特殊日规则的动作
+const (
+	DrawOverrideExclude = "exclude" // 不选择该候选人
+	DrawOverrideForce   = "force"   // 一定选择该候选人
+)
+
+// DrawOverride 项目的特殊日规则，在规则适用的日期改变随机选择的结果
+//
+//	{"type": "birthday", "action": "exclude"}
+//	{"type": "dates", "action": "force", "candidate_id": "...", "from": "2025-12-24", "to": "2025-12-26"}
+type DrawOverride struct {
+	Type        string     `json:"type"`                   // birthday 或 dates
+	Action      string     `json:"action"`                 // exclude 或 force
+	CandidateID *uuid.UUID `json:"candidate_id,omitempty"` // dates 必填；birthday 为空时适用于所有候选人
+	From        string     `json:"from,omitempty"`         // dates 的起止日期 YYYY-MM-DD，包括两端
+	To          string     `json:"to,omitempty"`
+	Message     string     `json:"message,omitempty"` // 自定义说明，替代默认的原因
+}
+
+// OverrideEffect 本次选择中生效的特殊日规则及受影响的候选人
+type OverrideEffect struct {
+	Type          string    `json:"type"`
+	Action        string    `json:"action"`
+	CandidateID   uuid.UUID `json:"candidate_id"`
+	CandidateName string    `json:"candidate_name"`
+	Reason        string    `json:"reason"`
+	// 排除会使没有候选人可选时，排除本次不生效
+	Relaxed bool `json:"relaxed"`
+}
+
+// EncodeDrawOverrides 校验特殊日规则并编码为 Project.DrawOverrides，无效时返回 ErrInvalidDrawOverride
+func EncodeDrawOverrides(overrides []DrawOverride) (string, error) {
+	if len(overrides) > maxDrawOverrides {
+		return "", fmt.Errorf("%w: at most %d overrides", ErrInvalidDrawOverride, maxDrawOverrides)
+	}
+	for i := range overrides {
+		override := &overrides[i]
+		if override.Action != DrawOverrideExclude && override.Action != DrawOverrideForce {
+			return "", fmt.Errorf("%w: action must be exclude or force", ErrInvalidDrawOverride)
+		}
+		override.Message = strings.TrimSpace(override.Message)
+		if len(override.Message) > maxDrawOverrideMessage {
+			return "", fmt.Errorf("%w: message is longer than %d characters", ErrInvalidDrawOverride, maxDrawOverrideMessage)
+		}
+		switch override.Type {
+		case DrawOverrideBirthday:
+			if override.From != "" || override.To != "" {
+				return "", fmt.Errorf("%w: birthday overrides take no dates", ErrInvalidDrawOverride)
+			}
+		case DrawOverrideDates:
+			if override.CandidateID == nil {
+				return "", fmt.Errorf("%w: dates overrides need a candidate_id", ErrInvalidDrawOverride)
+			}
+			from, err := time.Parse(time.DateOnly, override.From)
+			if err != nil {
+				return "", fmt.Errorf("%w: from must be YYYY-MM-DD", ErrInvalidDrawOverride)
+			}
+			to, err := time.Parse(time.DateOnly, override.To)
+			if err != nil {
+				return "", fmt.Errorf("%w: to must be YYYY-MM-DD", ErrInvalidDrawOverride)
+			}
+			if to.Before(from) {
+				return "", fmt.Errorf("%w: to is before from", ErrInvalidDrawOverride)
+			}
+		default:
+			return "", fmt.Errorf("%w: type must be birthday or dates", ErrInvalidDrawOverride)
+		}
+	}
+	if len(overrides) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(overrides)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// decodeDrawOverrides 解析项目的特殊日规则
+func decodeDrawOverrides(project *model.Project) ([]DrawOverride, error) {
+	var overrides []DrawOverride
+	if project.DrawOverrides != "" {
+		if err := json.Unmarshal([]byte(project.DrawOverrides), &overrides); err != nil {
+			return nil, err
+		}
+	}
+	return overrides, nil
+}
+
+// applies 判断规则在 today（YYYY-MM-DD）是否适用于候选人
+func (o *DrawOverride) applies(candidate *model.Candidate, today string) bool {
+	if o.CandidateID != nil && *o.CandidateID != candidate.ID {
+		return false
+	}
+	switch o.Type {
+	case DrawOverrideBirthday:
+		return isBirthday(candidate.Birthday, today)
+	case DrawOverrideDates:
+		// 同为 YYYY-MM-DD 格式，可以直接按字符串比较
+		return o.From <= today && today <= o.To
+	}
+	return false
+}
+
+// reason 规则对候选人生效的说明
+func (o *DrawOverride) reason(candidate *model.Candidate) string {
+	if o.Message != "" {
+		return o.Message
+	}
+	var when string
+	switch {
+	case o.Type == DrawOverrideBirthday:
+		when = "on their birthday"
+	case o.From == o.To:
+		when = "on " + o.From
+	default:
+		when = fmt.Sprintf("from %s to %s", o.From, o.To)
+	}
+	if o.Action == DrawOverrideForce {
+		return fmt.Sprintf("%s is always picked %s", candidate.Name, when)
+	}
+	return fmt.Sprintf("%s is never picked %s", candidate.Name, when)
+}
+
+// isBirthday 判断 today（YYYY-MM-DD）是否是生日；2 月 29 日的生日在平年按 2 月 28 日计算
+func isBirthday(birthday, today string) bool {
+	if len(birthday) != len(time.DateOnly) || len(today) != len(time.DateOnly) {
+		return false
+	}
+	monthDay := birthday[5:]
+	if monthDay == "02-29" {
+		year, err := time.Parse(time.DateOnly, today[:4]+"-03-01")
+		if err == nil && year.AddDate(0, 0, -1).Day() == 28 {
+			monthDay = "02-28"
+		}
+	}
+	return today[5:] == monthDay
+}
+
+// applyDrawOverrides 按项目在 now 所在日期（本地时区）适用的特殊日规则调整候选人：
+// 有一定选择的候选人时只在他们之中选择，否则去掉被排除的候选人；
+// 排除会使没有候选人可选时，排除本次不生效，在结果中标记为 Relaxed
+func applyDrawOverrides(project *model.Project, candidates []model.Candidate, now time.Time) ([]model.Candidate, []OverrideEffect, error) {
+	overrides, err := decodeDrawOverrides(project)
+	if err != nil {
+		return nil, nil, err
+	}
+	effects := make([]OverrideEffect, 0)
+	if len(overrides) == 0 {
+		return candidates, effects, nil
+	}
+
+	today := now.Local().Format(time.DateOnly)
+	effect := func(override *DrawOverride, candidate *model.Candidate) OverrideEffect {
+		return OverrideEffect{
+			Type:          override.Type,
+			Action:        override.Action,
+			CandidateID:   candidate.ID,
+			CandidateName: candidate.Name,
+			Reason:        override.reason(candidate),
+		}
+	}
+
+	// 一定选择的规则优先于排除
+	forced := make([]model.Candidate, 0)
+	for _, candidate := range candidates {
+		for i := range overrides {
+			if overrides[i].Action == DrawOverrideForce && overrides[i].applies(&candidate, today) {
+				forced = append(forced, candidate)
+				effects = append(effects, effect(&overrides[i], &candidate))
+				break
+			}
+		}
+	}
+	if len(forced) > 0 {
+		return forced, effects, nil
+	}
+
+	kept := make([]model.Candidate, 0, len(candidates))
+	for _, candidate := range candidates {
+		excluded := false
+		for i := range overrides {
+			if overrides[i].Action == DrawOverrideExclude && overrides[i].applies(&candidate, today) {
+				excluded = true
+				effects = append(effects, effect(&overrides[i], &candidate))
+				break
+			}
+		}
+		if !excluded {
+			kept = append(kept, candidate)
+		}
+	}
+	if len(kept) == 0 {
+		for i := range effects {
+			effects[i].Relaxed = true
+		}
+		return candidates, effects, nil
+	}
+	return kept, effects, nil
+}
+
+// overrideReason 生效的特殊日规则的说明，用于历史记录；没有生效的规则时为空
+func overrideReason(effects []OverrideEffect) string {
+	reasons := make([]string, 0, len(effects))
+	for _, effect := range effects {
+		if !effect.Relaxed {
+			reasons = append(reasons, effect.Reason)
+		}
+	}
+	return strings.Join(reasons, "; ")
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"whotakesshowers/internal/model"
+
+	"github.com/google/uuid"
+)
+
+func TestIsBirthday(t *testing.T) {
+	tests := []struct {
+		birthday string
+		today    string
+		want     bool
+	}{
+		{birthday: "2015-06-15", today: "2025-06-15", want: true},
+		{birthday: "2015-06-15", today: "2025-06-14", want: false},
+		{birthday: "2015-06-15", today: "2025-15-06", want: false},
+		// 2 月 29 日的生日在平年按 2 月 28 日计算，闰年仍是 2 月 29 日
+		{birthday: "2016-02-29", today: "2025-02-28", want: true},
+		{birthday: "2016-02-29", today: "2025-03-01", want: false},
+		{birthday: "2016-02-29", today: "2024-02-29", want: true},
+		{birthday: "2016-02-29", today: "2024-02-28", want: false},
+		{birthday: "2015-02-28", today: "2024-02-28", want: true},
+		{birthday: "2015-02-28", today: "2024-02-29", want: false},
+		{birthday: "", today: "2025-06-15", want: false},
+		{birthday: "2015-6-15", today: "2025-06-15", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.birthday+" "+tt.today, func(t *testing.T) {
+			if got := isBirthday(tt.birthday, tt.today); got != tt.want {
+				t.Errorf("isBirthday(%q, %q) = %v, want %v", tt.birthday, tt.today, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeDrawOverrides(t *testing.T) {
+	id := uuid.New()
+	tooMany := make([]DrawOverride, maxDrawOverrides+1)
+	for i := range tooMany {
+		tooMany[i] = DrawOverride{Type: DrawOverrideBirthday, Action: DrawOverrideExclude}
+	}
+
+	tests := []struct {
+		name      string
+		overrides []DrawOverride
+		wantErr   bool
+	}{
+		{name: "none"},
+		{name: "birthday", overrides: []DrawOverride{{Type: "birthday", Action: "exclude"}}},
+		{name: "single day", overrides: []DrawOverride{{Type: "dates", Action: "force", CandidateID: &id, From: "2025-12-25", To: "2025-12-25"}}},
+		{name: "unknown action", overrides: []DrawOverride{{Type: "birthday", Action: "skip"}}, wantErr: true},
+		{name: "unknown type", overrides: []DrawOverride{{Type: "weekday", Action: "force"}}, wantErr: true},
+		{name: "birthday with dates", overrides: []DrawOverride{{Type: "birthday", Action: "force", From: "2025-12-24"}}, wantErr: true},
+		{name: "dates without candidate", overrides: []DrawOverride{{Type: "dates", Action: "force", From: "2025-12-24", To: "2025-12-26"}}, wantErr: true},
+		{name: "invalid date", overrides: []DrawOverride{{Type: "dates", Action: "force", CandidateID: &id, From: "2025-12-24", To: "2025-12-32"}}, wantErr: true},
+		{name: "to before from", overrides: []DrawOverride{{Type: "dates", Action: "force", CandidateID: &id, From: "2025-12-26", To: "2025-12-24"}}, wantErr: true},
+		{name: "long message", overrides: []DrawOverride{{Type: "birthday", Action: "force", Message: strings.Repeat("a", maxDrawOverrideMessage+1)}}, wantErr: true},
+		{name: "too many", overrides: tooMany, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := EncodeDrawOverrides(tt.overrides)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("EncodeDrawOverrides() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidDrawOverride) {
+				t.Errorf("EncodeDrawOverrides() error = %v, want ErrInvalidDrawOverride", err)
+			}
+		})
+	}
+}
+
+func TestApplyDrawOverrides(t *testing.T) {
+	now := time.Date(2025, 12, 25, 20, 0, 0, 0, time.Local)
+	ann := model.Candidate{ID: uuid.New(), Name: "Ann", Birthday: "2015-12-25"}
+	ben := model.Candidate{ID: uuid.New(), Name: "Ben", Birthday: "2018-12-25"}
+	cat := model.Candidate{ID: uuid.New(), Name: "Cat", Birthday: "2016-03-01"}
+	candidates := []model.Candidate{ann, ben, cat}
+	dates := func(action string, candidate model.Candidate, from, to string) DrawOverride {
+		return DrawOverride{Type: DrawOverrideDates, Action: action, CandidateID: &candidate.ID, From: from, To: to}
+	}
+
+	tests := []struct {
+		name       string
+		overrides  []DrawOverride
+		candidates []model.Candidate // 为空时使用全部候选人
+		want       []string
+		effects    []string // 生效的规则说明，被放宽的规则以 "relaxed: " 开头
+	}{
+		{name: "no overrides", want: []string{"Ann", "Ben", "Cat"}},
+		{
+			name:      "exclude on birthday",
+			overrides: []DrawOverride{{Type: DrawOverrideBirthday, Action: DrawOverrideExclude}},
+			want:      []string{"Cat"},
+			effects:   []string{"Ann is never picked on their birthday", "Ben is never picked on their birthday"},
+		},
+		{
+			name:      "birthday override for one candidate",
+			overrides: []DrawOverride{{Type: DrawOverrideBirthday, Action: DrawOverrideForce, CandidateID: &ben.ID, Message: "Happy birthday"}},
+			want:      []string{"Ben"},
+			effects:   []string{"Happy birthday"},
+		},
+		{
+			name:      "date range includes both ends",
+			overrides: []DrawOverride{dates(DrawOverrideForce, cat, "2025-12-20", "2025-12-25")},
+			want:      []string{"Cat"},
+			effects:   []string{"Cat is always picked from 2025-12-20 to 2025-12-25"},
+		},
+		{
+			name:      "date range not today",
+			overrides: []DrawOverride{dates(DrawOverrideForce, cat, "2025-12-26", "2025-12-26")},
+			want:      []string{"Ann", "Ben", "Cat"},
+		},
+		{
+			name: "force takes precedence over exclude",
+			overrides: []DrawOverride{
+				{Type: DrawOverrideBirthday, Action: DrawOverrideExclude},
+				dates(DrawOverrideForce, ann, "2025-12-25", "2025-12-25"),
+			},
+			want:    []string{"Ann"},
+			effects: []string{"Ann is always picked on 2025-12-25"},
+		},
+		{
+			name: "first matching rule per candidate",
+			overrides: []DrawOverride{
+				{Type: DrawOverrideBirthday, Action: DrawOverrideExclude, Message: "Birthday off"},
+				{Type: DrawOverrideBirthday, Action: DrawOverrideExclude},
+			},
+			want:    []string{"Cat"},
+			effects: []string{"Birthday off", "Birthday off"},
+		},
+		{
+			name:       "excluding everyone is relaxed",
+			overrides:  []DrawOverride{{Type: DrawOverrideBirthday, Action: DrawOverrideExclude}},
+			candidates: []model.Candidate{ann, ben},
+			want:       []string{"Ann", "Ben"},
+			effects:    []string{"relaxed: Ann is never picked on their birthday", "relaxed: Ben is never picked on their birthday"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := EncodeDrawOverrides(tt.overrides)
+			if err != nil {
+				t.Fatalf("EncodeDrawOverrides(): %v", err)
+			}
+			pool := tt.candidates
+			if pool == nil {
+				pool = candidates
+			}
+			got, effects, err := applyDrawOverrides(&model.Project{DrawOverrides: encoded}, pool, now)
+			if err != nil {
+				t.Fatalf("applyDrawOverrides(): %v", err)
+			}
+			var names []string
+			for _, candidate := range got {
+				names = append(names, candidate.Name)
+			}
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("candidates = %v, want %v", names, tt.want)
+			}
+
+			var reasons []string
+			for _, effect := range effects {
+				reason := effect.Reason
+				if effect.Relaxed {
+					reason = "relaxed: " + reason
+				}
+				reasons = append(reasons, reason)
+			}
+			if !slices.Equal(reasons, tt.effects) {
+				t.Errorf("effects = %q, want %q", reasons, tt.effects)
+			}
+		})
+	}
+}
+
+func TestOverrideReason(t *testing.T) {
+	effects := []OverrideEffect{
+		{Reason: "Ann is never picked on their birthday"},
+		{Reason: "Ben is never picked on their birthday", Relaxed: true},
+		{Reason: "Happy holidays"},
+	}
+	if got, want := overrideReason(effects), "Ann is never picked on their birthday; Happy holidays"; got != want {
+		t.Errorf("overrideReason() = %q, want %q", got, want)
+	}
+	if got := overrideReason(nil); got != "" {
+		t.Errorf("overrideReason(nil) = %q, want empty", got)
+	}
+}
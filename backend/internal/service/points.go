@@ -0,0 +1,326 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"whotakesshowers/internal/logger"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// 积分流水备注的最大长度（字符数）
+	maxPointsNoteLength = 200
+	// 单次手动调整的积分上限
+	maxPointsAdjustment = 10000
+	// 项目被选中和按时完成获得的积分上限
+	maxProjectPoints = 1000
+)
+
+// PointsService 积分、奖励兑换与排行榜
+// 候选人的积分余额始终等于其积分流水之和：每次加减积分都在同一个事务中写入一条流水
+type PointsService struct {
+	store store.Store
+}
+
+// NewPointsService 创建积分服务
+func NewPointsService(s store.Store) *PointsService {
+	return &PointsService{store: s}
+}
+
+// RewardInput 创建或修改奖励的内容
+type RewardInput struct {
+	Name string `json:"name" binding:"required,max=100"`
+	Cost int    `json:"cost" binding:"required,min=1,max=100000"`
+}
+
+// ListRewards 获取奖励列表
+func (s *PointsService) ListRewards(userID uuid.UUID) ([]model.Reward, error) {
+	return s.store.Rewards().List(userID)
+}
+
+// CreateReward 创建奖励
+func (s *PointsService) CreateReward(userID uuid.UUID, input RewardInput) (*model.Reward, error) {
+	reward := &model.Reward{
+		UserID: userID,
+		Name:   strings.TrimSpace(input.Name),
+		Cost:   input.Cost,
+	}
+	if err := s.store.Rewards().Create(reward); err != nil {
+		return nil, err
+	}
+	return reward, nil
+}
+
+// UpdateReward 修改奖励，已有的兑换流水不受影响
+func (s *PointsService) UpdateReward(userID, rewardID uuid.UUID, input RewardInput) (*model.Reward, error) {
+	reward, err := s.store.Rewards().Get(rewardID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrRewardNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	reward.Name = strings.TrimSpace(input.Name)
+	reward.Cost = input.Cost
+	if err := s.store.Rewards().Update(reward); err != nil {
+		return nil, err
+	}
+	return reward, nil
+}
+
+// DeleteReward 删除奖励，已有的兑换流水保留
+func (s *PointsService) DeleteReward(userID, rewardID uuid.UUID) error {
+	err := s.store.Rewards().Delete(rewardID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrRewardNotFound
+	}
+	return err
+}
+
+// Redeem 为候选人兑换奖励，扣除奖励的积分；积分不足时返回 ErrInsufficientPoints
+func (s *PointsService) Redeem(userID, rewardID, candidateID uuid.UUID) (*model.PointEntry, error) {
+	var entry *model.PointEntry
+	err := s.store.Transaction(func(tx store.Store) error {
+		reward, err := tx.Rewards().Get(rewardID, userID)
+		if errors.Is(err, store.ErrNotFound) {
+			return ErrRewardNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		balance, err := tx.Candidates().SpendPoints(candidateID, userID, reward.Cost)
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			return ErrCandidateNotFound
+		case errors.Is(err, store.ErrInsufficientPoints):
+			return fmt.Errorf("%w: %s costs %d points", ErrInsufficientPoints, reward.Name, reward.Cost)
+		case err != nil:
+			return err
+		}
+
+		entry = &model.PointEntry{
+			UserID:      userID,
+			CandidateID: candidateID,
+			Kind:        model.PointKindRedeem,
+			Amount:      -reward.Cost,
+			Balance:     balance,
+			RewardID:    &reward.ID,
+			Note:        reward.Name,
+		}
+		return tx.PointEntries().Create(entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Reward redeemed",
+		zap.String("user_id", userID.String()),
+		zap.String("reward_id", rewardID.String()),
+		zap.String("candidate_id", candidateID.String()),
+		zap.Int("balance", entry.Balance),
+	)
+	return entry, nil
+}
+
+// Adjust 家长手动增减候选人的积分；扣除时余额不能变为负数
+func (s *PointsService) Adjust(userID, candidateID uuid.UUID, amount int, note string) (*model.PointEntry, error) {
+	note = strings.TrimSpace(note)
+	if amount == 0 || amount > maxPointsAdjustment || amount < -maxPointsAdjustment {
+		return nil, fmt.Errorf("%w: amount must be a non-zero number between %d and %d",
+			ErrInvalidPointsAdjustment, -maxPointsAdjustment, maxPointsAdjustment)
+	}
+	if utf8.RuneCountInString(note) > maxPointsNoteLength {
+		return nil, fmt.Errorf("%w: note is longer than %d characters", ErrInvalidPointsAdjustment, maxPointsNoteLength)
+	}
+
+	var entry *model.PointEntry
+	err := s.store.Transaction(func(tx store.Store) error {
+		candidates := tx.Candidates()
+		if _, err := candidates.Get(candidateID, userID); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return ErrCandidateNotFound
+			}
+			return err
+		}
+
+		var balance int
+		var err error
+		if amount > 0 {
+			balance, err = candidates.AddPoints(candidateID, userID, amount)
+		} else {
+			balance, err = candidates.SpendPoints(candidateID, userID, -amount)
+		}
+		if errors.Is(err, store.ErrInsufficientPoints) {
+			return fmt.Errorf("%w: cannot deduct %d points", ErrInsufficientPoints, -amount)
+		}
+		if err != nil {
+			return err
+		}
+
+		entry = &model.PointEntry{
+			UserID:      userID,
+			CandidateID: candidateID,
+			Kind:        model.PointKindAdjust,
+			Amount:      amount,
+			Balance:     balance,
+			Note:        note,
+		}
+		return tx.PointEntries().Create(entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Ledger 分页获取积分流水
+func (s *PointsService) Ledger(userID uuid.UUID, filter store.PointEntryFilter, page store.PageRequest) (*store.Page[model.PointEntry], error) {
+	return s.store.PointEntries().Find(userID, filter, page)
+}
+
+// LeaderboardEntry 排行榜中的一名候选人
+type LeaderboardEntry struct {
+	Rank          int       `json:"rank"` // 积分相同的候选人名次相同
+	CandidateID   uuid.UUID `json:"candidate_id"`
+	CandidateName string    `json:"candidate_name"`
+	PhotoURL      string    `json:"photo_url"`
+	Points        int       `json:"points"`   // 当前余额
+	Earned        int       `json:"earned"`   // 累计获得（包括扣回和手动调整）
+	Redeemed      int       `json:"redeemed"` // 累计兑换
+}
+
+// Leaderboard 按积分余额从高到低排列当前的候选人（不包括回收站中的）
+func (s *PointsService) Leaderboard(userID uuid.UUID) ([]LeaderboardEntry, error) {
+	candidates, err := s.store.Candidates().List(userID)
+	if err != nil {
+		return nil, err
+	}
+	totals, err := s.store.PointEntries().Totals(userID)
+	if err != nil {
+		return nil, err
+	}
+	byCandidate := make(map[uuid.UUID]store.PointTotals, len(totals))
+	for _, t := range totals {
+		byCandidate[t.CandidateID] = t
+	}
+
+	board := make([]LeaderboardEntry, len(candidates))
+	for i, candidate := range candidates {
+		t := byCandidate[candidate.ID]
+		board[i] = LeaderboardEntry{
+			CandidateID:   candidate.ID,
+			CandidateName: candidate.Name,
+			PhotoURL:      candidate.PhotoURL,
+			Points:        candidate.Points,
+			Earned:        t.Earned,
+			Redeemed:      t.Redeemed,
+		}
+	}
+	sort.SliceStable(board, func(i, j int) bool {
+		if board[i].Points != board[j].Points {
+			return board[i].Points > board[j].Points
+		}
+		return board[i].CandidateName < board[j].CandidateName
+	})
+	for i := range board {
+		board[i].Rank = i + 1
+		if i > 0 && board[i].Points == board[i-1].Points {
+			board[i].Rank = board[i-1].Rank
+		}
+	}
+	return board, nil
+}
+
+// validateProjectPoints 校验项目的积分设置
+func validateProjectPoints(pickPoints, onTimePoints int) error {
+	if pickPoints < 0 || pickPoints > maxProjectPoints || onTimePoints < 0 || onTimePoints > maxProjectPoints {
+		return fmt.Errorf("pick_points and on_time_points must be between 0 and %d", maxProjectPoints)
+	}
+	return nil
+}
+
+// syncHistoryPoints 使历史记录产生的积分与其当前状态一致
+// 选中获得 PickPoints，按时完成再获得 OnTimePoints；作废的记录不得分。
+// 与已记账的积分比较，差额作为新的流水记入，因此撤销完成、作废和撤销作废都会留下可解释的流水。
+// 扣回不受余额限制：候选人已兑换了这些积分时余额变为负数，流水仍与余额一致
+func syncHistoryPoints(tx store.Store, history *model.History) error {
+	want := map[string]int{model.PointKindPick: 0, model.PointKindOnTime: 0}
+	if history.VoidedAt == nil {
+		want[model.PointKindPick] = history.PickPoints
+		if history.OnTimePoints != 0 && completedOnTime(history) {
+			want[model.PointKindOnTime] = history.OnTimePoints
+		}
+	}
+
+	entries, err := tx.PointEntries().ListByHistory(history.ID)
+	if err != nil {
+		return err
+	}
+	have := make(map[string]int)
+	posted := make(map[string]bool)
+	for _, entry := range entries {
+		have[entry.Kind] += entry.Amount
+		posted[entry.Kind] = true
+	}
+
+	for _, kind := range []string{model.PointKindPick, model.PointKindOnTime} {
+		delta := want[kind] - have[kind]
+		if delta == 0 {
+			continue
+		}
+		balance, err := tx.Candidates().AddPoints(history.CandidateID, history.UserID, delta)
+		if errors.Is(err, store.ErrNotFound) {
+			// 候选人已被彻底删除，其积分流水也已删除
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var note string
+		switch {
+		case delta < 0 && history.VoidedAt != nil:
+			note = "记录作废"
+		case delta < 0:
+			note = "撤销按时完成"
+		case posted[kind]:
+			note = "重新记入"
+		}
+		historyID := history.ID
+		entry := &model.PointEntry{
+			UserID:      history.UserID,
+			CandidateID: history.CandidateID,
+			Kind:        kind,
+			Amount:      delta,
+			Balance:     balance,
+			HistoryID:   &historyID,
+			Note:        note,
+		}
+		if err := tx.PointEntries().Create(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// completedOnTime 任务是否已按时完成：从开始（没有开始时间时为选中）到完成不超过选中时项目的目标时长；
+// 选中时项目没有设置目标时长时，完成即算按时
+func completedOnTime(history *model.History) bool {
+	if history.Status != model.HistoryStatusCompleted || history.CompletedAt == nil {
+		return false
+	}
+	if history.TargetMinutes == nil || *history.TargetMinutes <= 0 {
+		return true
+	}
+	target := time.Duration(*history.TargetMinutes) * time.Minute
+	return history.CompletedAt.Sub(taskStartedAt(history)) <= target
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"testing"
+	"time"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store/memory"
+)
+
+func TestCompletedOnTime(t *testing.T) {
+	selected := time.Date(2025, 3, 10, 20, 0, 0, 0, time.Local)
+	minutes := func(n int) *int { return &n }
+	at := func(d time.Duration) *time.Time {
+		t := selected.Add(d)
+		return &t
+	}
+
+	tests := []struct {
+		name      string
+		status    string
+		target    *int
+		started   *time.Time
+		completed *time.Time
+		want      bool
+	}{
+		{name: "within target", status: model.HistoryStatusCompleted, target: minutes(15), completed: at(15 * time.Minute), want: true},
+		{name: "over target", status: model.HistoryStatusCompleted, target: minutes(15), completed: at(16 * time.Minute), want: false},
+		{name: "measured from start", status: model.HistoryStatusCompleted, target: minutes(15), started: at(time.Hour), completed: at(70 * time.Minute), want: true},
+		{name: "no target", status: model.HistoryStatusCompleted, completed: at(5 * time.Hour), want: true},
+		{name: "zero target", status: model.HistoryStatusCompleted, target: minutes(0), completed: at(5 * time.Hour), want: true},
+		{name: "not completed", status: model.HistoryStatusStarted, target: minutes(15), started: at(0), want: false},
+		{name: "skipped", status: model.HistoryStatusSkipped, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history := &model.History{
+				SelectedAt: selected, Status: tt.status, TargetMinutes: tt.target,
+				StartedAt: tt.started, CompletedAt: tt.completed,
+			}
+			if got := completedOnTime(history); got != tt.want {
+				t.Errorf("completedOnTime = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSyncHistoryPoints(t *testing.T) {
+	selected := time.Now().Add(-time.Hour)
+	completed := selected.Add(10 * time.Minute)
+	target := 15
+
+	tests := []struct {
+		name        string
+		late        bool // 超过目标时长才完成
+		redeem      int  // 记入积分后兑换掉的积分
+		changeGoal  bool // 完成后把项目的目标时长改为 5 分钟
+		trash       bool // 完成后把项目移入回收站
+		void        bool
+		wantBalance int
+		wantEntries int
+	}{
+		{name: "pick and on time", wantBalance: 8, wantEntries: 2},
+		{name: "project target changed later", changeGoal: true, wantBalance: 8, wantEntries: 2},
+		{name: "late", late: true, wantBalance: 5, wantEntries: 1},
+		{name: "late with the project in the trash", late: true, trash: true, wantBalance: 5, wantEntries: 1},
+		{name: "void", void: true, wantBalance: 0, wantEntries: 4},
+		{name: "void after redeeming", redeem: 8, void: true, wantBalance: -8, wantEntries: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := memory.New()
+			user := &model.User{Username: "alice", Email: "alice@example.com", Password: "x"}
+			if err := s.Users().Create(user); err != nil {
+				t.Fatal(err)
+			}
+			project := &model.Project{Name: "Shower", UserID: user.ID, CandidateIDs: "[]", TargetMinutes: &target, PickPoints: 5, OnTimePoints: 3}
+			if err := s.Projects().Create(project); err != nil {
+				t.Fatal(err)
+			}
+			candidate := &model.Candidate{Name: "Ann", UserID: user.ID}
+			if err := s.Candidates().Create(candidate); err != nil {
+				t.Fatal(err)
+			}
+
+			history := drawHistory(project, candidate, nil, selected)
+			if err := s.Histories().Create(history); err != nil {
+				t.Fatal(err)
+			}
+			done := completed
+			if tt.late {
+				done = selected.Add(20 * time.Minute)
+			}
+			history.Status, history.CompletedAt = model.HistoryStatusCompleted, &done
+			if err := syncHistoryPoints(s, history); err != nil {
+				t.Fatal(err)
+			}
+			if tt.redeem > 0 {
+				if _, err := s.Candidates().SpendPoints(candidate.ID, user.ID, tt.redeem); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.changeGoal {
+				shorter := 5
+				project.TargetMinutes = &shorter
+				if err := s.Projects().Update(project); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.trash {
+				if err := s.Projects().Delete(project.ID, user.ID); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.void {
+				now := time.Now()
+				history.VoidedAt = &now
+			}
+			// 再次同步：只有作废会改变应得的积分
+			if err := syncHistoryPoints(s, history); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := s.Candidates().Get(candidate.ID, user.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Points != tt.wantBalance {
+				t.Errorf("balance = %d, want %d", got.Points, tt.wantBalance)
+			}
+			entries, err := s.PointEntries().ListByHistory(history.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != tt.wantEntries {
+				t.Errorf("ledger entries = %d, want %d", len(entries), tt.wantEntries)
+			}
+			if len(entries) > 0 && entries[len(entries)-1].Balance != tt.wantBalance {
+				t.Errorf("last entry balance = %d, want %d", entries[len(entries)-1].Balance, tt.wantBalance)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"errors"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// ProjectService 项目服务
+type ProjectService struct {
+	projects store.ProjectRepository
+}
+
+// NewProjectService 创建项目服务
+func NewProjectService(projects store.ProjectRepository) *ProjectService {
+	return &ProjectService{projects: projects}
+}
+
+// Delete 将项目移入回收站；历史记录保留，彻底删除项目时再一并删除
+func (s *ProjectService) Delete(projectID, userID uuid.UUID) error {
+	if _, err := s.projects.Get(projectID, userID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return ErrProjectNotFound
+		}
+		return err
+	}
+	return s.projects.Delete(projectID, userID)
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// 降低权重时的默认权重
+const defaultConstraintWeight = 0.5
+
+// ProjectConstraintService 项目间约束
+// 随机选择时，今天（本地时区）在来源项目中被选中且未作废的候选人，在目标项目中被排除或降低权重
+type ProjectConstraintService struct {
+	store store.Store
+}
+
+// NewProjectConstraintService 创建项目间约束服务
+func NewProjectConstraintService(s store.Store) *ProjectConstraintService {
+	return &ProjectConstraintService{store: s}
+}
+
+// ProjectConstraintInput 创建或修改项目间约束的内容
+type ProjectConstraintInput struct {
+	SourceProjectID uuid.UUID `json:"source_project_id" binding:"required"`
+	TargetProjectID uuid.UUID `json:"target_project_id" binding:"required"`
+	Mode            string    `json:"mode" binding:"required"`
+	Weight          *float64  `json:"weight"` // 仅用于 down_weight，默认 0.5
+}
+
+// ConstraintEffect 一条约束对本次随机选择的影响
+type ConstraintEffect struct {
+	ConstraintID      uuid.UUID   `json:"constraint_id"`
+	SourceProjectID   uuid.UUID   `json:"source_project_id"`
+	SourceProjectName string      `json:"source_project_name"`
+	Mode              string      `json:"mode"`
+	Weight            float64     `json:"weight"`
+	CandidateIDs      []uuid.UUID `json:"candidate_ids"`   // 受影响的候选人
+	CandidateNames    []string    `json:"candidate_names"` // 受影响的候选人名称
+	Relaxed           bool        `json:"relaxed"`         // 排除了所有候选人，本次未生效
+}
+
+// List 获取项目间约束
+func (s *ProjectConstraintService) List(userID uuid.UUID) ([]model.ProjectConstraint, error) {
+	return s.store.ProjectConstraints().List(userID)
+}
+
+// Create 创建项目间约束
+func (s *ProjectConstraintService) Create(userID uuid.UUID, input ProjectConstraintInput) (*model.ProjectConstraint, error) {
+	constraint := &model.ProjectConstraint{UserID: userID}
+	err := s.store.Transaction(func(tx store.Store) error {
+		if err := applyConstraintInput(tx, constraint, input); err != nil {
+			return err
+		}
+		return tx.ProjectConstraints().Create(constraint)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return constraint, nil
+}
+
+// Update 修改项目间约束
+func (s *ProjectConstraintService) Update(userID, constraintID uuid.UUID, input ProjectConstraintInput) (*model.ProjectConstraint, error) {
+	var constraint *model.ProjectConstraint
+	err := s.store.Transaction(func(tx store.Store) error {
+		var err error
+		constraint, err = tx.ProjectConstraints().Get(constraintID, userID)
+		if errors.Is(err, store.ErrNotFound) {
+			return ErrProjectConstraintNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := applyConstraintInput(tx, constraint, input); err != nil {
+			return err
+		}
+		return tx.ProjectConstraints().Update(constraint)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return constraint, nil
+}
+
+// Delete 删除项目间约束
+func (s *ProjectConstraintService) Delete(userID, constraintID uuid.UUID) error {
+	err := s.store.ProjectConstraints().Delete(constraintID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrProjectConstraintNotFound
+	}
+	return err
+}
+
+// applyConstraintInput 校验并写入约束的内容：两个项目必须不同且存在，同一对项目只能有一条约束
+func applyConstraintInput(tx store.Store, constraint *model.ProjectConstraint, input ProjectConstraintInput) error {
+	if input.SourceProjectID == input.TargetProjectID {
+		return fmt.Errorf("%w: source and target must be different projects", ErrInvalidProjectConstraint)
+	}
+	weight := 0.0
+	switch input.Mode {
+	case model.ConstraintModeExclude:
+		if input.Weight != nil {
+			return fmt.Errorf("%w: weight only applies to %s", ErrInvalidProjectConstraint, model.ConstraintModeDownWeight)
+		}
+	case model.ConstraintModeDownWeight:
+		weight = defaultConstraintWeight
+		if input.Weight != nil {
+			weight = *input.Weight
+		}
+		if weight <= 0 || weight >= 1 {
+			return fmt.Errorf("%w: weight must be greater than 0 and less than 1", ErrInvalidProjectConstraint)
+		}
+	default:
+		return fmt.Errorf("%w: unknown mode %q", ErrInvalidProjectConstraint, input.Mode)
+	}
+
+	for _, projectID := range []uuid.UUID{input.SourceProjectID, input.TargetProjectID} {
+		if _, err := tx.Projects().Get(projectID, constraint.UserID); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return ErrProjectNotFound
+			}
+			return err
+		}
+	}
+
+	existing, err := tx.ProjectConstraints().ListByTarget(input.TargetProjectID, constraint.UserID)
+	if err != nil {
+		return err
+	}
+	for _, other := range existing {
+		if other.SourceProjectID == input.SourceProjectID && other.ID != constraint.ID {
+			return ErrProjectConstraintExists
+		}
+	}
+
+	constraint.SourceProjectID = input.SourceProjectID
+	constraint.TargetProjectID = input.TargetProjectID
+	constraint.Mode = input.Mode
+	constraint.Weight = weight
+	return nil
+}
+
+// constraintWeights 按作用于 project 的约束计算候选人的权重（与 candidates 一一对应），并返回生效的约束
+// 排除约束使所有候选人的权重都为 0 时，排除约束本次不生效，在结果中标记为 Relaxed
+func constraintWeights(s store.Store, project *model.Project, candidates []model.Candidate, now time.Time) ([]float64, []ConstraintEffect, error) {
+	constraints, err := s.ProjectConstraints().ListByTarget(project.ID, project.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	from := bucketStart(now, BucketDay)
+	voided := false
+	effects := make([]ConstraintEffect, 0, len(constraints))
+	affected := make([]map[uuid.UUID]bool, 0, len(constraints))
+	for _, constraint := range constraints {
+		source, err := s.Projects().Get(constraint.SourceProjectID, project.UserID)
+		if errors.Is(err, store.ErrNotFound) {
+			continue // 来源项目在回收站中
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		picked, err := pickedCandidates(s, project.UserID, store.HistoryFilter{
+			ProjectID: &source.ID,
+			From:      &from,
+			Voided:    &voided,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		effect := ConstraintEffect{
+			ConstraintID:      constraint.ID,
+			SourceProjectID:   source.ID,
+			SourceProjectName: source.Name,
+			Mode:              constraint.Mode,
+			Weight:            constraint.Weight,
+		}
+		for _, candidate := range candidates {
+			if picked[candidate.ID] {
+				effect.CandidateIDs = append(effect.CandidateIDs, candidate.ID)
+				effect.CandidateNames = append(effect.CandidateNames, candidate.Name)
+			}
+		}
+		if len(effect.CandidateIDs) > 0 {
+			effects = append(effects, effect)
+			affected = append(affected, picked)
+		}
+	}
+
+	weigh := func(relaxExclusions bool) ([]float64, float64) {
+		weights := make([]float64, len(candidates))
+		total := 0.0
+		for i, candidate := range candidates {
+			weights[i] = 1
+			for j, effect := range effects {
+				if !affected[j][candidate.ID] || (relaxExclusions && effect.Mode == model.ConstraintModeExclude) {
+					continue
+				}
+				weights[i] *= effect.Weight
+			}
+			total += weights[i]
+		}
+		return weights, total
+	}
+	weights, total := weigh(false)
+	if total == 0 {
+		weights, _ = weigh(true)
+		for i := range effects {
+			if effects[i].Mode == model.ConstraintModeExclude {
+				effects[i].Relaxed = true
+			}
+		}
+	}
+	return weights, effects, nil
+}
+
+// weightedPick 按权重随机选择一个候选人；所有权重都为 0 时等概率选择
+func weightedPick(candidates []model.Candidate, weights []float64) model.Candidate {
+	total := 0.0
+	for _, weight := range weights {
+		total += weight
+	}
+	if total <= 0 {
+		return candidates[rand.Intn(len(candidates))]
+	}
+	r := rand.Float64() * total
+	for i, weight := range weights {
+		if r < weight {
+			return candidates[i]
+		}
+		r -= weight
+	}
+	// 浮点误差时返回最后一个权重大于 0 的候选人
+	for i := len(weights) - 1; i >= 0; i-- {
+		if weights[i] > 0 {
+			return candidates[i]
+		}
+	}
+	return candidates[len(candidates)-1]
+}
+
+// pickedCandidates 符合过滤条件的历史记录中被选中过的候选人，逐页读取全部记录；分组不是随机选中，不计入
+func pickedCandidates(s store.Store, userID uuid.UUID, filter store.HistoryFilter) (map[uuid.UUID]bool, error) {
+	picked := make(map[uuid.UUID]bool)
+	page := store.PageRequest{Limit: store.MaxPageLimit}
+	for {
+		result, err := s.Histories().Find(userID, filter, page)
+		if err != nil {
+			return nil, err
+		}
+		for _, history := range result.Items {
+			if history.Source != model.HistorySourceSplit {
+				picked[history.CandidateID] = true
+			}
+		}
+		if result.NextCursor == "" {
+			return picked, nil
+		}
+		page.Cursor = result.NextCursor
+	}
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"testing"
+	"time"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store/memory"
+
+	"github.com/google/uuid"
+)
+
+func TestConstraintWeights(t *testing.T) {
+	now := time.Date(2025, 3, 10, 20, 0, 0, 0, time.Local)
+	ann := model.Candidate{ID: uuid.New(), Name: "Ann"}
+	ben := model.Candidate{ID: uuid.New(), Name: "Ben"}
+	cid := model.Candidate{ID: uuid.New(), Name: "Cid"}
+
+	tests := []struct {
+		name        string
+		mode        string
+		weight      float64
+		picked      []model.Candidate // 今天在来源项目中依次被选中的候选人
+		extraPicks  int               // 再选中 Cid 的次数，使 picked 落到后面的页
+		yesterday   bool              // picked 在昨天被选中
+		source      string
+		want        []float64
+		wantRelaxed bool
+	}{
+		{name: "exclude", mode: model.ConstraintModeExclude, picked: []model.Candidate{ann}, want: []float64{0, 1}},
+		{name: "down weight", mode: model.ConstraintModeDownWeight, weight: 0.25, picked: []model.Candidate{ben}, want: []float64{1, 0.25}},
+		{name: "beyond the first page", mode: model.ConstraintModeExclude, picked: []model.Candidate{ann}, extraPicks: 150, want: []float64{0, 1}},
+		{name: "yesterday", mode: model.ConstraintModeExclude, picked: []model.Candidate{ann}, yesterday: true, want: []float64{1, 1}},
+		{name: "split", mode: model.ConstraintModeExclude, picked: []model.Candidate{ann}, source: model.HistorySourceSplit, want: []float64{1, 1}},
+		{name: "everyone excluded", mode: model.ConstraintModeExclude, picked: []model.Candidate{ann, ben}, want: []float64{1, 1}, wantRelaxed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := memory.New()
+			user := &model.User{Username: "alice", Email: "alice@example.com", Password: "x"}
+			if err := s.Users().Create(user); err != nil {
+				t.Fatal(err)
+			}
+			source := &model.Project{Name: "Dishes", UserID: user.ID, CandidateIDs: "[]"}
+			target := &model.Project{Name: "Shower", UserID: user.ID, CandidateIDs: "[]"}
+			for _, project := range []*model.Project{source, target} {
+				if err := s.Projects().Create(project); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := s.ProjectConstraints().Create(&model.ProjectConstraint{
+				UserID: user.ID, SourceProjectID: source.ID, TargetProjectID: target.ID, Mode: tt.mode, Weight: tt.weight,
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			selectedAt := now.Add(-time.Hour)
+			if tt.yesterday {
+				selectedAt = now.Add(-24 * time.Hour)
+			}
+			pick := func(candidate model.Candidate, at time.Time) {
+				if err := s.Histories().Create(&model.History{
+					ProjectID: source.ID, ProjectName: source.Name, CandidateID: candidate.ID, CandidateName: candidate.Name,
+					SelectedAt: at, UserID: user.ID, Source: tt.source,
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, candidate := range tt.picked {
+				pick(candidate, selectedAt)
+			}
+			for i := 0; i < tt.extraPicks; i++ {
+				pick(cid, selectedAt.Add(time.Duration(i+1)*time.Second))
+			}
+
+			weights, effects, err := constraintWeights(s, target, []model.Candidate{ann, ben}, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, want := range tt.want {
+				if weights[i] != want {
+					t.Errorf("weights = %v, want %v", weights, tt.want)
+					break
+				}
+			}
+			relaxed := len(effects) > 0 && effects[0].Relaxed
+			if relaxed != tt.wantRelaxed {
+				t.Errorf("relaxed = %v, want %v (effects %+v)", relaxed, tt.wantRelaxed, effects)
+			}
+		})
+	}
+}
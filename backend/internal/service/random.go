@@ -2,18 +2,25 @@ package service
 
 import (
 	"encoding/json"
-	"math/rand"
+	"errors"
 	"time"
+	"whotakesshowers/internal/logger"
 	"whotakesshowers/internal/model"
 	"whotakesshowers/internal/store"
 
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 )
 
 // RandomizeService 随机选择服务
-type RandomizeService struct{}
+type RandomizeService struct {
+	store store.Store
+}
 
-var Randomizer = &RandomizeService{}
+// NewRandomizeService 创建随机选择服务
+func NewRandomizeService(s store.Store) *RandomizeService {
+	return &RandomizeService{store: s}
+}
 
 // RandomizeRequest 随机选择请求
 type RandomizeRequest struct {
@@ -22,61 +29,138 @@ type RandomizeRequest struct {
 
 // RandomizeResponse 随机选择响应
 type RandomizeResponse struct {
-	CandidateID   uuid.UUID `json:"candidate_id"`
-	CandidateName string    `json:"candidate_name"`
+	HistoryID     *uuid.UUID         `json:"history_id"` // 本次选择的历史记录，记录失败时为 null
+	CandidateID   uuid.UUID          `json:"candidate_id"`
+	CandidateName string             `json:"candidate_name"`
+	Constraints   []ConstraintEffect `json:"constraints"` // 影响了本次选择的项目间约束
+	Ineligible    []Ineligible       `json:"ineligible"`  // 不符合项目资格规则而未参与的候选人
+	Overrides     []OverrideEffect   `json:"overrides"`   // 今天生效的特殊日规则，Reason 可以直接展示给用户
 }
 
-// Execute 执行随机选择
+// Execute 执行随机选择：只在符合项目资格规则的候选人中选择，先按项目的特殊日规则一定选择或排除候选人，
+// 再按项目间约束排除今天已在其他项目中被选中的候选人或降低其权重
+// 项目没有候选人时返回 nil；候选人都不符合资格规则时返回 *NoEligibleCandidatesError
 func (s *RandomizeService) Execute(req *RandomizeRequest, userID uuid.UUID) (*RandomizeResponse, error) {
 	// 获取项目
-	project, err := store.Projects.Get(req.ProjectID, userID)
+	project, err := s.store.Projects().Get(req.ProjectID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrProjectNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	// 获取项目内的候选人ID列表
-	var candidateIDs []uuid.UUID
-	if project.CandidateIDs != "" {
-		if err := json.Unmarshal([]byte(project.CandidateIDs), &candidateIDs); err != nil {
-			return nil, err
-		}
+	// 获取项目内符合资格规则的候选人列表
+	now := time.Now()
+	candidates, ineligible, err := drawCandidates(s.store, project, now)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(candidateIDs) == 0 {
+	if len(candidates) == 0 {
+		if len(ineligible) > 0 {
+			return nil, &NoEligibleCandidatesError{Ineligible: ineligible}
+		}
 		return nil, nil
 	}
 
-	// 获取项目内的候选人列表
-	candidates, err := store.Candidates.GetByIDs(candidateIDs, userID)
+	// 按特殊日规则调整候选人，再按项目间约束加权随机选择一个候选人
+	pool := poolIDs(candidates, ineligible)
+	candidates, overrides, err := applyDrawOverrides(project, candidates, now)
 	if err != nil {
 		return nil, err
 	}
-
-	if len(candidates) == 0 {
-		return nil, nil
+	weights, effects, err := constraintWeights(s.store, project, candidates, now)
+	if err != nil {
+		return nil, err
 	}
-
-	// 随机选择一个候选人
-	rand.Seed(time.Now().UnixNano())
-	selectedIndex := rand.Intn(len(candidates))
-	selected := candidates[selectedIndex]
+	selected := weightedPick(candidates, weights)
 
 	// 记录历史
-	history := &model.History{
-		ProjectID:     project.ID,
-		ProjectName:   project.Name,
-		CandidateID:   selected.ID,
-		CandidateName: selected.Name,
-		SelectedAt:    time.Now(),
-		UserID:        userID,
-	}
-	if err := store.Histories.Create(history); err != nil {
+	history := drawHistory(project, &selected, pool, now)
+	history.OverrideReason = overrideReason(overrides)
+	history.Overridden = history.OverrideReason != ""
+	err = s.store.Transaction(func(tx store.Store) error {
+		return recordDraw(tx, history)
+	})
+	var historyID *uuid.UUID
+	if err != nil {
 		// 记录失败不影响主流程
-		// log.Printf("Warning: failed to create history: %v", err)
+		logger.Warn("Failed to record history", zap.String("project_id", project.ID.String()), zap.Error(err))
+	} else {
+		historyID = &history.ID
 	}
 
 	return &RandomizeResponse{
+		HistoryID:     historyID,
 		CandidateID:   selected.ID,
 		CandidateName: selected.Name,
+		Constraints:   effects,
+		Ineligible:    ineligible,
+		Overrides:     overrides,
 	}, nil
 }
+
+// projectCandidates 获取项目内当前的候选人（不包括回收站中的）
+// 项目设置了标签查询时为当前满足查询的候选人，否则为手动维护的候选人列表
+func projectCandidates(s store.Store, project *model.Project) ([]model.Candidate, error) {
+	if project.TagQuery != "" {
+		candidates, err := s.Candidates().List(project.UserID)
+		if err != nil {
+			return nil, err
+		}
+		return tagMembers(project.TagQuery, candidates)
+	}
+	var candidateIDs []uuid.UUID
+	if project.CandidateIDs != "" {
+		if err := json.Unmarshal([]byte(project.CandidateIDs), &candidateIDs); err != nil {
+			return nil, err
+		}
+	}
+	if len(candidateIDs) == 0 {
+		return nil, nil
+	}
+	return s.Candidates().GetByIDs(candidateIDs, project.UserID)
+}
+
+// drawHistory 创建选中 candidate 的历史记录，保存项目当前的积分设置、目标时长和候选人池
+func drawHistory(project *model.Project, candidate *model.Candidate, pool []uuid.UUID, at time.Time) *model.History {
+	data, _ := json.Marshal(pool)
+	return &model.History{
+		ProjectID:     project.ID,
+		ProjectName:   project.Name,
+		CandidateID:   candidate.ID,
+		CandidateName: candidate.Name,
+		SelectedAt:    at,
+		UserID:        project.UserID,
+		PickPoints:    project.PickPoints,
+		OnTimePoints:  project.OnTimePoints,
+		TargetMinutes: project.TargetMinutes,
+
+		PoolCandidateIDs: string(data),
+		TagQuery:         project.TagQuery,
+	}
+}
+
+// poolIDs 选择时项目的全部候选人，包括不符合资格规则的
+func poolIDs(eligible []model.Candidate, ineligible []Ineligible) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(eligible)+len(ineligible))
+	for _, candidate := range eligible {
+		ids = append(ids, candidate.ID)
+	}
+	for _, item := range ineligible {
+		ids = append(ids, item.CandidateID)
+	}
+	return ids
+}
+
+// recordDraw 在事务中保存随机选择的历史记录，为被选中的候选人记入积分并评估徽章
+func recordDraw(tx store.Store, history *model.History) error {
+	if err := tx.Histories().Create(history); err != nil {
+		return err
+	}
+	if err := syncHistoryPoints(tx, history); err != nil {
+		return err
+	}
+	return awardCandidateBadges(tx, history.UserID, history.CandidateID)
+}
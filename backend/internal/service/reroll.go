@@ -0,0 +1,222 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"whotakesshowers/internal/logger"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// rerollVoidReason 被重新选择取代的记录的作废原因
+const rerollVoidReason = "re-rolled"
+
+// maxRerollTokens 项目每周重新选择次数的上限，与项目接口的校验一致
+const maxRerollTokens = 10
+
+// RerollBalance 候选人本周在项目中的重新选择次数
+type RerollBalance struct {
+	CandidateID   uuid.UUID `json:"candidate_id"`
+	CandidateName string    `json:"candidate_name"`
+	Used          int       `json:"used"`
+	Remaining     int       `json:"remaining"`
+}
+
+// RerollBalances 项目本周的重新选择次数，每周一 0 点（本地时区）重置
+type RerollBalances struct {
+	ProjectID     uuid.UUID       `json:"project_id"`
+	TokensPerWeek int             `json:"tokens_per_week"`
+	WeekStart     time.Time       `json:"week_start"`
+	ResetsAt      time.Time       `json:"resets_at"`
+	Balances      []RerollBalance `json:"balances"`
+}
+
+// RerollResponse 重新选择的结果
+type RerollResponse struct {
+	RandomizeResponse
+	History    *model.History `json:"history"`    // 新的记录
+	Superseded *model.History `json:"superseded"` // 被取代并作废的原记录
+	Remaining  int            `json:"remaining"`  // 原候选人本周剩余的重新选择次数
+}
+
+// rerollUsage 返回项目中各候选人本周已使用的重新选择次数，以及本周的起止时间
+func rerollUsage(s store.Store, projectID uuid.UUID, now time.Time) (map[uuid.UUID]int, time.Time, time.Time, error) {
+	weekStart := bucketStart(now, BucketWeek)
+	counts, err := s.Histories().CountSuperseded(projectID, weekStart)
+	if err != nil {
+		return nil, time.Time{}, time.Time{}, err
+	}
+	used := make(map[uuid.UUID]int, len(counts))
+	for _, count := range counts {
+		used[count.CandidateID] = count.Count
+	}
+	return used, weekStart, nextBucket(weekStart, BucketWeek), nil
+}
+
+// RerollBalances 列出项目当前的候选人本周已使用和剩余的重新选择次数
+func (s *RandomizeService) RerollBalances(userID, projectID uuid.UUID) (*RerollBalances, error) {
+	project, err := s.store.Projects().Get(projectID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrProjectNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	candidates, err := projectCandidates(s.store, project)
+	if err != nil {
+		return nil, err
+	}
+	used, weekStart, resetsAt, err := rerollUsage(s.store, project.ID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RerollBalances{
+		ProjectID:     project.ID,
+		TokensPerWeek: project.RerollTokens,
+		WeekStart:     weekStart,
+		ResetsAt:      resetsAt,
+		Balances:      make([]RerollBalance, 0, len(candidates)),
+	}
+	for _, candidate := range candidates {
+		result.Balances = append(result.Balances, RerollBalance{
+			CandidateID:   candidate.ID,
+			CandidateName: candidate.Name,
+			Used:          used[candidate.ID],
+			Remaining:     max(project.RerollTokens-used[candidate.ID], 0),
+		})
+	}
+	return result, nil
+}
+
+// Reroll 被选中的候选人使用一次重新选择：在排除他（以及同一串重新选择中之前被取代的候选人）后重新选择，
+// 原记录作废并指向新的记录。只有随机选择产生、尚未开始的记录可以重新选择
+func (s *RandomizeService) Reroll(userID, historyID uuid.UUID) (*RerollResponse, error) {
+	var result *RerollResponse
+	err := s.store.Transaction(func(tx store.Store) error {
+		original, err := tx.Histories().Get(historyID, userID)
+		if errors.Is(err, store.ErrNotFound) {
+			return ErrHistoryNotFound
+		}
+		if err != nil {
+			return err
+		}
+		switch {
+		case original.VoidedAt != nil:
+			return fmt.Errorf("%w: the entry is voided", ErrHistoryNotRerollable)
+		case original.Source != model.HistorySourceDraw:
+			return fmt.Errorf("%w: the entry was not drawn", ErrHistoryNotRerollable)
+		case original.RoutineRunID != nil:
+			return fmt.Errorf("%w: the entry belongs to a routine run", ErrHistoryNotRerollable)
+		case original.Status != model.HistoryStatusPicked:
+			return fmt.Errorf("%w: the task is already %s", ErrHistoryNotRerollable, original.Status)
+		}
+
+		project, err := tx.Projects().Get(original.ProjectID, userID)
+		if errors.Is(err, store.ErrNotFound) {
+			return ErrProjectNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if project.RerollTokens == 0 {
+			return fmt.Errorf("%w: the project does not allow re-rolls", ErrHistoryNotRerollable)
+		}
+		now := time.Now()
+		used, _, _, err := rerollUsage(tx, project.ID, now)
+		if err != nil {
+			return err
+		}
+		if used[original.CandidateID] >= project.RerollTokens {
+			return fmt.Errorf("%w: %s has used all %d re-rolls this week", ErrNoRerollTokens, original.CandidateName, project.RerollTokens)
+		}
+
+		// 排除这一串重新选择中所有被取代的候选人
+		excluded := map[uuid.UUID]bool{original.CandidateID: true}
+		for previous := original.RerollOfID; previous != nil; {
+			history, err := tx.Histories().Get(*previous, userID)
+			if errors.Is(err, store.ErrNotFound) {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			excluded[history.CandidateID] = true
+			previous = history.RerollOfID
+		}
+
+		candidates, ineligible, err := drawCandidates(tx, project, now)
+		if err != nil {
+			return err
+		}
+		pool := poolIDs(candidates, ineligible)
+		remaining := make([]model.Candidate, 0, len(candidates))
+		for _, candidate := range candidates {
+			if !excluded[candidate.ID] {
+				remaining = append(remaining, candidate)
+			}
+		}
+		if len(remaining) == 0 {
+			return fmt.Errorf("%w: no other eligible candidates", ErrHistoryNotRerollable)
+		}
+
+		remaining, overrides, err := applyDrawOverrides(project, remaining, now)
+		if err != nil {
+			return err
+		}
+		weights, effects, err := constraintWeights(tx, project, remaining, now)
+		if err != nil {
+			return err
+		}
+		selected := weightedPick(remaining, weights)
+
+		history := drawHistory(project, &selected, pool, now)
+		history.ID = uuid.New()
+		history.RerollOfID = &original.ID
+		history.OverrideReason = overrideReason(overrides)
+		history.Overridden = history.OverrideReason != ""
+		if err := recordDraw(tx, history); err != nil {
+			return err
+		}
+
+		superseded, err := modifyHistory(tx, userID, original.ID, func(h *model.History, at time.Time) error {
+			h.VoidedAt = &at
+			h.VoidReason = rerollVoidReason
+			h.SupersededByID = &history.ID
+			h.SupersededAt = &at
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		result = &RerollResponse{
+			RandomizeResponse: RandomizeResponse{
+				HistoryID:     &history.ID,
+				CandidateID:   selected.ID,
+				CandidateName: selected.Name,
+				Constraints:   effects,
+				Ineligible:    ineligible,
+				Overrides:     overrides,
+			},
+			History:    history,
+			Superseded: superseded,
+			Remaining:  project.RerollTokens - used[original.CandidateID] - 1,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("History entry re-rolled",
+		zap.String("user_id", userID.String()),
+		zap.String("history_id", historyID.String()),
+		zap.String("reroll_id", result.History.ID.String()),
+	)
+	return result, nil
+}
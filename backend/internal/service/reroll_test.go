@@ -0,0 +1,209 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+	"whotakesshowers/internal/store/memory"
+
+	"github.com/google/uuid"
+)
+
+// rerollFixture 一个项目和若干候选人，用于测试重新选择
+type rerollFixture struct {
+	store      store.Store
+	user       *model.User
+	project    *model.Project
+	candidates []*model.Candidate
+}
+
+func newRerollFixture(t *testing.T, tokens int, names ...string) *rerollFixture {
+	t.Helper()
+	s := memory.New()
+	user := &model.User{Username: "alice", Email: "alice@example.com", Password: "x"}
+	if err := s.Users().Create(user); err != nil {
+		t.Fatal(err)
+	}
+	f := &rerollFixture{store: s, user: user}
+	var ids []uuid.UUID
+	for _, name := range names {
+		candidate := &model.Candidate{Name: name, UserID: user.ID}
+		if err := s.Candidates().Create(candidate); err != nil {
+			t.Fatal(err)
+		}
+		f.candidates = append(f.candidates, candidate)
+		ids = append(ids, candidate.ID)
+	}
+	data, err := json.Marshal(ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.project = &model.Project{Name: "Shower", UserID: user.ID, CandidateIDs: string(data), RerollTokens: tokens}
+	if err := s.Projects().Create(f.project); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// draw 记录一次选中 candidate 的随机选择
+func (f *rerollFixture) draw(t *testing.T, candidate *model.Candidate) *model.History {
+	t.Helper()
+	history := drawHistory(f.project, candidate, nil, time.Now())
+	history.Source = model.HistorySourceDraw
+	if err := f.store.Histories().Create(history); err != nil {
+		t.Fatal(err)
+	}
+	return history
+}
+
+// superseded 记录一条在 at 被重新选择取代的 candidate 的记录
+func (f *rerollFixture) superseded(t *testing.T, candidate *model.Candidate, at time.Time) {
+	t.Helper()
+	history := f.draw(t, candidate)
+	replacement := uuid.New()
+	history.VoidedAt, history.VoidReason = &at, rerollVoidReason
+	history.SupersededByID, history.SupersededAt = &replacement, &at
+	if err := f.store.Histories().Update(history); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRerollUsage(t *testing.T) {
+	// 2025-03-12 是周三，本周从 2025-03-10 开始
+	now := time.Date(2025, 3, 12, 20, 0, 0, 0, time.Local)
+	weekStart := time.Date(2025, 3, 10, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name string
+		at   []time.Time // Ann 的记录被取代的时间
+		want int
+	}{
+		{name: "none", want: 0},
+		{name: "this week", at: []time.Time{now.Add(-time.Hour), weekStart.Add(time.Hour)}, want: 2},
+		{name: "at the reset", at: []time.Time{weekStart}, want: 1},
+		{name: "last week resets", at: []time.Time{weekStart.Add(-time.Second), weekStart.AddDate(0, 0, -3)}, want: 0},
+		{name: "mixed", at: []time.Time{weekStart.Add(-time.Hour), now}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newRerollFixture(t, 2, "Ann", "Ben")
+			for _, at := range tt.at {
+				f.superseded(t, f.candidates[0], at)
+			}
+			f.superseded(t, f.candidates[1], now)
+
+			used, start, resetsAt, err := rerollUsage(f.store, f.project.ID, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !start.Equal(weekStart) || !resetsAt.Equal(weekStart.AddDate(0, 0, 7)) {
+				t.Errorf("week = %v to %v, want %v to %v", start, resetsAt, weekStart, weekStart.AddDate(0, 0, 7))
+			}
+			if used[f.candidates[0].ID] != tt.want || used[f.candidates[1].ID] != 1 {
+				t.Errorf("used = %v, want Ann %d and Ben 1", used, tt.want)
+			}
+		})
+	}
+}
+
+func TestReroll(t *testing.T) {
+	thisWeek := time.Now()
+	lastWeek := bucketStart(thisWeek, BucketWeek).Add(-time.Hour)
+
+	tests := []struct {
+		name       string
+		tokens     int
+		candidates []string
+		used       []time.Time                  // Ann 之前的记录被取代的时间
+		modify     func(history *model.History) // 在重新选择前修改 Ann 的记录
+		wantErr    error
+		remaining  int
+	}{
+		{name: "first re-roll", tokens: 2, candidates: []string{"Ann", "Ben"}, remaining: 1},
+		{name: "last token", tokens: 2, candidates: []string{"Ann", "Ben"}, used: []time.Time{thisWeek}, remaining: 0},
+		{name: "tokens used up", tokens: 2, candidates: []string{"Ann", "Ben"}, used: []time.Time{thisWeek, thisWeek}, wantErr: ErrNoRerollTokens},
+		{name: "tokens reset weekly", tokens: 1, candidates: []string{"Ann", "Ben"}, used: []time.Time{lastWeek, lastWeek}, remaining: 0},
+		{name: "project does not allow re-rolls", tokens: 0, candidates: []string{"Ann", "Ben"}, wantErr: ErrHistoryNotRerollable},
+		{name: "no other candidate", tokens: 1, candidates: []string{"Ann"}, wantErr: ErrHistoryNotRerollable},
+		{
+			name: "task already started", tokens: 1, candidates: []string{"Ann", "Ben"},
+			modify:  func(h *model.History) { h.Status = model.HistoryStatusStarted },
+			wantErr: ErrHistoryNotRerollable,
+		},
+		{
+			name: "voided entry", tokens: 1, candidates: []string{"Ann", "Ben"},
+			modify: func(h *model.History) {
+				now := time.Now()
+				h.VoidedAt = &now
+			},
+			wantErr: ErrHistoryNotRerollable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newRerollFixture(t, tt.tokens, tt.candidates...)
+			ann := f.candidates[0]
+			for _, at := range tt.used {
+				f.superseded(t, ann, at)
+			}
+			original := f.draw(t, ann)
+			if tt.modify != nil {
+				tt.modify(original)
+				if err := f.store.Histories().Update(original); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			result, err := NewRandomizeService(f.store).Reroll(f.user.ID, original.ID)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Reroll() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Reroll(): %v", err)
+			}
+			if result.Remaining != tt.remaining {
+				t.Errorf("remaining = %d, want %d", result.Remaining, tt.remaining)
+			}
+			if result.CandidateID == ann.ID {
+				t.Error("re-roll picked the superseded candidate again")
+			}
+			if *result.History.RerollOfID != original.ID || *result.Superseded.SupersededByID != result.History.ID {
+				t.Errorf("re-roll links = %v -> %v, want %v <-> %v",
+					result.History.RerollOfID, result.Superseded.SupersededByID, original.ID, result.History.ID)
+			}
+			if result.Superseded.VoidedAt == nil || result.Superseded.VoidReason != rerollVoidReason {
+				t.Errorf("superseded entry = %+v, want voided as %q", result.Superseded, rerollVoidReason)
+			}
+		})
+	}
+}
+
+func TestRerollChainExcludesEarlierCandidates(t *testing.T) {
+	f := newRerollFixture(t, 1, "Ann", "Ben", "Cat")
+	service := NewRandomizeService(f.store)
+	picked := map[uuid.UUID]bool{f.candidates[0].ID: true}
+
+	history := f.draw(t, f.candidates[0])
+	for range 2 {
+		result, err := service.Reroll(f.user.ID, history.ID)
+		if err != nil {
+			t.Fatalf("Reroll(): %v", err)
+		}
+		if picked[result.CandidateID] {
+			t.Fatalf("re-roll picked %s again", result.CandidateName)
+		}
+		picked[result.CandidateID] = true
+		history = result.History
+	}
+
+	// 三个候选人都已在这一串重新选择中被选中过
+	if _, err := service.Reroll(f.user.ID, history.ID); !errors.Is(err, ErrHistoryNotRerollable) {
+		t.Errorf("Reroll() error = %v, want ErrHistoryNotRerollable", err)
+	}
+}
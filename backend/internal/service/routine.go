@@ -0,0 +1,373 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"whotakesshowers/internal/logger"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// 例程的最大步骤数
+	maxRoutineSteps = 20
+	// 列出例程执行记录的条数
+	routineRunListLimit = 20
+)
+
+// RoutineService 例程：按顺序执行多个项目，一次执行为每个步骤随机选择并记录一组关联的历史记录
+type RoutineService struct {
+	store store.Store
+}
+
+// NewRoutineService 创建例程服务
+func NewRoutineService(s store.Store) *RoutineService {
+	return &RoutineService{store: s}
+}
+
+// RoutineInput 创建或修改例程的内容；修改时为空的字段保持不变
+type RoutineInput struct {
+	Name         string      `json:"name" binding:"max=100"`
+	ProjectIDs   []uuid.UUID `json:"project_ids"`
+	AvoidRepeats *bool       `json:"avoid_repeats"` // 创建时默认为 true
+}
+
+// RoutineRunDetail 例程执行记录及其各步骤的历史记录
+type RoutineRunDetail struct {
+	model.RoutineRun
+	Histories []model.History `json:"histories"`
+	Draws     []RoutineDraw   `json:"draws,omitempty"` // 各步骤的选择说明，只在执行例程的响应中返回
+}
+
+// RoutineDraw 例程执行时一个步骤的选择说明
+type RoutineDraw struct {
+	Step        int                `json:"step"`
+	ProjectID   uuid.UUID          `json:"project_id"`
+	HistoryID   uuid.UUID          `json:"history_id"`  // 该步骤的历史记录
+	Constraints []ConstraintEffect `json:"constraints"` // 影响了该步骤选择的项目间约束
+	Overrides   []OverrideEffect   `json:"overrides"`   // 该步骤的项目今天生效的特殊日规则
+}
+
+// List 获取例程列表
+func (s *RoutineService) List(userID uuid.UUID) ([]model.Routine, error) {
+	return s.store.Routines().List(userID)
+}
+
+// Get 获取例程
+func (s *RoutineService) Get(userID, routineID uuid.UUID) (*model.Routine, error) {
+	routine, err := s.store.Routines().Get(routineID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrRoutineNotFound
+	}
+	return routine, err
+}
+
+// Create 创建例程
+func (s *RoutineService) Create(userID uuid.UUID, input RoutineInput) (*model.Routine, error) {
+	if strings.TrimSpace(input.Name) == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidRoutine)
+	}
+	if input.ProjectIDs == nil {
+		return nil, fmt.Errorf("%w: project_ids is required", ErrInvalidRoutine)
+	}
+	routine := &model.Routine{UserID: userID, AvoidRepeats: true}
+	if err := s.apply(routine, input); err != nil {
+		return nil, err
+	}
+	if err := s.store.Routines().Create(routine); err != nil {
+		return nil, err
+	}
+	return routine, nil
+}
+
+// Update 修改例程，已有的执行记录不受影响
+func (s *RoutineService) Update(userID, routineID uuid.UUID, input RoutineInput) (*model.Routine, error) {
+	routine, err := s.Get(userID, routineID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.apply(routine, input); err != nil {
+		return nil, err
+	}
+	if err := s.store.Routines().Update(routine); err != nil {
+		return nil, err
+	}
+	return routine, nil
+}
+
+// Delete 删除例程，执行记录和历史记录保留
+func (s *RoutineService) Delete(userID, routineID uuid.UUID) error {
+	err := s.store.Routines().Delete(routineID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrRoutineNotFound
+	}
+	return err
+}
+
+// apply 校验并写入例程的内容：步骤必须是用户当前的项目，且不能重复
+func (s *RoutineService) apply(routine *model.Routine, input RoutineInput) error {
+	if name := strings.TrimSpace(input.Name); name != "" {
+		routine.Name = name
+	}
+	if input.AvoidRepeats != nil {
+		routine.AvoidRepeats = *input.AvoidRepeats
+	}
+	if input.ProjectIDs == nil {
+		return nil
+	}
+
+	if len(input.ProjectIDs) == 0 || len(input.ProjectIDs) > maxRoutineSteps {
+		return fmt.Errorf("%w: a routine needs 1 to %d steps", ErrInvalidRoutine, maxRoutineSteps)
+	}
+	seen := make(map[uuid.UUID]bool, len(input.ProjectIDs))
+	for _, projectID := range input.ProjectIDs {
+		if seen[projectID] {
+			return fmt.Errorf("%w: project %s appears more than once", ErrInvalidRoutine, projectID)
+		}
+		seen[projectID] = true
+		if _, err := s.store.Projects().Get(projectID, routine.UserID); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return ErrProjectNotFound
+			}
+			return err
+		}
+	}
+	data, err := json.Marshal(input.ProjectIDs)
+	if err != nil {
+		return err
+	}
+	routine.ProjectIDs = string(data)
+	return nil
+}
+
+// Run 执行例程：在一个事务中为每个步骤在符合资格规则的候选人中随机选择一个，记录为一组关联的历史记录
+// 每一步与单独的随机选择一样先应用项目的特殊日规则，再按项目间约束排除候选人或降低其权重，之前步骤的选择也计入约束；
+// AvoidRepeats 为 true 时，在约束允许的候选人中优先选择本次执行中被选中次数最少的
+func (s *RoutineService) Run(userID, routineID uuid.UUID) (*RoutineRunDetail, error) {
+	var detail *RoutineRunDetail
+	err := s.store.Transaction(func(tx store.Store) error {
+		routine, err := tx.Routines().Get(routineID, userID)
+		if errors.Is(err, store.ErrNotFound) {
+			return ErrRoutineNotFound
+		}
+		if err != nil {
+			return err
+		}
+		var projectIDs []uuid.UUID
+		if routine.ProjectIDs != "" {
+			if err := json.Unmarshal([]byte(routine.ProjectIDs), &projectIDs); err != nil {
+				return err
+			}
+		}
+		if len(projectIDs) == 0 {
+			return fmt.Errorf("%w: routine has no steps", ErrRoutineStepUnavailable)
+		}
+
+		now := time.Now()
+		run := &model.RoutineRun{
+			UserID:      userID,
+			RoutineID:   routine.ID,
+			RoutineName: routine.Name,
+			Steps:       len(projectIDs),
+			StartedAt:   now,
+		}
+		if err := tx.RoutineRuns().Create(run); err != nil {
+			return err
+		}
+
+		detail = &RoutineRunDetail{
+			RoutineRun: *run,
+			Histories:  make([]model.History, 0, len(projectIDs)),
+			Draws:      make([]RoutineDraw, 0, len(projectIDs)),
+		}
+		picked := make(map[uuid.UUID]int)
+		for step, projectID := range projectIDs {
+			project, err := tx.Projects().Get(projectID, userID)
+			if errors.Is(err, store.ErrNotFound) {
+				return fmt.Errorf("%w: the project of step %d no longer exists", ErrRoutineStepUnavailable, step+1)
+			}
+			if err != nil {
+				return err
+			}
+			candidates, ineligible, err := drawCandidates(tx, project, now)
+			if err != nil {
+				return err
+			}
+			if len(candidates) == 0 && len(ineligible) > 0 {
+				return fmt.Errorf("%w: %s (step %d) has no eligible candidates", ErrRoutineStepUnavailable, project.Name, step+1)
+			}
+			if len(candidates) == 0 {
+				return fmt.Errorf("%w: %s (step %d) has no candidates", ErrRoutineStepUnavailable, project.Name, step+1)
+			}
+
+			pool := poolIDs(candidates, ineligible)
+			candidates, overrides, err := applyDrawOverrides(project, candidates, now)
+			if err != nil {
+				return err
+			}
+			weights, effects, err := constraintWeights(tx, project, candidates, now)
+			if err != nil {
+				return err
+			}
+			selected := pickRoutineCandidate(candidates, weights, picked, routine.AvoidRepeats)
+			picked[selected.ID]++
+
+			history := drawHistory(project, &selected, pool, now)
+			history.OverrideReason = overrideReason(overrides)
+			history.Overridden = history.OverrideReason != ""
+			history.RoutineRunID = &run.ID
+			history.RoutineStep = &step
+			if err := recordDraw(tx, history); err != nil {
+				return err
+			}
+			detail.Histories = append(detail.Histories, *history)
+			detail.Draws = append(detail.Draws, RoutineDraw{
+				Step:        step,
+				ProjectID:   project.ID,
+				HistoryID:   history.ID,
+				Constraints: effects,
+				Overrides:   overrides,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Routine run started",
+		zap.String("user_id", userID.String()),
+		zap.String("routine_id", routineID.String()),
+		zap.String("run_id", detail.ID.String()),
+	)
+	return detail, nil
+}
+
+// pickRoutineCandidate 按约束权重（与 candidates 一一对应）随机选择一个候选人；
+// avoidRepeats 为 true 时只在权重大于 0 的候选人中本次执行被选中次数最少的之中选择
+func pickRoutineCandidate(candidates []model.Candidate, weights []float64, picked map[uuid.UUID]int, avoidRepeats bool) model.Candidate {
+	if !avoidRepeats {
+		return weightedPick(candidates, weights)
+	}
+	fewest := -1
+	for i, candidate := range candidates {
+		if n := picked[candidate.ID]; weights[i] > 0 && (fewest < 0 || n < fewest) {
+			fewest = n
+		}
+	}
+	if fewest < 0 {
+		return weightedPick(candidates, weights)
+	}
+	pool := make([]model.Candidate, 0, len(candidates))
+	poolWeights := make([]float64, 0, len(candidates))
+	for i, candidate := range candidates {
+		if weights[i] > 0 && picked[candidate.ID] == fewest {
+			pool = append(pool, candidate)
+			poolWeights = append(poolWeights, weights[i])
+		}
+	}
+	return weightedPick(pool, poolWeights)
+}
+
+// Advance 推进例程执行：完成（skip 为 true 时跳过）当前步骤的任务并开始下一步骤的任务，最后一步之后执行结束
+// 当前步骤的任务已经完成、跳过、作废或其项目已删除时直接进入下一步骤
+func (s *RoutineService) Advance(userID, runID uuid.UUID, skip bool) (*RoutineRunDetail, error) {
+	var detail *RoutineRunDetail
+	err := s.store.Transaction(func(tx store.Store) error {
+		run, err := tx.RoutineRuns().Get(runID, userID)
+		if errors.Is(err, store.ErrNotFound) {
+			return ErrRoutineRunNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if run.FinishedAt != nil {
+			return ErrRoutineRunFinished
+		}
+		histories, err := tx.Histories().ListByRoutineRun(run.ID)
+		if err != nil {
+			return err
+		}
+
+		status := model.HistoryStatusCompleted
+		if skip {
+			status = model.HistoryStatusSkipped
+		}
+		if err := advanceStep(tx, userID, histories, run.CurrentStep, status); err != nil {
+			return err
+		}
+
+		run.CurrentStep++
+		if run.CurrentStep >= run.Steps {
+			now := time.Now()
+			run.FinishedAt = &now
+		} else if err := advanceStep(tx, userID, histories, run.CurrentStep, model.HistoryStatusStarted); err != nil {
+			return err
+		}
+		if err := tx.RoutineRuns().Update(run); err != nil {
+			return err
+		}
+
+		detail, err = routineRunDetail(tx, run)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return detail, nil
+}
+
+// advanceStep 将步骤 step 的任务转换到 status；任务已经不能转换或已不可见时忽略
+func advanceStep(tx store.Store, userID uuid.UUID, histories []model.History, step int, status string) error {
+	for _, history := range histories {
+		if history.RoutineStep == nil || *history.RoutineStep != step {
+			continue
+		}
+		_, err := modifyHistory(tx, userID, history.ID, func(h *model.History, now time.Time) error {
+			return transitionTask(h, status, nil, now)
+		})
+		if errors.Is(err, ErrHistoryNotFound) || errors.Is(err, ErrHistoryVoided) || errors.Is(err, ErrInvalidTaskTransition) {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// GetRun 获取例程执行记录及其各步骤的历史记录
+func (s *RoutineService) GetRun(userID, runID uuid.UUID) (*RoutineRunDetail, error) {
+	run, err := s.store.RoutineRuns().Get(runID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrRoutineRunNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return routineRunDetail(s.store, run)
+}
+
+// ListRuns 获取例程最近的执行记录
+func (s *RoutineService) ListRuns(userID, routineID uuid.UUID) ([]model.RoutineRun, error) {
+	if _, err := s.Get(userID, routineID); err != nil {
+		return nil, err
+	}
+	return s.store.RoutineRuns().ListByRoutine(routineID, userID, routineRunListLimit)
+}
+
+// routineRunDetail 读取执行记录各步骤的历史记录
+func routineRunDetail(s store.Store, run *model.RoutineRun) (*RoutineRunDetail, error) {
+	histories, err := s.Histories().ListByRoutineRun(run.ID)
+	if err != nil {
+		return nil, err
+	}
+	if histories == nil {
+		histories = make([]model.History, 0)
+	}
+	return &RoutineRunDetail{RoutineRun: *run, Histories: histories}, nil
+}
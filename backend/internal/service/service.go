@@ -0,0 +1,54 @@
+package service
+
+import (
+	"time"
+	"whotakesshowers/internal/config"
+	"whotakesshowers/internal/store"
+)
+
+// Services 应用的所有服务，由 New 统一创建并注入依赖
+type Services struct {
+	Users           *UserService
+	Projects        *ProjectService
+	Candidates      *CandidateService
+	CandidatePhotos *CandidatePhotoService
+	Trash           *TrashService
+	Randomizer      *RandomizeService
+	Histories       *HistoryService
+	Export          *ExportService
+	Accounts        *AccountService
+	Stats           *StatsService
+	HistoryExport   *HistoryExportService
+	Points          *PointsService
+	Badges          *BadgeService
+	Routines        *RoutineService
+	Constraints     *ProjectConstraintService
+	Idempotency     *IdempotencyService
+	Uploads         *PhotoUploads
+}
+
+// New 基于配置和数据存储创建所有服务
+func New(cfg *config.Config, s store.Store) *Services {
+	gracePeriod := time.Duration(cfg.Account.DeletionGraceDays) * 24 * time.Hour
+	idempotencyTTL := time.Duration(cfg.Idempotency.TTL) * time.Minute
+	uploads := NewPhotoUploads(cfg.Upload)
+	return &Services{
+		Users:           NewUserService(s.Users()),
+		Projects:        NewProjectService(s.Projects()),
+		Candidates:      NewCandidateService(s),
+		CandidatePhotos: NewCandidatePhotoService(s),
+		Trash:           NewTrashService(s),
+		Randomizer:      NewRandomizeService(s),
+		Histories:       NewHistoryService(s),
+		Export:          NewExportService(s, uploads),
+		Accounts:        NewAccountService(s, gracePeriod),
+		Stats:           NewStatsService(s),
+		HistoryExport:   NewHistoryExportService(s),
+		Points:          NewPointsService(s),
+		Badges:          NewBadgeService(s),
+		Routines:        NewRoutineService(s),
+		Constraints:     NewProjectConstraintService(s),
+		Idempotency:     NewIdempotencyService(s, idempotencyTTL, cfg.Upload.MaxSize),
+		Uploads:         uploads,
+	}
+}
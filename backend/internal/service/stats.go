@@ -0,0 +1,644 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// 统计参数
+const (
+	BucketDay  = "day"
+	BucketWeek = "week"
+
+	// 默认统计窗口
+	DefaultStatsWindows = "7d,30d,all"
+
+	maxStatsWindows = 5
+	maxSeriesBucket = 1000
+	// 卡方检验的 p 值不低于该值时认为抽取结果与均匀分布一致
+	fairnessSignificance = 0.05
+)
+
+// StatsService 公平性统计服务，所有数据由未作废的历史记录计算得出
+type StatsService struct {
+	store store.Store
+}
+
+// NewStatsService 创建统计服务
+func NewStatsService(s store.Store) *StatsService {
+	return &StatsService{store: s}
+}
+
+// StatsQuery 统计参数
+type StatsQuery struct {
+	Windows string // 逗号分隔的统计窗口，如 "7d,4w,all"；为空时使用 DefaultStatsWindows
+	Bucket  string // 时间序列的粒度：day 或 week；为空时为 day
+}
+
+// Stats 统计结果
+type Stats struct {
+	ProjectID   *uuid.UUID       `json:"project_id,omitempty"` // 为空表示所有项目
+	GeneratedAt time.Time        `json:"generated_at"`
+	TotalDraws  int              `json:"total_draws"`
+	TotalSplits int              `json:"total_splits"` // 分组次数，分组不计入被选中次数和公平性
+	Candidates  []CandidateStats `json:"candidates"`
+	Windows     []WindowStats    `json:"windows"`
+	Series      Series           `json:"series"`
+}
+
+// CandidateStats 候选人在全部历史中的统计
+type CandidateStats struct {
+	CandidateID   uuid.UUID `json:"candidate_id"`
+	Name          string    `json:"name"`
+	Draws         int       `json:"draws"`
+	Splits        int       `json:"splits"`         // 参与分组的次数
+	Share         float64   `json:"share"`          // 被选中次数占总次数的比例
+	CurrentStreak int       `json:"current_streak"` // 最近连续被选中的次数
+	LongestStreak int       `json:"longest_streak"` // 历史上最长连续被选中的次数
+
+	// 距上次被选中的间隔，从未被选中时为空
+	LastPickedAt         *time.Time `json:"last_picked_at"`
+	DrawsSinceLastPick   *int       `json:"draws_since_last_pick"`
+	SecondsSinceLastPick *int64     `json:"seconds_since_last_pick"`
+
+	Tasks TaskStats `json:"tasks"`
+}
+
+// TaskStats 候选人被选中后的任务完成情况
+// 比例和时长在没有可计算的记录时为空
+type TaskStats struct {
+	Picked    int `json:"picked"` // 尚未开始
+	Started   int `json:"started"`
+	Completed int `json:"completed"`
+	Skipped   int `json:"skipped"`
+	// 完成率：完成次数 / (完成次数 + 跳过次数)，未结束的任务不计入
+	CompletionRate *float64 `json:"completion_rate"`
+
+	// 从开始到完成的时长，只统计记录了开始时间的已完成任务
+	AvgDurationSeconds    *float64 `json:"avg_duration_seconds"`
+	MedianDurationSeconds *float64 `json:"median_duration_seconds"`
+
+	// 在项目目标时长内完成的次数；按时率的分母为有目标时长且记录了时长的完成次数
+	OnTime     int      `json:"on_time"`
+	OnTimeRate *float64 `json:"on_time_rate"`
+}
+
+// WindowStats 一个统计窗口内的次数与公平性
+type WindowStats struct {
+	Window   string        `json:"window"`
+	From     *time.Time    `json:"from"` // 为空表示全部历史
+	Draws    int           `json:"draws"`
+	Counts   []WindowCount `json:"counts"`
+	Fairness *Fairness     `json:"fairness"` // 候选人少于 2 个或没有记录时为空
+}
+
+// WindowCount 候选人在窗口内的实际次数与均匀分布下的期望次数
+type WindowCount struct {
+	CandidateID uuid.UUID `json:"candidate_id"`
+	Name        string    `json:"name"`
+	Count       int       `json:"count"`
+	Expected    float64   `json:"expected"`
+}
+
+// Fairness 与均匀分布比较的卡方检验
+type Fairness struct {
+	ChiSquare        float64 `json:"chi_square"`
+	DegreesOfFreedom int     `json:"degrees_of_freedom"`
+	PValue           float64 `json:"p_value"`
+	Fair             bool    `json:"fair"` // p 值不低于 0.05
+}
+
+// Series 按时间分桶的被选中次数，用于绘制图表
+type Series struct {
+	Bucket  string         `json:"bucket"`
+	Buckets []SeriesBucket `json:"buckets"`
+}
+
+// SeriesBucket 一个时间桶
+type SeriesBucket struct {
+	Start  time.Time         `json:"start"`
+	Draws  int               `json:"draws"`
+	Counts map[uuid.UUID]int `json:"counts"` // 候选人 ID -> 次数，只包含次数大于 0 的候选人
+}
+
+// statsWindow 解析后的统计窗口
+type statsWindow struct {
+	name string
+	from *time.Time
+}
+
+// Project 计算单个项目的统计
+func (s *StatsService) Project(userID, projectID uuid.UUID, query StatsQuery) (*Stats, error) {
+	project, err := s.store.Projects().Get(projectID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrProjectNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	histories, err := s.store.Histories().List(userID, &projectID, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := s.compute(userID, []model.Project{*project}, histories, query)
+	if err != nil {
+		return nil, err
+	}
+	stats.ProjectID = &project.ID
+	return stats, nil
+}
+
+// Overall 计算用户所有项目（不包括回收站中的）的统计
+// 连续被选中次数按项目分别计算，取各项目中的最大值
+func (s *StatsService) Overall(userID uuid.UUID, query StatsQuery) (*Stats, error) {
+	projects, err := s.store.Projects().List(userID)
+	if err != nil {
+		return nil, err
+	}
+	histories, err := s.store.Histories().List(userID, nil, -1)
+	if err != nil {
+		return nil, err
+	}
+	return s.compute(userID, projects, histories, query)
+}
+
+// compute 基于项目和历史记录（任意顺序）计算统计
+func (s *StatsService) compute(userID uuid.UUID, projects []model.Project, histories []model.History, query StatsQuery) (*Stats, error) {
+	now := time.Now()
+	windows, err := parseStatsWindows(query.Windows, now)
+	if err != nil {
+		return nil, err
+	}
+	bucket := query.Bucket
+	if bucket == "" {
+		bucket = BucketDay
+	}
+	if bucket != BucketDay && bucket != BucketWeek {
+		return nil, fmt.Errorf("%w: bucket must be %s or %s", ErrInvalidStatsQuery, BucketDay, BucketWeek)
+	}
+
+	// 作废的记录视为没有发生过
+	histories = slices.DeleteFunc(histories, func(h model.History) bool {
+		return h.VoidedAt != nil
+	})
+	// 分组产生的记录不是随机选中，只单独计数
+	var splits []model.History
+	histories = slices.DeleteFunc(histories, func(h model.History) bool {
+		if h.Source == model.HistorySourceSplit {
+			splits = append(splits, h)
+			return true
+		}
+		return false
+	})
+	sort.SliceStable(histories, func(i, j int) bool {
+		return histories[i].SelectedAt.Before(histories[j].SelectedAt)
+	})
+
+	// 候选人名称使用当前名称，已删除的候选人使用历史记录中的名称
+	candidates, err := s.store.Candidates().List(userID)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[uuid.UUID]string, len(candidates))
+	for _, candidate := range candidates {
+		names[candidate.ID] = candidate.Name
+	}
+	for _, history := range histories {
+		if _, ok := names[history.CandidateID]; !ok {
+			names[history.CandidateID] = history.CandidateName
+		}
+	}
+
+	// 项目当前的候选人池，只包括未删除的候选人；标签查询按当前的标签解析
+	pools := make(map[uuid.UUID][]uuid.UUID, len(projects))
+	for _, project := range projects {
+		if project.TagQuery != "" {
+			members, err := tagMembers(project.TagQuery, candidates)
+			if err != nil {
+				return nil, err
+			}
+			for _, member := range members {
+				pools[project.ID] = append(pools[project.ID], member.ID)
+			}
+			continue
+		}
+		ids, err := s.store.Projects().GetCandidateIDs(project.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			if _, ok := names[id]; ok {
+				pools[project.ID] = append(pools[project.ID], id)
+			}
+		}
+	}
+
+	stats := &Stats{
+		GeneratedAt: now,
+		TotalDraws:  len(histories),
+		Candidates:  candidateStats(histories, pools, names, now),
+	}
+	tasks := taskStats(projects, histories)
+	splitCounts := make(map[uuid.UUID]int)
+	splitIDs := make(map[uuid.UUID]bool)
+	for _, history := range splits {
+		splitCounts[history.CandidateID]++
+		if history.SplitID != nil {
+			splitIDs[*history.SplitID] = true
+		}
+	}
+	stats.TotalSplits = len(splitIDs)
+	for i := range stats.Candidates {
+		stats.Candidates[i].Tasks = tasks[stats.Candidates[i].CandidateID]
+		stats.Candidates[i].Splits = splitCounts[stats.Candidates[i].CandidateID]
+	}
+	for _, window := range windows {
+		stats.Windows = append(stats.Windows, windowStats(window, histories, pools, names))
+	}
+	stats.Series, err = series(bucket, windows, histories, now)
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// parseStatsWindows 解析统计窗口，格式为 <n>d、<n>w 或 all
+func parseStatsWindows(spec string, now time.Time) ([]statsWindow, error) {
+	if spec == "" {
+		spec = DefaultStatsWindows
+	}
+	parts := strings.Split(spec, ",")
+	if len(parts) > maxStatsWindows {
+		return nil, fmt.Errorf("%w: at most %d windows", ErrInvalidStatsQuery, maxStatsWindows)
+	}
+
+	windows := make([]statsWindow, 0, len(parts))
+	for _, part := range parts {
+		name := strings.TrimSpace(part)
+		if name == "all" {
+			windows = append(windows, statsWindow{name: name})
+			continue
+		}
+		if len(name) < 2 {
+			return nil, fmt.Errorf("%w: invalid window %q", ErrInvalidStatsQuery, name)
+		}
+		n, err := strconv.Atoi(name[:len(name)-1])
+		if err != nil || n <= 0 || n > 3650 {
+			return nil, fmt.Errorf("%w: invalid window %q", ErrInvalidStatsQuery, name)
+		}
+		var from time.Time
+		switch name[len(name)-1] {
+		case 'd':
+			from = now.AddDate(0, 0, -n)
+		case 'w':
+			from = now.AddDate(0, 0, -7*n)
+		default:
+			return nil, fmt.Errorf("%w: invalid window %q", ErrInvalidStatsQuery, name)
+		}
+		windows = append(windows, statsWindow{name: name, from: &from})
+	}
+	return windows, nil
+}
+
+// candidateStats 计算每个候选人在全部历史中的次数、连续次数和间隔
+func candidateStats(histories []model.History, pools map[uuid.UUID][]uuid.UUID, names map[uuid.UUID]string, now time.Time) []CandidateStats {
+	byID := make(map[uuid.UUID]*CandidateStats)
+	get := func(id uuid.UUID) *CandidateStats {
+		cs, ok := byID[id]
+		if !ok {
+			cs = &CandidateStats{CandidateID: id, Name: names[id]}
+			byID[id] = cs
+		}
+		return cs
+	}
+	for _, pool := range pools {
+		for _, id := range pool {
+			get(id)
+		}
+	}
+
+	// 连续次数按项目分别计算：runs 记录每个项目最近一次被选中的候选人及其连续次数
+	type run struct {
+		candidateID uuid.UUID
+		length      int
+	}
+	runs := make(map[uuid.UUID]*run)
+	for i, history := range histories {
+		cs := get(history.CandidateID)
+		cs.Draws++
+		selectedAt := history.SelectedAt
+		cs.LastPickedAt = &selectedAt
+		since := len(histories) - 1 - i
+		cs.DrawsSinceLastPick = &since
+
+		r := runs[history.ProjectID]
+		if r == nil || r.candidateID != history.CandidateID {
+			r = &run{candidateID: history.CandidateID}
+			runs[history.ProjectID] = r
+		}
+		r.length++
+		if r.length > cs.LongestStreak {
+			cs.LongestStreak = r.length
+		}
+	}
+	// 候选人可能在多个项目中都正连续被选中，当前连续次数取其中的最大值
+	for _, r := range runs {
+		if cs := byID[r.candidateID]; r.length > cs.CurrentStreak {
+			cs.CurrentStreak = r.length
+		}
+	}
+
+	result := make([]CandidateStats, 0, len(byID))
+	for _, cs := range byID {
+		if len(histories) > 0 {
+			cs.Share = float64(cs.Draws) / float64(len(histories))
+		}
+		if cs.LastPickedAt != nil {
+			seconds := int64(now.Sub(*cs.LastPickedAt).Seconds())
+			cs.SecondsSinceLastPick = &seconds
+		}
+		result = append(result, *cs)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Draws != result[j].Draws {
+			return result[i].Draws > result[j].Draws
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// taskStats 按候选人统计任务状态、完成时长和按时完成情况
+func taskStats(projects []model.Project, histories []model.History) map[uuid.UUID]TaskStats {
+	targets := make(map[uuid.UUID]time.Duration, len(projects))
+	for _, project := range projects {
+		if target, ok := targetDuration(&project); ok {
+			targets[project.ID] = target
+		}
+	}
+
+	result := make(map[uuid.UUID]TaskStats)
+	durations := make(map[uuid.UUID][]float64)
+	timed := make(map[uuid.UUID]int) // 有目标时长且记录了时长的完成次数
+	for i := range histories {
+		history := &histories[i]
+		ts := result[history.CandidateID]
+		switch history.Status {
+		case model.HistoryStatusStarted:
+			ts.Started++
+		case model.HistoryStatusCompleted:
+			ts.Completed++
+			if d, ok := history.Duration(); ok {
+				durations[history.CandidateID] = append(durations[history.CandidateID], d.Seconds())
+				if target, ok := targets[history.ProjectID]; ok {
+					timed[history.CandidateID]++
+					if d <= target {
+						ts.OnTime++
+					}
+				}
+			}
+		case model.HistoryStatusSkipped:
+			ts.Skipped++
+		default:
+			ts.Picked++
+		}
+		result[history.CandidateID] = ts
+	}
+
+	for id, ts := range result {
+		if finished := ts.Completed + ts.Skipped; finished > 0 {
+			rate := float64(ts.Completed) / float64(finished)
+			ts.CompletionRate = &rate
+		}
+		if n := timed[id]; n > 0 {
+			rate := float64(ts.OnTime) / float64(n)
+			ts.OnTimeRate = &rate
+		}
+		if d := durations[id]; len(d) > 0 {
+			sort.Float64s(d)
+			var sum float64
+			for _, seconds := range d {
+				sum += seconds
+			}
+			avg := sum / float64(len(d))
+			median := d[len(d)/2]
+			if len(d)%2 == 0 {
+				median = (d[len(d)/2-1] + d[len(d)/2]) / 2
+			}
+			ts.AvgDurationSeconds = &avg
+			ts.MedianDurationSeconds = &median
+		}
+		result[id] = ts
+	}
+	return result
+}
+
+// targetDuration 项目的目标时长，没有设置时 ok 为 false
+func targetDuration(project *model.Project) (time.Duration, bool) {
+	if project.TargetMinutes == nil || *project.TargetMinutes <= 0 {
+		return 0, false
+	}
+	return time.Duration(*project.TargetMinutes) * time.Minute, true
+}
+
+// windowStats 计算窗口内的次数和卡方检验
+// 每个项目的期望次数为该项目在窗口内的次数除以候选人数，候选人包括当前候选人池和窗口内被选中过的候选人
+func windowStats(window statsWindow, histories []model.History, pools map[uuid.UUID][]uuid.UUID, names map[uuid.UUID]string) WindowStats {
+	draws := make(map[uuid.UUID]int)                  // 项目 ID -> 次数
+	members := make(map[uuid.UUID]map[uuid.UUID]bool) // 项目 ID -> 候选人
+	counts := make(map[uuid.UUID]int)                 // 候选人 ID -> 次数
+	for projectID, pool := range pools {
+		members[projectID] = make(map[uuid.UUID]bool)
+		for _, id := range pool {
+			members[projectID][id] = true
+		}
+	}
+
+	result := WindowStats{Window: window.name, From: window.from, Counts: make([]WindowCount, 0)}
+	for _, history := range histories {
+		if window.from != nil && history.SelectedAt.Before(*window.from) {
+			continue
+		}
+		result.Draws++
+		draws[history.ProjectID]++
+		counts[history.CandidateID]++
+		if members[history.ProjectID] == nil {
+			members[history.ProjectID] = make(map[uuid.UUID]bool)
+		}
+		members[history.ProjectID][history.CandidateID] = true
+	}
+
+	expected := make(map[uuid.UUID]float64)
+	for projectID, m := range members {
+		for id := range m {
+			expected[id] += float64(draws[projectID]) / float64(len(m))
+		}
+	}
+
+	chiSquare := 0.0
+	for id, e := range expected {
+		result.Counts = append(result.Counts, WindowCount{
+			CandidateID: id,
+			Name:        names[id],
+			Count:       counts[id],
+			Expected:    e,
+		})
+		if e > 0 {
+			diff := float64(counts[id]) - e
+			chiSquare += diff * diff / e
+		}
+	}
+	sort.Slice(result.Counts, func(i, j int) bool {
+		if result.Counts[i].Count != result.Counts[j].Count {
+			return result.Counts[i].Count > result.Counts[j].Count
+		}
+		return result.Counts[i].Name < result.Counts[j].Name
+	})
+
+	participants := 0
+	for _, e := range expected {
+		if e > 0 {
+			participants++
+		}
+	}
+	if result.Draws > 0 && participants >= 2 {
+		df := participants - 1
+		pValue := chiSquarePValue(chiSquare, df)
+		result.Fairness = &Fairness{
+			ChiSquare:        chiSquare,
+			DegreesOfFreedom: df,
+			PValue:           pValue,
+			Fair:             pValue >= fairnessSignificance,
+		}
+	}
+	return result
+}
+
+// series 按天或按周统计次数，范围为最长的有限窗口；只有 all 时从第一条记录开始
+func series(bucket string, windows []statsWindow, histories []model.History, now time.Time) (Series, error) {
+	result := Series{Bucket: bucket, Buckets: make([]SeriesBucket, 0)}
+
+	var from *time.Time
+	for _, window := range windows {
+		if window.from != nil && (from == nil || window.from.Before(*from)) {
+			from = window.from
+		}
+	}
+	if from == nil {
+		if len(histories) == 0 {
+			return result, nil
+		}
+		from = &histories[0].SelectedAt
+	}
+
+	start := bucketStart(*from, bucket)
+	end := bucketStart(now, bucket)
+	index := make(map[time.Time]int)
+	for t := start; !t.After(end); t = nextBucket(t, bucket) {
+		if len(result.Buckets) >= maxSeriesBucket {
+			return result, fmt.Errorf("%w: more than %d buckets, use a shorter window or bucket=%s",
+				ErrInvalidStatsQuery, maxSeriesBucket, BucketWeek)
+		}
+		index[t] = len(result.Buckets)
+		result.Buckets = append(result.Buckets, SeriesBucket{Start: t, Counts: make(map[uuid.UUID]int)})
+	}
+
+	for _, history := range histories {
+		i, ok := index[bucketStart(history.SelectedAt, bucket)]
+		if !ok {
+			continue
+		}
+		result.Buckets[i].Draws++
+		result.Buckets[i].Counts[history.CandidateID]++
+	}
+	return result, nil
+}
+
+// bucketStart 返回 t 所在时间桶的起点（本地时区的零点，按周时为周一）
+func bucketStart(t time.Time, bucket string) time.Time {
+	t = t.Local()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+	if bucket == BucketWeek {
+		offset := (int(day.Weekday()) + 6) % 7 // 周一为 0
+		day = day.AddDate(0, 0, -offset)
+	}
+	return day
+}
+
+// nextBucket 返回下一个时间桶的起点
+func nextBucket(t time.Time, bucket string) time.Time {
+	if bucket == BucketWeek {
+		return t.AddDate(0, 0, 7)
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+// chiSquarePValue 返回自由度为 df 的卡方分布中大于 x 的概率
+func chiSquarePValue(x float64, df int) float64 {
+	return gammaQ(float64(df)/2, x/2)
+}
+
+// gammaQ 正则化上不完全伽马函数 Q(a, x)
+func gammaQ(a, x float64) float64 {
+	if x <= 0 {
+		return 1
+	}
+	lgamma, _ := math.Lgamma(a)
+	prefix := math.Exp(-x + a*math.Log(x) - lgamma)
+
+	const (
+		maxIterations = 500
+		epsilon       = 1e-14
+		tiny          = 1e-300
+	)
+	if x < a+1 {
+		// 级数展开求 P(a, x)
+		ap, sum := a, 1/a
+		del := sum
+		for i := 0; i < maxIterations; i++ {
+			ap++
+			del *= x / ap
+			sum += del
+			if math.Abs(del) < math.Abs(sum)*epsilon {
+				break
+			}
+		}
+		return 1 - sum*prefix
+	}
+
+	// 连分式求 Q(a, x)
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for i := 1; i <= maxIterations; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+		if math.Abs(del-1) < epsilon {
+			break
+		}
+	}
+	return prefix * h
+}
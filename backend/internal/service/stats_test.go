@@ -0,0 +1,230 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+	"whotakesshowers/internal/model"
+
+	"github.com/google/uuid"
+)
+
+func TestGammaQ(t *testing.T) {
+	tests := []struct {
+		name string
+		a, x float64
+		want float64
+	}{
+		{name: "x is zero", a: 2, x: 0, want: 1},
+		{name: "negative x", a: 2, x: -1, want: 1},
+		// Q(1, x) = e^-x，覆盖级数展开和连分式两个分支
+		{name: "exponential series", a: 1, x: 0.5, want: math.Exp(-0.5)},
+		{name: "exponential continued fraction", a: 1, x: 5, want: math.Exp(-5)},
+		// Q(1/2, x) = erfc(√x)
+		{name: "half series", a: 0.5, x: 0.3, want: math.Erfc(math.Sqrt(0.3))},
+		{name: "half continued fraction", a: 0.5, x: 4, want: math.Erfc(2)},
+		// Q(2, x) = (1 + x)e^-x
+		{name: "integer a", a: 2, x: 3, want: 4 * math.Exp(-3)},
+		{name: "far tail", a: 2, x: 50, want: 51 * math.Exp(-50)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := gammaQ(tt.a, tt.x)
+			if math.Abs(got-tt.want) > 1e-9*tt.want {
+				t.Errorf("gammaQ(%v, %v) = %v, want %v", tt.a, tt.x, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChiSquarePValue(t *testing.T) {
+	// 自由度为 1 时 3.841 是 0.05 的临界值
+	if p := chiSquarePValue(3.841, 1); math.Abs(p-0.05) > 1e-4 {
+		t.Errorf("chiSquarePValue(3.841, 1) = %v, want 0.05", p)
+	}
+	// 自由度为 2 时 p = e^(-x/2)
+	if p := chiSquarePValue(4, 2); math.Abs(p-math.Exp(-2)) > 1e-12 {
+		t.Errorf("chiSquarePValue(4, 2) = %v, want %v", p, math.Exp(-2))
+	}
+}
+
+func TestWindowStats(t *testing.T) {
+	now := time.Date(2025, 3, 10, 20, 0, 0, 0, time.Local)
+	weekAgo := now.AddDate(0, 0, -7)
+	project, other := uuid.New(), uuid.New()
+	ann, ben, cat := uuid.New(), uuid.New(), uuid.New()
+	names := map[uuid.UUID]string{ann: "Ann", ben: "Ben", cat: "Cat"}
+
+	draws := func(projectID, candidateID uuid.UUID, n int, at time.Time) []model.History {
+		histories := make([]model.History, n)
+		for i := range histories {
+			histories[i] = model.History{ProjectID: projectID, CandidateID: candidateID, SelectedAt: at}
+		}
+		return histories
+	}
+	join := func(parts ...[]model.History) []model.History {
+		var histories []model.History
+		for _, part := range parts {
+			histories = append(histories, part...)
+		}
+		return histories
+	}
+
+	tests := []struct {
+		name      string
+		window    statsWindow
+		histories []model.History
+		pools     map[uuid.UUID][]uuid.UUID
+		draws     int
+		expected  map[uuid.UUID]float64
+		chiSquare float64 // 小于 0 表示没有公平性检验
+		fair      bool
+	}{
+		{
+			name:      "even",
+			window:    statsWindow{name: "all"},
+			histories: join(draws(project, ann, 5, now), draws(project, ben, 5, now)),
+			pools:     map[uuid.UUID][]uuid.UUID{project: {ann, ben}},
+			draws:     10,
+			expected:  map[uuid.UUID]float64{ann: 5, ben: 5},
+			chiSquare: 0,
+			fair:      true,
+		},
+		{
+			name:      "always the same candidate",
+			window:    statsWindow{name: "all"},
+			histories: draws(project, ann, 10, now),
+			pools:     map[uuid.UUID][]uuid.UUID{project: {ann, ben}},
+			draws:     10,
+			expected:  map[uuid.UUID]float64{ann: 5, ben: 5},
+			chiSquare: 10,
+			fair:      false,
+		},
+		{
+			name:      "window excludes older draws",
+			window:    statsWindow{name: "7d", from: &weekAgo},
+			histories: join(draws(project, ann, 10, weekAgo.Add(-time.Hour)), draws(project, ann, 1, now), draws(project, ben, 1, now)),
+			pools:     map[uuid.UUID][]uuid.UUID{project: {ann, ben}},
+			draws:     2,
+			expected:  map[uuid.UUID]float64{ann: 1, ben: 1},
+			chiSquare: 0,
+			fair:      true,
+		},
+		{
+			name:      "candidate removed from the pool still counts",
+			window:    statsWindow{name: "all"},
+			histories: join(draws(project, ann, 2, now), draws(project, cat, 2, now)),
+			pools:     map[uuid.UUID][]uuid.UUID{project: {ann}},
+			draws:     4,
+			expected:  map[uuid.UUID]float64{ann: 2, cat: 2},
+			chiSquare: 0,
+			fair:      true,
+		},
+		{
+			name:      "expected summed across projects",
+			window:    statsWindow{name: "all"},
+			histories: join(draws(project, ann, 4, now), draws(other, ann, 1, now), draws(other, ben, 1, now), draws(other, cat, 1, now)),
+			pools:     map[uuid.UUID][]uuid.UUID{project: {ann}, other: {ann, ben, cat}},
+			draws:     7,
+			expected:  map[uuid.UUID]float64{ann: 5, ben: 1, cat: 1},
+			chiSquare: 0,
+			fair:      true,
+		},
+		{
+			name:      "single candidate",
+			window:    statsWindow{name: "all"},
+			histories: draws(project, ann, 3, now),
+			pools:     map[uuid.UUID][]uuid.UUID{project: {ann}},
+			draws:     3,
+			expected:  map[uuid.UUID]float64{ann: 3},
+			chiSquare: -1,
+		},
+		{
+			name:      "no draws",
+			window:    statsWindow{name: "all"},
+			pools:     map[uuid.UUID][]uuid.UUID{project: {ann, ben}},
+			expected:  map[uuid.UUID]float64{ann: 0, ben: 0},
+			chiSquare: -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := windowStats(tt.window, tt.histories, tt.pools, names)
+			if got.Window != tt.window.name || got.Draws != tt.draws {
+				t.Errorf("window = %q, draws = %d, want %q and %d", got.Window, got.Draws, tt.window.name, tt.draws)
+			}
+			if len(got.Counts) != len(tt.expected) {
+				t.Fatalf("counts = %+v, want %d candidates", got.Counts, len(tt.expected))
+			}
+			for i, count := range got.Counts {
+				if want, ok := tt.expected[count.CandidateID]; !ok || math.Abs(count.Expected-want) > 1e-9 {
+					t.Errorf("%s expected = %v, want %v", count.Name, count.Expected, want)
+				}
+				if i > 0 && count.Count > got.Counts[i-1].Count {
+					t.Errorf("counts not sorted by count: %+v", got.Counts)
+				}
+			}
+
+			if tt.chiSquare < 0 {
+				if got.Fairness != nil {
+					t.Errorf("fairness = %+v, want none", got.Fairness)
+				}
+				return
+			}
+			if got.Fairness == nil {
+				t.Fatal("fairness = nil")
+			}
+			if math.Abs(got.Fairness.ChiSquare-tt.chiSquare) > 1e-9 || got.Fairness.Fair != tt.fair {
+				t.Errorf("fairness = %+v, want chi-square %v and fair %v", got.Fairness, tt.chiSquare, tt.fair)
+			}
+			if got.Fairness.DegreesOfFreedom != len(tt.expected)-1 {
+				t.Errorf("degrees of freedom = %d, want %d", got.Fairness.DegreesOfFreedom, len(tt.expected)-1)
+			}
+		})
+	}
+}
+
+func TestParseStatsWindows(t *testing.T) {
+	now := time.Date(2025, 3, 10, 20, 0, 0, 0, time.Local)
+	tests := []struct {
+		spec    string
+		want    []string
+		from    []time.Time // 与 want 对应，零值表示全部历史
+		wantErr bool
+	}{
+		{spec: "", want: []string{"7d", "30d", "all"}, from: []time.Time{now.AddDate(0, 0, -7), now.AddDate(0, 0, -30), {}}},
+		{spec: "2w, all", want: []string{"2w", "all"}, from: []time.Time{now.AddDate(0, 0, -14), {}}},
+		{spec: "0d", wantErr: true},
+		{spec: "3651d", wantErr: true},
+		{spec: "5m", wantErr: true},
+		{spec: "d", wantErr: true},
+		{spec: "7d,,all", wantErr: true},
+		{spec: "1d,2d,3d,4d,5d,6d", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			windows, err := parseStatsWindows(tt.spec, now)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidStatsQuery) {
+					t.Errorf("parseStatsWindows(%q) error = %v, want ErrInvalidStatsQuery", tt.spec, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseStatsWindows(%q): %v", tt.spec, err)
+			}
+			if len(windows) != len(tt.want) {
+				t.Fatalf("parseStatsWindows(%q) = %+v, want %v", tt.spec, windows, tt.want)
+			}
+			for i, window := range windows {
+				if window.name != tt.want[i] {
+					t.Errorf("window %d = %q, want %q", i, window.name, tt.want[i])
+				}
+				if tt.from[i].IsZero() != (window.from == nil) || (window.from != nil && !window.from.Equal(tt.from[i])) {
+					t.Errorf("window %q from = %v, want %v", window.name, window.from, tt.from[i])
+				}
+			}
+		})
+	}
+}
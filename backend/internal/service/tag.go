@@ -0,0 +1,288 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"whotakesshowers/internal/model"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// 每个候选人标签的最大数量
+	maxCandidateTags = 20
+	// 项目标签查询的最大长度
+	maxTagQueryLength = 500
+)
+
+// 标签查询的运算符，不区分大小写，不能用作标签
+const (
+	tagQueryAnd = "AND"
+	tagQueryOr  = "OR"
+	tagQueryNot = "NOT"
+)
+
+// 标签由小写字母、数字、- 和 _ 组成，以字母或数字开头
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,29}$`)
+
+// normalizeTag 将标签转为小写并校验，无效时 ok 为 false
+func normalizeTag(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if !tagPattern.MatchString(tag) {
+		return "", false
+	}
+	switch strings.ToUpper(tag) {
+	case tagQueryAnd, tagQueryOr, tagQueryNot:
+		return "", false
+	}
+	return tag, true
+}
+
+// encodeCandidateTags 校验标签并编码为 Candidate.Tags，去重并排序
+func encodeCandidateTags(tags []string) (string, error) {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		value, ok := normalizeTag(tag)
+		if !ok {
+			return "", fmt.Errorf("%w: invalid tag %q", ErrInvalidCandidateProfile, tag)
+		}
+		normalized = append(normalized, value)
+	}
+	slices.Sort(normalized)
+	normalized = slices.Compact(normalized)
+	if len(normalized) > maxCandidateTags {
+		return "", fmt.Errorf("%w: at most %d tags", ErrInvalidCandidateProfile, maxCandidateTags)
+	}
+	if len(normalized) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(normalized)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// candidateTags 解析候选人的标签
+func candidateTags(candidate *model.Candidate) ([]string, error) {
+	var tags []string
+	if candidate.Tags != "" {
+		if err := json.Unmarshal([]byte(candidate.Tags), &tags); err != nil {
+			return nil, err
+		}
+	}
+	return tags, nil
+}
+
+// TagCount 标签及使用它的候选人数量
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// Tags 列出用户的候选人使用的所有标签，按标签排序；回收站中的候选人不计入
+func (s *CandidateService) Tags(userID uuid.UUID) ([]TagCount, error) {
+	candidates, err := s.store.Candidates().List(userID)
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	for _, candidate := range candidates {
+		tags, err := candidateTags(&candidate)
+		if err != nil {
+			return nil, err
+		}
+		for _, tag := range tags {
+			counts[tag]++
+		}
+	}
+
+	result := make([]TagCount, 0, len(counts))
+	for tag, count := range counts {
+		result = append(result, TagCount{Tag: tag, Count: count})
+	}
+	slices.SortFunc(result, func(a, b TagCount) int { return strings.Compare(a.Tag, b.Tag) })
+	return result, nil
+}
+
+// tagQuery 解析后的标签查询表达式
+type tagQuery struct {
+	op       string // AND、OR、NOT，为空时是单个标签
+	tag      string
+	operands []*tagQuery
+}
+
+// match 判断标签集合是否满足查询
+func (q *tagQuery) match(tags map[string]bool) bool {
+	switch q.op {
+	case tagQueryAnd:
+		for _, operand := range q.operands {
+			if !operand.match(tags) {
+				return false
+			}
+		}
+		return true
+	case tagQueryOr:
+		for _, operand := range q.operands {
+			if operand.match(tags) {
+				return true
+			}
+		}
+		return false
+	case tagQueryNot:
+		return !q.operands[0].match(tags)
+	default:
+		return tags[q.tag]
+	}
+}
+
+// tagQueryParser 标签查询的递归下降解析器，优先级 NOT > AND > OR，可以用括号分组
+type tagQueryParser struct {
+	tokens []string
+	pos    int
+}
+
+// parseTagQuery 解析标签查询，例如 kids AND NOT away、(kids OR cousins) AND weekday
+func parseTagQuery(query string) (*tagQuery, error) {
+	if len(query) > maxTagQueryLength {
+		return nil, fmt.Errorf("%w: longer than %d characters", ErrInvalidTagQuery, maxTagQueryLength)
+	}
+	tokens := strings.Fields(strings.NewReplacer("(", " ( ", ")", " ) ").Replace(query))
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("%w: query is empty", ErrInvalidTagQuery)
+	}
+	p := &tagQueryParser{tokens: tokens}
+	q, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidTagQuery, p.tokens[p.pos])
+	}
+	return q, nil
+}
+
+// peek 返回下一个记号，运算符转为大写；没有更多记号时返回空字符串
+func (p *tagQueryParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	token := p.tokens[p.pos]
+	switch upper := strings.ToUpper(token); upper {
+	case tagQueryAnd, tagQueryOr, tagQueryNot:
+		return upper
+	}
+	return token
+}
+
+func (p *tagQueryParser) parseOr() (*tagQuery, error) {
+	return p.parseBinary(tagQueryOr, p.parseAnd)
+}
+
+func (p *tagQueryParser) parseAnd() (*tagQuery, error) {
+	return p.parseBinary(tagQueryAnd, p.parseUnary)
+}
+
+// parseBinary 解析由 op 连接的一个或多个操作数
+func (p *tagQueryParser) parseBinary(op string, operand func() (*tagQuery, error)) (*tagQuery, error) {
+	first, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	q := &tagQuery{op: op, operands: []*tagQuery{first}}
+	for p.peek() == op {
+		p.pos++
+		next, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		q.operands = append(q.operands, next)
+	}
+	if len(q.operands) == 1 {
+		return first, nil
+	}
+	return q, nil
+}
+
+func (p *tagQueryParser) parseUnary() (*tagQuery, error) {
+	token := p.peek()
+	switch token {
+	case "":
+		return nil, fmt.Errorf("%w: unexpected end of query", ErrInvalidTagQuery)
+	case tagQueryNot:
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &tagQuery{op: tagQueryNot, operands: []*tagQuery{operand}}, nil
+	case "(":
+		p.pos++
+		q, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("%w: missing closing parenthesis", ErrInvalidTagQuery)
+		}
+		p.pos++
+		return q, nil
+	}
+	tag, ok := normalizeTag(token)
+	if !ok {
+		return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidTagQuery, token)
+	}
+	p.pos++
+	return &tagQuery{tag: tag}, nil
+}
+
+// EncodeTagQuery 校验标签查询并规范化为 Project.TagQuery：运算符大写、标签小写、空白合并；
+// 空查询表示项目使用手动维护的候选人列表，无效时返回 ErrInvalidTagQuery
+func EncodeTagQuery(query string) (string, error) {
+	if strings.TrimSpace(query) == "" {
+		return "", nil
+	}
+	if _, err := parseTagQuery(query); err != nil {
+		return "", err
+	}
+	tokens := strings.Fields(strings.NewReplacer("(", " ( ", ")", " ) ").Replace(query))
+	var b strings.Builder
+	for i, token := range tokens {
+		if i > 0 && token != ")" && tokens[i-1] != "(" {
+			b.WriteByte(' ')
+		}
+		switch upper := strings.ToUpper(token); upper {
+		case tagQueryAnd, tagQueryOr, tagQueryNot:
+			b.WriteString(upper)
+		default:
+			b.WriteString(strings.ToLower(token))
+		}
+	}
+	return b.String(), nil
+}
+
+// tagMembers 返回满足标签查询的候选人
+func tagMembers(query string, candidates []model.Candidate) ([]model.Candidate, error) {
+	q, err := parseTagQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	members := make([]model.Candidate, 0, len(candidates))
+	for _, candidate := range candidates {
+		tags, err := candidateTags(&candidate)
+		if err != nil {
+			return nil, err
+		}
+		set := make(map[string]bool, len(tags))
+		for _, tag := range tags {
+			set[tag] = true
+		}
+		if q.match(set) {
+			members = append(members, candidate)
+		}
+	}
+	return members, nil
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// formatTagQuery 把解析结果写成完全加括号的形式，便于检查优先级
+func formatTagQuery(q *tagQuery) string {
+	switch q.op {
+	case "":
+		return q.tag
+	case tagQueryNot:
+		return "NOT " + formatTagQuery(q.operands[0])
+	}
+	parts := make([]string, len(q.operands))
+	for i, operand := range q.operands {
+		parts[i] = formatTagQuery(operand)
+	}
+	return "(" + strings.Join(parts, " "+q.op+" ") + ")"
+}
+
+func TestParseTagQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "kids", want: "kids"},
+		{query: "Kids", want: "kids"},
+		{query: "kids AND NOT away", want: "(kids AND NOT away)"},
+		{query: "kids and not away", want: "(kids AND NOT away)"},
+		// AND 优先于 OR
+		{query: "a OR b AND c", want: "(a OR (b AND c))"},
+		{query: "a AND b OR c", want: "((a AND b) OR c)"},
+		// NOT 优先于 AND
+		{query: "NOT a AND b", want: "(NOT a AND b)"},
+		{query: "NOT NOT a", want: "NOT NOT a"},
+		{query: "(a OR b) AND c", want: "((a OR b) AND c)"},
+		{query: "NOT (a OR b)", want: "NOT (a OR b)"},
+		{query: "a AND b AND c", want: "(a AND b AND c)"},
+		{query: "((a))", want: "a"},
+		{query: "(kids OR cousins)AND weekday", want: "((kids OR cousins) AND weekday)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := parseTagQuery(tt.query)
+			if err != nil {
+				t.Fatalf("parseTagQuery(%q): %v", tt.query, err)
+			}
+			if got := formatTagQuery(q); got != tt.want {
+				t.Errorf("parseTagQuery(%q) = %s, want %s", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTagQueryErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string // 错误信息包含的内容
+	}{
+		{name: "empty", query: "   ", want: "query is empty"},
+		{name: "trailing operator", query: "kids AND", want: "unexpected end of query"},
+		{name: "leading operator", query: "OR kids", want: `unexpected "OR"`},
+		{name: "missing operator", query: "kids away", want: `unexpected "away"`},
+		{name: "unclosed parenthesis", query: "(kids OR away", want: "missing closing parenthesis"},
+		{name: "extra closing parenthesis", query: "kids)", want: `unexpected ")"`},
+		{name: "empty parentheses", query: "()", want: `unexpected ")"`},
+		{name: "invalid tag", query: "kids AND a.b", want: `unexpected "a.b"`},
+		{name: "dangling not", query: "kids AND NOT", want: "unexpected end of query"},
+		{name: "too long", query: strings.Repeat("a OR ", maxTagQueryLength/5) + "a", want: "longer than"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseTagQuery(tt.query)
+			if !errors.Is(err, ErrInvalidTagQuery) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parseTagQuery(%q) error = %v, want ErrInvalidTagQuery containing %q", tt.query, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTagQueryMatch(t *testing.T) {
+	tests := []struct {
+		query string
+		tags  []string
+		want  bool
+	}{
+		{query: "kids AND NOT away", tags: []string{"kids"}, want: true},
+		{query: "kids AND NOT away", tags: []string{"kids", "away"}, want: false},
+		{query: "kids AND NOT away", tags: nil, want: false},
+		{query: "kids OR cousins AND weekday", tags: []string{"kids"}, want: true},
+		{query: "kids OR cousins AND weekday", tags: []string{"cousins"}, want: false},
+		{query: "(kids OR cousins) AND weekday", tags: []string{"kids"}, want: false},
+		{query: "(kids OR cousins) AND weekday", tags: []string{"cousins", "weekday"}, want: true},
+		{query: "NOT away", tags: nil, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query+" "+strings.Join(tt.tags, ","), func(t *testing.T) {
+			q, err := parseTagQuery(tt.query)
+			if err != nil {
+				t.Fatalf("parseTagQuery(%q): %v", tt.query, err)
+			}
+			set := make(map[string]bool, len(tt.tags))
+			for _, tag := range tt.tags {
+				set[tag] = true
+			}
+			if got := q.match(set); got != tt.want {
+				t.Errorf("match(%v) = %v, want %v", tt.tags, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeTagQuery(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    string
+		wantErr bool
+	}{
+		{query: "", want: ""},
+		{query: "  ", want: ""},
+		{query: "Kids  and not Away", want: "kids AND NOT away"},
+		{query: "( kids OR cousins )and weekday", want: "(kids OR cousins) AND weekday"},
+		{query: "NOT (a OR b)", want: "NOT (a OR b)"},
+		{query: "kids AND", wantErr: true},
+		{query: "and", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := EncodeTagQuery(tt.query)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidTagQuery) {
+					t.Errorf("EncodeTagQuery(%q) error = %v, want ErrInvalidTagQuery", tt.query, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("EncodeTagQuery(%q) = %q, %v, want %q", tt.query, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeCandidateTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		tags    []string
+		want    string
+		wantErr bool
+	}{
+		{name: "none", tags: []string{}, want: ""},
+		{name: "normalized", tags: []string{" Kids ", "away", "kids"}, want: `["away","kids"]`},
+		{name: "operator", tags: []string{"not"}, wantErr: true},
+		{name: "invalid character", tags: []string{"a b"}, wantErr: true},
+		{name: "leading dash", tags: []string{"-kids"}, wantErr: true},
+		{name: "too long", tags: []string{strings.Repeat("a", 31)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := encodeCandidateTags(tt.tags)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidCandidateProfile) {
+					t.Errorf("encodeCandidateTags(%q) error = %v, want ErrInvalidCandidateProfile", tt.tags, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("encodeCandidateTags(%q) = %s, %v, want %s", tt.tags, got, err, tt.want)
+			}
+		})
+	}
+}
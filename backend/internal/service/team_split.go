@@ -0,0 +1,265 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"time"
+	"whotakesshowers/internal/logger"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// 不能分在同一组的候选人对的最大数量
+	maxKeepApartPairs = 50
+	// 随机分组满足 keep_apart 的最大尝试次数
+	maxSplitAttempts = 200
+)
+
+// TeamSplitRequest 分组请求，group_count 和 group_size 二选一
+type TeamSplitRequest struct {
+	ProjectID  uuid.UUID      `json:"project_id" binding:"required"`
+	GroupCount int            `json:"group_count"` // 分成几组
+	GroupSize  int            `json:"group_size"`  // 每组最多几人
+	KeepApart  [][2]uuid.UUID `json:"keep_apart"`  // 不能分在同一组的候选人对
+}
+
+// TeamSplitResult 分组结果及为每个候选人记录的历史记录
+type TeamSplitResult struct {
+	model.TeamSplit
+	HistoryIDs []uuid.UUID `json:"history_ids"` // 按组和组内顺序排列
+}
+
+// TeamMember 分组中的候选人
+type TeamMember struct {
+	CandidateID   uuid.UUID `json:"candidate_id"`
+	CandidateName string    `json:"candidate_name"`
+}
+
+// Split 把项目中符合资格规则的候选人随机分成人数均衡（相差不超过 1）的若干组，并记录分组
+func (s *RandomizeService) Split(req *TeamSplitRequest, userID uuid.UUID) (*TeamSplitResult, error) {
+	project, err := s.store.Projects().Get(req.ProjectID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrProjectNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	candidates, _, err := drawCandidates(s.store, project, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) < 2 {
+		return nil, fmt.Errorf("%w: the project needs at least 2 eligible candidates", ErrInvalidTeamSplit)
+	}
+
+	groupCount, err := splitGroupCount(req, len(candidates))
+	if err != nil {
+		return nil, err
+	}
+	apart, err := keepApartPairs(req.KeepApart, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups [][]model.Candidate
+	for attempt := 0; attempt < maxSplitAttempts && groups == nil; attempt++ {
+		groups = assignGroups(candidates, groupCount, apart)
+	}
+	if groups == nil {
+		return nil, ErrTeamSplitUnsatisfiable
+	}
+
+	members := make([][]TeamMember, len(groups))
+	for i, group := range groups {
+		members[i] = make([]TeamMember, len(group))
+		for j, candidate := range group {
+			members[i][j] = TeamMember{CandidateID: candidate.ID, CandidateName: candidate.Name}
+		}
+	}
+	keepApart := req.KeepApart
+	if keepApart == nil {
+		keepApart = [][2]uuid.UUID{}
+	}
+	keepApartJSON, err := json.Marshal(keepApart)
+	if err != nil {
+		return nil, err
+	}
+
+	split := &model.TeamSplit{
+		UserID:      userID,
+		ProjectID:   project.ID,
+		ProjectName: project.Name,
+		GroupCount:  groupCount,
+		KeepApart:   string(keepApartJSON),
+		CreatedAt:   time.Now(),
+	}
+	var result *TeamSplitResult
+	err = s.store.Transaction(func(tx store.Store) error {
+		result, err = recordSplit(tx, split, members)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Team split recorded",
+		zap.String("project_id", project.ID.String()),
+		zap.Int("candidates", len(candidates)),
+		zap.Int("groups", groupCount),
+	)
+	return result, nil
+}
+
+// recordSplit 保存分组记录，并为每个候选人记录一条来源为 split 的历史记录；
+// 这些记录不积分，选中时的候选人池为参与分组的候选人
+func recordSplit(tx store.Store, split *model.TeamSplit, members [][]TeamMember) (*TeamSplitResult, error) {
+	groupsJSON, err := json.Marshal(members)
+	if err != nil {
+		return nil, err
+	}
+	split.Groups = string(groupsJSON)
+	if err := tx.TeamSplits().Create(split); err != nil {
+		return nil, err
+	}
+
+	var pool []uuid.UUID
+	for _, group := range members {
+		for _, member := range group {
+			pool = append(pool, member.CandidateID)
+		}
+	}
+	poolJSON, err := json.Marshal(pool)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TeamSplitResult{TeamSplit: *split, HistoryIDs: make([]uuid.UUID, 0, len(pool))}
+	for i, group := range members {
+		for _, member := range group {
+			history := &model.History{
+				ProjectID:        split.ProjectID,
+				ProjectName:      split.ProjectName,
+				CandidateID:      member.CandidateID,
+				CandidateName:    member.CandidateName,
+				SelectedAt:       split.CreatedAt,
+				UserID:           split.UserID,
+				Source:           model.HistorySourceSplit,
+				SplitID:          &split.ID,
+				SplitGroup:       &i,
+				PoolCandidateIDs: string(poolJSON),
+			}
+			if err := tx.Histories().Create(history); err != nil {
+				return nil, err
+			}
+			result.HistoryIDs = append(result.HistoryIDs, history.ID)
+		}
+	}
+	return result, nil
+}
+
+// Splits 分页获取分组记录，可按项目过滤
+func (s *RandomizeService) Splits(userID uuid.UUID, projectID *uuid.UUID, page store.PageRequest) (*store.Page[model.TeamSplit], error) {
+	return s.store.TeamSplits().Find(userID, projectID, page)
+}
+
+// splitGroupCount 按 group_count 或 group_size 计算组数，至少 2 组且每组至少 1 人
+func splitGroupCount(req *TeamSplitRequest, candidates int) (int, error) {
+	switch {
+	case req.GroupCount > 0 && req.GroupSize > 0:
+		return 0, fmt.Errorf("%w: specify either group_count or group_size, not both", ErrInvalidTeamSplit)
+	case req.GroupCount > 0:
+		if req.GroupCount < 2 || req.GroupCount > candidates {
+			return 0, fmt.Errorf("%w: group_count must be between 2 and %d", ErrInvalidTeamSplit, candidates)
+		}
+		return req.GroupCount, nil
+	case req.GroupSize > 0:
+		if req.GroupSize >= candidates {
+			return 0, fmt.Errorf("%w: group_size must be less than %d", ErrInvalidTeamSplit, candidates)
+		}
+		return (candidates + req.GroupSize - 1) / req.GroupSize, nil
+	default:
+		return 0, fmt.Errorf("%w: group_count or group_size is required", ErrInvalidTeamSplit)
+	}
+}
+
+// keepApartPairs 校验不能分在同一组的候选人对，返回每个候选人不能同组的候选人
+func keepApartPairs(pairs [][2]uuid.UUID, candidates []model.Candidate) (map[uuid.UUID]map[uuid.UUID]bool, error) {
+	if len(pairs) > maxKeepApartPairs {
+		return nil, fmt.Errorf("%w: at most %d keep_apart pairs", ErrInvalidTeamSplit, maxKeepApartPairs)
+	}
+	inProject := make(map[uuid.UUID]bool, len(candidates))
+	for _, candidate := range candidates {
+		inProject[candidate.ID] = true
+	}
+
+	apart := make(map[uuid.UUID]map[uuid.UUID]bool)
+	for _, pair := range pairs {
+		if pair[0] == pair[1] {
+			return nil, fmt.Errorf("%w: a keep_apart pair needs two different candidates", ErrInvalidTeamSplit)
+		}
+		for i, id := range pair {
+			if !inProject[id] {
+				return nil, fmt.Errorf("%w: candidate %s is not an eligible candidate of the project", ErrInvalidTeamSplit, id)
+			}
+			if apart[id] == nil {
+				apart[id] = make(map[uuid.UUID]bool)
+			}
+			apart[id][pair[1-i]] = true
+		}
+	}
+	return apart, nil
+}
+
+// assignGroups 随机分组一次：各组的人数相差不超过 1，先放置受 keep_apart 限制最多的候选人；
+// 无法满足 keep_apart 时返回 nil
+func assignGroups(candidates []model.Candidate, groupCount int, apart map[uuid.UUID]map[uuid.UUID]bool) [][]model.Candidate {
+	order := make([]model.Candidate, len(candidates))
+	copy(order, candidates)
+	rand.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+	// 稳定排序保留同等限制的候选人之间的随机顺序
+	slices.SortStableFunc(order, func(a, b model.Candidate) int {
+		return len(apart[b.ID]) - len(apart[a.ID])
+	})
+
+	// 前 len%groupCount 个（随机的）组多一人
+	capacity := make([]int, groupCount)
+	for i := range capacity {
+		capacity[i] = len(candidates) / groupCount
+	}
+	for _, i := range rand.Perm(groupCount)[:len(candidates)%groupCount] {
+		capacity[i]++
+	}
+
+	groups := make([][]model.Candidate, groupCount)
+	for _, candidate := range order {
+		var open []int
+		for i, group := range groups {
+			if len(group) < capacity[i] && !conflicts(group, apart[candidate.ID]) {
+				open = append(open, i)
+			}
+		}
+		if len(open) == 0 {
+			return nil
+		}
+		i := open[rand.Intn(len(open))]
+		groups[i] = append(groups[i], candidate)
+	}
+	return groups
+}
+
+// conflicts 组内是否有不能与其同组的候选人
+func conflicts(group []model.Candidate, apart map[uuid.UUID]bool) bool {
+	for _, member := range group {
+		if apart[member.ID] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"whotakesshowers/internal/model"
+
+	"github.com/google/uuid"
+)
+
+// splitCandidates 生成 n 个候选人，名字为 c0、c1……
+func splitCandidates(n int) []model.Candidate {
+	candidates := make([]model.Candidate, n)
+	for i := range candidates {
+		candidates[i] = model.Candidate{ID: uuid.New(), Name: fmt.Sprintf("c%d", i)}
+	}
+	return candidates
+}
+
+func TestSplitGroupCount(t *testing.T) {
+	tests := []struct {
+		name       string
+		req        TeamSplitRequest
+		candidates int
+		want       int
+		wantErr    bool
+	}{
+		{name: "group count", req: TeamSplitRequest{GroupCount: 3}, candidates: 7, want: 3},
+		{name: "one group per candidate", req: TeamSplitRequest{GroupCount: 4}, candidates: 4, want: 4},
+		{name: "group size rounds up", req: TeamSplitRequest{GroupSize: 3}, candidates: 7, want: 3},
+		{name: "group size divides evenly", req: TeamSplitRequest{GroupSize: 2}, candidates: 6, want: 3},
+		{name: "both", req: TeamSplitRequest{GroupCount: 2, GroupSize: 2}, candidates: 4, wantErr: true},
+		{name: "neither", candidates: 4, wantErr: true},
+		{name: "single group", req: TeamSplitRequest{GroupCount: 1}, candidates: 4, wantErr: true},
+		{name: "more groups than candidates", req: TeamSplitRequest{GroupCount: 5}, candidates: 4, wantErr: true},
+		{name: "group size covers everyone", req: TeamSplitRequest{GroupSize: 4}, candidates: 4, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitGroupCount(&tt.req, tt.candidates)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidTeamSplit) {
+					t.Errorf("splitGroupCount() error = %v, want ErrInvalidTeamSplit", err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("splitGroupCount() = %d, %v, want %d", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeepApartPairs(t *testing.T) {
+	c := splitCandidates(3)
+	outsider := uuid.New()
+	tooMany := make([][2]uuid.UUID, maxKeepApartPairs+1)
+	for i := range tooMany {
+		tooMany[i] = [2]uuid.UUID{c[0].ID, c[1].ID}
+	}
+
+	tests := []struct {
+		name    string
+		pairs   [][2]uuid.UUID
+		want    map[uuid.UUID][]uuid.UUID
+		wantErr bool
+	}{
+		{name: "none", want: map[uuid.UUID][]uuid.UUID{}},
+		{
+			name:  "symmetric",
+			pairs: [][2]uuid.UUID{{c[0].ID, c[1].ID}, {c[0].ID, c[2].ID}},
+			want:  map[uuid.UUID][]uuid.UUID{c[0].ID: {c[1].ID, c[2].ID}, c[1].ID: {c[0].ID}, c[2].ID: {c[0].ID}},
+		},
+		{name: "same candidate", pairs: [][2]uuid.UUID{{c[0].ID, c[0].ID}}, wantErr: true},
+		{name: "not in project", pairs: [][2]uuid.UUID{{c[0].ID, outsider}}, wantErr: true},
+		{name: "too many pairs", pairs: tooMany, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apart, err := keepApartPairs(tt.pairs, c)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidTeamSplit) {
+					t.Errorf("keepApartPairs() error = %v, want ErrInvalidTeamSplit", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("keepApartPairs(): %v", err)
+			}
+			if len(apart) != len(tt.want) {
+				t.Fatalf("keepApartPairs() = %v, want %v", apart, tt.want)
+			}
+			for id, others := range tt.want {
+				if len(apart[id]) != len(others) {
+					t.Errorf("apart[%s] = %v, want %v", id, apart[id], others)
+				}
+				for _, other := range others {
+					if !apart[id][other] {
+						t.Errorf("apart[%s] = %v, want %v", id, apart[id], others)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestAssignGroups(t *testing.T) {
+	c := splitCandidates(7)
+	pairs := func(p ...[2]int) [][2]uuid.UUID {
+		result := make([][2]uuid.UUID, len(p))
+		for i, pair := range p {
+			result[i] = [2]uuid.UUID{c[pair[0]].ID, c[pair[1]].ID}
+		}
+		return result
+	}
+
+	tests := []struct {
+		name          string
+		candidates    int
+		groupCount    int
+		keepApart     [][2]uuid.UUID
+		unsatisfiable bool
+	}{
+		{name: "even split", candidates: 6, groupCount: 2},
+		{name: "uneven split", candidates: 7, groupCount: 3},
+		{name: "one per group", candidates: 4, groupCount: 4},
+		{name: "keep apart", candidates: 4, groupCount: 2, keepApart: pairs([2]int{0, 1}, [2]int{2, 3})},
+		{name: "keep apart chain", candidates: 6, groupCount: 2, keepApart: pairs([2]int{0, 1}, [2]int{1, 2}, [2]int{2, 3}, [2]int{3, 4}, [2]int{4, 5})},
+		{name: "one candidate apart from everyone", candidates: 5, groupCount: 3, keepApart: pairs([2]int{0, 1}, [2]int{0, 2}, [2]int{0, 3}, [2]int{0, 4})},
+		{name: "three mutually apart in two groups", candidates: 4, groupCount: 2, keepApart: pairs([2]int{0, 1}, [2]int{1, 2}, [2]int{0, 2}), unsatisfiable: true},
+		{name: "group too large for keep apart", candidates: 4, groupCount: 2, keepApart: pairs([2]int{0, 1}, [2]int{0, 2}, [2]int{0, 3}), unsatisfiable: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidates := c[:tt.candidates]
+			apart, err := keepApartPairs(tt.keepApart, candidates)
+			if err != nil {
+				t.Fatalf("keepApartPairs(): %v", err)
+			}
+
+			// 与 Split 一样重试，分组是随机的，多跑几次
+			for run := 0; run < 50; run++ {
+				var groups [][]model.Candidate
+				for attempt := 0; attempt < maxSplitAttempts && groups == nil; attempt++ {
+					groups = assignGroups(candidates, tt.groupCount, apart)
+				}
+				if tt.unsatisfiable {
+					if groups != nil {
+						t.Fatalf("assignGroups() = %v, want nil", groups)
+					}
+					continue
+				}
+				if groups == nil {
+					t.Fatal("assignGroups() = nil, want a split")
+				}
+				if len(groups) != tt.groupCount {
+					t.Fatalf("assignGroups() = %d groups, want %d", len(groups), tt.groupCount)
+				}
+
+				placed := make(map[uuid.UUID]bool)
+				small, large := tt.candidates/tt.groupCount, (tt.candidates+tt.groupCount-1)/tt.groupCount
+				for _, group := range groups {
+					if len(group) < small || len(group) > large {
+						t.Fatalf("group sizes unbalanced: %v", groups)
+					}
+					for _, member := range group {
+						if placed[member.ID] {
+							t.Fatalf("%s placed twice: %v", member.Name, groups)
+						}
+						placed[member.ID] = true
+						if conflicts(group, apart[member.ID]) {
+							t.Fatalf("%s grouped with a keep_apart candidate: %v", member.Name, groups)
+						}
+					}
+				}
+				if len(placed) != tt.candidates {
+					t.Fatalf("placed %d candidates, want %d", len(placed), tt.candidates)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package service
+
+import (
+	"errors"
+	"time"
+	"whotakesshowers/internal/logger"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// TrashService 回收站服务：列出、恢复和彻底删除软删除的数据
+type TrashService struct {
+	store store.Store
+}
+
+// NewTrashService 创建回收站服务
+func NewTrashService(s store.Store) *TrashService {
+	return &TrashService{store: s}
+}
+
+// TrashListing 回收站内容
+type TrashListing struct {
+	Projects   []model.Project        `json:"projects"`
+	Candidates []model.Candidate      `json:"candidates"`
+	Photos     []model.CandidatePhoto `json:"photos"`
+}
+
+// List 获取用户回收站中的项目、候选人和单独删除的照片
+func (s *TrashService) List(userID uuid.UUID) (*TrashListing, error) {
+	projects, err := s.store.Projects().ListDeleted(userID)
+	if err != nil {
+		return nil, err
+	}
+	candidates, err := s.store.Candidates().ListDeleted(userID)
+	if err != nil {
+		return nil, err
+	}
+	photos, err := s.store.CandidatePhotos().ListDeleted(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &TrashListing{
+		Projects:   projects,
+		Candidates: candidates,
+		Photos:     photos,
+	}, nil
+}
+
+// RestoreProject 从回收站恢复项目
+func (s *TrashService) RestoreProject(projectID, userID uuid.UUID) error {
+	err := s.store.Projects().Restore(projectID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrProjectNotFound
+	}
+	return err
+}
+
+// RestoreCandidate 从回收站恢复候选人，以及与其一起删除的照片
+func (s *TrashService) RestoreCandidate(candidateID, userID uuid.UUID) error {
+	return s.store.Transaction(func(tx store.Store) error {
+		candidate, err := tx.Candidates().GetDeleted(candidateID, userID)
+		if errors.Is(err, store.ErrNotFound) {
+			return ErrCandidateNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.CandidatePhotos().RestoreByCandidateID(candidateID, candidate.DeletedAt.Time); err != nil {
+			return err
+		}
+		return tx.Candidates().Restore(candidateID, userID)
+	})
+}
+
+// RestorePhoto 从回收站恢复照片；候选人没有头像时恢复的照片成为头像
+func (s *TrashService) RestorePhoto(photoID, userID uuid.UUID) error {
+	return s.store.Transaction(func(tx store.Store) error {
+		photoStore := tx.CandidatePhotos()
+		photo, err := photoStore.GetDeleted(photoID, userID)
+		if errors.Is(err, store.ErrNotFound) {
+			return ErrPhotoNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		candidate, err := getCandidate(tx, photo.CandidateID, userID)
+		if err != nil {
+			return err
+		}
+		if err := photoStore.Restore(photoID); err != nil {
+			return err
+		}
+
+		if candidate.PhotoURL != "" {
+			return nil
+		}
+		if _, err := photoStore.GetAvatar(candidate.ID); err == nil {
+			return nil
+		} else if !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+		return promoteAvatar(tx, candidate.ID, userID, photo)
+	})
+}
+
+// PurgeProject 彻底删除回收站中的项目
+func (s *TrashService) PurgeProject(projectID, userID uuid.UUID) error {
+	project, err := s.store.Projects().GetDeleted(projectID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrProjectNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return s.purgeProject(project)
+}
+
+// PurgeCandidate 彻底删除回收站中的候选人
+func (s *TrashService) PurgeCandidate(candidateID, userID uuid.UUID) error {
+	candidate, err := s.store.Candidates().GetDeleted(candidateID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrCandidateNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return s.purgeCandidate(candidate)
+}
+
+// PurgePhoto 彻底删除回收站中的照片
+func (s *TrashService) PurgePhoto(photoID, userID uuid.UUID) error {
+	photo, err := s.store.CandidatePhotos().GetDeleted(photoID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrPhotoNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return s.purgePhoto(photo)
+}
+
+// PurgeExpired 彻底删除在 before 之前移入回收站的所有数据，返回删除的条数
+func (s *TrashService) PurgeExpired(before time.Time) (int, error) {
+	purged := 0
+
+	photos, err := s.store.CandidatePhotos().ListExpired(before)
+	if err != nil {
+		return purged, err
+	}
+	for i := range photos {
+		if err := s.purgePhoto(&photos[i]); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	candidates, err := s.store.Candidates().ListExpired(before)
+	if err != nil {
+		return purged, err
+	}
+	for i := range candidates {
+		if err := s.purgeCandidate(&candidates[i]); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	projects, err := s.store.Projects().ListExpired(before)
+	if err != nil {
+		return purged, err
+	}
+	for i := range projects {
+		if err := s.purgeProject(&projects[i]); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+// StartPurger 启动后台任务，每隔 interval 彻底删除超过保留期 retention 的回收站数据
+// retention 或 interval 不大于 0 时不启动
+func (s *TrashService) StartPurger(retention, interval time.Duration) {
+	if retention <= 0 || interval <= 0 {
+		logger.Warn("Trash purger disabled",
+			zap.Duration("retention", retention),
+			zap.Duration("interval", interval),
+		)
+		return
+	}
+
+	run := func() {
+		purged, err := s.PurgeExpired(time.Now().Add(-retention))
+		if err != nil {
+			logger.Error("Failed to purge trash", zap.Error(err))
+			return
+		}
+		if purged > 0 {
+			logger.Info("Purged expired trash", zap.Int("count", purged))
+		}
+	}
+
+	go func() {
+		run()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			run()
+		}
+	}()
+}
+
+// purgeProject 在事务中彻底删除项目及其历史记录、分组记录和项目间约束，并从例程的步骤中移除
+func (s *TrashService) purgeProject(project *model.Project) error {
+	return s.store.Transaction(func(tx store.Store) error {
+		if err := tx.Histories().DeleteByProject(project.ID, project.UserID); err != nil {
+			return err
+		}
+		if err := tx.Routines().RemoveProject(project.ID, project.UserID); err != nil {
+			return err
+		}
+		if err := tx.ProjectConstraints().DeleteByProject(project.ID); err != nil {
+			return err
+		}
+		if err := tx.TeamSplits().DeleteByProject(project.ID); err != nil {
+			return err
+		}
+		return tx.Projects().Purge(project.ID, project.UserID)
+	})
+}
+
+// purgeCandidate 在事务中彻底删除候选人、照片记录、积分流水、徽章和项目中的引用，提交后删除照片文件
+func (s *TrashService) purgeCandidate(candidate *model.Candidate) error {
+	var photos []model.CandidatePhoto
+	err := s.store.Transaction(func(tx store.Store) error {
+		photoStore := tx.CandidatePhotos()
+		var err error
+		photos, err = photoStore.ListAll(candidate.ID)
+		if err != nil {
+			return err
+		}
+		if err := photoStore.PurgeByCandidateID(candidate.ID); err != nil {
+			return err
+		}
+		if err := tx.PointEntries().DeleteByCandidate(candidate.ID); err != nil {
+			return err
+		}
+		if err := tx.Badges().DeleteByCandidate(candidate.ID); err != nil {
+			return err
+		}
+		if err := tx.Projects().RemoveCandidate(candidate.ID, candidate.UserID); err != nil {
+			return err
+		}
+		return tx.Candidates().Purge(candidate.ID, candidate.UserID)
+	})
+	if err != nil {
+		return err
+	}
+
+	removeUpload(candidate.PhotoURL)
+	for _, photo := range photos {
+		removeUpload(photo.PhotoURL)
+	}
+	return nil
+}
+
+// purgePhoto 彻底删除照片记录，成功后删除照片文件
+func (s *TrashService) purgePhoto(photo *model.CandidatePhoto) error {
+	if err := s.store.CandidatePhotos().Purge(photo.ID); err != nil {
+		return err
+	}
+	removeUpload(photo.PhotoURL)
+	return nil
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"whotakesshowers/internal/config"
+	"whotakesshowers/internal/logger"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// UploadDir 上传文件的存储目录
+const UploadDir = "./uploads"
+
+// uploadURLPrefix 上传文件对外访问的URL前缀
+const uploadURLPrefix = "/uploads/"
+
+var (
+	// ErrPhotoTooLarge 照片超过上传大小限制
+	ErrPhotoTooLarge = errors.New("photo is too large")
+	// ErrPhotoType 照片不是允许的图片类型
+	ErrPhotoType = errors.New("photo type is not allowed")
+)
+
+// photoExtensions 图片类型对应的扩展名；保存的文件名只使用这些扩展名，不沿用上传或归档中的文件名，
+// 避免上传目录中出现可被浏览器执行的 .html、.svg 等文件
+var photoExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// PhotoUploads 按上传配置校验照片的大小和类型并写入上传目录，上传接口和数据导入共用
+type PhotoUploads struct {
+	maxSize      int64
+	allowedTypes []string
+}
+
+// NewPhotoUploads 根据上传配置创建照片存储
+func NewPhotoUploads(cfg config.UploadConfig) *PhotoUploads {
+	return &PhotoUploads{maxSize: cfg.MaxSize, allowedTypes: cfg.AllowedTypes}
+}
+
+// Read 读取照片内容，最多读取 MaxSize+1 字节；超过大小限制或内容不是允许的图片类型时返回错误。
+// 类型根据内容判断，返回对应的扩展名
+func (u *PhotoUploads) Read(r io.Reader) ([]byte, string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, u.maxSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(data)) > u.maxSize {
+		return nil, "", fmt.Errorf("%w (max %d bytes)", ErrPhotoTooLarge, u.maxSize)
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := photoExtensions[contentType]
+	if !ok || !slices.Contains(u.allowedTypes, contentType) {
+		return nil, "", fmt.Errorf("%w: %s", ErrPhotoType, contentType)
+	}
+	return data, ext, nil
+}
+
+// Save 校验照片并以随机文件名写入上传目录，返回照片 URL
+func (u *PhotoUploads) Save(r io.Reader) (string, error) {
+	data, ext, err := u.Read(r)
+	if err != nil {
+		return "", err
+	}
+	return writeUpload(data, ext)
+}
+
+// Remove 删除已保存的照片，用于后续步骤失败时清理
+func (u *PhotoUploads) Remove(photoURL string) {
+	removeUpload(photoURL)
+}
+
+// writeUpload 以随机文件名将内容写入上传目录，返回文件 URL
+func writeUpload(data []byte, ext string) (string, error) {
+	if err := os.MkdirAll(UploadDir, 0755); err != nil {
+		return "", err
+	}
+	name := uuid.New().String() + ext
+	f, err := os.OpenFile(filepath.Join(UploadDir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", err
+	}
+	photoURL := uploadURLPrefix + name
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		removeUpload(photoURL)
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		removeUpload(photoURL)
+		return "", err
+	}
+	return photoURL, nil
+}
+
+// removeUpload 删除URL指向的上传文件；非本地上传的URL会被忽略
+func removeUpload(photoURL string) {
+	if !strings.HasPrefix(photoURL, uploadURLPrefix) {
+		return
+	}
+
+	name := path.Base(photoURL)
+	if name == "." || name == "/" || name == ".." {
+		return
+	}
+
+	if err := os.Remove(filepath.Join(UploadDir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Warn("Failed to remove uploaded file",
+			zap.String("photo_url", photoURL),
+			zap.Error(err),
+		)
+	}
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// UserService 用户服务：注册、登录校验
+type UserService struct {
+	users store.UserRepository
+}
+
+// NewUserService 创建用户服务
+func NewUserService(users store.UserRepository) *UserService {
+	return &UserService{users: users}
+}
+
+// Register 注册新用户
+func (s *UserService) Register(username, email, password string) (*model.User, error) {
+	// 检查用户名是否已存在
+	if _, err := s.users.GetByUsername(username); err == nil {
+		return nil, ErrUsernameTaken
+	}
+
+	// 检查邮箱是否已存在（如果提供了邮箱）
+	if email != "" {
+		if _, err := s.users.GetByEmail(email); err == nil {
+			return nil, ErrEmailTaken
+		}
+	}
+
+	// 加密密码
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &model.User{
+		Username: username,
+		Email:    email,
+		Password: string(hashedPassword),
+	}
+	if err := s.users.Create(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Authenticate 校验用户名和密码，失败时返回 ErrInvalidCredentials
+func (s *UserService) Authenticate(username, password string) (*model.User, error) {
+	user, err := s.users.GetByUsername(username)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+// Get 获取用户信息
+func (s *UserService) Get(id uuid.UUID) (*model.User, error) {
+	return s.users.Get(id)
+}
+
+// EnsureDefaultUser 创建默认用户（如果不存在）
+func (s *UserService) EnsureDefaultUser() error {
+	if _, err := s.users.GetByUsername("default"); err == nil {
+		return nil
+	}
+
+	defaultUser := &model.User{
+		Username: "default",
+	}
+	if err := s.users.Create(defaultUser); err != nil {
+		return err
+	}
+	log.Printf("Default user created: %s", defaultUser.ID)
+	return nil
+}
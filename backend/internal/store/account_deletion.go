@@ -0,0 +1,22 @@
+package store
+
+import (
+	"whotakesshowers/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// AccountDeletionStore 账号删除审计记录存储
+type AccountDeletionStore struct {
+	db *gorm.DB
+}
+
+// NewAccountDeletionStore 创建绑定到指定数据库连接（或事务）的审计记录存储
+func NewAccountDeletionStore(db *gorm.DB) *AccountDeletionStore {
+	return &AccountDeletionStore{db: db}
+}
+
+// Create 创建审计记录
+func (s *AccountDeletionStore) Create(deletion *model.AccountDeletion) error {
+	return s.db.Create(deletion).Error
+}
@@ -0,0 +1,110 @@
+package store
+
+import (
+	"whotakesshowers/internal/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BadgeRuleStore 徽章规则存储
+type BadgeRuleStore struct {
+	db *gorm.DB
+}
+
+// NewBadgeRuleStore 创建绑定到指定数据库连接（或事务）的徽章规则存储
+func NewBadgeRuleStore(db *gorm.DB) *BadgeRuleStore {
+	return &BadgeRuleStore{db: db}
+}
+
+// List 获取用户的徽章规则，按创建时间排序
+func (s *BadgeRuleStore) List(userID uuid.UUID) ([]model.BadgeRule, error) {
+	var rules []model.BadgeRule
+	err := s.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&rules).Error
+	return rules, err
+}
+
+// Get 获取徽章规则
+func (s *BadgeRuleStore) Get(id uuid.UUID, userID uuid.UUID) (*model.BadgeRule, error) {
+	var rule model.BadgeRule
+	err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&rule).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// Create 创建徽章规则
+func (s *BadgeRuleStore) Create(rule *model.BadgeRule) error {
+	return s.db.Create(rule).Error
+}
+
+// Update 更新徽章规则
+func (s *BadgeRuleStore) Update(rule *model.BadgeRule) error {
+	return s.db.Save(rule).Error
+}
+
+// Delete 删除徽章规则，规则不存在时返回 ErrNotFound
+func (s *BadgeRuleStore) Delete(id uuid.UUID, userID uuid.UUID) error {
+	result := s.db.Where("id = ? AND user_id = ?", id, userID).Delete(&model.BadgeRule{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteByUser 删除用户的所有徽章规则
+func (s *BadgeRuleStore) DeleteByUser(userID uuid.UUID) error {
+	return s.db.Where("user_id = ?", userID).Delete(&model.BadgeRule{}).Error
+}
+
+// BadgeStore 徽章存储
+type BadgeStore struct {
+	db *gorm.DB
+}
+
+// NewBadgeStore 创建绑定到指定数据库连接（或事务）的徽章存储
+func NewBadgeStore(db *gorm.DB) *BadgeStore {
+	return &BadgeStore{db: db}
+}
+
+// List 获取用户已获得的徽章，candidateID 不为空时只返回该候选人的；按获得时间倒序
+func (s *BadgeStore) List(userID uuid.UUID, candidateID *uuid.UUID) ([]model.Badge, error) {
+	var badges []model.Badge
+	query := s.db.Where("user_id = ?", userID)
+	if candidateID != nil {
+		query = query.Where("candidate_id = ?", *candidateID)
+	}
+	err := query.Order("earned_at DESC").Find(&badges).Error
+	return badges, err
+}
+
+// ListByRule 获取规则授予的所有徽章
+func (s *BadgeStore) ListByRule(ruleID uuid.UUID) ([]model.Badge, error) {
+	var badges []model.Badge
+	err := s.db.Where("rule_id = ?", ruleID).Order("earned_at ASC").Find(&badges).Error
+	return badges, err
+}
+
+// Create 创建徽章
+func (s *BadgeStore) Create(badge *model.Badge) error {
+	return s.db.Create(badge).Error
+}
+
+// DeleteByRule 删除规则授予的所有徽章
+func (s *BadgeStore) DeleteByRule(ruleID uuid.UUID) error {
+	return s.db.Where("rule_id = ?", ruleID).Delete(&model.Badge{}).Error
+}
+
+// DeleteByCandidate 删除候选人的所有徽章
+func (s *BadgeStore) DeleteByCandidate(candidateID uuid.UUID) error {
+	return s.db.Where("candidate_id = ?", candidateID).Delete(&model.Badge{}).Error
+}
+
+// DeleteByUser 删除用户的所有徽章
+func (s *BadgeStore) DeleteByUser(userID uuid.UUID) error {
+	return s.db.Where("user_id = ?", userID).Delete(&model.Badge{}).Error
+}
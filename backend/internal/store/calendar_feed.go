@@ -0,0 +1,57 @@
+package store
+
+import (
+	"whotakesshowers/internal/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CalendarFeedStore 日历订阅存储
+type CalendarFeedStore struct {
+	db *gorm.DB
+}
+
+// NewCalendarFeedStore 创建绑定到指定数据库连接（或事务）的日历订阅存储
+func NewCalendarFeedStore(db *gorm.DB) *CalendarFeedStore {
+	return &CalendarFeedStore{db: db}
+}
+
+// List 获取用户的日历订阅
+func (s *CalendarFeedStore) List(userID uuid.UUID) ([]model.CalendarFeed, error) {
+	var feeds []model.CalendarFeed
+	err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&feeds).Error
+	return feeds, err
+}
+
+// GetByTokenHash 根据令牌哈希获取日历订阅
+func (s *CalendarFeedStore) GetByTokenHash(tokenHash string) (*model.CalendarFeed, error) {
+	var feed model.CalendarFeed
+	err := s.db.Where("token_hash = ?", tokenHash).First(&feed).Error
+	if err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+// Create 创建日历订阅
+func (s *CalendarFeedStore) Create(feed *model.CalendarFeed) error {
+	return s.db.Create(feed).Error
+}
+
+// Delete 删除日历订阅，订阅不存在时返回 ErrNotFound
+func (s *CalendarFeedStore) Delete(id uuid.UUID, userID uuid.UUID) error {
+	result := s.db.Where("id = ? AND user_id = ?", id, userID).Delete(&model.CalendarFeed{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteByUser 删除用户的所有日历订阅
+func (s *CalendarFeedStore) DeleteByUser(userID uuid.UUID) error {
+	return s.db.Where("user_id = ?", userID).Delete(&model.CalendarFeed{}).Error
+}
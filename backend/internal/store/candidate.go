@@ -1,27 +1,57 @@
 package store
 
 import (
+	"time"
 	"whotakesshowers/internal/model"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // CandidateStore 候选人存储
-type CandidateStore struct{}
+type CandidateStore struct {
+	db *gorm.DB
+}
 
-var Candidates = &CandidateStore{}
+// NewCandidateStore 创建绑定到指定数据库连接（或事务）的候选人存储
+func NewCandidateStore(db *gorm.DB) *CandidateStore {
+	return &CandidateStore{db: db}
+}
 
 // List 获取候选人列表
 func (s *CandidateStore) List(userID uuid.UUID) ([]model.Candidate, error) {
 	var candidates []model.Candidate
-	err := DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&candidates).Error
+	err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&candidates).Error
 	return candidates, err
 }
 
+// Find 按过滤条件分页获取候选人列表
+func (s *CandidateStore) Find(userID uuid.UUID, filter CandidateFilter, page PageRequest) (*Page[model.Candidate], error) {
+	query := s.db.Model(&model.Candidate{}).Where("user_id = ?", userID)
+	if filter.Query != "" {
+		query = query.Where(`LOWER(name) LIKE ? ESCAPE '\'`, containsPattern(filter.Query))
+	}
+	if filter.Tag != "" {
+		// 标签以 JSON 数组保存，按带引号的完整标签匹配
+		query = query.Where(`tags LIKE ? ESCAPE '\'`, containsPattern(`"`+filter.Tag+`"`))
+	}
+
+	return paginate(query, page, CandidateSortFields, func(c *model.Candidate, field string) (any, uuid.UUID) {
+		switch field {
+		case "name":
+			return c.Name, c.ID
+		case "updated_at":
+			return c.UpdatedAt, c.ID
+		default:
+			return c.CreatedAt, c.ID
+		}
+	})
+}
+
 // Get 获取候选人详情
 func (s *CandidateStore) Get(id uuid.UUID, userID uuid.UUID) (*model.Candidate, error) {
 	var candidate model.Candidate
-	err := DB.Where("id = ? AND user_id = ?", id, userID).First(&candidate).Error
+	err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&candidate).Error
 	if err != nil {
 		return nil, err
 	}
@@ -31,28 +61,122 @@ func (s *CandidateStore) Get(id uuid.UUID, userID uuid.UUID) (*model.Candidate,
 // GetByIDs 根据ID列表获取候选人
 func (s *CandidateStore) GetByIDs(ids []uuid.UUID, userID uuid.UUID) ([]model.Candidate, error) {
 	var candidates []model.Candidate
-	err := DB.Where("id IN ? AND user_id = ?", ids, userID).Find(&candidates).Error
+	err := s.db.Where("id IN ? AND user_id = ?", ids, userID).Find(&candidates).Error
 	return candidates, err
 }
 
 // Create 创建候选人
 func (s *CandidateStore) Create(candidate *model.Candidate) error {
-	return DB.Create(candidate).Error
+	return s.db.Create(candidate).Error
 }
 
-// Update 更新候选人
+// Update 更新候选人；积分余额只能通过 AddPoints 和 SpendPoints 修改
 func (s *CandidateStore) Update(candidate *model.Candidate) error {
-	return DB.Save(candidate).Error
+	return s.db.Omit("points").Save(candidate).Error
 }
 
-// Delete 删除候选人
-func (s *CandidateStore) Delete(id uuid.UUID, userID uuid.UUID) error {
-	return DB.Where("id = ? AND user_id = ?", id, userID).Delete(&model.Candidate{}).Error
+// Trash 将候选人移入回收站，删除时间为 at
+func (s *CandidateStore) Trash(id uuid.UUID, userID uuid.UUID, at time.Time) error {
+	result := s.db.Model(&model.Candidate{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Update("deleted_at", at)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListDeleted 获取回收站中的候选人
+func (s *CandidateStore) ListDeleted(userID uuid.UUID) ([]model.Candidate, error) {
+	var candidates []model.Candidate
+	err := s.db.Unscoped().
+		Where("user_id = ? AND deleted_at IS NOT NULL", userID).
+		Order("deleted_at DESC").
+		Find(&candidates).Error
+	return candidates, err
+}
+
+// GetDeleted 获取回收站中的候选人
+func (s *CandidateStore) GetDeleted(id uuid.UUID, userID uuid.UUID) (*model.Candidate, error) {
+	var candidate model.Candidate
+	err := s.db.Unscoped().
+		Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", id, userID).
+		First(&candidate).Error
+	if err != nil {
+		return nil, err
+	}
+	return &candidate, nil
+}
+
+// ListExpired 获取在 before 之前移入回收站的候选人（所有用户）
+func (s *CandidateStore) ListExpired(before time.Time) ([]model.Candidate, error) {
+	var candidates []model.Candidate
+	err := s.db.Unscoped().Where("deleted_at < ?", before).Find(&candidates).Error
+	return candidates, err
+}
+
+// Restore 从回收站恢复候选人
+func (s *CandidateStore) Restore(id uuid.UUID, userID uuid.UUID) error {
+	result := s.db.Unscoped().Model(&model.Candidate{}).
+		Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", id, userID).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Purge 彻底删除候选人
+func (s *CandidateStore) Purge(id uuid.UUID, userID uuid.UUID) error {
+	return s.db.Unscoped().Where("id = ? AND user_id = ?", id, userID).Delete(&model.Candidate{}).Error
 }
 
 // UpdatePhoto 更新候选人照片
 func (s *CandidateStore) UpdatePhoto(id uuid.UUID, userID uuid.UUID, photoURL string) error {
-	return DB.Model(&model.Candidate{}).
+	return s.db.Model(&model.Candidate{}).
 		Where("id = ? AND user_id = ?", id, userID).
 		Update("photo_url", photoURL).Error
 }
+
+// AddPoints 增减候选人的积分余额（包括回收站中的候选人），返回新的余额；余额可以为负数
+func (s *CandidateStore) AddPoints(id uuid.UUID, userID uuid.UUID, delta int) (int, error) {
+	result := s.db.Unscoped().Model(&model.Candidate{}).Where("id = ? AND user_id = ?", id, userID).
+		UpdateColumn("points", gorm.Expr("points + ?", delta))
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return 0, ErrNotFound
+	}
+	return s.points(id)
+}
+
+// SpendPoints 扣除候选人的积分，余额不足时返回 ErrInsufficientPoints；返回新的余额
+func (s *CandidateStore) SpendPoints(id uuid.UUID, userID uuid.UUID, amount int) (int, error) {
+	// 在同一条语句中检查并扣除余额，并发兑换也不会使余额变为负数
+	result := s.db.Model(&model.Candidate{}).Where("id = ? AND user_id = ? AND points >= ?", id, userID, amount).
+		UpdateColumn("points", gorm.Expr("points - ?", amount))
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := s.Get(id, userID); err != nil {
+			return 0, err
+		}
+		return 0, ErrInsufficientPoints
+	}
+	return s.points(id)
+}
+
+// points 读取候选人的积分余额
+func (s *CandidateStore) points(id uuid.UUID) (int, error) {
+	var points int
+	err := s.db.Unscoped().Model(&model.Candidate{}).Where("id = ?", id).Select("points").Scan(&points).Error
+	return points, err
+}
@@ -1,26 +1,33 @@
 package store
 
 import (
+	"time"
 	"whotakesshowers/internal/model"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // CandidatePhotoStore 候选人照片存储
-type CandidatePhotoStore struct{}
+type CandidatePhotoStore struct {
+	db *gorm.DB
+}
 
-var CandidatePhotos = &CandidatePhotoStore{}
+// NewCandidatePhotoStore 创建绑定到指定数据库连接（或事务）的照片存储
+func NewCandidatePhotoStore(db *gorm.DB) *CandidatePhotoStore {
+	return &CandidatePhotoStore{db: db}
+}
 
 // List 获取候选人的所有照片
 func (s *CandidatePhotoStore) List(candidateID uuid.UUID) ([]model.CandidatePhoto, error) {
 	var photos []model.CandidatePhoto
-	err := DB.Where("candidate_id = ?", candidateID).Order("created_at DESC").Find(&photos).Error
+	err := s.db.Where("candidate_id = ?", candidateID).Order("created_at DESC").Find(&photos).Error
 	return photos, err
 }
 
 // Create 创建照片记录
 func (s *CandidatePhotoStore) Create(photo *model.CandidatePhoto) error {
-	return DB.Create(photo).Error
+	return s.db.Create(photo).Error
 }
 
 // CreateBatch 批量创建照片记录
@@ -28,38 +35,121 @@ func (s *CandidatePhotoStore) CreateBatch(photos []model.CandidatePhoto) error {
 	if len(photos) == 0 {
 		return nil
 	}
-	return DB.Create(&photos).Error
+	return s.db.Create(&photos).Error
 }
 
-// Delete 删除照片
+// Delete 删除照片（软删除，移入回收站）
 func (s *CandidatePhotoStore) Delete(id uuid.UUID) error {
-	return DB.Delete(&model.CandidatePhoto{}, "id = ?", id).Error
+	return s.db.Delete(&model.CandidatePhoto{}, "id = ?", id).Error
+}
+
+// TrashByCandidateID 将候选人的所有照片移入回收站，删除时间为 at
+func (s *CandidatePhotoStore) TrashByCandidateID(candidateID uuid.UUID, at time.Time) error {
+	return s.db.Model(&model.CandidatePhoto{}).
+		Where("candidate_id = ?", candidateID).
+		Update("deleted_at", at).Error
+}
+
+// RestoreByCandidateID 恢复候选人在 at 时刻一起移入回收站的照片
+func (s *CandidatePhotoStore) RestoreByCandidateID(candidateID uuid.UUID, at time.Time) error {
+	return s.db.Unscoped().Model(&model.CandidatePhoto{}).
+		Where("candidate_id = ? AND deleted_at = ?", candidateID, at).
+		Update("deleted_at", nil).Error
+}
+
+// ListAll 获取候选人的所有照片（包括回收站中的照片）
+func (s *CandidatePhotoStore) ListAll(candidateID uuid.UUID) ([]model.CandidatePhoto, error) {
+	var photos []model.CandidatePhoto
+	err := s.db.Unscoped().Where("candidate_id = ?", candidateID).Find(&photos).Error
+	return photos, err
+}
+
+// ListDeleted 获取用户回收站中单独删除的照片（所属候选人未被删除）
+func (s *CandidatePhotoStore) ListDeleted(userID uuid.UUID) ([]model.CandidatePhoto, error) {
+	var photos []model.CandidatePhoto
+	err := s.db.Unscoped().
+		Joins("JOIN candidates ON candidates.id = candidate_photos.candidate_id").
+		Where("candidates.user_id = ? AND candidates.deleted_at IS NULL", userID).
+		Where("candidate_photos.deleted_at IS NOT NULL").
+		Order("candidate_photos.deleted_at DESC").
+		Find(&photos).Error
+	return photos, err
+}
+
+// GetDeleted 获取用户回收站中的照片
+func (s *CandidatePhotoStore) GetDeleted(id uuid.UUID, userID uuid.UUID) (*model.CandidatePhoto, error) {
+	var photo model.CandidatePhoto
+	err := s.db.Unscoped().
+		Joins("JOIN candidates ON candidates.id = candidate_photos.candidate_id").
+		Where("candidate_photos.id = ? AND candidates.user_id = ?", id, userID).
+		Where("candidate_photos.deleted_at IS NOT NULL").
+		First(&photo).Error
+	if err != nil {
+		return nil, err
+	}
+	return &photo, nil
+}
+
+// ListExpired 获取在 before 之前移入回收站的照片（所有用户）
+func (s *CandidatePhotoStore) ListExpired(before time.Time) ([]model.CandidatePhoto, error) {
+	var photos []model.CandidatePhoto
+	err := s.db.Unscoped().Where("deleted_at < ?", before).Find(&photos).Error
+	return photos, err
+}
+
+// Restore 从回收站恢复照片
+func (s *CandidatePhotoStore) Restore(id uuid.UUID) error {
+	return s.db.Unscoped().Model(&model.CandidatePhoto{}).
+		Where("id = ?", id).
+		Update("deleted_at", nil).Error
+}
+
+// Purge 彻底删除照片
+func (s *CandidatePhotoStore) Purge(id uuid.UUID) error {
+	return s.db.Unscoped().Delete(&model.CandidatePhoto{}, "id = ?", id).Error
 }
 
-// DeleteByCandidateID 删除候选人的所有照片
-func (s *CandidatePhotoStore) DeleteByCandidateID(candidateID uuid.UUID) error {
-	return DB.Delete(&model.CandidatePhoto{}, "candidate_id = ?", candidateID).Error
+// PurgeByCandidateID 彻底删除候选人的所有照片
+func (s *CandidatePhotoStore) PurgeByCandidateID(candidateID uuid.UUID) error {
+	return s.db.Unscoped().Delete(&model.CandidatePhoto{}, "candidate_id = ?", candidateID).Error
 }
 
 // SetAvatar 设置头像（将指定照片设为头像，其他照片取消头像标记）
+// 两次更新在同一事务内完成，照片不存在时返回 gorm.ErrRecordNotFound
 func (s *CandidatePhotoStore) SetAvatar(candidateID uuid.UUID, photoID uuid.UUID) error {
-	// 先取消该候选人的所有头像标记
-	if err := DB.Model(&model.CandidatePhoto{}).
-		Where("candidate_id = ?", candidateID).
-		Update("is_avatar", false).Error; err != nil {
-		return err
-	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// 先取消该候选人的所有头像标记（包括回收站中的照片，唯一索引覆盖所有行）
+		if err := tx.Unscoped().Model(&model.CandidatePhoto{}).
+			Where("candidate_id = ? AND is_avatar = ?", candidateID, true).
+			Update("is_avatar", false).Error; err != nil {
+			return err
+		}
+
+		// 设置新的头像
+		result := tx.Model(&model.CandidatePhoto{}).
+			Where("id = ? AND candidate_id = ?", photoID, candidateID).
+			Update("is_avatar", true)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
 
-	// 设置新的头像
-	return DB.Model(&model.CandidatePhoto{}).
-		Where("id = ? AND candidate_id = ?", photoID, candidateID).
-		Update("is_avatar", true).Error
+// ClearAvatar 取消候选人的头像标记
+func (s *CandidatePhotoStore) ClearAvatar(candidateID uuid.UUID) error {
+	return s.db.Unscoped().Model(&model.CandidatePhoto{}).
+		Where("candidate_id = ? AND is_avatar = ?", candidateID, true).
+		Update("is_avatar", false).Error
 }
 
 // Get 获取单张照片
 func (s *CandidatePhotoStore) Get(id uuid.UUID, candidateID uuid.UUID) (*model.CandidatePhoto, error) {
 	var photo model.CandidatePhoto
-	err := DB.Where("id = ? AND candidate_id = ?", id, candidateID).First(&photo).Error
+	err := s.db.Where("id = ? AND candidate_id = ?", id, candidateID).First(&photo).Error
 	if err != nil {
 		return nil, err
 	}
@@ -69,9 +159,51 @@ func (s *CandidatePhotoStore) Get(id uuid.UUID, candidateID uuid.UUID) (*model.C
 // GetAvatar 获取候选人的头像
 func (s *CandidatePhotoStore) GetAvatar(candidateID uuid.UUID) (*model.CandidatePhoto, error) {
 	var photo model.CandidatePhoto
-	err := DB.Where("candidate_id = ? AND is_avatar = ?", candidateID, true).First(&photo).Error
+	err := s.db.Where("candidate_id = ? AND is_avatar = ?", candidateID, true).First(&photo).Error
 	if err != nil {
 		return nil, err
 	}
 	return &photo, nil
 }
+
+// GetLatest 获取候选人最新上传的照片
+func (s *CandidatePhotoStore) GetLatest(candidateID uuid.UUID) (*model.CandidatePhoto, error) {
+	var photo model.CandidatePhoto
+	err := s.db.Where("candidate_id = ?", candidateID).Order("created_at DESC").First(&photo).Error
+	if err != nil {
+		return nil, err
+	}
+	return &photo, nil
+}
+
+// RepairAvatars 修复头像数据：每个候选人最多保留一张头像（最新的），
+// 并将 candidates.photo_url 与头像照片同步。在迁移前执行，不依赖 deleted_at 列
+func (s *CandidatePhotoStore) RepairAvatars() error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var avatars []model.CandidatePhoto
+		if err := tx.Unscoped().Where("is_avatar = ?", true).
+			Order("candidate_id, created_at DESC").
+			Find(&avatars).Error; err != nil {
+			return err
+		}
+
+		seen := make(map[uuid.UUID]bool)
+		for _, photo := range avatars {
+			if seen[photo.CandidateID] {
+				if err := tx.Unscoped().Model(&model.CandidatePhoto{}).
+					Where("id = ?", photo.ID).
+					Update("is_avatar", false).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			seen[photo.CandidateID] = true
+			if err := tx.Unscoped().Model(&model.Candidate{}).
+				Where("id = ?", photo.CandidateID).
+				Update("photo_url", photo.PhotoURL).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -1,82 +1,156 @@
 package store
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"net/url"
 	"os"
 	"path/filepath"
-	"whotakesshowers/internal/model"
+	"strconv"
+	"whotakesshowers/internal/config"
+	"whotakesshowers/internal/logger"
+	"whotakesshowers/internal/migrate"
 
-	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
+	"gorm.io/plugin/dbresolver"
 )
 
-// DB 全局数据库实例
-var DB *gorm.DB
+// InitDB 初始化数据库：打开连接、校验并执行版本化迁移
+func InitDB(cfg config.DatabaseConfig) (*gorm.DB, error) {
+	db, err := OpenDB(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	migrator, err := NewMigrator(db, cfg)
+	if err != nil {
+		return nil, err
+	}
 
-// InitDB 初始化数据库
-func InitDB() (*gorm.DB, error) {
-	// 确保数据目录存在
-	dataDir := "./data"
-	if err := os.MkdirAll(dataDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	// 程序不认识的新版本或被修改过的迁移：拒绝启动
+	if err := migrator.Verify(); err != nil {
+		return nil, err
 	}
 
-	dbPath := filepath.Join(dataDir, "whotakesshowers.db")
+	if cfg.AutoMigrate {
+		applied, err := migrator.Up()
+		if err != nil {
+			return nil, err
+		}
+		for _, migration := range applied {
+			log.Printf("InitDB: applied migration %d_%s", migration.Version, migration.Name)
+		}
+	} else {
+		pending, err := migrator.Pending()
+		if err != nil {
+			return nil, err
+		}
+		if len(pending) > 0 {
+			return nil, fmt.Errorf("%w: %d pending, run \"migrate up\"", migrate.ErrPendingMigrations, len(pending))
+		}
+	}
 
-	log.Printf("InitDB: dbPath: %s", dbPath)
+	// 迁移完成后再启用只读连接池，迁移始终在写连接上执行
+	if db.Dialector.Name() == config.DriverSQLite {
+		if err := useReadPool(db, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Println("Database initialized successfully")
+	return db, nil
+}
+
+// OpenDB 根据配置的驱动打开数据库连接，不执行迁移
+func OpenDB(cfg config.DatabaseConfig) (*gorm.DB, error) {
+	dialector, err := newDialector(cfg)
+	if err != nil {
+		return nil, err
+	}
 
-	// 打开数据库连接
-	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger: logger.NewGormLogger(cfg.Log),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	// 自动迁移
-	if err := db.AutoMigrate(
-		&model.User{},
-		&model.Project{},
-		&model.Candidate{},
-		&model.CandidatePhoto{},
-		&model.History{},
-	); err != nil {
-		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	if db.Dialector.Name() == config.DriverSQLite {
+		// SQLite 同一时间只允许一个写者：写操作共用一个连接排队执行，
+		// 避免多个连接争抢写锁导致 "database is locked"
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		sqlDB.SetMaxOpenConns(1)
 	}
-
-	log.Println("Database initialized successfully")
 	return db, nil
 }
 
-// Init 初始化存储层
-func Init(db *gorm.DB) {
-	DB = db
-
-	// 创建默认用户（如果不存在）
-	var defaultUser model.User
-	result := DB.Where("username = ?", "default").First(&defaultUser)
-	if result.Error != nil {
-		// 创建默认用户
-		defaultUser = model.User{
-			Username: "default",
-		}
-		if err := DB.Create(&defaultUser).Error; err != nil {
-			log.Printf("Warning: failed to create default user: %v", err)
-		} else {
-			log.Printf("Default user created: %s", defaultUser.ID)
-		}
+// useReadPool 为 SQLite 注册只读连接池：查询走只读连接，写操作和事务走写连接
+// WAL 模式下读写互不阻塞
+func useReadPool(db *gorm.DB, cfg config.DatabaseConfig) error {
+	if cfg.SQLite.ReadConnections <= 0 {
+		return nil
+	}
+
+	readDB, err := sql.Open("sqlite3", sqliteDSN(cfg, true))
+	if err != nil {
+		return fmt.Errorf("failed to open read pool: %w", err)
+	}
+	readDB.SetMaxOpenConns(cfg.SQLite.ReadConnections)
+	readDB.SetMaxIdleConns(cfg.SQLite.ReadConnections)
+
+	return db.Use(dbresolver.Register(dbresolver.Config{
+		Replicas: []gorm.Dialector{sqlite.New(sqlite.Config{Conn: readDB})},
+	}))
+}
+
+// sqliteDSN 根据配置生成 SQLite 连接串，连接参数对每个新连接生效
+func sqliteDSN(cfg config.DatabaseConfig, readOnly bool) string {
+	params := url.Values{}
+	if cfg.SQLite.JournalMode != "" {
+		params.Set("_journal_mode", cfg.SQLite.JournalMode)
+	}
+	if cfg.SQLite.BusyTimeout > 0 {
+		params.Set("_busy_timeout", strconv.Itoa(cfg.SQLite.BusyTimeout))
+	}
+	if cfg.SQLite.Synchronous != "" {
+		params.Set("_synchronous", cfg.SQLite.Synchronous)
 	}
+	if cfg.SQLite.ForeignKeys {
+		params.Set("_foreign_keys", "on")
+	}
+	if readOnly {
+		params.Set("_query_only", "on")
+	} else {
+		// 写事务一开始就获取写锁，避免读锁升级为写锁时绕过 busy_timeout 直接失败
+		params.Set("_txlock", "immediate")
+	}
+	return "file:" + cfg.Path + "?" + params.Encode()
 }
 
-// GetDefaultUserID 获取默认用户ID
-func GetDefaultUserID() uuid.UUID {
-	var user model.User
-	if err := DB.Where("username = ?", "default").First(&user).Error; err != nil {
-		log.Printf("Error getting default user: %v", err)
-		return uuid.Nil
+// newDialector 创建数据库驱动对应的 gorm Dialector
+func newDialector(cfg config.DatabaseConfig) (gorm.Dialector, error) {
+	switch cfg.Driver {
+	case config.DriverSQLite, "":
+		// 确保数据目录存在
+		if err := os.MkdirAll(filepath.Dir(cfg.Path), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create data directory: %w", err)
+		}
+		log.Printf("InitDB: driver: sqlite, dbPath: %s", cfg.Path)
+		return sqlite.Open(sqliteDSN(cfg, false)), nil
+	case config.DriverPostgres:
+		if cfg.DSN == "" {
+			return nil, errors.New("database.dsn is required for the postgres driver")
+		}
+		log.Printf("InitDB: driver: postgres")
+		return postgres.Open(cfg.DSN), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", cfg.Driver)
 	}
-	return user.ID
 }
@@ -0,0 +1,223 @@
+package store_test
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"whotakesshowers/internal/config"
+	"whotakesshowers/internal/store"
+	"whotakesshowers/internal/store/memory"
+
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// 集成测试在 SQLite 和 PostgreSQL 上各执行一遍
+//
+// PostgreSQL 的连接方式：
+//   - 设置 TEST_POSTGRES_DSN 时使用该数据库（如 CI 中的 postgres 容器），连接失败时测试失败
+//   - 否则启动嵌入式 PostgreSQL（首次运行需要下载二进制文件，不能以 root 运行），
+//     启动失败或超过 embeddedPostgresTimeout 时跳过
+//   - TEST_POSTGRES=off 时跳过 PostgreSQL
+//
+// 每个测试使用独立的 schema，测试结束后删除
+
+// embeddedPostgresTimeout 启动嵌入式 PostgreSQL（包括下载二进制文件）的最长时间，
+// 下载没有超时设置，无法访问网络时会一直等待
+const embeddedPostgresTimeout = 2 * time.Minute
+
+var (
+	postgresDSN  string // 为空表示没有可用的 PostgreSQL
+	postgresSkip string // 没有可用的 PostgreSQL 的原因
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(runTests(m))
+}
+
+// runTests 准备 PostgreSQL 后执行测试，测试结束后停止嵌入式实例
+func runTests(m *testing.M) int {
+	switch {
+	case os.Getenv("TEST_POSTGRES_DSN") != "":
+		postgresDSN = os.Getenv("TEST_POSTGRES_DSN")
+	case os.Getenv("TEST_POSTGRES") == "off":
+		postgresSkip = "TEST_POSTGRES=off"
+	default:
+		stop, err := startEmbeddedPostgres()
+		if err != nil {
+			postgresSkip = fmt.Sprintf("no PostgreSQL available (set TEST_POSTGRES_DSN): %v", err)
+			break
+		}
+		defer stop()
+	}
+	return m.Run()
+}
+
+// startEmbeddedPostgres 在空闲端口上启动嵌入式 PostgreSQL，数据目录在临时目录中
+func startEmbeddedPostgres() (func(), error) {
+	if os.Geteuid() == 0 {
+		return nil, errors.New("embedded postgres cannot run as root")
+	}
+	dir, err := os.MkdirTemp("", "store-postgres-")
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	port := uint32(listener.Addr().(*net.TCPAddr).Port)
+	listener.Close()
+
+	cfg := embeddedpostgres.DefaultConfig().
+		Port(port).
+		RuntimePath(filepath.Join(dir, "runtime")).
+		DataPath(filepath.Join(dir, "data")).
+		Logger(nil)
+	database := embeddedpostgres.NewDatabase(cfg)
+	started := make(chan error, 1)
+	go func() { started <- database.Start() }()
+	select {
+	case err := <-started:
+		if err != nil {
+			os.RemoveAll(dir)
+			return nil, err
+		}
+	case <-time.After(embeddedPostgresTimeout):
+		return nil, fmt.Errorf("embedded postgres did not start within %s", embeddedPostgresTimeout)
+	}
+	postgresDSN = cfg.GetConnectionURL() + "?sslmode=disable"
+	return func() {
+		if err := database.Stop(); err != nil {
+			log.Printf("failed to stop embedded postgres: %v", err)
+		}
+		os.RemoveAll(dir)
+	}, nil
+}
+
+// testDriver 集成测试使用的数据库驱动
+type testDriver struct {
+	name   string
+	config func(t *testing.T) config.DatabaseConfig
+}
+
+var testDrivers = []testDriver{
+	{name: config.DriverSQLite, config: sqliteConfig},
+	{name: config.DriverPostgres, config: postgresConfig},
+}
+
+// forEachDriver 在每个数据库驱动上执行 fn，fn 收到一个尚未初始化的空数据库的配置
+func forEachDriver(t *testing.T, fn func(t *testing.T, cfg config.DatabaseConfig)) {
+	for _, driver := range testDrivers {
+		t.Run(driver.name, func(t *testing.T) {
+			fn(t, driver.config(t))
+		})
+	}
+}
+
+// forEachStore 在每个数据库驱动和内存实现上执行 fn，fn 收到一个空的 Store；
+// 内存实现用于 handler 和 service 的测试，需要与数据库实现的行为保持一致
+func forEachStore(t *testing.T, fn func(t *testing.T, s store.Store)) {
+	forEachDriver(t, func(t *testing.T, cfg config.DatabaseConfig) {
+		fn(t, store.New(openTestDB(t, cfg)))
+	})
+	t.Run("memory", func(t *testing.T) {
+		fn(t, memory.New())
+	})
+}
+
+// openTestDB 初始化数据库并执行全部迁移，测试结束时关闭连接
+func openTestDB(t *testing.T, cfg config.DatabaseConfig) *gorm.DB {
+	t.Helper()
+	db, err := store.InitDB(cfg)
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// sqliteConfig 返回临时目录中的 SQLite 数据库配置，连接参数与 config.yaml 的默认值一致
+func sqliteConfig(t *testing.T) config.DatabaseConfig {
+	dir := t.TempDir()
+	return config.DatabaseConfig{
+		Driver:      config.DriverSQLite,
+		Path:        filepath.Join(dir, "test.db"),
+		AutoMigrate: true,
+		BackupDir:   filepath.Join(dir, "backups"),
+		SQLite: config.SQLiteConfig{
+			JournalMode:     "WAL",
+			BusyTimeout:     5000,
+			Synchronous:     "NORMAL",
+			ForeignKeys:     true,
+			ReadConnections: 2,
+		},
+		Log: config.DatabaseLogConfig{Level: "silent"},
+	}
+}
+
+// postgresConfig 在 PostgreSQL 中创建测试专用的 schema，返回以它为 search_path 的配置
+// 没有可用的 PostgreSQL 时跳过测试
+func postgresConfig(t *testing.T) config.DatabaseConfig {
+	if postgresDSN == "" {
+		t.Skip(postgresSkip)
+	}
+
+	admin, err := gorm.Open(postgres.Open(postgresDSN), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("failed to connect to postgres: %v", err)
+	}
+	schema := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := admin.Exec("DROP SCHEMA " + schema + " CASCADE").Error; err != nil {
+			t.Errorf("failed to drop schema %s: %v", schema, err)
+		}
+		if sqlDB, err := admin.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	dsn, err := withSearchPath(postgresDSN, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config.DatabaseConfig{
+		Driver:      config.DriverPostgres,
+		DSN:         dsn,
+		AutoMigrate: true,
+		Log:         config.DatabaseLogConfig{Level: "silent"},
+	}
+}
+
+// withSearchPath 在连接串中设置 search_path，支持 URL 和 key=value 两种格式
+func withSearchPath(dsn string, schema string) (string, error) {
+	if !strings.HasPrefix(dsn, "postgres://") && !strings.HasPrefix(dsn, "postgresql://") {
+		return dsn + " search_path=" + schema, nil
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
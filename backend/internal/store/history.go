@@ -1,20 +1,27 @@
 package store
 
 import (
+	"time"
 	"whotakesshowers/internal/model"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // HistoryStore 历史记录存储
-type HistoryStore struct{}
+type HistoryStore struct {
+	db *gorm.DB
+}
 
-var Histories = &HistoryStore{}
+// NewHistoryStore 创建绑定到指定数据库连接（或事务）的历史记录存储
+func NewHistoryStore(db *gorm.DB) *HistoryStore {
+	return &HistoryStore{db: db}
+}
 
-// List 获取历史记录列表
+// List 获取历史记录列表（不包括回收站中项目的记录）
 func (s *HistoryStore) List(userID uuid.UUID, projectID *uuid.UUID, limit int) ([]model.History, error) {
 	var histories []model.History
-	query := DB.Where("user_id = ?", userID)
+	query := s.visible(userID)
 
 	if projectID != nil {
 		query = query.Where("project_id = ?", *projectID)
@@ -24,12 +31,110 @@ func (s *HistoryStore) List(userID uuid.UUID, projectID *uuid.UUID, limit int) (
 	return histories, err
 }
 
+// Find 按过滤条件分页获取历史记录（不包括回收站中项目的记录）
+func (s *HistoryStore) Find(userID uuid.UUID, filter HistoryFilter, page PageRequest) (*Page[model.History], error) {
+	query := s.visible(userID)
+	if filter.ProjectID != nil {
+		query = query.Where("project_id = ?", *filter.ProjectID)
+	}
+	if filter.CandidateID != nil {
+		query = query.Where("candidate_id = ?", *filter.CandidateID)
+	}
+	if filter.From != nil {
+		query = query.Where("selected_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("selected_at < ?", *filter.To)
+	}
+	if filter.Source != "" {
+		query = query.Where("source = ?", filter.Source)
+	}
+	if filter.Voided != nil {
+		if *filter.Voided {
+			query = query.Where("voided_at IS NOT NULL")
+		} else {
+			query = query.Where("voided_at IS NULL")
+		}
+	}
+
+	return paginate(query, page, HistorySortFields, func(h *model.History, field string) (any, uuid.UUID) {
+		switch field {
+		case "candidate_name":
+			return h.CandidateName, h.ID
+		case "project_name":
+			return h.ProjectName, h.ID
+		default:
+			return h.SelectedAt, h.ID
+		}
+	})
+}
+
+// ListByRoutineRun 按步骤顺序获取例程执行产生的历史记录（包括回收站中项目的记录）
+func (s *HistoryStore) ListByRoutineRun(runID uuid.UUID) ([]model.History, error) {
+	var histories []model.History
+	err := s.db.Where("routine_run_id = ?", runID).Order("routine_step ASC").Find(&histories).Error
+	return histories, err
+}
+
+// CountSuperseded 按候选人统计项目中自 since 起被重新选择取代的记录数
+func (s *HistoryStore) CountSuperseded(projectID uuid.UUID, since time.Time) ([]SupersededCount, error) {
+	var counts []SupersededCount
+	err := s.db.Model(&model.History{}).
+		Select("candidate_id, COUNT(*) AS count").
+		Where("project_id = ? AND superseded_at >= ?", projectID, since).
+		Group("candidate_id").Scan(&counts).Error
+	return counts, err
+}
+
+// visible 用户可见的历史记录，隐藏回收站中项目的记录
+func (s *HistoryStore) visible(userID uuid.UUID) *gorm.DB {
+	return s.db.Model(&model.History{}).Where("user_id = ?", userID).
+		Where("project_id NOT IN (?)", s.db.Unscoped().Model(&model.Project{}).
+			Select("id").Where("deleted_at IS NOT NULL"))
+}
+
+// Get 获取单条历史记录（不包括回收站中项目的记录）
+func (s *HistoryStore) Get(id uuid.UUID, userID uuid.UUID) (*model.History, error) {
+	var history model.History
+	err := s.visible(userID).Where("id = ?", id).First(&history).Error
+	if err != nil {
+		return nil, err
+	}
+	return &history, nil
+}
+
 // Create 创建历史记录
 func (s *HistoryStore) Create(history *model.History) error {
-	return DB.Create(history).Error
+	return s.db.Create(history).Error
 }
 
-// DeleteByProject 删除项目相关的历史记录
+// Update 更新历史记录的备注、任务状态、作废标记和重新选择的关联，其余字段不可修改
+func (s *HistoryStore) Update(history *model.History) error {
+	return s.db.Model(history).Where("user_id = ?", history.UserID).
+		Select("note", "status", "started_at", "completed_at", "skipped_at", "voided_at", "void_reason",
+			"superseded_by_id", "superseded_at", "reroll_of_id").
+		Updates(history).Error
+}
+
+// DeleteByProject 删除项目相关的历史记录及其修改记录
 func (s *HistoryStore) DeleteByProject(projectID uuid.UUID, userID uuid.UUID) error {
-	return DB.Where("project_id = ? AND user_id = ?", projectID, userID).Delete(&model.History{}).Error
+	if err := s.deleteChanges(s.db.Model(&model.History{}).Select("id").
+		Where("project_id = ? AND user_id = ?", projectID, userID)); err != nil {
+		return err
+	}
+	return s.db.Where("project_id = ? AND user_id = ?", projectID, userID).Delete(&model.History{}).Error
+}
+
+// DeleteByUser 删除用户的所有历史记录及其修改记录，返回删除的历史记录条数
+func (s *HistoryStore) DeleteByUser(userID uuid.UUID) (int64, error) {
+	if err := s.deleteChanges(s.db.Model(&model.History{}).Select("id").Where("user_id = ?", userID)); err != nil {
+		return 0, err
+	}
+	result := s.db.Where("user_id = ?", userID).Delete(&model.History{})
+	return result.RowsAffected, result.Error
+}
+
+// deleteChanges 删除子查询 ids 选出的历史记录的修改记录；未启用外键约束时不会级联删除
+func (s *HistoryStore) deleteChanges(ids *gorm.DB) error {
+	return s.db.Where("history_id IN (?)", ids).Delete(&model.HistoryChange{}).Error
 }
@@ -0,0 +1,33 @@
+package store
+
+import (
+	"whotakesshowers/internal/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// HistoryChangeStore 历史记录修改记录存储
+type HistoryChangeStore struct {
+	db *gorm.DB
+}
+
+// NewHistoryChangeStore 创建绑定到指定数据库连接（或事务）的修改记录存储
+func NewHistoryChangeStore(db *gorm.DB) *HistoryChangeStore {
+	return &HistoryChangeStore{db: db}
+}
+
+// List 按时间顺序获取历史记录的修改记录
+func (s *HistoryChangeStore) List(historyID uuid.UUID) ([]model.HistoryChange, error) {
+	var changes []model.HistoryChange
+	err := s.db.Where("history_id = ?", historyID).Order("changed_at ASC, field ASC").Find(&changes).Error
+	return changes, err
+}
+
+// Create 批量创建修改记录
+func (s *HistoryChangeStore) Create(changes []model.HistoryChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	return s.db.Create(&changes).Error
+}
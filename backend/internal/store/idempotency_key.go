@@ -0,0 +1,54 @@
+package store
+
+import (
+	"time"
+	"whotakesshowers/internal/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// IdempotencyKeyStore 幂等键存储
+type IdempotencyKeyStore struct {
+	db *gorm.DB
+}
+
+// NewIdempotencyKeyStore 创建绑定到指定数据库连接（或事务）的幂等键存储
+func NewIdempotencyKeyStore(db *gorm.DB) *IdempotencyKeyStore {
+	return &IdempotencyKeyStore{db: db}
+}
+
+// Get 获取用户的幂等键
+func (s *IdempotencyKeyStore) Get(userID uuid.UUID, key string) (*model.IdempotencyKey, error) {
+	var record model.IdempotencyKey
+	if err := s.db.Where("user_id = ? AND key = ?", userID, key).First(&record).Error; err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Create 创建幂等键，同一用户的键已存在时返回错误
+func (s *IdempotencyKeyStore) Create(record *model.IdempotencyKey) error {
+	return s.db.Create(record).Error
+}
+
+// Complete 保存幂等键对应请求的响应
+func (s *IdempotencyKeyStore) Complete(record *model.IdempotencyKey) error {
+	return s.db.Model(record).Select("status_code", "content_type", "response_body").Updates(record).Error
+}
+
+// Delete 删除幂等键
+func (s *IdempotencyKeyStore) Delete(id uuid.UUID) error {
+	return s.db.Delete(&model.IdempotencyKey{}, "id = ?", id).Error
+}
+
+// DeleteExpired 删除在 before 之前过期的幂等键，返回删除的条数
+func (s *IdempotencyKeyStore) DeleteExpired(before time.Time) (int64, error) {
+	result := s.db.Where("expires_at < ?", before).Delete(&model.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteByUser 删除用户的所有幂等键
+func (s *IdempotencyKeyStore) DeleteByUser(userID uuid.UUID) error {
+	return s.db.Where("user_id = ?", userID).Delete(&model.IdempotencyKey{}).Error
+}
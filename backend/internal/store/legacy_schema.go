@@ -0,0 +1,83 @@
+package store
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 以下是迁移版本 1（0001_init）对应的表结构的冻结副本，只用于接管版本化迁移之前的数据库
+// 不能直接使用 model 包中的结构：它们随之后的迁移不断增加字段，用来补齐旧表会提前加上之后迁移才添加的列，
+// 导致那些迁移执行失败。这里的定义不能再修改，表结构的变更只能通过新的迁移完成
+
+// legacyUser 基线版本的 users 表
+type legacyUser struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key"`
+	Username  string    `gorm:"type:varchar(50);uniqueIndex;not null"`
+	Email     string    `gorm:"type:varchar(100);uniqueIndex"`
+	Password  string    `gorm:"type:varchar(255)"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	Projects   []legacyProject   `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Candidates []legacyCandidate `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Histories  []legacyHistory   `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+func (legacyUser) TableName() string { return "users" }
+
+// legacyProject 基线版本的 projects 表
+type legacyProject struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key"`
+	Name         string    `gorm:"type:varchar(200);not null"`
+	UserID       uuid.UUID `gorm:"type:uuid;not null"`
+	CandidateIDs string    `gorm:"type:text"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	DeletedAt    gorm.DeletedAt `gorm:"index"`
+
+	Histories []legacyHistory `gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE"`
+}
+
+func (legacyProject) TableName() string { return "projects" }
+
+// legacyCandidate 基线版本的 candidates 表
+type legacyCandidate struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key"`
+	Name      string    `gorm:"type:varchar(100);not null"`
+	PhotoURL  string    `gorm:"type:varchar(500)"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+
+	Photos []legacyCandidatePhoto `gorm:"foreignKey:CandidateID;constraint:OnDelete:CASCADE"`
+}
+
+func (legacyCandidate) TableName() string { return "candidates" }
+
+// legacyCandidatePhoto 基线版本的 candidate_photos 表
+type legacyCandidatePhoto struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key"`
+	CandidateID uuid.UUID `gorm:"type:uuid;not null;index;uniqueIndex:idx_candidate_photos_avatar,where:is_avatar"`
+	PhotoURL    string    `gorm:"type:varchar(500);not null"`
+	IsAvatar    bool      `gorm:"default:false"`
+	CreatedAt   time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+}
+
+func (legacyCandidatePhoto) TableName() string { return "candidate_photos" }
+
+// legacyHistory 基线版本的 histories 表
+type legacyHistory struct {
+	ID            uuid.UUID `gorm:"type:uuid;primary_key"`
+	ProjectID     uuid.UUID `gorm:"type:uuid;not null"`
+	ProjectName   string    `gorm:"type:varchar(200);not null"`
+	CandidateID   uuid.UUID `gorm:"type:uuid;not null"`
+	CandidateName string    `gorm:"type:varchar(100);not null"`
+	SelectedAt    time.Time `gorm:"not null"`
+	UserID        uuid.UUID `gorm:"type:uuid;not null"`
+}
+
+func (legacyHistory) TableName() string { return "histories" }
@@ -0,0 +1,27 @@
+package memory
+
+import (
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// AccountDeletionRepository 账号删除审计记录仓储的内存实现
+type AccountDeletionRepository struct {
+	data *data
+}
+
+var _ store.AccountDeletionRepository = (*AccountDeletionRepository)(nil)
+
+// Create 创建审计记录
+func (r *AccountDeletionRepository) Create(deletion *model.AccountDeletion) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	if deletion.ID == uuid.Nil {
+		deletion.ID = uuid.New()
+	}
+	r.data.deletions[deletion.ID] = *deletion
+	return nil
+}
@@ -0,0 +1,181 @@
+package memory
+
+import (
+	"fmt"
+	"time"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// BadgeRuleRepository 徽章规则仓储的内存实现
+type BadgeRuleRepository struct {
+	data *data
+}
+
+var _ store.BadgeRuleRepository = (*BadgeRuleRepository)(nil)
+
+// List 获取用户的徽章规则，按创建时间排序
+func (r *BadgeRuleRepository) List(userID uuid.UUID) ([]model.BadgeRule, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	return filter(r.data.rules, func(rule model.BadgeRule) bool {
+		return rule.UserID == userID
+	}, func(a, b model.BadgeRule) bool {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}), nil
+}
+
+// Get 获取徽章规则
+func (r *BadgeRuleRepository) Get(id uuid.UUID, userID uuid.UUID) (*model.BadgeRule, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	rule, ok := r.data.rules[id]
+	if !ok || rule.UserID != userID {
+		return nil, store.ErrNotFound
+	}
+	return &rule, nil
+}
+
+// Create 创建徽章规则
+func (r *BadgeRuleRepository) Create(rule *model.BadgeRule) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	if rule.ID == uuid.Nil {
+		rule.ID = uuid.New()
+	}
+	now := time.Now()
+	if rule.CreatedAt.IsZero() {
+		rule.CreatedAt = now
+	}
+	rule.UpdatedAt = now
+	r.data.rules[rule.ID] = storedBadgeRule(rule)
+	return nil
+}
+
+// Update 更新徽章规则
+func (r *BadgeRuleRepository) Update(rule *model.BadgeRule) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	rule.UpdatedAt = time.Now()
+	r.data.rules[rule.ID] = storedBadgeRule(rule)
+	return nil
+}
+
+// Delete 删除徽章规则并级联删除其授予的徽章，规则不存在时返回 store.ErrNotFound
+func (r *BadgeRuleRepository) Delete(id uuid.UUID, userID uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	rule, ok := r.data.rules[id]
+	if !ok || rule.UserID != userID {
+		return store.ErrNotFound
+	}
+	r.data.deleteBadgeRule(id)
+	return nil
+}
+
+// DeleteByUser 删除用户的所有徽章规则
+func (r *BadgeRuleRepository) DeleteByUser(userID uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for id, rule := range r.data.rules {
+		if rule.UserID == userID {
+			r.data.deleteBadgeRule(id)
+		}
+	}
+	return nil
+}
+
+// storedBadgeRule 返回去掉关联数据的副本
+func storedBadgeRule(rule *model.BadgeRule) model.BadgeRule {
+	stored := *rule
+	stored.Badges = nil
+	if rule.ProjectID != nil {
+		projectID := *rule.ProjectID
+		stored.ProjectID = &projectID
+	}
+	return stored
+}
+
+// BadgeRepository 徽章仓储的内存实现
+type BadgeRepository struct {
+	data *data
+}
+
+var _ store.BadgeRepository = (*BadgeRepository)(nil)
+
+// List 获取用户已获得的徽章，candidateID 不为空时只返回该候选人的；按获得时间倒序
+func (r *BadgeRepository) List(userID uuid.UUID, candidateID *uuid.UUID) ([]model.Badge, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	return filter(r.data.badges, func(b model.Badge) bool {
+		return b.UserID == userID && (candidateID == nil || b.CandidateID == *candidateID)
+	}, func(a, b model.Badge) bool {
+		return a.EarnedAt.After(b.EarnedAt)
+	}), nil
+}
+
+// ListByRule 获取规则授予的所有徽章
+func (r *BadgeRepository) ListByRule(ruleID uuid.UUID) ([]model.Badge, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	return filter(r.data.badges, func(b model.Badge) bool {
+		return b.RuleID == ruleID
+	}, func(a, b model.Badge) bool {
+		return a.EarnedAt.Before(b.EarnedAt)
+	}), nil
+}
+
+// Create 创建徽章，同一规则、候选人和周期只能有一个
+func (r *BadgeRepository) Create(badge *model.Badge) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for _, existing := range r.data.badges {
+		if existing.RuleID == badge.RuleID && existing.CandidateID == badge.CandidateID && existing.Period == badge.Period {
+			return fmt.Errorf("UNIQUE constraint failed: badges.rule_id, badges.candidate_id, badges.period")
+		}
+	}
+	if badge.ID == uuid.Nil {
+		badge.ID = uuid.New()
+	}
+	r.data.badges[badge.ID] = *badge
+	return nil
+}
+
+// DeleteByRule 删除规则授予的所有徽章
+func (r *BadgeRepository) DeleteByRule(ruleID uuid.UUID) error {
+	return r.deleteWhere(func(b model.Badge) bool { return b.RuleID == ruleID })
+}
+
+// DeleteByCandidate 删除候选人的所有徽章
+func (r *BadgeRepository) DeleteByCandidate(candidateID uuid.UUID) error {
+	return r.deleteWhere(func(b model.Badge) bool { return b.CandidateID == candidateID })
+}
+
+// DeleteByUser 删除用户的所有徽章
+func (r *BadgeRepository) DeleteByUser(userID uuid.UUID) error {
+	return r.deleteWhere(func(b model.Badge) bool { return b.UserID == userID })
+}
+
+// deleteWhere 删除满足 match 的徽章
+func (r *BadgeRepository) deleteWhere(match func(model.Badge) bool) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for id, badge := range r.data.badges {
+		if match(badge) {
+			delete(r.data.badges, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package memory
+
+import (
+	"fmt"
+	"time"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// CalendarFeedRepository 日历订阅仓储的内存实现
+type CalendarFeedRepository struct {
+	data *data
+}
+
+var _ store.CalendarFeedRepository = (*CalendarFeedRepository)(nil)
+
+// List 获取用户的日历订阅
+func (r *CalendarFeedRepository) List(userID uuid.UUID) ([]model.CalendarFeed, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	return filter(r.data.feeds, func(f model.CalendarFeed) bool {
+		return f.UserID == userID
+	}, func(a, b model.CalendarFeed) bool {
+		return a.CreatedAt.After(b.CreatedAt)
+	}), nil
+}
+
+// GetByTokenHash 根据令牌哈希获取日历订阅
+func (r *CalendarFeedRepository) GetByTokenHash(tokenHash string) (*model.CalendarFeed, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for _, feed := range r.data.feeds {
+		if feed.TokenHash == tokenHash {
+			return &feed, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+// Create 创建日历订阅
+func (r *CalendarFeedRepository) Create(feed *model.CalendarFeed) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for _, existing := range r.data.feeds {
+		if existing.TokenHash == feed.TokenHash {
+			return fmt.Errorf("UNIQUE constraint failed: calendar_feeds.token_hash")
+		}
+	}
+	if feed.ID == uuid.Nil {
+		feed.ID = uuid.New()
+	}
+	if feed.CreatedAt.IsZero() {
+		feed.CreatedAt = time.Now()
+	}
+	r.data.feeds[feed.ID] = *feed
+	return nil
+}
+
+// Delete 删除日历订阅，订阅不存在时返回 store.ErrNotFound
+func (r *CalendarFeedRepository) Delete(id uuid.UUID, userID uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	feed, ok := r.data.feeds[id]
+	if !ok || feed.UserID != userID {
+		return store.ErrNotFound
+	}
+	delete(r.data.feeds, id)
+	return nil
+}
+
+// DeleteByUser 删除用户的所有日历订阅
+func (r *CalendarFeedRepository) DeleteByUser(userID uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for id, feed := range r.data.feeds {
+		if feed.UserID == userID {
+			delete(r.data.feeds, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package memory
+
+import (
+	"strings"
+	"time"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CandidateRepository 候选人仓储的内存实现
+type CandidateRepository struct {
+	data *data
+}
+
+var _ store.CandidateRepository = (*CandidateRepository)(nil)
+
+// List 获取候选人列表
+func (r *CandidateRepository) List(userID uuid.UUID) ([]model.Candidate, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	return filter(r.data.candidates, func(c model.Candidate) bool {
+		return c.UserID == userID && !c.DeletedAt.Valid
+	}, func(a, b model.Candidate) bool {
+		return a.CreatedAt.After(b.CreatedAt)
+	}), nil
+}
+
+// Find 按过滤条件分页获取候选人列表
+func (r *CandidateRepository) Find(userID uuid.UUID, f store.CandidateFilter, page store.PageRequest) (*store.Page[model.Candidate], error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	query := strings.ToLower(f.Query)
+	items := filter(r.data.candidates, func(c model.Candidate) bool {
+		return c.UserID == userID && !c.DeletedAt.Valid &&
+			strings.Contains(strings.ToLower(c.Name), query) &&
+			(f.Tag == "" || strings.Contains(c.Tags, `"`+f.Tag+`"`))
+	}, nil)
+
+	return paginate(items, page, store.CandidateSortFields, func(c *model.Candidate, field string) (any, uuid.UUID) {
+		switch field {
+		case "name":
+			return c.Name, c.ID
+		case "updated_at":
+			return c.UpdatedAt, c.ID
+		default:
+			return c.CreatedAt, c.ID
+		}
+	})
+}
+
+// Get 获取候选人详情
+func (r *CandidateRepository) Get(id uuid.UUID, userID uuid.UUID) (*model.Candidate, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	candidate, ok := r.data.candidates[id]
+	if !ok || candidate.UserID != userID || candidate.DeletedAt.Valid {
+		return nil, store.ErrNotFound
+	}
+	return &candidate, nil
+}
+
+// GetByIDs 根据ID列表获取候选人
+func (r *CandidateRepository) GetByIDs(ids []uuid.UUID, userID uuid.UUID) ([]model.Candidate, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	wanted := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	return filter(r.data.candidates, func(c model.Candidate) bool {
+		return wanted[c.ID] && c.UserID == userID && !c.DeletedAt.Valid
+	}, nil), nil
+}
+
+// Create 创建候选人
+func (r *CandidateRepository) Create(candidate *model.Candidate) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	if candidate.ID == uuid.Nil {
+		candidate.ID = uuid.New()
+	}
+	now := time.Now()
+	if candidate.CreatedAt.IsZero() {
+		candidate.CreatedAt = now
+	}
+	candidate.UpdatedAt = now
+	r.data.candidates[candidate.ID] = storedCandidate(candidate)
+	return nil
+}
+
+// Update 更新候选人；积分余额只能通过 AddPoints 和 SpendPoints 修改
+func (r *CandidateRepository) Update(candidate *model.Candidate) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	candidate.UpdatedAt = time.Now()
+	stored := storedCandidate(candidate)
+	stored.Points = r.data.candidates[candidate.ID].Points
+	r.data.candidates[candidate.ID] = stored
+	return nil
+}
+
+// Trash 将候选人移入回收站，删除时间为 at
+func (r *CandidateRepository) Trash(id uuid.UUID, userID uuid.UUID, at time.Time) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	candidate, ok := r.data.candidates[id]
+	if !ok || candidate.UserID != userID || candidate.DeletedAt.Valid {
+		return store.ErrNotFound
+	}
+	candidate.DeletedAt = gorm.DeletedAt{Time: at, Valid: true}
+	r.data.candidates[id] = candidate
+	return nil
+}
+
+// ListDeleted 获取回收站中的候选人
+func (r *CandidateRepository) ListDeleted(userID uuid.UUID) ([]model.Candidate, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	return filter(r.data.candidates, func(c model.Candidate) bool {
+		return c.UserID == userID && c.DeletedAt.Valid
+	}, func(a, b model.Candidate) bool {
+		return a.DeletedAt.Time.After(b.DeletedAt.Time)
+	}), nil
+}
+
+// GetDeleted 获取回收站中的候选人
+func (r *CandidateRepository) GetDeleted(id uuid.UUID, userID uuid.UUID) (*model.Candidate, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	candidate, ok := r.data.candidates[id]
+	if !ok || candidate.UserID != userID || !candidate.DeletedAt.Valid {
+		return nil, store.ErrNotFound
+	}
+	return &candidate, nil
+}
+
+// ListExpired 获取在 before 之前移入回收站的候选人（所有用户）
+func (r *CandidateRepository) ListExpired(before time.Time) ([]model.Candidate, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	return filter(r.data.candidates, func(c model.Candidate) bool {
+		return c.DeletedAt.Valid && c.DeletedAt.Time.Before(before)
+	}, nil), nil
+}
+
+// Restore 从回收站恢复候选人
+func (r *CandidateRepository) Restore(id uuid.UUID, userID uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	candidate, ok := r.data.candidates[id]
+	if !ok || candidate.UserID != userID || !candidate.DeletedAt.Valid {
+		return store.ErrNotFound
+	}
+	candidate.DeletedAt = gorm.DeletedAt{}
+	r.data.candidates[id] = candidate
+	return nil
+}
+
+// Purge 彻底删除候选人，级联删除其照片记录和积分流水
+func (r *CandidateRepository) Purge(id uuid.UUID, userID uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	candidate, ok := r.data.candidates[id]
+	if !ok || candidate.UserID != userID {
+		return nil
+	}
+	r.data.deleteCandidate(id)
+	return nil
+}
+
+// UpdatePhoto 更新候选人照片
+func (r *CandidateRepository) UpdatePhoto(id uuid.UUID, userID uuid.UUID, photoURL string) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	candidate, ok := r.data.candidates[id]
+	if !ok || candidate.UserID != userID || candidate.DeletedAt.Valid {
+		return nil
+	}
+	candidate.PhotoURL = photoURL
+	candidate.UpdatedAt = time.Now()
+	r.data.candidates[id] = candidate
+	return nil
+}
+
+// AddPoints 增减候选人的积分余额（包括回收站中的候选人），返回新的余额；余额可以为负数
+func (r *CandidateRepository) AddPoints(id uuid.UUID, userID uuid.UUID, delta int) (int, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	candidate, ok := r.data.candidates[id]
+	if !ok || candidate.UserID != userID {
+		return 0, store.ErrNotFound
+	}
+	candidate.Points += delta
+	r.data.candidates[id] = candidate
+	return candidate.Points, nil
+}
+
+// SpendPoints 扣除候选人的积分，余额不足时返回 store.ErrInsufficientPoints；返回新的余额
+func (r *CandidateRepository) SpendPoints(id uuid.UUID, userID uuid.UUID, amount int) (int, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	candidate, ok := r.data.candidates[id]
+	if !ok || candidate.UserID != userID || candidate.DeletedAt.Valid {
+		return 0, store.ErrNotFound
+	}
+	if candidate.Points < amount {
+		return 0, store.ErrInsufficientPoints
+	}
+	candidate.Points -= amount
+	r.data.candidates[id] = candidate
+	return candidate.Points, nil
+}
+
+// storedCandidate 返回去掉关联数据的副本
+func storedCandidate(candidate *model.Candidate) model.Candidate {
+	stored := *candidate
+	stored.Photos = nil
+	stored.PointEntries = nil
+	return stored
+}
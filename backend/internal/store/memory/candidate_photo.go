@@ -0,0 +1,271 @@
+package memory
+
+import (
+	"fmt"
+	"time"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CandidatePhotoRepository 候选人照片仓储的内存实现
+type CandidatePhotoRepository struct {
+	data *data
+}
+
+var _ store.CandidatePhotoRepository = (*CandidatePhotoRepository)(nil)
+
+// newestFirst 按创建时间倒序
+func newestFirst(a, b model.CandidatePhoto) bool {
+	return a.CreatedAt.After(b.CreatedAt)
+}
+
+// List 获取候选人的所有照片
+func (r *CandidatePhotoRepository) List(candidateID uuid.UUID) ([]model.CandidatePhoto, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	return filter(r.data.photos, func(p model.CandidatePhoto) bool {
+		return p.CandidateID == candidateID && !p.DeletedAt.Valid
+	}, newestFirst), nil
+}
+
+// Get 获取单张照片
+func (r *CandidatePhotoRepository) Get(id uuid.UUID, candidateID uuid.UUID) (*model.CandidatePhoto, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	photo, ok := r.data.photos[id]
+	if !ok || photo.CandidateID != candidateID || photo.DeletedAt.Valid {
+		return nil, store.ErrNotFound
+	}
+	return &photo, nil
+}
+
+// GetAvatar 获取候选人的头像
+func (r *CandidatePhotoRepository) GetAvatar(candidateID uuid.UUID) (*model.CandidatePhoto, error) {
+	return r.first(func(p model.CandidatePhoto) bool {
+		return p.CandidateID == candidateID && p.IsAvatar && !p.DeletedAt.Valid
+	})
+}
+
+// GetLatest 获取候选人最新上传的照片
+func (r *CandidatePhotoRepository) GetLatest(candidateID uuid.UUID) (*model.CandidatePhoto, error) {
+	return r.first(func(p model.CandidatePhoto) bool {
+		return p.CandidateID == candidateID && !p.DeletedAt.Valid
+	})
+}
+
+// Create 创建照片记录
+func (r *CandidatePhotoRepository) Create(photo *model.CandidatePhoto) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	return r.create(photo)
+}
+
+// CreateBatch 批量创建照片记录
+func (r *CandidatePhotoRepository) CreateBatch(photos []model.CandidatePhoto) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for i := range photos {
+		if err := r.create(&photos[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete 删除照片（软删除，移入回收站）
+func (r *CandidatePhotoRepository) Delete(id uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	photo, ok := r.data.photos[id]
+	if !ok || photo.DeletedAt.Valid {
+		return nil
+	}
+	photo.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	r.data.photos[id] = photo
+	return nil
+}
+
+// TrashByCandidateID 将候选人的所有照片移入回收站，删除时间为 at
+func (r *CandidatePhotoRepository) TrashByCandidateID(candidateID uuid.UUID, at time.Time) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for id, photo := range r.data.photos {
+		if photo.CandidateID == candidateID && !photo.DeletedAt.Valid {
+			photo.DeletedAt = gorm.DeletedAt{Time: at, Valid: true}
+			r.data.photos[id] = photo
+		}
+	}
+	return nil
+}
+
+// RestoreByCandidateID 恢复候选人在 at 时刻一起移入回收站的照片
+func (r *CandidatePhotoRepository) RestoreByCandidateID(candidateID uuid.UUID, at time.Time) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for id, photo := range r.data.photos {
+		if photo.CandidateID == candidateID && photo.DeletedAt.Valid && photo.DeletedAt.Time.Equal(at) {
+			photo.DeletedAt = gorm.DeletedAt{}
+			r.data.photos[id] = photo
+		}
+	}
+	return nil
+}
+
+// ListAll 获取候选人的所有照片（包括回收站中的照片）
+func (r *CandidatePhotoRepository) ListAll(candidateID uuid.UUID) ([]model.CandidatePhoto, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	return filter(r.data.photos, func(p model.CandidatePhoto) bool {
+		return p.CandidateID == candidateID
+	}, nil), nil
+}
+
+// ListDeleted 获取用户回收站中单独删除的照片（所属候选人未被删除）
+func (r *CandidatePhotoRepository) ListDeleted(userID uuid.UUID) ([]model.CandidatePhoto, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	return filter(r.data.photos, func(p model.CandidatePhoto) bool {
+		candidate, ok := r.data.candidates[p.CandidateID]
+		return ok && candidate.UserID == userID && !candidate.DeletedAt.Valid && p.DeletedAt.Valid
+	}, func(a, b model.CandidatePhoto) bool {
+		return a.DeletedAt.Time.After(b.DeletedAt.Time)
+	}), nil
+}
+
+// GetDeleted 获取用户回收站中的照片
+func (r *CandidatePhotoRepository) GetDeleted(id uuid.UUID, userID uuid.UUID) (*model.CandidatePhoto, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	photo, ok := r.data.photos[id]
+	if !ok || !photo.DeletedAt.Valid {
+		return nil, store.ErrNotFound
+	}
+	candidate, ok := r.data.candidates[photo.CandidateID]
+	if !ok || candidate.UserID != userID {
+		return nil, store.ErrNotFound
+	}
+	return &photo, nil
+}
+
+// ListExpired 获取在 before 之前移入回收站的照片（所有用户）
+func (r *CandidatePhotoRepository) ListExpired(before time.Time) ([]model.CandidatePhoto, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	return filter(r.data.photos, func(p model.CandidatePhoto) bool {
+		return p.DeletedAt.Valid && p.DeletedAt.Time.Before(before)
+	}, nil), nil
+}
+
+// Restore 从回收站恢复照片
+func (r *CandidatePhotoRepository) Restore(id uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	if photo, ok := r.data.photos[id]; ok {
+		photo.DeletedAt = gorm.DeletedAt{}
+		r.data.photos[id] = photo
+	}
+	return nil
+}
+
+// Purge 彻底删除照片
+func (r *CandidatePhotoRepository) Purge(id uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	delete(r.data.photos, id)
+	return nil
+}
+
+// PurgeByCandidateID 彻底删除候选人的所有照片
+func (r *CandidatePhotoRepository) PurgeByCandidateID(candidateID uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for id, photo := range r.data.photos {
+		if photo.CandidateID == candidateID {
+			delete(r.data.photos, id)
+		}
+	}
+	return nil
+}
+
+// SetAvatar 设置头像（将指定照片设为头像，其他照片取消头像标记）
+// 照片不存在时返回 store.ErrNotFound，且不修改任何数据
+func (r *CandidatePhotoRepository) SetAvatar(candidateID uuid.UUID, photoID uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	photo, ok := r.data.photos[photoID]
+	if !ok || photo.CandidateID != candidateID || photo.DeletedAt.Valid {
+		return store.ErrNotFound
+	}
+	r.clearAvatar(candidateID)
+	photo.IsAvatar = true
+	r.data.photos[photoID] = photo
+	return nil
+}
+
+// ClearAvatar 取消候选人的头像标记
+func (r *CandidatePhotoRepository) ClearAvatar(candidateID uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	r.clearAvatar(candidateID)
+	return nil
+}
+
+// create 每个候选人最多一张头像，调用方需持有锁
+func (r *CandidatePhotoRepository) create(photo *model.CandidatePhoto) error {
+	if photo.IsAvatar {
+		for _, existing := range r.data.photos {
+			if existing.CandidateID == photo.CandidateID && existing.IsAvatar {
+				return fmt.Errorf("UNIQUE constraint failed: candidate_photos.candidate_id")
+			}
+		}
+	}
+	if photo.ID == uuid.Nil {
+		photo.ID = uuid.New()
+	}
+	if photo.CreatedAt.IsZero() {
+		photo.CreatedAt = time.Now()
+	}
+	r.data.photos[photo.ID] = *photo
+	return nil
+}
+
+// clearAvatar 取消候选人所有照片（包括回收站中的照片）的头像标记，调用方需持有锁
+func (r *CandidatePhotoRepository) clearAvatar(candidateID uuid.UUID) {
+	for id, photo := range r.data.photos {
+		if photo.CandidateID == candidateID && photo.IsAvatar {
+			photo.IsAvatar = false
+			r.data.photos[id] = photo
+		}
+	}
+}
+
+// first 返回满足条件的最新照片
+func (r *CandidatePhotoRepository) first(match func(model.CandidatePhoto) bool) (*model.CandidatePhoto, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	photos := filter(r.data.photos, match, newestFirst)
+	if len(photos) == 0 {
+		return nil, store.ErrNotFound
+	}
+	return &photos[0], nil
+}
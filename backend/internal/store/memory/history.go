@@ -0,0 +1,192 @@
+package memory
+
+import (
+	"time"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// HistoryRepository 历史记录仓储的内存实现
+type HistoryRepository struct {
+	data *data
+}
+
+var _ store.HistoryRepository = (*HistoryRepository)(nil)
+
+// List 获取历史记录列表（不包括回收站中项目的记录）；limit 小于 0 时不限制条数
+func (r *HistoryRepository) List(userID uuid.UUID, projectID *uuid.UUID, limit int) ([]model.History, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	histories := filter(r.data.histories, func(h model.History) bool {
+		if h.UserID != userID {
+			return false
+		}
+		if projectID != nil && h.ProjectID != *projectID {
+			return false
+		}
+		project, ok := r.data.projects[h.ProjectID]
+		return !ok || !project.DeletedAt.Valid
+	}, func(a, b model.History) bool {
+		return a.SelectedAt.After(b.SelectedAt)
+	})
+
+	if limit >= 0 && len(histories) > limit {
+		histories = histories[:limit]
+	}
+	return histories, nil
+}
+
+// Find 按过滤条件分页获取历史记录（不包括回收站中项目的记录）
+func (r *HistoryRepository) Find(userID uuid.UUID, f store.HistoryFilter, page store.PageRequest) (*store.Page[model.History], error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	items := filter(r.data.histories, func(h model.History) bool {
+		switch {
+		case h.UserID != userID:
+			return false
+		case f.ProjectID != nil && h.ProjectID != *f.ProjectID:
+			return false
+		case f.CandidateID != nil && h.CandidateID != *f.CandidateID:
+			return false
+		case f.From != nil && h.SelectedAt.Before(*f.From):
+			return false
+		case f.To != nil && !h.SelectedAt.Before(*f.To):
+			return false
+		case f.Source != "" && h.Source != f.Source:
+			return false
+		case f.Voided != nil && *f.Voided != (h.VoidedAt != nil):
+			return false
+		}
+		project, ok := r.data.projects[h.ProjectID]
+		return !ok || !project.DeletedAt.Valid
+	}, nil)
+
+	return paginate(items, page, store.HistorySortFields, func(h *model.History, field string) (any, uuid.UUID) {
+		switch field {
+		case "candidate_name":
+			return h.CandidateName, h.ID
+		case "project_name":
+			return h.ProjectName, h.ID
+		default:
+			return h.SelectedAt, h.ID
+		}
+	})
+}
+
+// Get 获取单条历史记录（不包括回收站中项目的记录）
+func (r *HistoryRepository) Get(id uuid.UUID, userID uuid.UUID) (*model.History, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	history, ok := r.data.histories[id]
+	if !ok || history.UserID != userID {
+		return nil, store.ErrNotFound
+	}
+	if project, ok := r.data.projects[history.ProjectID]; ok && project.DeletedAt.Valid {
+		return nil, store.ErrNotFound
+	}
+	return &history, nil
+}
+
+// Create 创建历史记录
+func (r *HistoryRepository) Create(history *model.History) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	if history.ID == uuid.Nil {
+		history.ID = uuid.New()
+	}
+	if history.Source == "" {
+		history.Source = model.HistorySourceDraw
+	}
+	if history.Status == "" {
+		history.Status = model.HistoryStatusPicked
+	}
+	r.data.histories[history.ID] = *history
+	return nil
+}
+
+// Update 更新历史记录的备注、任务状态、作废标记和重新选择的关联，其余字段不可修改
+func (r *HistoryRepository) Update(history *model.History) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	existing, ok := r.data.histories[history.ID]
+	if !ok || existing.UserID != history.UserID {
+		return nil
+	}
+	existing.Note = history.Note
+	existing.Status = history.Status
+	existing.StartedAt = history.StartedAt
+	existing.CompletedAt = history.CompletedAt
+	existing.SkippedAt = history.SkippedAt
+	existing.VoidedAt = history.VoidedAt
+	existing.VoidReason = history.VoidReason
+	existing.SupersededByID = history.SupersededByID
+	existing.SupersededAt = history.SupersededAt
+	existing.RerollOfID = history.RerollOfID
+	r.data.histories[history.ID] = existing
+	return nil
+}
+
+// ListByRoutineRun 按步骤顺序获取例程执行产生的历史记录（包括回收站中项目的记录）
+func (r *HistoryRepository) ListByRoutineRun(runID uuid.UUID) ([]model.History, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	return filter(r.data.histories, func(h model.History) bool {
+		return h.RoutineRunID != nil && *h.RoutineRunID == runID
+	}, func(a, b model.History) bool {
+		return *a.RoutineStep < *b.RoutineStep
+	}), nil
+}
+
+// CountSuperseded 按候选人统计项目中自 since 起被重新选择取代的记录数
+func (r *HistoryRepository) CountSuperseded(projectID uuid.UUID, since time.Time) ([]store.SupersededCount, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	byCandidate := make(map[uuid.UUID]int)
+	for _, history := range r.data.histories {
+		if history.ProjectID == projectID && history.SupersededAt != nil && !history.SupersededAt.Before(since) {
+			byCandidate[history.CandidateID]++
+		}
+	}
+	counts := make([]store.SupersededCount, 0, len(byCandidate))
+	for candidateID, count := range byCandidate {
+		counts = append(counts, store.SupersededCount{CandidateID: candidateID, Count: count})
+	}
+	return counts, nil
+}
+
+// DeleteByProject 删除项目相关的历史记录及其修改记录
+func (r *HistoryRepository) DeleteByProject(projectID uuid.UUID, userID uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for id, history := range r.data.histories {
+		if history.ProjectID == projectID && history.UserID == userID {
+			r.data.deleteHistory(id)
+		}
+	}
+	return nil
+}
+
+// DeleteByUser 删除用户的所有历史记录及其修改记录，返回删除的历史记录条数
+func (r *HistoryRepository) DeleteByUser(userID uuid.UUID) (int64, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	var deleted int64
+	for id, history := range r.data.histories {
+		if history.UserID == userID {
+			r.data.deleteHistory(id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,44 @@
+package memory
+
+import (
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// HistoryChangeRepository 历史记录修改记录仓储的内存实现
+type HistoryChangeRepository struct {
+	data *data
+}
+
+var _ store.HistoryChangeRepository = (*HistoryChangeRepository)(nil)
+
+// List 按时间顺序获取历史记录的修改记录
+func (r *HistoryChangeRepository) List(historyID uuid.UUID) ([]model.HistoryChange, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	return filter(r.data.changes, func(c model.HistoryChange) bool {
+		return c.HistoryID == historyID
+	}, func(a, b model.HistoryChange) bool {
+		if !a.ChangedAt.Equal(b.ChangedAt) {
+			return a.ChangedAt.Before(b.ChangedAt)
+		}
+		return a.Field < b.Field
+	}), nil
+}
+
+// Create 批量创建修改记录
+func (r *HistoryChangeRepository) Create(changes []model.HistoryChange) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for i := range changes {
+		if changes[i].ID == uuid.Nil {
+			changes[i].ID = uuid.New()
+		}
+		r.data.changes[changes[i].ID] = changes[i]
+	}
+	return nil
+}
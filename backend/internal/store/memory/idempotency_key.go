@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"fmt"
+	"time"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyKeyRepository 幂等键仓储的内存实现
+type IdempotencyKeyRepository struct {
+	data *data
+}
+
+var _ store.IdempotencyKeyRepository = (*IdempotencyKeyRepository)(nil)
+
+// Get 获取用户的幂等键
+func (r *IdempotencyKeyRepository) Get(userID uuid.UUID, key string) (*model.IdempotencyKey, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for _, record := range r.data.idempotency {
+		if record.UserID == userID && record.Key == key {
+			return &record, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+// Create 创建幂等键，同一用户的键已存在时返回错误
+func (r *IdempotencyKeyRepository) Create(record *model.IdempotencyKey) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for _, existing := range r.data.idempotency {
+		if existing.UserID == record.UserID && existing.Key == record.Key {
+			return fmt.Errorf("UNIQUE constraint failed: idempotency_keys.user_id, idempotency_keys.key")
+		}
+	}
+	if record.ID == uuid.Nil {
+		record.ID = uuid.New()
+	}
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now()
+	}
+	r.data.idempotency[record.ID] = *record
+	return nil
+}
+
+// Complete 保存幂等键对应请求的响应
+func (r *IdempotencyKeyRepository) Complete(record *model.IdempotencyKey) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	existing, ok := r.data.idempotency[record.ID]
+	if !ok {
+		return nil
+	}
+	existing.StatusCode = record.StatusCode
+	existing.ContentType = record.ContentType
+	existing.ResponseBody = record.ResponseBody
+	r.data.idempotency[record.ID] = existing
+	return nil
+}
+
+// Delete 删除幂等键
+func (r *IdempotencyKeyRepository) Delete(id uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	delete(r.data.idempotency, id)
+	return nil
+}
+
+// DeleteExpired 删除在 before 之前过期的幂等键，返回删除的条数
+func (r *IdempotencyKeyRepository) DeleteExpired(before time.Time) (int64, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	var deleted int64
+	for id, record := range r.data.idempotency {
+		if record.ExpiresAt.Before(before) {
+			delete(r.data.idempotency, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// DeleteByUser 删除用户的所有幂等键
+func (r *IdempotencyKeyRepository) DeleteByUser(userID uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for id, record := range r.data.idempotency {
+		if record.UserID == userID {
+			delete(r.data.idempotency, id)
+		}
+	}
+	return nil
+}
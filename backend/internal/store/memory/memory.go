@@ -0,0 +1,276 @@
+// Package memory 提供 store.Store 的内存实现，用于不依赖数据库的快速测试
+//
+// 行为与数据库实现保持一致：软删除、级联删除、唯一约束和排序规则相同，
+// 找不到记录时返回 store.ErrNotFound。事务之间串行执行，失败时通过快照回滚；
+// 不提供事务隔离，事务外的并发读写可能看到未提交的数据。
+package memory
+
+import (
+	"sort"
+	"sync"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// Store 内存数据存储
+type Store struct {
+	data *data
+	inTx bool
+}
+
+// data 所有表的数据
+type data struct {
+	mu   sync.Mutex // 保护以下所有表
+	txMu sync.Mutex // 串行化事务
+
+	users       map[uuid.UUID]model.User
+	projects    map[uuid.UUID]model.Project
+	candidates  map[uuid.UUID]model.Candidate
+	photos      map[uuid.UUID]model.CandidatePhoto
+	histories   map[uuid.UUID]model.History
+	deletions   map[uuid.UUID]model.AccountDeletion
+	feeds       map[uuid.UUID]model.CalendarFeed
+	changes     map[uuid.UUID]model.HistoryChange
+	rewards     map[uuid.UUID]model.Reward
+	points      map[uuid.UUID]model.PointEntry
+	rules       map[uuid.UUID]model.BadgeRule
+	badges      map[uuid.UUID]model.Badge
+	routines    map[uuid.UUID]model.Routine
+	runs        map[uuid.UUID]model.RoutineRun
+	constraints map[uuid.UUID]model.ProjectConstraint
+	splits      map[uuid.UUID]model.TeamSplit
+	idempotency map[uuid.UUID]model.IdempotencyKey
+}
+
+var _ store.Store = (*Store)(nil)
+
+// New 创建空的内存数据存储
+func New() *Store {
+	return &Store{data: &data{
+		users:       make(map[uuid.UUID]model.User),
+		projects:    make(map[uuid.UUID]model.Project),
+		candidates:  make(map[uuid.UUID]model.Candidate),
+		photos:      make(map[uuid.UUID]model.CandidatePhoto),
+		histories:   make(map[uuid.UUID]model.History),
+		deletions:   make(map[uuid.UUID]model.AccountDeletion),
+		feeds:       make(map[uuid.UUID]model.CalendarFeed),
+		changes:     make(map[uuid.UUID]model.HistoryChange),
+		rewards:     make(map[uuid.UUID]model.Reward),
+		points:      make(map[uuid.UUID]model.PointEntry),
+		rules:       make(map[uuid.UUID]model.BadgeRule),
+		badges:      make(map[uuid.UUID]model.Badge),
+		routines:    make(map[uuid.UUID]model.Routine),
+		runs:        make(map[uuid.UUID]model.RoutineRun),
+		constraints: make(map[uuid.UUID]model.ProjectConstraint),
+		splits:      make(map[uuid.UUID]model.TeamSplit),
+		idempotency: make(map[uuid.UUID]model.IdempotencyKey),
+	}}
+}
+
+func (s *Store) Users() store.UserRepository {
+	return &UserRepository{data: s.data}
+}
+
+func (s *Store) Projects() store.ProjectRepository {
+	return &ProjectRepository{data: s.data}
+}
+
+func (s *Store) Candidates() store.CandidateRepository {
+	return &CandidateRepository{data: s.data}
+}
+
+func (s *Store) CandidatePhotos() store.CandidatePhotoRepository {
+	return &CandidatePhotoRepository{data: s.data}
+}
+
+func (s *Store) Histories() store.HistoryRepository {
+	return &HistoryRepository{data: s.data}
+}
+
+func (s *Store) AccountDeletions() store.AccountDeletionRepository {
+	return &AccountDeletionRepository{data: s.data}
+}
+
+func (s *Store) CalendarFeeds() store.CalendarFeedRepository {
+	return &CalendarFeedRepository{data: s.data}
+}
+
+func (s *Store) HistoryChanges() store.HistoryChangeRepository {
+	return &HistoryChangeRepository{data: s.data}
+}
+
+func (s *Store) Rewards() store.RewardRepository {
+	return &RewardRepository{data: s.data}
+}
+
+func (s *Store) PointEntries() store.PointEntryRepository {
+	return &PointEntryRepository{data: s.data}
+}
+
+func (s *Store) BadgeRules() store.BadgeRuleRepository {
+	return &BadgeRuleRepository{data: s.data}
+}
+
+func (s *Store) Badges() store.BadgeRepository {
+	return &BadgeRepository{data: s.data}
+}
+
+func (s *Store) Routines() store.RoutineRepository {
+	return &RoutineRepository{data: s.data}
+}
+
+func (s *Store) RoutineRuns() store.RoutineRunRepository {
+	return &RoutineRunRepository{data: s.data}
+}
+
+func (s *Store) ProjectConstraints() store.ProjectConstraintRepository {
+	return &ProjectConstraintRepository{data: s.data}
+}
+
+func (s *Store) TeamSplits() store.TeamSplitRepository {
+	return &TeamSplitRepository{data: s.data}
+}
+
+func (s *Store) IdempotencyKeys() store.IdempotencyKeyRepository {
+	return &IdempotencyKeyRepository{data: s.data}
+}
+
+// Transaction 在事务中执行 fn，fn 返回错误时恢复到事务开始前的数据；嵌套调用直接执行 fn
+func (s *Store) Transaction(fn func(tx store.Store) error) error {
+	if s.inTx {
+		return fn(s)
+	}
+
+	s.data.txMu.Lock()
+	defer s.data.txMu.Unlock()
+
+	snapshot := s.data.snapshot()
+	if err := fn(&Store{data: s.data, inTx: true}); err != nil {
+		s.data.restore(snapshot)
+		return err
+	}
+	return nil
+}
+
+// snapshot 复制当前所有表
+func (d *data) snapshot() *data {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return &data{
+		users:       cloneMap(d.users),
+		projects:    cloneMap(d.projects),
+		candidates:  cloneMap(d.candidates),
+		photos:      cloneMap(d.photos),
+		histories:   cloneMap(d.histories),
+		deletions:   cloneMap(d.deletions),
+		feeds:       cloneMap(d.feeds),
+		changes:     cloneMap(d.changes),
+		rewards:     cloneMap(d.rewards),
+		points:      cloneMap(d.points),
+		rules:       cloneMap(d.rules),
+		badges:      cloneMap(d.badges),
+		routines:    cloneMap(d.routines),
+		runs:        cloneMap(d.runs),
+		constraints: cloneMap(d.constraints),
+		splits:      cloneMap(d.splits),
+		idempotency: cloneMap(d.idempotency),
+	}
+}
+
+// restore 恢复到快照时的数据
+func (d *data) restore(snapshot *data) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.users = snapshot.users
+	d.projects = snapshot.projects
+	d.candidates = snapshot.candidates
+	d.photos = snapshot.photos
+	d.histories = snapshot.histories
+	d.deletions = snapshot.deletions
+	d.feeds = snapshot.feeds
+	d.changes = snapshot.changes
+	d.rewards = snapshot.rewards
+	d.points = snapshot.points
+	d.rules = snapshot.rules
+	d.badges = snapshot.badges
+	d.routines = snapshot.routines
+	d.runs = snapshot.runs
+	d.constraints = snapshot.constraints
+	d.splits = snapshot.splits
+	d.idempotency = snapshot.idempotency
+}
+
+// deleteHistory 删除历史记录并级联删除其修改记录，调用方需持有 d.mu
+func (d *data) deleteHistory(id uuid.UUID) {
+	delete(d.histories, id)
+	for changeID, change := range d.changes {
+		if change.HistoryID == id {
+			delete(d.changes, changeID)
+		}
+	}
+}
+
+// deleteProjectConstraints 删除以项目为来源或目标的所有约束，调用方需持有 d.mu
+func (d *data) deleteProjectConstraints(projectID uuid.UUID) {
+	for id, constraint := range d.constraints {
+		if constraint.SourceProjectID == projectID || constraint.TargetProjectID == projectID {
+			delete(d.constraints, id)
+		}
+	}
+}
+
+// deleteCandidate 删除候选人并级联删除其照片记录、积分流水和徽章，调用方需持有 d.mu
+func (d *data) deleteCandidate(id uuid.UUID) {
+	delete(d.candidates, id)
+	for photoID, photo := range d.photos {
+		if photo.CandidateID == id {
+			delete(d.photos, photoID)
+		}
+	}
+	for entryID, entry := range d.points {
+		if entry.CandidateID == id {
+			delete(d.points, entryID)
+		}
+	}
+	for badgeID, badge := range d.badges {
+		if badge.CandidateID == id {
+			delete(d.badges, badgeID)
+		}
+	}
+}
+
+// deleteBadgeRule 删除徽章规则并级联删除其授予的徽章，调用方需持有 d.mu
+func (d *data) deleteBadgeRule(id uuid.UUID) {
+	delete(d.rules, id)
+	for badgeID, badge := range d.badges {
+		if badge.RuleID == id {
+			delete(d.badges, badgeID)
+		}
+	}
+}
+
+func cloneMap[K comparable, V any](m map[K]V) map[K]V {
+	cloned := make(map[K]V, len(m))
+	for k, v := range m {
+		cloned[k] = v
+	}
+	return cloned
+}
+
+// filter 返回 m 中满足 keep 的值，按 less 排序
+func filter[V any](m map[uuid.UUID]V, keep func(V) bool, less func(a, b V) bool) []V {
+	values := make([]V, 0)
+	for _, v := range m {
+		if keep(v) {
+			values = append(values, v)
+		}
+	}
+	if less != nil {
+		sort.SliceStable(values, func(i, j int) bool {
+			return less(values[i], values[j])
+		})
+	}
+	return values
+}
@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// paginate 对已过滤的记录做游标分页，排序和游标规则与数据库实现一致
+// key 返回记录的排序字段值（string 或 time.Time）和 ID
+func paginate[T any](items []T, page store.PageRequest, allowed []string, key func(item *T, field string) (any, uuid.UUID)) (*store.Page[T], error) {
+	field, err := store.ParseSort(page.Sort, allowed)
+	if err != nil {
+		return nil, err
+	}
+
+	// 排序字段相同时按 ID 排序，保证顺序稳定
+	compare := func(value any, id uuid.UUID, otherValue any, otherID uuid.UUID) int {
+		c := compareValues(value, otherValue)
+		if c == 0 {
+			c = strings.Compare(id.String(), otherID.String())
+		}
+		if field.Desc {
+			return -c
+		}
+		return c
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		vi, idi := key(&items[i], field.Name)
+		vj, idj := key(&items[j], field.Name)
+		return compare(vi, idi, vj, idj) < 0
+	})
+
+	result := &store.Page[T]{Items: make([]T, 0), Total: int64(len(items))}
+	if page.Cursor != "" {
+		cursorValue, cursorID, err := store.DecodeCursor(page.Cursor, field)
+		if err != nil {
+			return nil, err
+		}
+		start := len(items)
+		for i := range items {
+			v, id := key(&items[i], field.Name)
+			if compare(v, id, cursorValue, cursorID) > 0 {
+				start = i
+				break
+			}
+		}
+		items = items[start:]
+	}
+
+	limit := page.PageLimit()
+	if len(items) > limit {
+		v, id := key(&items[limit-1], field.Name)
+		result.NextCursor = store.EncodeCursor(field, v, id)
+		items = items[:limit]
+	}
+	result.Items = append(result.Items, items...)
+	return result, nil
+}
+
+// compareValues 比较两个同类型的排序字段值
+func compareValues(a, b any) int {
+	switch a := a.(type) {
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	case string:
+		return strings.Compare(a, b.(string))
+	}
+	return 0
+}
@@ -0,0 +1,113 @@
+package memory
+
+import (
+	"time"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// PointEntryRepository 积分流水仓储的内存实现
+type PointEntryRepository struct {
+	data *data
+}
+
+var _ store.PointEntryRepository = (*PointEntryRepository)(nil)
+
+// Find 按过滤条件分页获取积分流水
+func (r *PointEntryRepository) Find(userID uuid.UUID, f store.PointEntryFilter, page store.PageRequest) (*store.Page[model.PointEntry], error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	items := filter(r.data.points, func(e model.PointEntry) bool {
+		return e.UserID == userID &&
+			(f.CandidateID == nil || e.CandidateID == *f.CandidateID) &&
+			(f.Kind == "" || e.Kind == f.Kind)
+	}, nil)
+
+	return paginate(items, page, store.PointEntrySortFields, func(e *model.PointEntry, field string) (any, uuid.UUID) {
+		return e.CreatedAt, e.ID
+	})
+}
+
+// ListByHistory 获取历史记录产生的积分流水
+func (r *PointEntryRepository) ListByHistory(historyID uuid.UUID) ([]model.PointEntry, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	return filter(r.data.points, func(e model.PointEntry) bool {
+		return e.HistoryID != nil && *e.HistoryID == historyID
+	}, func(a, b model.PointEntry) bool {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}), nil
+}
+
+// Totals 按候选人汇总用户的积分流水
+func (r *PointEntryRepository) Totals(userID uuid.UUID) ([]store.PointTotals, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	byCandidate := make(map[uuid.UUID]*store.PointTotals)
+	totals := make([]store.PointTotals, 0)
+	for _, entry := range r.data.points {
+		if entry.UserID != userID {
+			continue
+		}
+		t, ok := byCandidate[entry.CandidateID]
+		if !ok {
+			t = &store.PointTotals{CandidateID: entry.CandidateID}
+			byCandidate[entry.CandidateID] = t
+		}
+		if entry.Kind == model.PointKindRedeem {
+			t.Redeemed -= entry.Amount
+		} else {
+			t.Earned += entry.Amount
+		}
+	}
+	for _, t := range byCandidate {
+		totals = append(totals, *t)
+	}
+	return totals, nil
+}
+
+// Create 创建积分流水
+func (r *PointEntryRepository) Create(entry *model.PointEntry) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	if entry.ID == uuid.Nil {
+		entry.ID = uuid.New()
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	r.data.points[entry.ID] = *entry
+	return nil
+}
+
+// DeleteByCandidate 删除候选人的积分流水
+func (r *PointEntryRepository) DeleteByCandidate(candidateID uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for id, entry := range r.data.points {
+		if entry.CandidateID == candidateID {
+			delete(r.data.points, id)
+		}
+	}
+	return nil
+}
+
+// DeleteByUser 删除用户的所有积分流水
+func (r *PointEntryRepository) DeleteByUser(userID uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for id, entry := range r.data.points {
+		if entry.UserID == userID {
+			delete(r.data.points, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+package memory
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ProjectRepository 项目仓储的内存实现
+type ProjectRepository struct {
+	data *data
+}
+
+var _ store.ProjectRepository = (*ProjectRepository)(nil)
+
+// List 获取项目列表
+func (r *ProjectRepository) List(userID uuid.UUID) ([]model.Project, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	return filter(r.data.projects, func(p model.Project) bool {
+		return p.UserID == userID && !p.DeletedAt.Valid
+	}, func(a, b model.Project) bool {
+		return a.CreatedAt.After(b.CreatedAt)
+	}), nil
+}
+
+// Find 按过滤条件分页获取项目列表
+func (r *ProjectRepository) Find(userID uuid.UUID, f store.ProjectFilter, page store.PageRequest) (*store.Page[model.Project], error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	query := strings.ToLower(f.Query)
+	items := filter(r.data.projects, func(p model.Project) bool {
+		return p.UserID == userID && !p.DeletedAt.Valid &&
+			strings.Contains(strings.ToLower(p.Name), query)
+	}, nil)
+
+	return paginate(items, page, store.ProjectSortFields, func(p *model.Project, field string) (any, uuid.UUID) {
+		switch field {
+		case "name":
+			return p.Name, p.ID
+		case "updated_at":
+			return p.UpdatedAt, p.ID
+		default:
+			return p.CreatedAt, p.ID
+		}
+	})
+}
+
+// Get 获取项目详情
+func (r *ProjectRepository) Get(id uuid.UUID, userID uuid.UUID) (*model.Project, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	project, ok := r.data.projects[id]
+	if !ok || project.UserID != userID || project.DeletedAt.Valid {
+		return nil, store.ErrNotFound
+	}
+	return &project, nil
+}
+
+// Create 创建项目
+func (r *ProjectRepository) Create(project *model.Project) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	if project.ID == uuid.Nil {
+		project.ID = uuid.New()
+	}
+	now := time.Now()
+	if project.CreatedAt.IsZero() {
+		project.CreatedAt = now
+	}
+	project.UpdatedAt = now
+	r.data.projects[project.ID] = storedProject(project)
+	return nil
+}
+
+// Update 更新项目
+func (r *ProjectRepository) Update(project *model.Project) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	project.UpdatedAt = time.Now()
+	r.data.projects[project.ID] = storedProject(project)
+	return nil
+}
+
+// Delete 删除项目（软删除，移入回收站）
+func (r *ProjectRepository) Delete(id uuid.UUID, userID uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	project, ok := r.data.projects[id]
+	if !ok || project.UserID != userID || project.DeletedAt.Valid {
+		return nil
+	}
+	project.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	r.data.projects[id] = project
+	return nil
+}
+
+// ListDeleted 获取回收站中的项目
+func (r *ProjectRepository) ListDeleted(userID uuid.UUID) ([]model.Project, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	return filter(r.data.projects, func(p model.Project) bool {
+		return p.UserID == userID && p.DeletedAt.Valid
+	}, func(a, b model.Project) bool {
+		return a.DeletedAt.Time.After(b.DeletedAt.Time)
+	}), nil
+}
+
+// GetDeleted 获取回收站中的项目
+func (r *ProjectRepository) GetDeleted(id uuid.UUID, userID uuid.UUID) (*model.Project, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	project, ok := r.data.projects[id]
+	if !ok || project.UserID != userID || !project.DeletedAt.Valid {
+		return nil, store.ErrNotFound
+	}
+	return &project, nil
+}
+
+// ListExpired 获取在 before 之前移入回收站的项目（所有用户）
+func (r *ProjectRepository) ListExpired(before time.Time) ([]model.Project, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	return filter(r.data.projects, func(p model.Project) bool {
+		return p.DeletedAt.Valid && p.DeletedAt.Time.Before(before)
+	}, nil), nil
+}
+
+// Restore 从回收站恢复项目
+func (r *ProjectRepository) Restore(id uuid.UUID, userID uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	project, ok := r.data.projects[id]
+	if !ok || project.UserID != userID || !project.DeletedAt.Valid {
+		return store.ErrNotFound
+	}
+	project.DeletedAt = gorm.DeletedAt{}
+	r.data.projects[id] = project
+	return nil
+}
+
+// Purge 彻底删除项目，级联删除其历史记录
+func (r *ProjectRepository) Purge(id uuid.UUID, userID uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	project, ok := r.data.projects[id]
+	if !ok || project.UserID != userID {
+		return nil
+	}
+	delete(r.data.projects, id)
+	for historyID, history := range r.data.histories {
+		if history.ProjectID == id {
+			r.data.deleteHistory(historyID)
+		}
+	}
+	r.data.deleteProjectConstraints(id)
+	for splitID, split := range r.data.splits {
+		if split.ProjectID == id {
+			delete(r.data.splits, splitID)
+		}
+	}
+	return nil
+}
+
+// GetCandidateIDs 获取项目的候选人ID列表
+func (r *ProjectRepository) GetCandidateIDs(projectID uuid.UUID) ([]uuid.UUID, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	project, ok := r.data.projects[projectID]
+	if !ok || project.DeletedAt.Valid {
+		return nil, store.ErrNotFound
+	}
+	return decodeCandidateIDs(project.CandidateIDs)
+}
+
+// SetCandidateIDs 设置项目的候选人ID列表
+func (r *ProjectRepository) SetCandidateIDs(projectID uuid.UUID, candidateIDs []uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	return r.setCandidateIDs(projectID, candidateIDs)
+}
+
+// RemoveCandidate 从用户所有项目（包括回收站中的项目）的候选人列表中移除指定候选人
+func (r *ProjectRepository) RemoveCandidate(candidateID uuid.UUID, userID uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for _, project := range r.data.projects {
+		if project.UserID != userID {
+			continue
+		}
+		candidateIDs, err := decodeCandidateIDs(project.CandidateIDs)
+		if err != nil {
+			return err
+		}
+
+		kept := make([]uuid.UUID, 0, len(candidateIDs))
+		for _, id := range candidateIDs {
+			if id != candidateID {
+				kept = append(kept, id)
+			}
+		}
+		if len(kept) == len(candidateIDs) {
+			continue
+		}
+		if err := r.setCandidateIDs(project.ID, kept); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setCandidateIDs 调用方需持有锁
+func (r *ProjectRepository) setCandidateIDs(projectID uuid.UUID, candidateIDs []uuid.UUID) error {
+	data, err := json.Marshal(candidateIDs)
+	if err != nil {
+		return err
+	}
+	project, ok := r.data.projects[projectID]
+	if !ok {
+		return nil
+	}
+	project.CandidateIDs = string(data)
+	project.UpdatedAt = time.Now()
+	r.data.projects[projectID] = project
+	return nil
+}
+
+// decodeCandidateIDs 解析项目中 JSON 格式的候选人ID列表
+func decodeCandidateIDs(raw string) ([]uuid.UUID, error) {
+	var candidateIDs []uuid.UUID
+	if raw == "" {
+		return candidateIDs, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &candidateIDs); err != nil {
+		return nil, err
+	}
+	return candidateIDs, nil
+}
+
+// storedProject 返回去掉关联数据的副本
+func storedProject(project *model.Project) model.Project {
+	stored := *project
+	stored.Histories = nil
+	if project.TargetMinutes != nil {
+		minutes := *project.TargetMinutes
+		stored.TargetMinutes = &minutes
+	}
+	return stored
+}
@@ -0,0 +1,120 @@
+package memory
+
+import (
+	"fmt"
+	"time"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// ProjectConstraintRepository 项目间约束仓储的内存实现
+type ProjectConstraintRepository struct {
+	data *data
+}
+
+var _ store.ProjectConstraintRepository = (*ProjectConstraintRepository)(nil)
+
+// List 获取用户的项目间约束，按创建时间排序
+func (r *ProjectConstraintRepository) List(userID uuid.UUID) ([]model.ProjectConstraint, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	return filter(r.data.constraints, func(pc model.ProjectConstraint) bool {
+		return pc.UserID == userID
+	}, func(a, b model.ProjectConstraint) bool {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}), nil
+}
+
+// ListByTarget 获取作用于目标项目的约束，按创建时间排序
+func (r *ProjectConstraintRepository) ListByTarget(targetProjectID uuid.UUID, userID uuid.UUID) ([]model.ProjectConstraint, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	return filter(r.data.constraints, func(pc model.ProjectConstraint) bool {
+		return pc.TargetProjectID == targetProjectID && pc.UserID == userID
+	}, func(a, b model.ProjectConstraint) bool {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}), nil
+}
+
+// Get 获取项目间约束
+func (r *ProjectConstraintRepository) Get(id uuid.UUID, userID uuid.UUID) (*model.ProjectConstraint, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	constraint, ok := r.data.constraints[id]
+	if !ok || constraint.UserID != userID {
+		return nil, store.ErrNotFound
+	}
+	return &constraint, nil
+}
+
+// Create 创建项目间约束，来源和目标项目相同的约束已存在时返回错误
+func (r *ProjectConstraintRepository) Create(constraint *model.ProjectConstraint) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for _, existing := range r.data.constraints {
+		if existing.SourceProjectID == constraint.SourceProjectID && existing.TargetProjectID == constraint.TargetProjectID {
+			return fmt.Errorf("UNIQUE constraint failed: project_constraints.source_project_id, project_constraints.target_project_id")
+		}
+	}
+	if constraint.ID == uuid.Nil {
+		constraint.ID = uuid.New()
+	}
+	now := time.Now()
+	if constraint.CreatedAt.IsZero() {
+		constraint.CreatedAt = now
+	}
+	constraint.UpdatedAt = now
+	r.data.constraints[constraint.ID] = *constraint
+	return nil
+}
+
+// Update 更新项目间约束
+func (r *ProjectConstraintRepository) Update(constraint *model.ProjectConstraint) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	constraint.UpdatedAt = time.Now()
+	r.data.constraints[constraint.ID] = *constraint
+	return nil
+}
+
+// Delete 删除项目间约束，约束不存在时返回 store.ErrNotFound
+func (r *ProjectConstraintRepository) Delete(id uuid.UUID, userID uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	constraint, ok := r.data.constraints[id]
+	if !ok || constraint.UserID != userID {
+		return store.ErrNotFound
+	}
+	delete(r.data.constraints, id)
+	return nil
+}
+
+// DeleteByProject 删除以项目为来源或目标的所有约束
+func (r *ProjectConstraintRepository) DeleteByProject(projectID uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	r.data.deleteProjectConstraints(projectID)
+	return nil
+}
+
+// DeleteByUser 删除用户的所有项目间约束
+func (r *ProjectConstraintRepository) DeleteByUser(userID uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for id, constraint := range r.data.constraints {
+		if constraint.UserID == userID {
+			delete(r.data.constraints, id)
+		}
+	}
+	return nil
+}
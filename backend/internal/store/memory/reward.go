@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"time"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// RewardRepository 奖励仓储的内存实现
+type RewardRepository struct {
+	data *data
+}
+
+var _ store.RewardRepository = (*RewardRepository)(nil)
+
+// List 获取用户的奖励，按积分从低到高排序
+func (r *RewardRepository) List(userID uuid.UUID) ([]model.Reward, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	return filter(r.data.rewards, func(rw model.Reward) bool {
+		return rw.UserID == userID
+	}, func(a, b model.Reward) bool {
+		if a.Cost != b.Cost {
+			return a.Cost < b.Cost
+		}
+		return a.Name < b.Name
+	}), nil
+}
+
+// Get 获取奖励
+func (r *RewardRepository) Get(id uuid.UUID, userID uuid.UUID) (*model.Reward, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	reward, ok := r.data.rewards[id]
+	if !ok || reward.UserID != userID {
+		return nil, store.ErrNotFound
+	}
+	return &reward, nil
+}
+
+// Create 创建奖励
+func (r *RewardRepository) Create(reward *model.Reward) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	if reward.ID == uuid.Nil {
+		reward.ID = uuid.New()
+	}
+	now := time.Now()
+	if reward.CreatedAt.IsZero() {
+		reward.CreatedAt = now
+	}
+	reward.UpdatedAt = now
+	r.data.rewards[reward.ID] = *reward
+	return nil
+}
+
+// Update 更新奖励
+func (r *RewardRepository) Update(reward *model.Reward) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	reward.UpdatedAt = time.Now()
+	r.data.rewards[reward.ID] = *reward
+	return nil
+}
+
+// Delete 删除奖励，奖励不存在时返回 store.ErrNotFound；已有的兑换流水保留
+func (r *RewardRepository) Delete(id uuid.UUID, userID uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	reward, ok := r.data.rewards[id]
+	if !ok || reward.UserID != userID {
+		return store.ErrNotFound
+	}
+	delete(r.data.rewards, id)
+	return nil
+}
+
+// DeleteByUser 删除用户的所有奖励
+func (r *RewardRepository) DeleteByUser(userID uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for id, reward := range r.data.rewards {
+		if reward.UserID == userID {
+			delete(r.data.rewards, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package memory
+
+import (
+	"encoding/json"
+	"time"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// RoutineRepository 例程仓储的内存实现
+type RoutineRepository struct {
+	data *data
+}
+
+var _ store.RoutineRepository = (*RoutineRepository)(nil)
+
+// List 获取用户的例程，按创建时间排序
+func (r *RoutineRepository) List(userID uuid.UUID) ([]model.Routine, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	return filter(r.data.routines, func(rt model.Routine) bool {
+		return rt.UserID == userID
+	}, func(a, b model.Routine) bool {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}), nil
+}
+
+// Get 获取例程
+func (r *RoutineRepository) Get(id uuid.UUID, userID uuid.UUID) (*model.Routine, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	routine, ok := r.data.routines[id]
+	if !ok || routine.UserID != userID {
+		return nil, store.ErrNotFound
+	}
+	return &routine, nil
+}
+
+// Create 创建例程
+func (r *RoutineRepository) Create(routine *model.Routine) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	if routine.ID == uuid.Nil {
+		routine.ID = uuid.New()
+	}
+	now := time.Now()
+	if routine.CreatedAt.IsZero() {
+		routine.CreatedAt = now
+	}
+	routine.UpdatedAt = now
+	r.data.routines[routine.ID] = *routine
+	return nil
+}
+
+// Update 更新例程
+func (r *RoutineRepository) Update(routine *model.Routine) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	routine.UpdatedAt = time.Now()
+	r.data.routines[routine.ID] = *routine
+	return nil
+}
+
+// Delete 删除例程，例程不存在时返回 store.ErrNotFound；执行记录保留
+func (r *RoutineRepository) Delete(id uuid.UUID, userID uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	routine, ok := r.data.routines[id]
+	if !ok || routine.UserID != userID {
+		return store.ErrNotFound
+	}
+	delete(r.data.routines, id)
+	return nil
+}
+
+// RemoveProject 从用户所有例程的步骤中移除指定项目
+func (r *RoutineRepository) RemoveProject(projectID uuid.UUID, userID uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for id, routine := range r.data.routines {
+		if routine.UserID != userID || routine.ProjectIDs == "" {
+			continue
+		}
+		var projectIDs []uuid.UUID
+		if err := json.Unmarshal([]byte(routine.ProjectIDs), &projectIDs); err != nil {
+			return err
+		}
+
+		kept := make([]uuid.UUID, 0, len(projectIDs))
+		for _, pid := range projectIDs {
+			if pid != projectID {
+				kept = append(kept, pid)
+			}
+		}
+		if len(kept) == len(projectIDs) {
+			continue
+		}
+		data, err := json.Marshal(kept)
+		if err != nil {
+			return err
+		}
+		routine.ProjectIDs = string(data)
+		r.data.routines[id] = routine
+	}
+	return nil
+}
+
+// DeleteByUser 删除用户的所有例程
+func (r *RoutineRepository) DeleteByUser(userID uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for id, routine := range r.data.routines {
+		if routine.UserID == userID {
+			delete(r.data.routines, id)
+		}
+	}
+	return nil
+}
+
+// RoutineRunRepository 例程执行记录仓储的内存实现
+type RoutineRunRepository struct {
+	data *data
+}
+
+var _ store.RoutineRunRepository = (*RoutineRunRepository)(nil)
+
+// Get 获取例程执行记录
+func (r *RoutineRunRepository) Get(id uuid.UUID, userID uuid.UUID) (*model.RoutineRun, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	run, ok := r.data.runs[id]
+	if !ok || run.UserID != userID {
+		return nil, store.ErrNotFound
+	}
+	return &run, nil
+}
+
+// ListByRoutine 获取例程最近的执行记录，按开始时间倒序；limit 小于 0 时不限制条数
+func (r *RoutineRunRepository) ListByRoutine(routineID uuid.UUID, userID uuid.UUID, limit int) ([]model.RoutineRun, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	runs := filter(r.data.runs, func(run model.RoutineRun) bool {
+		return run.RoutineID == routineID && run.UserID == userID
+	}, func(a, b model.RoutineRun) bool {
+		return a.StartedAt.After(b.StartedAt)
+	})
+	if limit >= 0 && len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs, nil
+}
+
+// Create 创建例程执行记录
+func (r *RoutineRunRepository) Create(run *model.RoutineRun) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	if run.ID == uuid.Nil {
+		run.ID = uuid.New()
+	}
+	r.data.runs[run.ID] = storedRoutineRun(run)
+	return nil
+}
+
+// Update 更新例程执行记录
+func (r *RoutineRunRepository) Update(run *model.RoutineRun) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	r.data.runs[run.ID] = storedRoutineRun(run)
+	return nil
+}
+
+// DeleteByUser 删除用户的所有例程执行记录
+func (r *RoutineRunRepository) DeleteByUser(userID uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for id, run := range r.data.runs {
+		if run.UserID == userID {
+			delete(r.data.runs, id)
+		}
+	}
+	return nil
+}
+
+// storedRoutineRun 返回不与调用方共享指针字段的副本
+func storedRoutineRun(run *model.RoutineRun) model.RoutineRun {
+	stored := *run
+	if run.FinishedAt != nil {
+		finishedAt := *run.FinishedAt
+		stored.FinishedAt = &finishedAt
+	}
+	return stored
+}
@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"time"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// TeamSplitRepository 分组记录仓储的内存实现
+type TeamSplitRepository struct {
+	data *data
+}
+
+var _ store.TeamSplitRepository = (*TeamSplitRepository)(nil)
+
+// Find 分页获取分组记录，可按项目过滤（不包括回收站中项目的记录）
+func (r *TeamSplitRepository) Find(userID uuid.UUID, projectID *uuid.UUID, page store.PageRequest) (*store.Page[model.TeamSplit], error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	items := filter(r.data.splits, func(ts model.TeamSplit) bool {
+		if ts.UserID != userID || (projectID != nil && ts.ProjectID != *projectID) {
+			return false
+		}
+		project, ok := r.data.projects[ts.ProjectID]
+		return !ok || !project.DeletedAt.Valid
+	}, nil)
+
+	return paginate(items, page, store.TeamSplitSortFields, func(ts *model.TeamSplit, field string) (any, uuid.UUID) {
+		return ts.CreatedAt, ts.ID
+	})
+}
+
+// Create 创建分组记录
+func (r *TeamSplitRepository) Create(split *model.TeamSplit) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	if split.ID == uuid.Nil {
+		split.ID = uuid.New()
+	}
+	if split.CreatedAt.IsZero() {
+		split.CreatedAt = time.Now()
+	}
+	r.data.splits[split.ID] = *split
+	return nil
+}
+
+// DeleteByProject 删除项目的所有分组记录
+func (r *TeamSplitRepository) DeleteByProject(projectID uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for id, split := range r.data.splits {
+		if split.ProjectID == projectID {
+			delete(r.data.splits, id)
+		}
+	}
+	return nil
+}
+
+// DeleteByUser 删除用户的所有分组记录
+func (r *TeamSplitRepository) DeleteByUser(userID uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for id, split := range r.data.splits {
+		if split.UserID == userID {
+			delete(r.data.splits, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package memory
+
+import (
+	"fmt"
+	"time"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// UserRepository 用户仓储的内存实现
+type UserRepository struct {
+	data *data
+}
+
+var _ store.UserRepository = (*UserRepository)(nil)
+
+// Get 根据ID获取用户
+func (r *UserRepository) Get(id uuid.UUID) (*model.User, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	user, ok := r.data.users[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &user, nil
+}
+
+// GetByUsername 根据用户名获取用户
+func (r *UserRepository) GetByUsername(username string) (*model.User, error) {
+	return r.find(func(u model.User) bool { return u.Username == username })
+}
+
+// GetByEmail 根据邮箱获取用户
+func (r *UserRepository) GetByEmail(email string) (*model.User, error) {
+	return r.find(func(u model.User) bool { return u.Email == email })
+}
+
+// Create 创建用户；用户名和邮箱唯一
+func (r *UserRepository) Create(user *model.User) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for _, existing := range r.data.users {
+		if existing.Username == user.Username {
+			return fmt.Errorf("UNIQUE constraint failed: users.username")
+		}
+		if existing.Email == user.Email {
+			return fmt.Errorf("UNIQUE constraint failed: users.email")
+		}
+	}
+
+	if user.ID == uuid.Nil {
+		user.ID = uuid.New()
+	}
+	now := time.Now()
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now
+	}
+	user.UpdatedAt = now
+
+	stored := *user
+	stored.Projects, stored.Candidates, stored.Histories = nil, nil, nil
+	r.data.users[user.ID] = stored
+	return nil
+}
+
+// SetDeletionRequestedAt 设置申请删除账号的时间，nil 表示撤销申请
+func (r *UserRepository) SetDeletionRequestedAt(id uuid.UUID, at *time.Time) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	user, ok := r.data.users[id]
+	if !ok {
+		return store.ErrNotFound
+	}
+	user.DeletionRequestedAt = at
+	user.UpdatedAt = time.Now()
+	r.data.users[id] = user
+	return nil
+}
+
+// ListDeletionDue 获取在 before 之前申请删除的用户
+func (r *UserRepository) ListDeletionDue(before time.Time) ([]model.User, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	return filter(r.data.users, func(u model.User) bool {
+		return u.DeletionRequestedAt != nil && u.DeletionRequestedAt.Before(before)
+	}, nil), nil
+}
+
+// Delete 彻底删除用户，并像外键级联一样删除用户拥有的所有数据
+func (r *UserRepository) Delete(id uuid.UUID) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	delete(r.data.users, id)
+	for projectID, project := range r.data.projects {
+		if project.UserID == id {
+			delete(r.data.projects, projectID)
+		}
+	}
+	for candidateID, candidate := range r.data.candidates {
+		if candidate.UserID == id {
+			r.data.deleteCandidate(candidateID)
+		}
+	}
+	for historyID, history := range r.data.histories {
+		if history.UserID == id {
+			r.data.deleteHistory(historyID)
+		}
+	}
+	for feedID, feed := range r.data.feeds {
+		if feed.UserID == id {
+			delete(r.data.feeds, feedID)
+		}
+	}
+	for rewardID, reward := range r.data.rewards {
+		if reward.UserID == id {
+			delete(r.data.rewards, rewardID)
+		}
+	}
+	for ruleID, rule := range r.data.rules {
+		if rule.UserID == id {
+			r.data.deleteBadgeRule(ruleID)
+		}
+	}
+	for routineID, routine := range r.data.routines {
+		if routine.UserID == id {
+			delete(r.data.routines, routineID)
+		}
+	}
+	for runID, run := range r.data.runs {
+		if run.UserID == id {
+			delete(r.data.runs, runID)
+		}
+	}
+	for constraintID, constraint := range r.data.constraints {
+		if constraint.UserID == id {
+			delete(r.data.constraints, constraintID)
+		}
+	}
+	for splitID, split := range r.data.splits {
+		if split.UserID == id {
+			delete(r.data.splits, splitID)
+		}
+	}
+	for keyID, key := range r.data.idempotency {
+		if key.UserID == id {
+			delete(r.data.idempotency, keyID)
+		}
+	}
+	return nil
+}
+
+// find 查找第一个满足条件的用户
+func (r *UserRepository) find(match func(model.User) bool) (*model.User, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for _, user := range r.data.users {
+		if match(user) {
+			return &user, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
@@ -0,0 +1,157 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+	"whotakesshowers/internal/config"
+	"whotakesshowers/internal/migrate"
+	"whotakesshowers/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// NewMigrator 创建数据库迁移器
+// 执行迁移前自动备份 SQLite 数据库文件（PostgreSQL 需自行用 pg_dump 备份）；
+// 版本化迁移之前创建的数据库会先被接管为基线版本
+func NewMigrator(db *gorm.DB, cfg config.DatabaseConfig) (*migrate.Migrator, error) {
+	legacy, err := isLegacySchema(db)
+	if err != nil {
+		return nil, err
+	}
+
+	migrator, err := migrate.New(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+	migrator.BeforeMigrate = func(fromVersion int) error {
+		if fromVersion == 0 && !legacy {
+			// 全新数据库，无需备份
+			return nil
+		}
+		if db.Dialector.Name() != config.DriverSQLite {
+			log.Printf("Migrate: automatic backup is only supported on sqlite, back up the database before migrating")
+			return nil
+		}
+		backupPath, err := backupDatabase(db, cfg.BackupDir, fromVersion)
+		if err != nil {
+			return err
+		}
+		log.Printf("Migrate: database backed up to %s", backupPath)
+		return nil
+	}
+
+	if legacy {
+		if err := adoptLegacySchema(db, migrator); err != nil {
+			return nil, err
+		}
+	}
+	return migrator, nil
+}
+
+// isLegacySchema 判断数据库是否由版本化迁移之前的 AutoMigrate 创建
+// 版本化迁移之前只支持 SQLite，其他数据库不存在旧表结构
+func isLegacySchema(db *gorm.DB) (bool, error) {
+	if db.Dialector.Name() != config.DriverSQLite {
+		return false, nil
+	}
+	if db.Migrator().HasTable(&migrate.Record{}) {
+		var count int64
+		if err := db.Model(&migrate.Record{}).Count(&count).Error; err != nil {
+			return false, err
+		}
+		if count > 0 {
+			return false, nil
+		}
+	}
+	return db.Migrator().HasTable(&model.User{}), nil
+}
+
+// legacyBaselineVersion AutoMigrate 时代的表结构对应的迁移版本
+const legacyBaselineVersion = 1
+
+// adoptLegacySchema 接管旧数据库：先备份，再修复历史数据并用 AutoMigrate 按冻结的基线表结构（legacy_schema.go）补齐，
+// 最后将基线版本记为已执行，此后的变更都通过版本化迁移完成
+func adoptLegacySchema(db *gorm.DB, migrator *migrate.Migrator) error {
+	log.Printf("Migrate: adopting database created before versioned migrations")
+	if err := migrator.BeforeMigrate(0); err != nil {
+		return err
+	}
+
+	// 在单个连接上进行，并临时关闭外键：
+	// SQLite 添加约束需要重建表，重建父表时不能触发级联删除
+	if err := db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("PRAGMA foreign_keys = OFF").Error; err != nil {
+			return err
+		}
+		defer conn.Exec("PRAGMA foreign_keys = ON")
+
+		// 修复重复头像，否则无法创建“每个候选人最多一张头像”的唯一索引
+		if err := NewCandidatePhotoStore(conn).RepairAvatars(); err != nil {
+			return fmt.Errorf("failed to repair candidate avatars: %w", err)
+		}
+		if err := conn.AutoMigrate(
+			&legacyUser{},
+			&legacyProject{},
+			&legacyCandidate{},
+			&legacyCandidatePhoto{},
+			&legacyHistory{},
+		); err != nil {
+			return fmt.Errorf("failed to migrate legacy schema: %w", err)
+		}
+		// 清理启用外键之前遗留的孤儿数据
+		if err := purgeOrphans(conn); err != nil {
+			return fmt.Errorf("failed to purge orphaned rows: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return migrator.Baseline(legacyBaselineVersion)
+}
+
+// backupDatabase 使用 VACUUM INTO 生成数据库的一致性快照，返回备份文件路径
+func backupDatabase(db *gorm.DB, dir string, version int) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	// 同一秒内多次迁移（如连续回滚）时在文件名后加序号，避免与已有的备份重名
+	base := fmt.Sprintf("whotakesshowers-v%d-%s", version, time.Now().Format("20060102-150405"))
+	backupPath := filepath.Join(dir, base+".db")
+	for i := 2; ; i++ {
+		if _, err := os.Stat(backupPath); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		backupPath = filepath.Join(dir, fmt.Sprintf("%s-%d.db", base, i))
+	}
+	if err := db.Exec("VACUUM INTO ?", backupPath).Error; err != nil {
+		return "", fmt.Errorf("failed to back up database: %w", err)
+	}
+	return backupPath, nil
+}
+
+// purgeOrphans 删除引用了不存在记录的行
+func purgeOrphans(db *gorm.DB) error {
+	statements := []string{
+		"DELETE FROM projects WHERE user_id NOT IN (SELECT id FROM users)",
+		"DELETE FROM candidates WHERE user_id NOT IN (SELECT id FROM users)",
+		"DELETE FROM candidate_photos WHERE candidate_id NOT IN (SELECT id FROM candidates)",
+		"DELETE FROM histories WHERE project_id NOT IN (SELECT id FROM projects) OR user_id NOT IN (SELECT id FROM users)",
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			result := tx.Exec(statement)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				log.Printf("purgeOrphans: %d rows removed by %q", result.RowsAffected, statement)
+			}
+		}
+		return nil
+	})
+}
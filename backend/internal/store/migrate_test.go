@@ -0,0 +1,286 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"whotakesshowers/internal/config"
+	"whotakesshowers/internal/migrate"
+	"whotakesshowers/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// sqliteTestConfig 返回使用 dir 下数据库文件的 SQLite 配置，与 config.yaml 的默认连接参数一致
+func sqliteTestConfig(dir string) config.DatabaseConfig {
+	return config.DatabaseConfig{
+		Driver:      config.DriverSQLite,
+		Path:        filepath.Join(dir, "test.db"),
+		AutoMigrate: true,
+		BackupDir:   filepath.Join(dir, "backups"),
+		SQLite: config.SQLiteConfig{
+			JournalMode:     "WAL",
+			BusyTimeout:     5000,
+			Synchronous:     "NORMAL",
+			ForeignKeys:     true,
+			ReadConnections: 2,
+		},
+		Log: config.DatabaseLogConfig{Level: "silent"},
+	}
+}
+
+// closeDB 关闭数据库的所有连接
+func closeDB(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sqlDB.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// sqliteSchema 返回 SQLite 数据库中所有表的列、外键和索引，用于比较两个数据库的表结构
+func sqliteSchema(t *testing.T, db *gorm.DB) []string {
+	t.Helper()
+	var tables []string
+	if err := db.Raw("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND name <> 'schema_migrations'").
+		Scan(&tables).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var schema []string
+	for _, table := range tables {
+		var columns []struct {
+			Name      string
+			Type      string
+			NotNull   bool
+			DfltValue *string
+			Pk        int
+		}
+		if err := db.Raw(fmt.Sprintf("PRAGMA table_info(%q)", table)).Scan(&columns).Error; err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range columns {
+			dflt := "<nil>"
+			if c.DfltValue != nil {
+				dflt = *c.DfltValue
+			}
+			schema = append(schema, fmt.Sprintf("%s column %s %s notnull=%v default=%s pk=%d",
+				table, c.Name, strings.ToLower(c.Type), c.NotNull, dflt, c.Pk))
+		}
+
+		var keys []struct {
+			Table    string
+			From     string
+			To       string
+			OnDelete string
+		}
+		if err := db.Raw(fmt.Sprintf("PRAGMA foreign_key_list(%q)", table)).Scan(&keys).Error; err != nil {
+			t.Fatal(err)
+		}
+		for _, k := range keys {
+			schema = append(schema, fmt.Sprintf("%s foreign key %s -> %s.%s on delete %s", table, k.From, k.Table, k.To, k.OnDelete))
+		}
+
+		var indexes []struct {
+			Name    string
+			Unique  bool
+			Partial bool
+		}
+		if err := db.Raw(fmt.Sprintf("PRAGMA index_list(%q)", table)).Scan(&indexes).Error; err != nil {
+			t.Fatal(err)
+		}
+		for _, index := range indexes {
+			var columns []string
+			if err := db.Raw(fmt.Sprintf("SELECT name FROM pragma_index_info(%q) ORDER BY seqno", index.Name)).
+				Scan(&columns).Error; err != nil {
+				t.Fatal(err)
+			}
+			schema = append(schema, fmt.Sprintf("%s index %s (%s) unique=%v partial=%v",
+				table, index.Name, strings.Join(columns, ", "), index.Unique, index.Partial))
+		}
+	}
+	slices.Sort(schema)
+	return schema
+}
+
+// loadLegacyFixture 在 cfg 指向的空数据库中创建版本化迁移之前的表结构和数据
+func loadLegacyFixture(t *testing.T, cfg config.DatabaseConfig) {
+	t.Helper()
+	fixture, err := os.ReadFile(filepath.Join("testdata", "legacy_baseline.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy, err := OpenDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, legacy)
+	for _, statement := range strings.Split(string(fixture), ";\n") {
+		if strings.TrimSpace(statement) == "" {
+			continue
+		}
+		if err := legacy.Exec(statement).Error; err != nil {
+			t.Fatalf("failed to load fixture: %v", err)
+		}
+	}
+}
+
+// compareSchemas 比较两个数据库的表结构，列出缺少和多出的部分
+func compareSchemas(t *testing.T, context string, got, want []string) {
+	t.Helper()
+	if slices.Equal(got, want) {
+		return
+	}
+	for _, line := range want {
+		if !slices.Contains(got, line) {
+			t.Errorf("missing %s: %s", context, line)
+		}
+	}
+	for _, line := range got {
+		if !slices.Contains(want, line) {
+			t.Errorf("unexpected %s: %s", context, line)
+		}
+	}
+}
+
+// TestInitDBAdoptsLegacySchema 升级版本化迁移之前创建的数据库：接管后所有迁移都能执行，
+// 表结构与全新数据库一致，原有数据被保留并修复
+func TestInitDBAdoptsLegacySchema(t *testing.T) {
+	cfg := sqliteTestConfig(t.TempDir())
+	loadLegacyFixture(t, cfg)
+
+	db, err := InitDB(cfg)
+	if err != nil {
+		t.Fatalf("InitDB on a legacy database: %v", err)
+	}
+
+	migrator, err := migrate.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := migrator.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current != migrator.Latest() {
+		t.Fatalf("current version = %d, want %d", current, migrator.Latest())
+	}
+
+	fresh, err := InitDB(sqliteTestConfig(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDB(t, fresh)
+	compareSchemas(t, "after upgrade", sqliteSchema(t, db), sqliteSchema(t, fresh))
+
+	// 原有数据保留，新增的列取默认值
+	var histories []model.History
+	if err := db.Find(&histories).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(histories) != 1 {
+		t.Fatalf("histories = %d, want 1 (the orphaned entry is purged)", len(histories))
+	}
+	if h := histories[0]; h.CandidateName != "Ann" || h.Source != model.HistorySourceDraw || h.Status != model.HistoryStatusPicked {
+		t.Errorf("history = %+v, want Ann drawn and picked", h)
+	}
+
+	// 重复的头像只保留最新的一张，候选人头像与之一致
+	var avatars []model.CandidatePhoto
+	if err := db.Where("is_avatar = ?", true).Find(&avatars).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(avatars) != 1 || avatars[0].PhotoURL != "/uploads/new.jpg" {
+		t.Errorf("avatars = %+v, want only /uploads/new.jpg", avatars)
+	}
+	var candidate model.Candidate
+	if err := db.First(&candidate, "name = ?", "Ann").Error; err != nil {
+		t.Fatal(err)
+	}
+	if candidate.PhotoURL != "/uploads/new.jpg" {
+		t.Errorf("candidate photo = %q, want /uploads/new.jpg", candidate.PhotoURL)
+	}
+
+	// 接管前自动备份了原数据库
+	backups, err := os.ReadDir(cfg.BackupDir)
+	if err != nil || len(backups) == 0 {
+		t.Errorf("no backup of the legacy database in %s: %v", cfg.BackupDir, err)
+	}
+
+	// 再次启动时不再接管，也没有待执行的迁移
+	closeDB(t, db)
+	restarted, err := InitDB(cfg)
+	if err != nil {
+		t.Fatalf("restart after upgrade: %v", err)
+	}
+	closeDB(t, restarted)
+}
+
+// TestAdoptedSchemaMigratesDownAndUp 接管后的数据库与全新数据库一起回滚到每个版本再重新执行到最新版本，
+// 每一步的表结构都一致；覆盖在旧表上 ADD COLUMN 的迁移（0003、0004、0006、0007、0013-0016）
+func TestAdoptedSchemaMigratesDownAndUp(t *testing.T) {
+	adoptedCfg := sqliteTestConfig(t.TempDir())
+	loadLegacyFixture(t, adoptedCfg)
+	db, err := InitDB(adoptedCfg)
+	if err != nil {
+		t.Fatalf("InitDB on a legacy database: %v", err)
+	}
+	closeDB(t, db)
+
+	freshCfg := sqliteTestConfig(t.TempDir())
+	fresh, err := InitDB(freshCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	closeDB(t, fresh)
+
+	// 与 migrate 命令一样在写连接上执行迁移
+	open := func(cfg config.DatabaseConfig) (*gorm.DB, *migrate.Migrator) {
+		db, err := OpenDB(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { closeDB(t, db) })
+		migrator, err := NewMigrator(db, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return db, migrator
+	}
+	adopted, adoptedMigrator := open(adoptedCfg)
+	fresh, freshMigrator := open(freshCfg)
+
+	migrators := map[string]*migrate.Migrator{"adopted": adoptedMigrator, "fresh": freshMigrator}
+	latest := adoptedMigrator.Latest()
+	for version := latest; version > legacyBaselineVersion; version-- {
+		// 回滚到 version 之前，再重新执行到最新版本
+		for name, migrator := range migrators {
+			if reverted, err := migrator.Down(latest - version + 1); err != nil || len(reverted) != latest-version+1 {
+				t.Fatalf("down to version %d on the %s database reverted %d migrations: %v", version-1, name, len(reverted), err)
+			}
+		}
+		compareSchemas(t, fmt.Sprintf("at version %d", version-1), sqliteSchema(t, adopted), sqliteSchema(t, fresh))
+
+		for name, migrator := range migrators {
+			if applied, err := migrator.Up(); err != nil || len(applied) != latest-version+1 {
+				t.Fatalf("up from version %d on the %s database applied %d migrations: %v", version-1, name, len(applied), err)
+			}
+		}
+		compareSchemas(t, fmt.Sprintf("after reapplying from version %d", version-1), sqliteSchema(t, adopted), sqliteSchema(t, fresh))
+	}
+
+	// 回滚和重新执行后原有数据仍然保留
+	var histories []model.History
+	if err := adopted.Find(&histories).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(histories) != 1 || histories[0].CandidateName != "Ann" {
+		t.Errorf("histories = %+v, want the entry for Ann", histories)
+	}
+}
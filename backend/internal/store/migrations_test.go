@@ -0,0 +1,162 @@
+package store_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+	"whotakesshowers/internal/config"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// TestMigrationsUpDown 与 migrate 命令一样在写连接上执行全部迁移，逐个回滚到空数据库后再次执行
+func TestMigrationsUpDown(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, cfg config.DatabaseConfig) {
+		db, err := store.OpenDB(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			if sqlDB, err := db.DB(); err == nil {
+				sqlDB.Close()
+			}
+		})
+		migrator, err := store.NewMigrator(db, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if applied, err := migrator.Up(); err != nil || len(applied) != migrator.Latest() {
+			t.Fatalf("up applied %d migrations (%v), want %d", len(applied), err, migrator.Latest())
+		}
+
+		for version := migrator.Latest(); version > 0; version-- {
+			reverted, err := migrator.Down(1)
+			if err != nil {
+				t.Fatalf("down from version %d: %v", version, err)
+			}
+			if len(reverted) != 1 || reverted[0].Version != version {
+				t.Fatalf("down from version %d reverted %+v", version, reverted)
+			}
+		}
+		tables, err := db.Migrator().GetTables()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, table := range tables {
+			if table != "schema_migrations" {
+				t.Errorf("table %s left after reverting all migrations", table)
+			}
+		}
+
+		if applied, err := migrator.Up(); err != nil || len(applied) != migrator.Latest() {
+			t.Fatalf("up after down applied %d migrations (%v), want %d", len(applied), err, migrator.Latest())
+		}
+		if current, err := migrator.Current(); err != nil || current != migrator.Latest() {
+			t.Errorf("current version = %d (%v), want %d", current, err, migrator.Latest())
+		}
+
+		// 重新执行迁移后的数据库可以正常使用
+		s := store.New(db)
+		if _, err := s.Users().GetByUsername("nobody"); err != store.ErrNotFound {
+			t.Errorf("GetByUsername on an empty database = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+// TestSplitHistoriesBackfill 0018 为迁移前已有的分组补充每个候选人的历史记录，回滚时删除
+func TestSplitHistoriesBackfill(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, cfg config.DatabaseConfig) {
+		db, err := store.OpenDB(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			if sqlDB, err := db.DB(); err == nil {
+				sqlDB.Close()
+			}
+		})
+		migrator, err := store.NewMigrator(db, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := migrator.Up(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := migrator.Down(migrator.Latest() - 17); err != nil {
+			t.Fatal(err)
+		}
+
+		s := store.New(db)
+		user := mustCreateUser(t, s, "alice")
+		project := mustCreateProject(t, s, user.ID, "Games")
+		ann, ben, cid := uuid.New(), uuid.New(), uuid.New()
+		groups, err := json.Marshal([][]map[string]any{
+			{{"candidate_id": ann, "candidate_name": "Ann"}, {"candidate_id": ben, "candidate_name": "Ben"}},
+			{{"candidate_id": cid, "candidate_name": "Cid"}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		split := &model.TeamSplit{
+			UserID:      user.ID,
+			ProjectID:   project.ID,
+			ProjectName: project.Name,
+			GroupCount:  2,
+			Groups:      string(groups),
+			KeepApart:   "[]",
+			CreatedAt:   time.Now().Add(-time.Hour),
+		}
+		if err := s.TeamSplits().Create(split); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := migrator.Up(); err != nil {
+			t.Fatalf("up to version 18: %v", err)
+		}
+		var histories []model.History
+		if err := db.Where("split_id = ?", split.ID).Order("candidate_name").Find(&histories).Error; err != nil {
+			t.Fatal(err)
+		}
+		want := []struct {
+			id    uuid.UUID
+			name  string
+			group int
+		}{{ann, "Ann", 0}, {ben, "Ben", 0}, {cid, "Cid", 1}}
+		if len(histories) != len(want) {
+			t.Fatalf("backfilled %d histories, want %d", len(histories), len(want))
+		}
+		ids := make(map[uuid.UUID]bool)
+		for i, h := range histories {
+			w := want[i]
+			if h.CandidateID != w.id || h.CandidateName != w.name || h.SplitGroup == nil || *h.SplitGroup != w.group {
+				t.Errorf("history %d = %+v, want %s in group %d", i, h, w.name, w.group)
+			}
+			if h.Source != model.HistorySourceSplit || h.UserID != user.ID || h.ProjectID != project.ID || h.Status != model.HistoryStatusPicked {
+				t.Errorf("history %d = %+v, want a picked split entry of the project", i, h)
+			}
+			if !h.SelectedAt.Equal(split.CreatedAt.Truncate(time.Microsecond)) && !h.SelectedAt.Round(time.Second).Equal(split.CreatedAt.Round(time.Second)) {
+				t.Errorf("history %d selected at %v, want the split time %v", i, h.SelectedAt, split.CreatedAt)
+			}
+			if h.ID == uuid.Nil || ids[h.ID] {
+				t.Errorf("history %d has a missing or duplicate id %s", i, h.ID)
+			}
+			ids[h.ID] = true
+		}
+
+		// 通过仓储可以正常读取补充的记录
+		page, err := s.Histories().Find(user.ID, store.HistoryFilter{Source: model.HistorySourceSplit}, store.PageRequest{})
+		if err != nil || page.Total != 3 {
+			t.Errorf("split histories via the repository = %v (%v), want 3", page, err)
+		}
+
+		if _, err := migrator.Down(migrator.Latest() - 17); err != nil {
+			t.Fatalf("down to version 17: %v", err)
+		}
+		var count int64
+		if err := db.Table("histories").Count(&count).Error; err != nil || count != 0 {
+			t.Errorf("histories after down = %d (%v), want 0", count, err)
+		}
+	})
+}
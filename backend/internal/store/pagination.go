@@ -0,0 +1,187 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 每页条数
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+var (
+	// ErrInvalidCursor 游标无法解析，或不是由相同排序方式生成的
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrInvalidSort 不支持的排序方式
+	ErrInvalidSort = errors.New("invalid sort")
+)
+
+// 各列表支持的排序字段，第一个为默认排序字段（倒序）
+var (
+	HistorySortFields    = []string{"selected_at", "candidate_name", "project_name"}
+	CandidateSortFields  = []string{"created_at", "updated_at", "name"}
+	ProjectSortFields    = []string{"created_at", "updated_at", "name"}
+	PointEntrySortFields = []string{"created_at"}
+	TeamSplitSortFields  = []string{"created_at"}
+)
+
+// PageRequest 游标分页请求
+type PageRequest struct {
+	Limit  int    // 每页条数，超出范围时使用默认值或上限
+	Cursor string // 上一页返回的 NextCursor，为空表示第一页
+	Sort   string // 排序字段，前缀 "-" 表示倒序；为空时使用默认排序
+}
+
+// Page 一页查询结果
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	Total      int64  `json:"total"`                 // 符合过滤条件的总条数
+	NextCursor string `json:"next_cursor,omitempty"` // 为空表示没有下一页
+}
+
+// PageLimit 返回实际使用的每页条数
+func (p PageRequest) PageLimit() int {
+	if p.Limit <= 0 {
+		return DefaultPageLimit
+	}
+	if p.Limit > MaxPageLimit {
+		return MaxPageLimit
+	}
+	return p.Limit
+}
+
+// SortField 排序字段，Name 同时也是数据库列名
+type SortField struct {
+	Name string
+	Desc bool
+}
+
+// String 返回请求中使用的排序写法，如 "-created_at"
+func (f SortField) String() string {
+	if f.Desc {
+		return "-" + f.Name
+	}
+	return f.Name
+}
+
+// IsTime 字段是否为时间类型
+func (f SortField) IsTime() bool {
+	return strings.HasSuffix(f.Name, "_at")
+}
+
+// ParseSort 解析排序方式，sort 为空时按 allowed 的第一个字段倒序
+func ParseSort(sort string, allowed []string) (SortField, error) {
+	if sort == "" {
+		return SortField{Name: allowed[0], Desc: true}, nil
+	}
+	field := SortField{Name: strings.TrimPrefix(sort, "-"), Desc: strings.HasPrefix(sort, "-")}
+	for _, name := range allowed {
+		if field.Name == name {
+			return field, nil
+		}
+	}
+	return SortField{}, fmt.Errorf("%w: %q (allowed: %s)", ErrInvalidSort, sort, strings.Join(allowed, ", "))
+}
+
+// Cursor 游标，记录上一页最后一条记录的排序字段值和 ID
+type Cursor struct {
+	Sort  string    `json:"s"`
+	Value string    `json:"v"`
+	ID    uuid.UUID `json:"id"`
+}
+
+// EncodeCursor 生成指向 value/id 之后的游标
+func EncodeCursor(sort SortField, value any, id uuid.UUID) string {
+	cursor := Cursor{Sort: sort.String(), ID: id}
+	switch v := value.(type) {
+	case time.Time:
+		cursor.Value = v.Format(time.RFC3339Nano)
+	default:
+		cursor.Value = fmt.Sprint(v)
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor 解析游标，并检查其排序方式与 sort 一致；返回的值按字段类型转换
+func DecodeCursor(encoded string, sort SortField) (value any, id uuid.UUID, err error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, uuid.Nil, ErrInvalidCursor
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == uuid.Nil {
+		return nil, uuid.Nil, ErrInvalidCursor
+	}
+	if cursor.Sort != sort.String() {
+		return nil, uuid.Nil, fmt.Errorf("%w: cursor was created for sort %q", ErrInvalidCursor, cursor.Sort)
+	}
+	if sort.IsTime() {
+		t, err := time.Parse(time.RFC3339Nano, cursor.Value)
+		if err != nil {
+			return nil, uuid.Nil, ErrInvalidCursor
+		}
+		// SQLite 按文本比较时间，参数需要和写入时一样使用本地时区
+		return t.Local(), cursor.ID, nil
+	}
+	return cursor.Value, cursor.ID, nil
+}
+
+// paginate 对 query 做游标分页：统计总数，按 sort 和 ID 排序，取游标之后的一页
+// key 返回记录的排序字段值和 ID，用于生成下一页游标
+func paginate[T any](query *gorm.DB, page PageRequest, allowed []string, key func(item *T, field string) (any, uuid.UUID)) (*Page[T], error) {
+	sort, err := ParseSort(page.Sort, allowed)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Page[T]{Items: make([]T, 0)}
+	if err := query.Session(&gorm.Session{}).Count(&result.Total).Error; err != nil {
+		return nil, err
+	}
+
+	// 排序字段相同时按 ID 排序，保证顺序稳定
+	op, dir := ">", "ASC"
+	if sort.Desc {
+		op, dir = "<", "DESC"
+	}
+	if page.Cursor != "" {
+		cursorValue, cursorID, err := DecodeCursor(page.Cursor, sort)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(
+			fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", sort.Name, op),
+			cursorValue, cursorValue, cursorID,
+		)
+	}
+
+	limit := page.PageLimit()
+	err = query.Order(sort.Name + " " + dir).Order("id " + dir).
+		Limit(limit + 1).Find(&result.Items).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.Items) > limit {
+		result.Items = result.Items[:limit]
+		v, id := key(&result.Items[limit-1], sort.Name)
+		result.NextCursor = EncodeCursor(sort, v, id)
+	}
+	return result, nil
+}
+
+// containsPattern 返回不区分大小写的 LIKE 子串匹配模式，转义通配符
+func containsPattern(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + strings.ToLower(replacer.Replace(s)) + "%"
+}
@@ -0,0 +1,67 @@
+package store
+
+import (
+	"whotakesshowers/internal/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PointEntryStore 积分流水存储
+type PointEntryStore struct {
+	db *gorm.DB
+}
+
+// NewPointEntryStore 创建绑定到指定数据库连接（或事务）的积分流水存储
+func NewPointEntryStore(db *gorm.DB) *PointEntryStore {
+	return &PointEntryStore{db: db}
+}
+
+// Find 按过滤条件分页获取积分流水
+func (s *PointEntryStore) Find(userID uuid.UUID, filter PointEntryFilter, page PageRequest) (*Page[model.PointEntry], error) {
+	query := s.db.Model(&model.PointEntry{}).Where("user_id = ?", userID)
+	if filter.CandidateID != nil {
+		query = query.Where("candidate_id = ?", *filter.CandidateID)
+	}
+	if filter.Kind != "" {
+		query = query.Where("kind = ?", filter.Kind)
+	}
+
+	return paginate(query, page, PointEntrySortFields, func(e *model.PointEntry, field string) (any, uuid.UUID) {
+		return e.CreatedAt, e.ID
+	})
+}
+
+// ListByHistory 获取历史记录产生的积分流水
+func (s *PointEntryStore) ListByHistory(historyID uuid.UUID) ([]model.PointEntry, error) {
+	var entries []model.PointEntry
+	err := s.db.Where("history_id = ?", historyID).Order("created_at ASC").Find(&entries).Error
+	return entries, err
+}
+
+// Totals 按候选人汇总用户的积分流水
+func (s *PointEntryStore) Totals(userID uuid.UUID) ([]PointTotals, error) {
+	var totals []PointTotals
+	err := s.db.Model(&model.PointEntry{}).
+		Select("candidate_id, "+
+			"COALESCE(SUM(CASE WHEN kind <> ? THEN amount ELSE 0 END), 0) AS earned, "+
+			"COALESCE(-SUM(CASE WHEN kind = ? THEN amount ELSE 0 END), 0) AS redeemed",
+			model.PointKindRedeem, model.PointKindRedeem).
+		Where("user_id = ?", userID).Group("candidate_id").Scan(&totals).Error
+	return totals, err
+}
+
+// Create 创建积分流水
+func (s *PointEntryStore) Create(entry *model.PointEntry) error {
+	return s.db.Create(entry).Error
+}
+
+// DeleteByCandidate 删除候选人的积分流水
+func (s *PointEntryStore) DeleteByCandidate(candidateID uuid.UUID) error {
+	return s.db.Where("candidate_id = ?", candidateID).Delete(&model.PointEntry{}).Error
+}
+
+// DeleteByUser 删除用户的所有积分流水
+func (s *PointEntryStore) DeleteByUser(userID uuid.UUID) error {
+	return s.db.Where("user_id = ?", userID).Delete(&model.PointEntry{}).Error
+}
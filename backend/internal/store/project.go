@@ -2,27 +2,53 @@ package store
 
 import (
 	"encoding/json"
+	"time"
 	"whotakesshowers/internal/model"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // ProjectStore 项目存储
-type ProjectStore struct{}
+type ProjectStore struct {
+	db *gorm.DB
+}
 
-var Projects = &ProjectStore{}
+// NewProjectStore 创建绑定到指定数据库连接（或事务）的项目存储
+func NewProjectStore(db *gorm.DB) *ProjectStore {
+	return &ProjectStore{db: db}
+}
 
 // List 获取项目列表
 func (s *ProjectStore) List(userID uuid.UUID) ([]model.Project, error) {
 	var projects []model.Project
-	err := DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&projects).Error
+	err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&projects).Error
 	return projects, err
 }
 
+// Find 按过滤条件分页获取项目列表
+func (s *ProjectStore) Find(userID uuid.UUID, filter ProjectFilter, page PageRequest) (*Page[model.Project], error) {
+	query := s.db.Model(&model.Project{}).Where("user_id = ?", userID)
+	if filter.Query != "" {
+		query = query.Where(`LOWER(name) LIKE ? ESCAPE '\'`, containsPattern(filter.Query))
+	}
+
+	return paginate(query, page, ProjectSortFields, func(p *model.Project, field string) (any, uuid.UUID) {
+		switch field {
+		case "name":
+			return p.Name, p.ID
+		case "updated_at":
+			return p.UpdatedAt, p.ID
+		default:
+			return p.CreatedAt, p.ID
+		}
+	})
+}
+
 // Get 获取项目详情
 func (s *ProjectStore) Get(id uuid.UUID, userID uuid.UUID) (*model.Project, error) {
 	var project model.Project
-	err := DB.Where("id = ? AND user_id = ?", id, userID).First(&project).Error
+	err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&project).Error
 	if err != nil {
 		return nil, err
 	}
@@ -31,23 +57,71 @@ func (s *ProjectStore) Get(id uuid.UUID, userID uuid.UUID) (*model.Project, erro
 
 // Create 创建项目
 func (s *ProjectStore) Create(project *model.Project) error {
-	return DB.Create(project).Error
+	return s.db.Create(project).Error
 }
 
 // Update 更新项目
 func (s *ProjectStore) Update(project *model.Project) error {
-	return DB.Save(project).Error
+	return s.db.Save(project).Error
 }
 
-// Delete 删除项目
+// Delete 删除项目（软删除，移入回收站）
 func (s *ProjectStore) Delete(id uuid.UUID, userID uuid.UUID) error {
-	return DB.Where("id = ? AND user_id = ?", id, userID).Delete(&model.Project{}).Error
+	return s.db.Where("id = ? AND user_id = ?", id, userID).Delete(&model.Project{}).Error
+}
+
+// ListDeleted 获取回收站中的项目
+func (s *ProjectStore) ListDeleted(userID uuid.UUID) ([]model.Project, error) {
+	var projects []model.Project
+	err := s.db.Unscoped().
+		Where("user_id = ? AND deleted_at IS NOT NULL", userID).
+		Order("deleted_at DESC").
+		Find(&projects).Error
+	return projects, err
+}
+
+// GetDeleted 获取回收站中的项目
+func (s *ProjectStore) GetDeleted(id uuid.UUID, userID uuid.UUID) (*model.Project, error) {
+	var project model.Project
+	err := s.db.Unscoped().
+		Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", id, userID).
+		First(&project).Error
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// ListExpired 获取在 before 之前移入回收站的项目（所有用户）
+func (s *ProjectStore) ListExpired(before time.Time) ([]model.Project, error) {
+	var projects []model.Project
+	err := s.db.Unscoped().Where("deleted_at < ?", before).Find(&projects).Error
+	return projects, err
+}
+
+// Restore 从回收站恢复项目
+func (s *ProjectStore) Restore(id uuid.UUID, userID uuid.UUID) error {
+	result := s.db.Unscoped().Model(&model.Project{}).
+		Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", id, userID).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Purge 彻底删除项目
+func (s *ProjectStore) Purge(id uuid.UUID, userID uuid.UUID) error {
+	return s.db.Unscoped().Where("id = ? AND user_id = ?", id, userID).Delete(&model.Project{}).Error
 }
 
 // GetCandidateIDs 获取项目的候选人ID列表
 func (s *ProjectStore) GetCandidateIDs(projectID uuid.UUID) ([]uuid.UUID, error) {
 	var project model.Project
-	if err := DB.First(&project, projectID).Error; err != nil {
+	if err := s.db.First(&project, projectID).Error; err != nil {
 		return nil, err
 	}
 
@@ -67,5 +141,37 @@ func (s *ProjectStore) SetCandidateIDs(projectID uuid.UUID, candidateIDs []uuid.
 	if err != nil {
 		return err
 	}
-	return DB.Model(&model.Project{}).Where("id = ?", projectID).Update("candidate_ids", string(data)).Error
+	return s.db.Unscoped().Model(&model.Project{}).Where("id = ?", projectID).Update("candidate_ids", string(data)).Error
+}
+
+// RemoveCandidate 从用户所有项目（包括回收站中的项目）的候选人列表中移除指定候选人
+func (s *ProjectStore) RemoveCandidate(candidateID uuid.UUID, userID uuid.UUID) error {
+	var projects []model.Project
+	if err := s.db.Unscoped().Where("user_id = ?", userID).Find(&projects).Error; err != nil {
+		return err
+	}
+
+	for _, project := range projects {
+		if project.CandidateIDs == "" {
+			continue
+		}
+		var candidateIDs []uuid.UUID
+		if err := json.Unmarshal([]byte(project.CandidateIDs), &candidateIDs); err != nil {
+			return err
+		}
+
+		kept := make([]uuid.UUID, 0, len(candidateIDs))
+		for _, id := range candidateIDs {
+			if id != candidateID {
+				kept = append(kept, id)
+			}
+		}
+		if len(kept) == len(candidateIDs) {
+			continue
+		}
+		if err := s.SetCandidateIDs(project.ID, kept); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"whotakesshowers/internal/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ProjectConstraintStore 项目间约束存储
+type ProjectConstraintStore struct {
+	db *gorm.DB
+}
+
+// NewProjectConstraintStore 创建绑定到指定数据库连接（或事务）的项目间约束存储
+func NewProjectConstraintStore(db *gorm.DB) *ProjectConstraintStore {
+	return &ProjectConstraintStore{db: db}
+}
+
+// List 获取用户的项目间约束，按创建时间排序
+func (s *ProjectConstraintStore) List(userID uuid.UUID) ([]model.ProjectConstraint, error) {
+	var constraints []model.ProjectConstraint
+	err := s.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&constraints).Error
+	return constraints, err
+}
+
+// ListByTarget 获取作用于目标项目的约束，按创建时间排序
+func (s *ProjectConstraintStore) ListByTarget(targetProjectID uuid.UUID, userID uuid.UUID) ([]model.ProjectConstraint, error) {
+	var constraints []model.ProjectConstraint
+	err := s.db.Where("target_project_id = ? AND user_id = ?", targetProjectID, userID).
+		Order("created_at ASC").Find(&constraints).Error
+	return constraints, err
+}
+
+// Get 获取项目间约束
+func (s *ProjectConstraintStore) Get(id uuid.UUID, userID uuid.UUID) (*model.ProjectConstraint, error) {
+	var constraint model.ProjectConstraint
+	err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&constraint).Error
+	if err != nil {
+		return nil, err
+	}
+	return &constraint, nil
+}
+
+// Create 创建项目间约束
+func (s *ProjectConstraintStore) Create(constraint *model.ProjectConstraint) error {
+	return s.db.Create(constraint).Error
+}
+
+// Update 更新项目间约束
+func (s *ProjectConstraintStore) Update(constraint *model.ProjectConstraint) error {
+	return s.db.Save(constraint).Error
+}
+
+// Delete 删除项目间约束，约束不存在时返回 ErrNotFound
+func (s *ProjectConstraintStore) Delete(id uuid.UUID, userID uuid.UUID) error {
+	result := s.db.Where("id = ? AND user_id = ?", id, userID).Delete(&model.ProjectConstraint{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteByProject 删除以项目为来源或目标的所有约束
+func (s *ProjectConstraintStore) DeleteByProject(projectID uuid.UUID) error {
+	return s.db.Where("source_project_id = ? OR target_project_id = ?", projectID, projectID).
+		Delete(&model.ProjectConstraint{}).Error
+}
+
+// DeleteByUser 删除用户的所有项目间约束
+func (s *ProjectConstraintStore) DeleteByUser(userID uuid.UUID) error {
+	return s.db.Where("user_id = ?", userID).Delete(&model.ProjectConstraint{}).Error
+}
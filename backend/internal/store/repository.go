@@ -0,0 +1,378 @@
+package store
+
+import (
+	"errors"
+	"time"
+	"whotakesshowers/internal/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrNotFound 记录不存在；所有仓储实现在找不到记录时都返回该错误
+var ErrNotFound = gorm.ErrRecordNotFound
+
+// ErrInsufficientPoints 候选人的积分余额不足
+var ErrInsufficientPoints = errors.New("insufficient points")
+
+// Store 数据访问入口，聚合所有仓储
+type Store interface {
+	Users() UserRepository
+	Projects() ProjectRepository
+	Candidates() CandidateRepository
+	CandidatePhotos() CandidatePhotoRepository
+	Histories() HistoryRepository
+	AccountDeletions() AccountDeletionRepository
+	CalendarFeeds() CalendarFeedRepository
+	HistoryChanges() HistoryChangeRepository
+	Rewards() RewardRepository
+	PointEntries() PointEntryRepository
+	BadgeRules() BadgeRuleRepository
+	Badges() BadgeRepository
+	Routines() RoutineRepository
+	RoutineRuns() RoutineRunRepository
+	ProjectConstraints() ProjectConstraintRepository
+	TeamSplits() TeamSplitRepository
+	IdempotencyKeys() IdempotencyKeyRepository
+
+	// Transaction 在事务中执行 fn，fn 返回错误时回滚；
+	// fn 中必须通过参数 tx 访问仓储，操作才属于该事务
+	Transaction(fn func(tx Store) error) error
+}
+
+// UserRepository 用户仓储
+type UserRepository interface {
+	Get(id uuid.UUID) (*model.User, error)
+	GetByUsername(username string) (*model.User, error)
+	GetByEmail(email string) (*model.User, error)
+	Create(user *model.User) error
+	SetDeletionRequestedAt(id uuid.UUID, at *time.Time) error
+	ListDeletionDue(before time.Time) ([]model.User, error)
+	Delete(id uuid.UUID) error
+}
+
+// ProjectFilter 项目列表过滤条件，零值字段不参与过滤
+type ProjectFilter struct {
+	Query string // 名称包含 Query（不区分大小写）
+}
+
+// ProjectRepository 项目仓储
+type ProjectRepository interface {
+	List(userID uuid.UUID) ([]model.Project, error)
+	Find(userID uuid.UUID, filter ProjectFilter, page PageRequest) (*Page[model.Project], error)
+	Get(id uuid.UUID, userID uuid.UUID) (*model.Project, error)
+	Create(project *model.Project) error
+	Update(project *model.Project) error
+	Delete(id uuid.UUID, userID uuid.UUID) error
+	ListDeleted(userID uuid.UUID) ([]model.Project, error)
+	GetDeleted(id uuid.UUID, userID uuid.UUID) (*model.Project, error)
+	ListExpired(before time.Time) ([]model.Project, error)
+	Restore(id uuid.UUID, userID uuid.UUID) error
+	Purge(id uuid.UUID, userID uuid.UUID) error
+	GetCandidateIDs(projectID uuid.UUID) ([]uuid.UUID, error)
+	SetCandidateIDs(projectID uuid.UUID, candidateIDs []uuid.UUID) error
+	RemoveCandidate(candidateID uuid.UUID, userID uuid.UUID) error
+}
+
+// CandidateFilter 候选人列表过滤条件，零值字段不参与过滤
+type CandidateFilter struct {
+	Query string // 名称包含 Query（不区分大小写）
+	Tag   string // 带有标签 Tag（小写）
+}
+
+// CandidateRepository 候选人仓储
+type CandidateRepository interface {
+	List(userID uuid.UUID) ([]model.Candidate, error)
+	Find(userID uuid.UUID, filter CandidateFilter, page PageRequest) (*Page[model.Candidate], error)
+	Get(id uuid.UUID, userID uuid.UUID) (*model.Candidate, error)
+	GetByIDs(ids []uuid.UUID, userID uuid.UUID) ([]model.Candidate, error)
+	Create(candidate *model.Candidate) error
+	Update(candidate *model.Candidate) error
+	Trash(id uuid.UUID, userID uuid.UUID, at time.Time) error
+	ListDeleted(userID uuid.UUID) ([]model.Candidate, error)
+	GetDeleted(id uuid.UUID, userID uuid.UUID) (*model.Candidate, error)
+	ListExpired(before time.Time) ([]model.Candidate, error)
+	Restore(id uuid.UUID, userID uuid.UUID) error
+	Purge(id uuid.UUID, userID uuid.UUID) error
+	UpdatePhoto(id uuid.UUID, userID uuid.UUID, photoURL string) error
+	AddPoints(id uuid.UUID, userID uuid.UUID, delta int) (int, error)
+	SpendPoints(id uuid.UUID, userID uuid.UUID, amount int) (int, error)
+}
+
+// CandidatePhotoRepository 候选人照片仓储
+type CandidatePhotoRepository interface {
+	List(candidateID uuid.UUID) ([]model.CandidatePhoto, error)
+	Get(id uuid.UUID, candidateID uuid.UUID) (*model.CandidatePhoto, error)
+	GetAvatar(candidateID uuid.UUID) (*model.CandidatePhoto, error)
+	GetLatest(candidateID uuid.UUID) (*model.CandidatePhoto, error)
+	Create(photo *model.CandidatePhoto) error
+	CreateBatch(photos []model.CandidatePhoto) error
+	Delete(id uuid.UUID) error
+	TrashByCandidateID(candidateID uuid.UUID, at time.Time) error
+	RestoreByCandidateID(candidateID uuid.UUID, at time.Time) error
+	ListAll(candidateID uuid.UUID) ([]model.CandidatePhoto, error)
+	ListDeleted(userID uuid.UUID) ([]model.CandidatePhoto, error)
+	GetDeleted(id uuid.UUID, userID uuid.UUID) (*model.CandidatePhoto, error)
+	ListExpired(before time.Time) ([]model.CandidatePhoto, error)
+	Restore(id uuid.UUID) error
+	Purge(id uuid.UUID) error
+	PurgeByCandidateID(candidateID uuid.UUID) error
+	SetAvatar(candidateID uuid.UUID, photoID uuid.UUID) error
+	ClearAvatar(candidateID uuid.UUID) error
+}
+
+// HistoryFilter 历史记录过滤条件，零值字段不参与过滤
+type HistoryFilter struct {
+	ProjectID   *uuid.UUID
+	CandidateID *uuid.UUID
+	From        *time.Time // selected_at >= From
+	To          *time.Time // selected_at < To
+	Source      string
+	Voided      *bool // true 只返回作废的记录，false 只返回未作废的记录
+}
+
+// SupersededCount 候选人被重新选择取代的记录数，即已使用的重新选择次数
+type SupersededCount struct {
+	CandidateID uuid.UUID
+	Count       int
+}
+
+// HistoryRepository 历史记录仓储
+type HistoryRepository interface {
+	List(userID uuid.UUID, projectID *uuid.UUID, limit int) ([]model.History, error)
+	Find(userID uuid.UUID, filter HistoryFilter, page PageRequest) (*Page[model.History], error)
+	Get(id uuid.UUID, userID uuid.UUID) (*model.History, error)
+	Create(history *model.History) error
+	Update(history *model.History) error
+	ListByRoutineRun(runID uuid.UUID) ([]model.History, error)
+	CountSuperseded(projectID uuid.UUID, since time.Time) ([]SupersededCount, error)
+	DeleteByProject(projectID uuid.UUID, userID uuid.UUID) error
+	DeleteByUser(userID uuid.UUID) (int64, error)
+}
+
+// AccountDeletionRepository 账号删除审计记录仓储
+type AccountDeletionRepository interface {
+	Create(deletion *model.AccountDeletion) error
+}
+
+// CalendarFeedRepository 日历订阅仓储
+type CalendarFeedRepository interface {
+	List(userID uuid.UUID) ([]model.CalendarFeed, error)
+	GetByTokenHash(tokenHash string) (*model.CalendarFeed, error)
+	Create(feed *model.CalendarFeed) error
+	Delete(id uuid.UUID, userID uuid.UUID) error
+	DeleteByUser(userID uuid.UUID) error
+}
+
+// HistoryChangeRepository 历史记录修改记录仓储
+type HistoryChangeRepository interface {
+	List(historyID uuid.UUID) ([]model.HistoryChange, error)
+	Create(changes []model.HistoryChange) error
+}
+
+// RewardRepository 奖励仓储
+type RewardRepository interface {
+	List(userID uuid.UUID) ([]model.Reward, error)
+	Get(id uuid.UUID, userID uuid.UUID) (*model.Reward, error)
+	Create(reward *model.Reward) error
+	Update(reward *model.Reward) error
+	Delete(id uuid.UUID, userID uuid.UUID) error
+	DeleteByUser(userID uuid.UUID) error
+}
+
+// PointEntryFilter 积分流水过滤条件，零值字段不参与过滤
+type PointEntryFilter struct {
+	CandidateID *uuid.UUID
+	Kind        string
+}
+
+// PointTotals 候选人的积分汇总
+type PointTotals struct {
+	CandidateID uuid.UUID
+	Earned      int // 兑换以外的流水之和
+	Redeemed    int // 兑换奖励扣除的积分（正数）
+}
+
+// PointEntryRepository 积分流水仓储
+type PointEntryRepository interface {
+	Find(userID uuid.UUID, filter PointEntryFilter, page PageRequest) (*Page[model.PointEntry], error)
+	ListByHistory(historyID uuid.UUID) ([]model.PointEntry, error)
+	Totals(userID uuid.UUID) ([]PointTotals, error)
+	Create(entry *model.PointEntry) error
+	DeleteByCandidate(candidateID uuid.UUID) error
+	DeleteByUser(userID uuid.UUID) error
+}
+
+// BadgeRuleRepository 徽章规则仓储
+type BadgeRuleRepository interface {
+	List(userID uuid.UUID) ([]model.BadgeRule, error)
+	Get(id uuid.UUID, userID uuid.UUID) (*model.BadgeRule, error)
+	Create(rule *model.BadgeRule) error
+	Update(rule *model.BadgeRule) error
+	Delete(id uuid.UUID, userID uuid.UUID) error
+	DeleteByUser(userID uuid.UUID) error
+}
+
+// BadgeRepository 徽章仓储
+type BadgeRepository interface {
+	List(userID uuid.UUID, candidateID *uuid.UUID) ([]model.Badge, error)
+	ListByRule(ruleID uuid.UUID) ([]model.Badge, error)
+	Create(badge *model.Badge) error
+	DeleteByRule(ruleID uuid.UUID) error
+	DeleteByCandidate(candidateID uuid.UUID) error
+	DeleteByUser(userID uuid.UUID) error
+}
+
+// RoutineRepository 例程仓储
+type RoutineRepository interface {
+	List(userID uuid.UUID) ([]model.Routine, error)
+	Get(id uuid.UUID, userID uuid.UUID) (*model.Routine, error)
+	Create(routine *model.Routine) error
+	Update(routine *model.Routine) error
+	Delete(id uuid.UUID, userID uuid.UUID) error
+	RemoveProject(projectID uuid.UUID, userID uuid.UUID) error
+	DeleteByUser(userID uuid.UUID) error
+}
+
+// RoutineRunRepository 例程执行记录仓储
+type RoutineRunRepository interface {
+	Get(id uuid.UUID, userID uuid.UUID) (*model.RoutineRun, error)
+	ListByRoutine(routineID uuid.UUID, userID uuid.UUID, limit int) ([]model.RoutineRun, error)
+	Create(run *model.RoutineRun) error
+	Update(run *model.RoutineRun) error
+	DeleteByUser(userID uuid.UUID) error
+}
+
+// ProjectConstraintRepository 项目间约束仓储
+type ProjectConstraintRepository interface {
+	List(userID uuid.UUID) ([]model.ProjectConstraint, error)
+	ListByTarget(targetProjectID uuid.UUID, userID uuid.UUID) ([]model.ProjectConstraint, error)
+	Get(id uuid.UUID, userID uuid.UUID) (*model.ProjectConstraint, error)
+	Create(constraint *model.ProjectConstraint) error
+	Update(constraint *model.ProjectConstraint) error
+	Delete(id uuid.UUID, userID uuid.UUID) error
+	DeleteByProject(projectID uuid.UUID) error
+	DeleteByUser(userID uuid.UUID) error
+}
+
+// TeamSplitRepository 分组记录仓储
+type TeamSplitRepository interface {
+	Find(userID uuid.UUID, projectID *uuid.UUID, page PageRequest) (*Page[model.TeamSplit], error)
+	Create(split *model.TeamSplit) error
+	DeleteByProject(projectID uuid.UUID) error
+	DeleteByUser(userID uuid.UUID) error
+}
+
+// IdempotencyKeyRepository 幂等键仓储
+type IdempotencyKeyRepository interface {
+	Get(userID uuid.UUID, key string) (*model.IdempotencyKey, error)
+	Create(record *model.IdempotencyKey) error
+	Complete(record *model.IdempotencyKey) error
+	Delete(id uuid.UUID) error
+	DeleteExpired(before time.Time) (int64, error)
+	DeleteByUser(userID uuid.UUID) error
+}
+
+var (
+	_ UserRepository              = (*UserStore)(nil)
+	_ ProjectRepository           = (*ProjectStore)(nil)
+	_ CandidateRepository         = (*CandidateStore)(nil)
+	_ CandidatePhotoRepository    = (*CandidatePhotoStore)(nil)
+	_ HistoryRepository           = (*HistoryStore)(nil)
+	_ AccountDeletionRepository   = (*AccountDeletionStore)(nil)
+	_ CalendarFeedRepository      = (*CalendarFeedStore)(nil)
+	_ HistoryChangeRepository     = (*HistoryChangeStore)(nil)
+	_ RewardRepository            = (*RewardStore)(nil)
+	_ PointEntryRepository        = (*PointEntryStore)(nil)
+	_ BadgeRuleRepository         = (*BadgeRuleStore)(nil)
+	_ BadgeRepository             = (*BadgeStore)(nil)
+	_ RoutineRepository           = (*RoutineStore)(nil)
+	_ RoutineRunRepository        = (*RoutineRunStore)(nil)
+	_ ProjectConstraintRepository = (*ProjectConstraintStore)(nil)
+	_ TeamSplitRepository         = (*TeamSplitStore)(nil)
+	_ IdempotencyKeyRepository    = (*IdempotencyKeyStore)(nil)
+)
+
+// dbStore 基于 gorm 的 Store 实现
+type dbStore struct {
+	db *gorm.DB
+}
+
+// New 创建基于 gorm 数据库连接的 Store
+func New(db *gorm.DB) Store {
+	return &dbStore{db: db}
+}
+
+func (s *dbStore) Users() UserRepository {
+	return NewUserStore(s.db)
+}
+
+func (s *dbStore) Projects() ProjectRepository {
+	return NewProjectStore(s.db)
+}
+
+func (s *dbStore) Candidates() CandidateRepository {
+	return NewCandidateStore(s.db)
+}
+
+func (s *dbStore) CandidatePhotos() CandidatePhotoRepository {
+	return NewCandidatePhotoStore(s.db)
+}
+
+func (s *dbStore) Histories() HistoryRepository {
+	return NewHistoryStore(s.db)
+}
+
+func (s *dbStore) AccountDeletions() AccountDeletionRepository {
+	return NewAccountDeletionStore(s.db)
+}
+
+func (s *dbStore) CalendarFeeds() CalendarFeedRepository {
+	return NewCalendarFeedStore(s.db)
+}
+
+func (s *dbStore) HistoryChanges() HistoryChangeRepository {
+	return NewHistoryChangeStore(s.db)
+}
+
+func (s *dbStore) Rewards() RewardRepository {
+	return NewRewardStore(s.db)
+}
+
+func (s *dbStore) PointEntries() PointEntryRepository {
+	return NewPointEntryStore(s.db)
+}
+
+func (s *dbStore) BadgeRules() BadgeRuleRepository {
+	return NewBadgeRuleStore(s.db)
+}
+
+func (s *dbStore) Badges() BadgeRepository {
+	return NewBadgeStore(s.db)
+}
+
+func (s *dbStore) Routines() RoutineRepository {
+	return NewRoutineStore(s.db)
+}
+
+func (s *dbStore) RoutineRuns() RoutineRunRepository {
+	return NewRoutineRunStore(s.db)
+}
+
+func (s *dbStore) ProjectConstraints() ProjectConstraintRepository {
+	return NewProjectConstraintStore(s.db)
+}
+
+func (s *dbStore) TeamSplits() TeamSplitRepository {
+	return NewTeamSplitStore(s.db)
+}
+
+func (s *dbStore) IdempotencyKeys() IdempotencyKeyRepository {
+	return NewIdempotencyKeyStore(s.db)
+}
+
+func (s *dbStore) Transaction(fn func(tx Store) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&dbStore{db: tx})
+	})
+}
@@ -0,0 +1,745 @@
+package store_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"whotakesshowers/internal/model"
+	"whotakesshowers/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// mustCreateUser 创建测试用户
+func mustCreateUser(t *testing.T, s store.Store, username string) *model.User {
+	t.Helper()
+	user := &model.User{Username: username, Email: username + "@example.com", Password: "x"}
+	if err := s.Users().Create(user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+// mustCreateProject 创建测试项目
+func mustCreateProject(t *testing.T, s store.Store, userID uuid.UUID, name string) *model.Project {
+	t.Helper()
+	project := &model.Project{Name: name, UserID: userID, CandidateIDs: "[]"}
+	if err := s.Projects().Create(project); err != nil {
+		t.Fatalf("create project: %v", err)
+	}
+	return project
+}
+
+// mustCreateCandidate 创建测试候选人
+func mustCreateCandidate(t *testing.T, s store.Store, candidate *model.Candidate) *model.Candidate {
+	t.Helper()
+	if err := s.Candidates().Create(candidate); err != nil {
+		t.Fatalf("create candidate: %v", err)
+	}
+	return candidate
+}
+
+// collectPages 从第一页开始按 NextCursor 读取所有页，返回每条记录的 ID
+func collectPages[T any](t *testing.T, find func(page store.PageRequest) (*store.Page[T], error), page store.PageRequest, id func(item T) uuid.UUID) []uuid.UUID {
+	t.Helper()
+	var ids []uuid.UUID
+	for pages := 0; ; pages++ {
+		if pages > 100 {
+			t.Fatal("pagination does not terminate")
+		}
+		result, err := find(page)
+		if err != nil {
+			t.Fatalf("find (cursor %q): %v", page.Cursor, err)
+		}
+		for _, item := range result.Items {
+			ids = append(ids, id(item))
+		}
+		if result.NextCursor == "" {
+			return ids
+		}
+		page.Cursor = result.NextCursor
+	}
+}
+
+// candidateIDs 返回候选人的 ID
+func candidateIDs(candidates ...*model.Candidate) []uuid.UUID {
+	ids := make([]uuid.UUID, len(candidates))
+	for i, c := range candidates {
+		ids[i] = c.ID
+	}
+	return ids
+}
+
+func TestUserRepository(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store) {
+		user := mustCreateUser(t, s, "alice")
+		if user.ID == uuid.Nil {
+			t.Fatal("Create did not assign an ID")
+		}
+
+		got, err := s.Users().GetByUsername("alice")
+		if err != nil || got.ID != user.ID {
+			t.Fatalf("GetByUsername = %+v, %v", got, err)
+		}
+		if got, err := s.Users().GetByEmail("alice@example.com"); err != nil || got.ID != user.ID {
+			t.Fatalf("GetByEmail = %+v, %v", got, err)
+		}
+		if _, err := s.Users().Get(uuid.New()); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("Get of a missing user = %v, want ErrNotFound", err)
+		}
+		if err := s.Users().Create(&model.User{Username: "alice", Email: "other@example.com"}); err == nil {
+			t.Error("Create with a duplicate username succeeded")
+		}
+
+		due := time.Now().Add(-time.Hour).Truncate(time.Second)
+		if err := s.Users().SetDeletionRequestedAt(user.ID, &due); err != nil {
+			t.Fatal(err)
+		}
+		users, err := s.Users().ListDeletionDue(time.Now())
+		if err != nil || len(users) != 1 || users[0].ID != user.ID {
+			t.Errorf("ListDeletionDue = %+v, %v", users, err)
+		}
+	})
+}
+
+func TestCandidateFind(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store) {
+		user := mustCreateUser(t, s, "alice")
+		other := mustCreateUser(t, s, "bob")
+		percent := mustCreateCandidate(t, s, &model.Candidate{Name: "50% Off", UserID: user.ID, Tags: `["kid","red"]`})
+		digits := mustCreateCandidate(t, s, &model.Candidate{Name: "5000", UserID: user.ID, Tags: `["kids"]`})
+		underscore := mustCreateCandidate(t, s, &model.Candidate{Name: "a_b", UserID: user.ID})
+		plain := mustCreateCandidate(t, s, &model.Candidate{Name: "Axb", UserID: user.ID, Tags: `["kid"]`})
+		mustCreateCandidate(t, s, &model.Candidate{Name: "50% Off", UserID: other.ID, Tags: `["kid"]`})
+
+		tests := []struct {
+			name   string
+			filter store.CandidateFilter
+			want   []uuid.UUID
+		}{
+			{"all", store.CandidateFilter{}, candidateIDs(percent, digits, underscore, plain)},
+			{"percent is literal", store.CandidateFilter{Query: "0%"}, candidateIDs(percent)},
+			{"underscore is literal", store.CandidateFilter{Query: "_"}, candidateIDs(underscore)},
+			{"case insensitive", store.CandidateFilter{Query: "OFF"}, candidateIDs(percent)},
+			{"whole tag", store.CandidateFilter{Tag: "kid"}, candidateIDs(percent, plain)},
+			{"query and tag", store.CandidateFilter{Query: "a", Tag: "kid"}, candidateIDs(plain)},
+		}
+		for _, tt := range tests {
+			page, err := s.Candidates().Find(user.ID, tt.filter, store.PageRequest{Limit: store.MaxPageLimit})
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			var got []uuid.UUID
+			for _, c := range page.Items {
+				got = append(got, c.ID)
+			}
+			slices.SortFunc(got, compareUUID)
+			want := slices.Clone(tt.want)
+			slices.SortFunc(want, compareUUID)
+			if !slices.Equal(got, want) || page.Total != int64(len(want)) {
+				t.Errorf("%s: got %v (total %d), want %v", tt.name, got, page.Total, want)
+			}
+		}
+	})
+}
+
+func TestCandidatePagination(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store) {
+		user := mustCreateUser(t, s, "alice")
+		base := time.Now().Add(-time.Hour).Truncate(time.Second)
+		var created []*model.Candidate
+		for i, name := range []string{"e", "d", "c", "b", "a"} {
+			// 两两相同的创建时间，分页需要按 ID 区分
+			at := base.Add(time.Duration(i/2) * time.Minute)
+			created = append(created, mustCreateCandidate(t, s, &model.Candidate{
+				Name: name, UserID: user.ID, CreatedAt: at, UpdatedAt: at,
+			}))
+		}
+
+		find := func(page store.PageRequest) (*store.Page[model.Candidate], error) {
+			return s.Candidates().Find(user.ID, store.CandidateFilter{}, page)
+		}
+		id := func(c model.Candidate) uuid.UUID { return c.ID }
+
+		// 默认按创建时间倒序，时间相同时按 ID 倒序
+		want := slices.Clone(created)
+		slices.SortStableFunc(want, func(a, b *model.Candidate) int {
+			if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+				return c
+			}
+			return compareUUID(b.ID, a.ID)
+		})
+		if got := collectPages(t, find, store.PageRequest{Limit: 2}, id); !slices.Equal(got, candidateIDs(want...)) {
+			t.Errorf("-created_at pages = %v, want %v", got, candidateIDs(want...))
+		}
+
+		byName := slices.Clone(created)
+		slices.Reverse(byName)
+		if got := collectPages(t, find, store.PageRequest{Limit: 2, Sort: "name"}, id); !slices.Equal(got, candidateIDs(byName...)) {
+			t.Errorf("name pages = %v, want %v", got, candidateIDs(byName...))
+		}
+
+		first, err := find(store.PageRequest{Limit: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := find(store.PageRequest{Limit: 2, Sort: "name", Cursor: first.NextCursor}); !errors.Is(err, store.ErrInvalidCursor) {
+			t.Errorf("cursor reused with another sort = %v, want ErrInvalidCursor", err)
+		}
+	})
+}
+
+func TestCandidateTrashAndPoints(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store) {
+		user := mustCreateUser(t, s, "alice")
+		candidate := mustCreateCandidate(t, s, &model.Candidate{Name: "Ann", UserID: user.ID})
+
+		if balance, err := s.Candidates().AddPoints(candidate.ID, user.ID, 5); err != nil || balance != 5 {
+			t.Fatalf("AddPoints = %d, %v", balance, err)
+		}
+		if _, err := s.Candidates().SpendPoints(candidate.ID, user.ID, 6); !errors.Is(err, store.ErrInsufficientPoints) {
+			t.Errorf("SpendPoints over the balance = %v, want ErrInsufficientPoints", err)
+		}
+		if balance, err := s.Candidates().SpendPoints(candidate.ID, user.ID, 3); err != nil || balance != 2 {
+			t.Errorf("SpendPoints = %d, %v", balance, err)
+		}
+		if _, err := s.Candidates().AddPoints(uuid.New(), user.ID, 1); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("AddPoints on a missing candidate = %v, want ErrNotFound", err)
+		}
+
+		// Update 不会覆盖积分余额
+		candidate.Name = "Anna"
+		candidate.Points = 100
+		if err := s.Candidates().Update(candidate); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := s.Candidates().Get(candidate.ID, user.ID); err != nil || got.Name != "Anna" || got.Points != 2 {
+			t.Errorf("after Update: %+v, %v", got, err)
+		}
+
+		if err := s.Candidates().Trash(candidate.ID, user.ID, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Candidates().Get(candidate.ID, user.ID); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("Get of a trashed candidate = %v, want ErrNotFound", err)
+		}
+		if deleted, err := s.Candidates().ListDeleted(user.ID); err != nil || len(deleted) != 1 {
+			t.Errorf("ListDeleted = %+v, %v", deleted, err)
+		}
+		if err := s.Candidates().Restore(candidate.ID, user.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Candidates().Get(candidate.ID, user.ID); err != nil {
+			t.Errorf("Get after Restore: %v", err)
+		}
+	})
+}
+
+func TestProjectRepository(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store) {
+		user := mustCreateUser(t, s, "alice")
+		shower := mustCreateProject(t, s, user.ID, "Shower_1")
+		dishes := mustCreateProject(t, s, user.ID, "Dishes")
+		ann := mustCreateCandidate(t, s, &model.Candidate{Name: "Ann", UserID: user.ID})
+		ben := mustCreateCandidate(t, s, &model.Candidate{Name: "Ben", UserID: user.ID})
+
+		page, err := s.Projects().Find(user.ID, store.ProjectFilter{Query: "_"}, store.PageRequest{})
+		if err != nil || len(page.Items) != 1 || page.Items[0].ID != shower.ID {
+			t.Errorf("Find with a literal underscore = %+v, %v", page, err)
+		}
+
+		for _, project := range []*model.Project{shower, dishes} {
+			if err := s.Projects().SetCandidateIDs(project.ID, candidateIDs(ann, ben)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := s.Projects().Delete(dishes.ID, user.ID); err != nil {
+			t.Fatal(err)
+		}
+		// 回收站中的项目也会移除候选人
+		if err := s.Projects().RemoveCandidate(ann.ID, user.ID); err != nil {
+			t.Fatal(err)
+		}
+		if ids, err := s.Projects().GetCandidateIDs(shower.ID); err != nil || !slices.Equal(ids, candidateIDs(ben)) {
+			t.Errorf("candidate IDs = %v, %v, want [%s]", ids, err, ben.ID)
+		}
+
+		if _, err := s.Projects().Get(dishes.ID, user.ID); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("Get of a trashed project = %v, want ErrNotFound", err)
+		}
+		if projects, err := s.Projects().List(user.ID); err != nil || len(projects) != 1 {
+			t.Errorf("List = %+v, %v", projects, err)
+		}
+		if err := s.Projects().Restore(dishes.ID, user.ID); err != nil {
+			t.Fatal(err)
+		}
+		if projects, err := s.Projects().List(user.ID); err != nil || len(projects) != 2 {
+			t.Errorf("List after Restore = %+v, %v", projects, err)
+		}
+		if ids, err := s.Projects().GetCandidateIDs(dishes.ID); err != nil || !slices.Equal(ids, candidateIDs(ben)) {
+			t.Errorf("candidate IDs of the restored project = %v, %v, want [%s]", ids, err, ben.ID)
+		}
+	})
+}
+
+func TestHistoryRepository(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store) {
+		user := mustCreateUser(t, s, "alice")
+		shower := mustCreateProject(t, s, user.ID, "Shower")
+		dishes := mustCreateProject(t, s, user.ID, "Dishes")
+		ann := mustCreateCandidate(t, s, &model.Candidate{Name: "Ann", UserID: user.ID})
+
+		base := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+		var histories []*model.History
+		for i := 0; i < 6; i++ {
+			project := shower
+			if i%3 == 2 {
+				project = dishes
+			}
+			history := &model.History{
+				ProjectID: project.ID, ProjectName: project.Name,
+				CandidateID: ann.ID, CandidateName: ann.Name,
+				// 两两相同的选择时间
+				SelectedAt: base.Add(time.Duration(i/2) * time.Hour),
+				UserID:     user.ID, Source: model.HistorySourceDraw, Status: model.HistoryStatusPicked,
+			}
+			if err := s.Histories().Create(history); err != nil {
+				t.Fatal(err)
+			}
+			histories = append(histories, history)
+		}
+
+		find := func(filter store.HistoryFilter) func(page store.PageRequest) (*store.Page[model.History], error) {
+			return func(page store.PageRequest) (*store.Page[model.History], error) {
+				return s.Histories().Find(user.ID, filter, page)
+			}
+		}
+		id := func(h model.History) uuid.UUID { return h.ID }
+
+		want := slices.Clone(histories)
+		slices.SortStableFunc(want, func(a, b *model.History) int {
+			if c := b.SelectedAt.Compare(a.SelectedAt); c != 0 {
+				return c
+			}
+			return compareUUID(b.ID, a.ID)
+		})
+		wantIDs := make([]uuid.UUID, len(want))
+		for i, h := range want {
+			wantIDs[i] = h.ID
+		}
+		if got := collectPages(t, find(store.HistoryFilter{}), store.PageRequest{Limit: 4}, id); !slices.Equal(got, wantIDs) {
+			t.Errorf("-selected_at pages = %v, want %v", got, wantIDs)
+		}
+		ascending := slices.Clone(wantIDs)
+		slices.Reverse(ascending)
+		if got := collectPages(t, find(store.HistoryFilter{}), store.PageRequest{Limit: 1, Sort: "selected_at"}, id); !slices.Equal(got, ascending) {
+			t.Errorf("selected_at pages = %v, want %v", got, ascending)
+		}
+
+		from, to := base.Add(time.Hour), base.Add(2*time.Hour)
+		if got := collectPages(t, find(store.HistoryFilter{From: &from, To: &to}), store.PageRequest{}, id); len(got) != 2 {
+			t.Errorf("From/To = %v, want the 2 entries selected at %v", got, from)
+		}
+		if got := collectPages(t, find(store.HistoryFilter{ProjectID: &dishes.ID}), store.PageRequest{}, id); len(got) != 2 {
+			t.Errorf("ProjectID filter = %v, want 2 entries", got)
+		}
+
+		// 作废并被重新选择取代
+		voided, superseded := histories[0], histories[1]
+		now := time.Now().Truncate(time.Second)
+		voided.VoidedAt, voided.VoidReason = &now, "mistake"
+		superseded.SupersededByID, superseded.SupersededAt = &histories[2].ID, &now
+		for _, h := range []*model.History{voided, superseded} {
+			if err := s.Histories().Update(h); err != nil {
+				t.Fatal(err)
+			}
+		}
+		yes := true
+		if got := collectPages(t, find(store.HistoryFilter{Voided: &yes}), store.PageRequest{}, id); !slices.Equal(got, []uuid.UUID{voided.ID}) {
+			t.Errorf("Voided filter = %v, want [%s]", got, voided.ID)
+		}
+		got, err := s.Histories().Get(superseded.ID, user.ID)
+		if err != nil || got.SupersededByID == nil || *got.SupersededByID != histories[2].ID || got.SupersededAt == nil {
+			t.Errorf("superseded entry = %+v, %v", got, err)
+		}
+		counts, err := s.Histories().CountSuperseded(shower.ID, now.Add(-time.Minute))
+		if err != nil || len(counts) != 1 || counts[0].CandidateID != ann.ID || counts[0].Count != 1 {
+			t.Errorf("CountSuperseded = %+v, %v", counts, err)
+		}
+
+		// 回收站中项目的记录不可见
+		if err := s.Projects().Delete(dishes.ID, user.ID); err != nil {
+			t.Fatal(err)
+		}
+		if got := collectPages(t, find(store.HistoryFilter{}), store.PageRequest{}, id); len(got) != 4 {
+			t.Errorf("entries with the project in the trash = %d, want 4", len(got))
+		}
+		if _, err := s.Histories().Get(histories[2].ID, user.ID); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("Get of an entry of a trashed project = %v, want ErrNotFound", err)
+		}
+
+		if deleted, err := s.Histories().DeleteByUser(user.ID); err != nil || deleted != 6 {
+			t.Errorf("DeleteByUser = %d, %v, want 6", deleted, err)
+		}
+	})
+}
+
+func TestPointEntryRepository(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store) {
+		user := mustCreateUser(t, s, "alice")
+		ann := mustCreateCandidate(t, s, &model.Candidate{Name: "Ann", UserID: user.ID})
+		for _, entry := range []model.PointEntry{
+			{Kind: model.PointKindPick, Amount: 3},
+			{Kind: model.PointKindOnTime, Amount: 2},
+			{Kind: model.PointKindRedeem, Amount: -4},
+		} {
+			entry.UserID, entry.CandidateID = user.ID, ann.ID
+			if err := s.PointEntries().Create(&entry); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		totals, err := s.PointEntries().Totals(user.ID)
+		if err != nil || len(totals) != 1 || totals[0].Earned != 5 || totals[0].Redeemed != 4 {
+			t.Errorf("Totals = %+v, %v, want earned 5 and redeemed 4", totals, err)
+		}
+		page, err := s.PointEntries().Find(user.ID, store.PointEntryFilter{Kind: model.PointKindRedeem}, store.PageRequest{})
+		if err != nil || page.Total != 1 {
+			t.Errorf("Find by kind = %+v, %v", page, err)
+		}
+	})
+}
+
+func TestBadgeUniquePerPeriod(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store) {
+		user := mustCreateUser(t, s, "alice")
+		ann := mustCreateCandidate(t, s, &model.Candidate{Name: "Ann", UserID: user.ID})
+		rule := &model.BadgeRule{UserID: user.ID, Name: "Clean week", Type: model.BadgeRuleCleanWeek, Threshold: 3}
+		if err := s.BadgeRules().Create(rule); err != nil {
+			t.Fatal(err)
+		}
+
+		badge := func(period string) *model.Badge {
+			return &model.Badge{UserID: user.ID, RuleID: rule.ID, CandidateID: ann.ID, Period: period, EarnedAt: time.Now()}
+		}
+		if err := s.Badges().Create(badge("2026-01-05")); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Badges().Create(badge("2026-01-05")); err == nil {
+			t.Error("second badge for the same period was created")
+		}
+		if err := s.Badges().Create(badge("2026-01-12")); err != nil {
+			t.Errorf("badge for another period: %v", err)
+		}
+		if badges, err := s.Badges().List(user.ID, &ann.ID); err != nil || len(badges) != 2 {
+			t.Errorf("List = %+v, %v", badges, err)
+		}
+	})
+}
+
+func TestCalendarFeedRepository(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store) {
+		user := mustCreateUser(t, s, "alice")
+		feed := &model.CalendarFeed{UserID: user.ID, Name: "All", TokenHash: "abc123"}
+		if err := s.CalendarFeeds().Create(feed); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := s.CalendarFeeds().GetByTokenHash("abc123"); err != nil || got.ID != feed.ID {
+			t.Errorf("GetByTokenHash = %+v, %v", got, err)
+		}
+		if _, err := s.CalendarFeeds().GetByTokenHash("other"); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("GetByTokenHash of an unknown token = %v, want ErrNotFound", err)
+		}
+		if err := s.CalendarFeeds().Delete(feed.ID, uuid.New()); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("Delete of another user's feed = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestIdempotencyKeyRepository(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store) {
+		user := mustCreateUser(t, s, "alice")
+		now := time.Now().Truncate(time.Second)
+		record := &model.IdempotencyKey{
+			UserID: user.ID, Key: "retry-1", Method: "POST", Path: "/api/randomize",
+			RequestHash: "hash", ExpiresAt: now.Add(time.Hour),
+		}
+		if err := s.IdempotencyKeys().Create(record); err != nil {
+			t.Fatal(err)
+		}
+		duplicate := *record
+		duplicate.ID = uuid.Nil
+		if err := s.IdempotencyKeys().Create(&duplicate); err == nil {
+			t.Error("duplicate key for the same user was created")
+		}
+
+		record.StatusCode, record.ContentType, record.ResponseBody = 201, "application/json", `{"ok":true}`
+		if err := s.IdempotencyKeys().Complete(record); err != nil {
+			t.Fatal(err)
+		}
+		got, err := s.IdempotencyKeys().Get(user.ID, "retry-1")
+		if err != nil || got.StatusCode != 201 || got.ResponseBody != `{"ok":true}` || got.RequestHash != "hash" {
+			t.Errorf("Get = %+v, %v", got, err)
+		}
+
+		expired := &model.IdempotencyKey{
+			UserID: user.ID, Key: "retry-2", Method: "POST", Path: "/api/randomize",
+			RequestHash: "hash", ExpiresAt: now.Add(-time.Hour),
+		}
+		if err := s.IdempotencyKeys().Create(expired); err != nil {
+			t.Fatal(err)
+		}
+		if deleted, err := s.IdempotencyKeys().DeleteExpired(now); err != nil || deleted != 1 {
+			t.Errorf("DeleteExpired = %d, %v, want 1", deleted, err)
+		}
+		if _, err := s.IdempotencyKeys().Get(user.ID, "retry-2"); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("Get of an expired key = %v, want ErrNotFound", err)
+		}
+
+		// 路径包括查询参数，长度不受限制
+		long := &model.IdempotencyKey{
+			UserID: user.ID, Key: "retry-3", Method: "POST", Path: "/api/randomize?q=" + strings.Repeat("x", 2000),
+			RequestHash: "hash", ExpiresAt: now.Add(time.Hour),
+		}
+		if err := s.IdempotencyKeys().Create(long); err != nil {
+			t.Errorf("Create with a long path: %v", err)
+		}
+	})
+}
+
+func TestTeamSplitRepository(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store) {
+		user := mustCreateUser(t, s, "alice")
+		shower := mustCreateProject(t, s, user.ID, "Shower")
+		dishes := mustCreateProject(t, s, user.ID, "Dishes")
+		for _, project := range []*model.Project{shower, dishes} {
+			split := &model.TeamSplit{UserID: user.ID, ProjectID: project.ID, ProjectName: project.Name, GroupCount: 2, Groups: "[]"}
+			if err := s.TeamSplits().Create(split); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if page, err := s.TeamSplits().Find(user.ID, &shower.ID, store.PageRequest{}); err != nil || page.Total != 1 {
+			t.Errorf("Find by project = %+v, %v", page, err)
+		}
+		if err := s.Projects().Delete(dishes.ID, user.ID); err != nil {
+			t.Fatal(err)
+		}
+		if page, err := s.TeamSplits().Find(user.ID, nil, store.PageRequest{}); err != nil || page.Total != 1 {
+			t.Errorf("Find with a project in the trash = %+v, %v, want 1", page, err)
+		}
+	})
+}
+
+func TestTransactionRollback(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store) {
+		user := mustCreateUser(t, s, "alice")
+		rollback := errors.New("rollback")
+		err := s.Transaction(func(tx store.Store) error {
+			mustCreateCandidate(t, tx, &model.Candidate{Name: "Ann", UserID: user.ID})
+			if _, err := tx.Candidates().List(user.ID); err != nil {
+				return err
+			}
+			return rollback
+		})
+		if !errors.Is(err, rollback) {
+			t.Fatalf("Transaction = %v, want the error returned by fn", err)
+		}
+		if candidates, err := s.Candidates().List(user.ID); err != nil || len(candidates) != 0 {
+			t.Errorf("candidates after rollback = %+v, %v", candidates, err)
+		}
+	})
+}
+
+func TestCandidatePhotoRepository(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store) {
+		user := mustCreateUser(t, s, "alice")
+		ann := mustCreateCandidate(t, s, &model.Candidate{Name: "Ann", UserID: user.ID})
+		base := time.Now().Add(-time.Hour).Truncate(time.Second)
+		photos := []model.CandidatePhoto{
+			{CandidateID: ann.ID, PhotoURL: "/uploads/a.jpg", CreatedAt: base},
+			{CandidateID: ann.ID, PhotoURL: "/uploads/b.jpg", CreatedAt: base.Add(time.Minute)},
+		}
+		if err := s.CandidatePhotos().CreateBatch(photos); err != nil {
+			t.Fatal(err)
+		}
+		older, newer := photos[0], photos[1]
+
+		if latest, err := s.CandidatePhotos().GetLatest(ann.ID); err != nil || latest.ID != newer.ID {
+			t.Errorf("GetLatest = %+v, %v, want %s", latest, err, newer.ID)
+		}
+		if _, err := s.CandidatePhotos().GetAvatar(ann.ID); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("GetAvatar without an avatar = %v, want ErrNotFound", err)
+		}
+
+		// 每个候选人只有一张头像
+		for _, photo := range []model.CandidatePhoto{older, newer} {
+			if err := s.CandidatePhotos().SetAvatar(ann.ID, photo.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if avatar, err := s.CandidatePhotos().GetAvatar(ann.ID); err != nil || avatar.ID != newer.ID {
+			t.Errorf("GetAvatar = %+v, %v, want %s", avatar, err, newer.ID)
+		}
+		if err := s.CandidatePhotos().SetAvatar(ann.ID, uuid.New()); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("SetAvatar of a missing photo = %v, want ErrNotFound", err)
+		}
+		if avatar, err := s.CandidatePhotos().GetAvatar(ann.ID); err != nil || avatar.ID != newer.ID {
+			t.Errorf("GetAvatar after a failed SetAvatar = %+v, %v, want %s", avatar, err, newer.ID)
+		}
+
+		// 单独删除的照片出现在回收站中，随候选人一起删除的不出现
+		if err := s.CandidatePhotos().Delete(older.ID); err != nil {
+			t.Fatal(err)
+		}
+		if deleted, err := s.CandidatePhotos().ListDeleted(user.ID); err != nil || len(deleted) != 1 || deleted[0].ID != older.ID {
+			t.Errorf("ListDeleted = %+v, %v", deleted, err)
+		}
+		trashedAt := time.Now()
+		if err := s.Candidates().Trash(ann.ID, user.ID, trashedAt); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.CandidatePhotos().TrashByCandidateID(ann.ID, trashedAt); err != nil {
+			t.Fatal(err)
+		}
+		if deleted, err := s.CandidatePhotos().ListDeleted(user.ID); err != nil || len(deleted) != 0 {
+			t.Errorf("ListDeleted with the candidate in the trash = %+v, %v", deleted, err)
+		}
+
+		// 恢复候选人时只恢复和它一起删除的照片
+		trashed, err := s.Candidates().GetDeleted(ann.ID, user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Candidates().Restore(ann.ID, user.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.CandidatePhotos().RestoreByCandidateID(ann.ID, trashed.DeletedAt.Time); err != nil {
+			t.Fatal(err)
+		}
+		if list, err := s.CandidatePhotos().List(ann.ID); err != nil || len(list) != 1 || list[0].ID != newer.ID {
+			t.Errorf("List after restoring the candidate = %+v, %v, want only %s", list, err, newer.ID)
+		}
+		if all, err := s.CandidatePhotos().ListAll(ann.ID); err != nil || len(all) != 2 {
+			t.Errorf("ListAll = %+v, %v", all, err)
+		}
+	})
+}
+
+func TestHistoryChangeRepository(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store) {
+		user := mustCreateUser(t, s, "alice")
+		project := mustCreateProject(t, s, user.ID, "Shower")
+		ann := mustCreateCandidate(t, s, &model.Candidate{Name: "Ann", UserID: user.ID})
+		at := time.Now().Truncate(time.Second)
+		history := &model.History{
+			ProjectID: project.ID, ProjectName: project.Name, CandidateID: ann.ID, CandidateName: ann.Name,
+			SelectedAt: at, UserID: user.ID, Source: model.HistorySourceDraw, Status: model.HistoryStatusPicked,
+		}
+		if err := s.Histories().Create(history); err != nil {
+			t.Fatal(err)
+		}
+		historyID := history.ID
+		changes := []model.HistoryChange{
+			{HistoryID: historyID, UserID: user.ID, Field: "status", OldValue: "picked", NewValue: "started", ChangedAt: at},
+			{HistoryID: historyID, UserID: user.ID, Field: "note", NewValue: "late", ChangedAt: at},
+			{HistoryID: historyID, UserID: user.ID, Field: "status", OldValue: "started", NewValue: "completed", ChangedAt: at.Add(time.Minute)},
+		}
+		if err := s.HistoryChanges().Create(changes); err != nil {
+			t.Fatal(err)
+		}
+
+		// 按修改时间排序，同一次修改按字段名排序
+		got, err := s.HistoryChanges().List(historyID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fields []string
+		for _, change := range got {
+			fields = append(fields, change.Field+"="+change.NewValue)
+		}
+		if want := []string{"note=late", "status=started", "status=completed"}; !slices.Equal(fields, want) {
+			t.Errorf("List = %v, want %v", fields, want)
+		}
+	})
+}
+
+func TestRoutineRepository(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store) {
+		user := mustCreateUser(t, s, "alice")
+		shower := mustCreateProject(t, s, user.ID, "Shower")
+		dishes := mustCreateProject(t, s, user.ID, "Dishes")
+		routine := &model.Routine{
+			Name: "Evening", UserID: user.ID,
+			ProjectIDs: `["` + shower.ID.String() + `","` + dishes.ID.String() + `"]`,
+		}
+		if err := s.Routines().Create(routine); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := s.Routines().RemoveProject(shower.ID, user.ID); err != nil {
+			t.Fatal(err)
+		}
+		got, err := s.Routines().Get(routine.ID, user.ID)
+		if err != nil || got.ProjectIDs != `["`+dishes.ID.String()+`"]` {
+			t.Errorf("routine after RemoveProject = %+v, %v", got, err)
+		}
+		if err := s.Routines().Delete(routine.ID, uuid.New()); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("Delete of another user's routine = %v, want ErrNotFound", err)
+		}
+
+		for i := 0; i < 3; i++ {
+			run := &model.RoutineRun{
+				UserID: user.ID, RoutineID: routine.ID, RoutineName: routine.Name, Steps: 1,
+				StartedAt: time.Now().Add(time.Duration(i) * time.Minute).Truncate(time.Second),
+			}
+			if err := s.RoutineRuns().Create(run); err != nil {
+				t.Fatal(err)
+			}
+		}
+		runs, err := s.RoutineRuns().ListByRoutine(routine.ID, user.ID, 2)
+		if err != nil || len(runs) != 2 || !runs[0].StartedAt.After(runs[1].StartedAt) {
+			t.Errorf("ListByRoutine = %+v, %v, want the 2 latest runs", runs, err)
+		}
+	})
+}
+
+func TestProjectConstraintRepository(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store) {
+		user := mustCreateUser(t, s, "alice")
+		shower := mustCreateProject(t, s, user.ID, "Shower")
+		dishes := mustCreateProject(t, s, user.ID, "Dishes")
+		trash := mustCreateProject(t, s, user.ID, "Trash")
+		constraint := func(source, target *model.Project) *model.ProjectConstraint {
+			return &model.ProjectConstraint{
+				UserID: user.ID, SourceProjectID: source.ID, TargetProjectID: target.ID,
+				Mode: model.ConstraintModeExclude,
+			}
+		}
+		for _, c := range []*model.ProjectConstraint{constraint(shower, dishes), constraint(trash, dishes), constraint(dishes, trash)} {
+			if err := s.ProjectConstraints().Create(c); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := s.ProjectConstraints().Create(constraint(shower, dishes)); err == nil {
+			t.Error("duplicate constraint between the same projects was created")
+		}
+
+		if got, err := s.ProjectConstraints().ListByTarget(dishes.ID, user.ID); err != nil || len(got) != 2 {
+			t.Errorf("ListByTarget = %+v, %v", got, err)
+		}
+		if err := s.ProjectConstraints().DeleteByProject(trash.ID); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := s.ProjectConstraints().List(user.ID); err != nil || len(got) != 1 || got[0].SourceProjectID != shower.ID {
+			t.Errorf("List after DeleteByProject = %+v, %v", got, err)
+		}
+	})
+}
+
+// compareUUID 按字节比较 UUID，与数据库中 ID 的排序一致
+func compareUUID(a, b uuid.UUID) int {
+	return slices.Compare(a[:], b[:])
+}
@@ -0,0 +1,62 @@
+package store
+
+import (
+	"whotakesshowers/internal/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RewardStore 奖励存储
+type RewardStore struct {
+	db *gorm.DB
+}
+
+// NewRewardStore 创建绑定到指定数据库连接（或事务）的奖励存储
+func NewRewardStore(db *gorm.DB) *RewardStore {
+	return &RewardStore{db: db}
+}
+
+// List 获取用户的奖励，按积分从低到高排序
+func (s *RewardStore) List(userID uuid.UUID) ([]model.Reward, error) {
+	var rewards []model.Reward
+	err := s.db.Where("user_id = ?", userID).Order("cost ASC, name ASC").Find(&rewards).Error
+	return rewards, err
+}
+
+// Get 获取奖励
+func (s *RewardStore) Get(id uuid.UUID, userID uuid.UUID) (*model.Reward, error) {
+	var reward model.Reward
+	err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&reward).Error
+	if err != nil {
+		return nil, err
+	}
+	return &reward, nil
+}
+
+// Create 创建奖励
+func (s *RewardStore) Create(reward *model.Reward) error {
+	return s.db.Create(reward).Error
+}
+
+// Update 更新奖励
+func (s *RewardStore) Update(reward *model.Reward) error {
+	return s.db.Save(reward).Error
+}
+
+// Delete 删除奖励，奖励不存在时返回 ErrNotFound；已有的兑换流水保留
+func (s *RewardStore) Delete(id uuid.UUID, userID uuid.UUID) error {
+	result := s.db.Where("id = ? AND user_id = ?", id, userID).Delete(&model.Reward{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteByUser 删除用户的所有奖励
+func (s *RewardStore) DeleteByUser(userID uuid.UUID) error {
+	return s.db.Where("user_id = ?", userID).Delete(&model.Reward{}).Error
+}
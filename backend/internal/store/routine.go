@@ -0,0 +1,142 @@
+package store
+
+import (
+	"encoding/json"
+	"whotakesshowers/internal/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RoutineStore 例程存储
+type RoutineStore struct {
+	db *gorm.DB
+}
+
+// NewRoutineStore 创建绑定到指定数据库连接（或事务）的例程存储
+func NewRoutineStore(db *gorm.DB) *RoutineStore {
+	return &RoutineStore{db: db}
+}
+
+// List 获取用户的例程，按创建时间排序
+func (s *RoutineStore) List(userID uuid.UUID) ([]model.Routine, error) {
+	var routines []model.Routine
+	err := s.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&routines).Error
+	return routines, err
+}
+
+// Get 获取例程
+func (s *RoutineStore) Get(id uuid.UUID, userID uuid.UUID) (*model.Routine, error) {
+	var routine model.Routine
+	err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&routine).Error
+	if err != nil {
+		return nil, err
+	}
+	return &routine, nil
+}
+
+// Create 创建例程
+func (s *RoutineStore) Create(routine *model.Routine) error {
+	return s.db.Create(routine).Error
+}
+
+// Update 更新例程
+func (s *RoutineStore) Update(routine *model.Routine) error {
+	return s.db.Save(routine).Error
+}
+
+// Delete 删除例程，例程不存在时返回 ErrNotFound；执行记录保留
+func (s *RoutineStore) Delete(id uuid.UUID, userID uuid.UUID) error {
+	result := s.db.Where("id = ? AND user_id = ?", id, userID).Delete(&model.Routine{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RemoveProject 从用户所有例程的步骤中移除指定项目
+func (s *RoutineStore) RemoveProject(projectID uuid.UUID, userID uuid.UUID) error {
+	routines, err := s.List(userID)
+	if err != nil {
+		return err
+	}
+
+	for _, routine := range routines {
+		if routine.ProjectIDs == "" {
+			continue
+		}
+		var projectIDs []uuid.UUID
+		if err := json.Unmarshal([]byte(routine.ProjectIDs), &projectIDs); err != nil {
+			return err
+		}
+
+		kept := make([]uuid.UUID, 0, len(projectIDs))
+		for _, id := range projectIDs {
+			if id != projectID {
+				kept = append(kept, id)
+			}
+		}
+		if len(kept) == len(projectIDs) {
+			continue
+		}
+		data, err := json.Marshal(kept)
+		if err != nil {
+			return err
+		}
+		if err := s.db.Model(&model.Routine{}).Where("id = ?", routine.ID).Update("project_ids", string(data)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteByUser 删除用户的所有例程
+func (s *RoutineStore) DeleteByUser(userID uuid.UUID) error {
+	return s.db.Where("user_id = ?", userID).Delete(&model.Routine{}).Error
+}
+
+// RoutineRunStore 例程执行记录存储
+type RoutineRunStore struct {
+	db *gorm.DB
+}
+
+// NewRoutineRunStore 创建绑定到指定数据库连接（或事务）的例程执行记录存储
+func NewRoutineRunStore(db *gorm.DB) *RoutineRunStore {
+	return &RoutineRunStore{db: db}
+}
+
+// Get 获取例程执行记录
+func (s *RoutineRunStore) Get(id uuid.UUID, userID uuid.UUID) (*model.RoutineRun, error) {
+	var run model.RoutineRun
+	err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&run).Error
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// ListByRoutine 获取例程最近的执行记录，按开始时间倒序
+func (s *RoutineRunStore) ListByRoutine(routineID uuid.UUID, userID uuid.UUID, limit int) ([]model.RoutineRun, error) {
+	var runs []model.RoutineRun
+	err := s.db.Where("routine_id = ? AND user_id = ?", routineID, userID).
+		Order("started_at DESC").Limit(limit).Find(&runs).Error
+	return runs, err
+}
+
+// Create 创建例程执行记录
+func (s *RoutineRunStore) Create(run *model.RoutineRun) error {
+	return s.db.Create(run).Error
+}
+
+// Update 更新例程执行记录
+func (s *RoutineRunStore) Update(run *model.RoutineRun) error {
+	return s.db.Save(run).Error
+}
+
+// DeleteByUser 删除用户的所有例程执行记录
+func (s *RoutineRunStore) DeleteByUser(userID uuid.UUID) error {
+	return s.db.Where("user_id = ?", userID).Delete(&model.RoutineRun{}).Error
+}
@@ -0,0 +1,47 @@
+package store
+
+import (
+	"whotakesshowers/internal/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TeamSplitStore 分组记录存储
+type TeamSplitStore struct {
+	db *gorm.DB
+}
+
+// NewTeamSplitStore 创建绑定到指定数据库连接（或事务）的分组记录存储
+func NewTeamSplitStore(db *gorm.DB) *TeamSplitStore {
+	return &TeamSplitStore{db: db}
+}
+
+// Find 分页获取分组记录，可按项目过滤（不包括回收站中项目的记录）
+func (s *TeamSplitStore) Find(userID uuid.UUID, projectID *uuid.UUID, page PageRequest) (*Page[model.TeamSplit], error) {
+	query := s.db.Model(&model.TeamSplit{}).Where("user_id = ?", userID).
+		Where("project_id NOT IN (?)", s.db.Unscoped().Model(&model.Project{}).
+			Select("id").Where("deleted_at IS NOT NULL"))
+	if projectID != nil {
+		query = query.Where("project_id = ?", *projectID)
+	}
+
+	return paginate(query, page, TeamSplitSortFields, func(ts *model.TeamSplit, field string) (any, uuid.UUID) {
+		return ts.CreatedAt, ts.ID
+	})
+}
+
+// Create 创建分组记录
+func (s *TeamSplitStore) Create(split *model.TeamSplit) error {
+	return s.db.Create(split).Error
+}
+
+// DeleteByProject 删除项目的所有分组记录
+func (s *TeamSplitStore) DeleteByProject(projectID uuid.UUID) error {
+	return s.db.Where("project_id = ?", projectID).Delete(&model.TeamSplit{}).Error
+}
+
+// DeleteByUser 删除用户的所有分组记录
+func (s *TeamSplitStore) DeleteByUser(userID uuid.UUID) error {
+	return s.db.Where("user_id = ?", userID).Delete(&model.TeamSplit{}).Error
+}
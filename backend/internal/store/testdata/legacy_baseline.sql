@@ -0,0 +1,19 @@
+-- 版本化迁移之前的数据库：由基线版本的 AutoMigrate 创建（没有外键、软删除列和头像唯一索引），
+-- 包含一个用户的候选人、项目和一条历史记录，以及需要接管时修复的数据：
+-- 同一个候选人的两张头像、一条项目已不存在的孤儿历史记录
+CREATE TABLE `users` (`id` uuid,`username` varchar(50) NOT NULL,`email` varchar(100),`password` varchar(255),`created_at` datetime,`updated_at` datetime,PRIMARY KEY (`id`));
+CREATE UNIQUE INDEX `idx_users_email` ON `users`(`email`);
+CREATE UNIQUE INDEX `idx_users_username` ON `users`(`username`);
+CREATE TABLE `projects` (`id` uuid,`name` varchar(200) NOT NULL,`user_id` uuid NOT NULL,`candidate_ids` text,`created_at` datetime,`updated_at` datetime,PRIMARY KEY (`id`));
+CREATE TABLE `candidates` (`id` uuid,`name` varchar(100) NOT NULL,`photo_url` varchar(500),`user_id` uuid NOT NULL,`created_at` datetime,`updated_at` datetime,PRIMARY KEY (`id`));
+CREATE TABLE `candidate_photos` (`id` uuid,`candidate_id` uuid NOT NULL,`photo_url` varchar(500) NOT NULL,`is_avatar` numeric DEFAULT false,`created_at` datetime,PRIMARY KEY (`id`));
+CREATE TABLE `histories` (`id` uuid,`project_id` uuid NOT NULL,`project_name` varchar(200) NOT NULL,`candidate_id` uuid NOT NULL,`candidate_name` varchar(100) NOT NULL,`selected_at` datetime NOT NULL,`user_id` uuid NOT NULL,PRIMARY KEY (`id`));
+
+INSERT INTO "users" VALUES('23092f55-c79d-49f0-ae36-94f02f08290a','default','','','2026-10-19 01:56:27.72537756+00:00','2026-10-19 01:56:27.72537756+00:00');
+INSERT INTO "users" VALUES('169c224c-2854-47ba-812c-7bcb0c5256d5','legacy','l@x.io','$2a$10$DNXvsAjx33DVgNhA0BylmepLmypB7xaTy9FYPW0KNSKE178W6It8.','2026-10-19 01:56:30.850584759+00:00','2026-10-19 01:56:30.850584759+00:00');
+INSERT INTO "candidates" VALUES('cd97393d-a7e3-4033-be88-f5d727b0ad7a','Ann','/uploads/old.jpg','169c224c-2854-47ba-812c-7bcb0c5256d5','2026-10-19 01:56:30.86928238+00:00','2026-10-19 01:56:30.86928238+00:00');
+INSERT INTO "candidate_photos" VALUES('6f1d3c1e-6a43-4a55-9d0e-1c2b8d9e0a01','cd97393d-a7e3-4033-be88-f5d727b0ad7a','/uploads/old.jpg',1,'2026-10-19 01:56:30.90000000+00:00');
+INSERT INTO "candidate_photos" VALUES('6f1d3c1e-6a43-4a55-9d0e-1c2b8d9e0a02','cd97393d-a7e3-4033-be88-f5d727b0ad7a','/uploads/new.jpg',1,'2026-10-19 01:56:30.95000000+00:00');
+INSERT INTO "projects" VALUES('b8ff8705-fb5c-4808-bdab-8d03d95ed8dd','Shower','169c224c-2854-47ba-812c-7bcb0c5256d5','["cd97393d-a7e3-4033-be88-f5d727b0ad7a"]','2026-10-19 01:56:30.943182251+00:00','2026-10-19 01:56:30.944120022+00:00');
+INSERT INTO "histories" VALUES('d0f30928-8155-4584-934f-c24e87129390','b8ff8705-fb5c-4808-bdab-8d03d95ed8dd','Shower','cd97393d-a7e3-4033-be88-f5d727b0ad7a','Ann','2026-10-19 01:56:31.006339176+00:00','169c224c-2854-47ba-812c-7bcb0c5256d5');
+INSERT INTO "histories" VALUES('0a6c8f3e-2f51-4a8e-b1de-5b1d4c7e9f10','7c0e4b1a-0000-4000-8000-000000000000','Deleted project','cd97393d-a7e3-4033-be88-f5d727b0ad7a','Ann','2026-10-18 08:00:00+00:00','169c224c-2854-47ba-812c-7bcb0c5256d5');
@@ -0,0 +1,75 @@
+package store
+
+import (
+	"time"
+	"whotakesshowers/internal/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UserStore 用户存储
+type UserStore struct {
+	db *gorm.DB
+}
+
+// NewUserStore 创建绑定到指定数据库连接（或事务）的用户存储
+func NewUserStore(db *gorm.DB) *UserStore {
+	return &UserStore{db: db}
+}
+
+// Get 根据ID获取用户
+func (s *UserStore) Get(id uuid.UUID) (*model.User, error) {
+	var user model.User
+	if err := s.db.Where("id = ?", id).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetByUsername 根据用户名获取用户
+func (s *UserStore) GetByUsername(username string) (*model.User, error) {
+	var user model.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetByEmail 根据邮箱获取用户
+func (s *UserStore) GetByEmail(email string) (*model.User, error) {
+	var user model.User
+	if err := s.db.Where("email = ?", email).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Create 创建用户
+func (s *UserStore) Create(user *model.User) error {
+	return s.db.Create(user).Error
+}
+
+// SetDeletionRequestedAt 设置申请删除账号的时间，nil 表示撤销申请
+func (s *UserStore) SetDeletionRequestedAt(id uuid.UUID, at *time.Time) error {
+	result := s.db.Model(&model.User{}).Where("id = ?", id).Update("deletion_requested_at", at)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListDeletionDue 获取在 before 之前申请删除的用户
+func (s *UserStore) ListDeletionDue(before time.Time) ([]model.User, error) {
+	var users []model.User
+	err := s.db.Where("deletion_requested_at IS NOT NULL AND deletion_requested_at < ?", before).Find(&users).Error
+	return users, err
+}
+
+// Delete 彻底删除用户
+func (s *UserStore) Delete(id uuid.UUID) error {
+	return s.db.Where("id = ?", id).Delete(&model.User{}).Error
+}
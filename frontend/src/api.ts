@@ -83,6 +83,12 @@ export interface Candidate {
   id: string;
   name: string;
   photo_url?: string;
+  points: number;
+  nickname: string;
+  birthday: string; // YYYY-MM-DD，为空表示未设置
+  color: string; // #rrggbb，用于转盘的扇区
+  custom_fields: string; // JSON object
+  tags: string; // JSON: string[]
   created_at: string;
   updated_at: string;
 }
@@ -99,6 +105,13 @@ export interface Project {
   id: string;
   name: string;
   candidate_ids: string;
+  target_minutes: number | null;
+  pick_points: number;
+  on_time_points: number;
+  eligibility_rules: string; // JSON: EligibilityRule[]
+  tag_query: string;
+  draw_overrides: string; // JSON: DrawOverride[]
+  reroll_tokens: number;
   created_at: string;
   updated_at: string;
 }
@@ -111,30 +124,326 @@ export interface History {
   candidate_name: string;
   selected_at: string;
   user_id: string;
+  source: string;
+  note: string;
+  status: 'picked' | 'started' | 'completed' | 'skipped';
+  started_at: string | null;
+  completed_at: string | null;
+  skipped_at: string | null;
+  voided_at: string | null;
+  void_reason: string;
+  pick_points: number;
+  on_time_points: number;
+  target_minutes: number | null; // 选中时项目的目标时长
+  routine_run_id: string | null;
+  routine_step: number | null;
+  pool_candidate_ids: string; // JSON: string[]
+  tag_query: string;
+  overridden: boolean;
+  override_reason: string;
+  superseded_by_id: string | null;
+  superseded_at: string | null;
+  reroll_of_id: string | null;
+  split_id: string | null;
+  split_group: number | null; // 从 0 开始的组号
+}
+
+export interface HistoryChange {
+  id: string;
+  history_id: string;
+  user_id: string;
+  field: string;
+  old_value: string;
+  new_value: string;
+  changed_at: string;
+}
+
+export interface Reward {
+  id: string;
+  user_id: string;
+  name: string;
+  cost: number;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface PointEntry {
+  id: string;
+  user_id: string;
+  candidate_id: string;
+  kind: 'pick' | 'on_time' | 'redeem' | 'adjust';
+  amount: number;
+  balance: number; // 作废记录扣回积分后可能为负数
+  history_id: string | null;
+  reward_id: string | null;
+  note: string;
+  created_at: string;
+}
+
+export interface LeaderboardEntry {
+  rank: number;
+  candidate_id: string;
+  candidate_name: string;
+  photo_url: string;
+  points: number;
+  earned: number;
+  redeemed: number;
+}
+
+export type BadgeRuleType = 'completed_streak' | 'total_completed' | 'first_to' | 'clean_week';
+
+export interface BadgeRule {
+  id: string;
+  user_id: string;
+  name: string;
+  type: BadgeRuleType;
+  threshold: number;
+  project_id: string | null;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface EarnedBadge {
+  id: string;
+  user_id: string;
+  rule_id: string;
+  candidate_id: string;
+  period: string;
+  history_id: string | null;
+  earned_at: string;
+  rule_name: string;
+  rule_type: BadgeRuleType;
+  candidate_name: string;
+}
+
+export interface BadgeProgress {
+  rule_id: string;
+  rule_name: string;
+  rule_type: BadgeRuleType;
+  candidate_id: string;
+  candidate_name: string;
+  current: number;
+  threshold: number;
+}
+
+export interface Routine {
+  id: string;
+  name: string;
+  user_id: string;
+  project_ids: string; // JSON array，按步骤顺序
+  avoid_repeats: boolean;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface RoutineRun {
+  id: string;
+  user_id: string;
+  routine_id: string;
+  routine_name: string;
+  steps: number;
+  current_step: number;
+  started_at: string;
+  finished_at: string | null;
+}
+
+export interface RoutineRunDetail extends RoutineRun {
+  histories: History[];
+  draws?: RoutineDraw[]; // 只在执行例程的响应中返回
+}
+
+export interface RoutineDraw {
+  step: number;
+  project_id: string;
+  history_id: string;
+  constraints: ConstraintEffect[];
+  overrides: OverrideEffect[];
+}
+
+// 分页列表响应
+export interface Page<T> {
+  items: T[];
+  total: number;
+  next_cursor?: string;
+}
+
+export interface ListParams {
+  q?: string;
+  tag?: string;
+  sort?: string;
+  limit?: number;
+  cursor?: string;
+}
+
+export interface HistoryParams {
+  project_id?: string;
+  candidate_id?: string;
+  from?: string;
+  to?: string;
+  source?: string;
+  voided?: boolean;
+  sort?: string;
+  limit?: number;
+  cursor?: string;
+}
+
+// 依次请求所有分页，返回完整列表
+const listAll = async <T>(url: string, params?: ListParams) => {
+  const items: T[] = [];
+  let cursor: string | undefined;
+  do {
+    const response = await api.get<Page<T>>(url, { params: { ...params, limit: 100, cursor } });
+    items.push(...response.data.items);
+    cursor = response.data.next_cursor;
+  } while (cursor);
+  return { data: items };
+};
+
+export type ConstraintMode = 'exclude' | 'down_weight';
+
+export interface ProjectConstraint {
+  id: string;
+  user_id: string;
+  source_project_id: string;
+  target_project_id: string;
+  mode: ConstraintMode;
+  weight: number;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface ConstraintEffect {
+  constraint_id: string;
+  source_project_id: string;
+  source_project_name: string;
+  mode: ConstraintMode;
+  weight: number;
+  candidate_ids: string[];
+  candidate_names: string[];
+  relaxed: boolean;
+}
+
+export interface CandidateProfile {
+  nickname?: string;
+  birthday?: string;
+  color?: string;
+  custom_fields?: Record<string, string>;
+  tags?: string[];
+}
+
+export interface EligibilityRule {
+  field: string; // 'age' 或 'custom.<名称>'
+  op: 'gte' | 'lte' | 'eq' | 'neq' | 'exists' | 'missing';
+  value?: number | string;
+}
+
+export interface Ineligible {
+  candidate_id: string;
+  candidate_name: string;
+  reasons: string[];
+}
+
+export interface DrawOverride {
+  type: 'birthday' | 'dates';
+  action: 'exclude' | 'force';
+  candidate_id?: string;
+  from?: string;
+  to?: string;
+  message?: string;
+}
+
+export interface OverrideEffect {
+  type: 'birthday' | 'dates';
+  action: 'exclude' | 'force';
+  candidate_id: string;
+  candidate_name: string;
+  reason: string;
+  relaxed: boolean;
 }
 
 export interface RandomizeResponse {
+  history_id: string | null;
+  candidate_id: string;
+  candidate_name: string;
+  constraints: ConstraintEffect[];
+  ineligible: Ineligible[];
+  overrides: OverrideEffect[];
+}
+
+export interface RerollResponse extends RandomizeResponse {
+  history: History;
+  superseded: History;
+  remaining: number;
+}
+
+export interface RerollBalances {
+  project_id: string;
+  tokens_per_week: number;
+  week_start: string;
+  resets_at: string;
+  balances: { candidate_id: string; candidate_name: string; used: number; remaining: number }[];
+}
+
+export interface TeamMember {
   candidate_id: string;
   candidate_name: string;
 }
 
+export interface TeamSplit {
+  id: string;
+  user_id: string;
+  project_id: string;
+  project_name: string;
+  group_count: number;
+  groups: string; // JSON: TeamMember[][]
+  keep_apart: string; // JSON: [string, string][]
+  created_at: string;
+}
+
+export interface TeamSplitResult extends TeamSplit {
+  history_ids: string[];
+}
+
 // API 方法
 export const apiClient = {
   // 项目相关
-  getProjects: () => api.get<Project[]>('/projects'),
+  getProjects: (params?: ListParams) => listAll<Project>('/projects', params),
   getProject: (id: string) => api.get<Project>(`/projects/${id}`),
-  createProject: (data: { name: string; candidate_ids: string[] }) =>
-    api.post<Project>('/projects', data),
-  updateProject: (id: string, data: { name?: string; candidate_ids?: string[] }) =>
+  createProject: (data: {
+    name: string;
+    candidate_ids: string[];
+    target_minutes?: number;
+    pick_points?: number;
+    on_time_points?: number;
+    eligibility_rules?: EligibilityRule[];
+    tag_query?: string;
+    draw_overrides?: DrawOverride[];
+    reroll_tokens?: number;
+  }) => api.post<Project>('/projects', data),
+  updateProject: (id: string, data: {
+    name?: string;
+    candidate_ids?: string[];
+    target_minutes?: number;
+    pick_points?: number;
+    on_time_points?: number;
+    eligibility_rules?: EligibilityRule[];
+    tag_query?: string;
+    draw_overrides?: DrawOverride[];
+    reroll_tokens?: number;
+  }) =>
     api.put<Project>(`/projects/${id}`, data),
   deleteProject: (id: string) => api.delete(`/projects/${id}`),
+  getProjectEligibility: (id: string) =>
+    api.get<{ eligible: Candidate[]; ineligible: Ineligible[] }>(`/projects/${id}/eligibility`),
+  getProjectRerolls: (id: string) => api.get<RerollBalances>(`/projects/${id}/rerolls`),
 
   // 候选人相关
-  getCandidates: () => api.get<Candidate[]>('/candidates'),
+  getCandidates: (params?: ListParams) => listAll<Candidate>('/candidates', params),
+  getCandidateTags: () => api.get<{ tag: string; count: number }[]>('/candidates/tags'),
   getCandidate: (id: string) => api.get<Candidate>(`/candidates/${id}`),
-  createCandidate: (data: { name: string; photo_url?: string }) =>
+  createCandidate: (data: { name: string; photo_url?: string } & CandidateProfile) =>
     api.post<Candidate>('/candidates', data),
-  updateCandidate: (id: string, data: { name?: string; photo_url?: string }) =>
+  updateCandidate: (id: string, data: { name?: string; photo_url?: string } & CandidateProfile) =>
     api.put<Candidate>(`/candidates/${id}`, data),
   deleteCandidate: (id: string) => api.delete(`/candidates/${id}`),
   uploadCandidatePhoto: (id: string, file: File) => {
@@ -164,12 +473,76 @@ export const apiClient = {
     api.delete(`/candidates/${candidateId}/photos/${photoId}`),
 
   // 历史记录相关
-  getHistory: (params?: { project_id?: string; limit?: number }) =>
-    api.get<History[]>('/history', { params }),
+  getHistory: (params?: HistoryParams) =>
+    api.get<Page<History>>('/history', { params }),
+  updateHistory: (id: string, data: { note?: string; completed?: boolean; completed_at?: string }) =>
+    api.patch<History>(`/history/${id}`, data),
+  voidHistory: (id: string, reason: string) =>
+    api.post<History>(`/history/${id}/void`, { reason }),
+  unvoidHistory: (id: string) => api.delete<History>(`/history/${id}/void`),
+  rerollHistory: (id: string) => api.post<RerollResponse>(`/history/${id}/reroll`),
+  getHistoryChanges: (id: string) => api.get<HistoryChange[]>(`/history/${id}/changes`),
+  startTask: (id: string) => api.post<History>(`/history/${id}/start`),
+  completeTask: (id: string) => api.post<History>(`/history/${id}/complete`),
+  skipTask: (id: string) => api.post<History>(`/history/${id}/skip`),
+  reopenTask: (id: string) => api.post<History>(`/history/${id}/reopen`),
+
+  // 积分与奖励
+  getRewards: () => api.get<Reward[]>('/rewards'),
+  createReward: (data: { name: string; cost: number }) => api.post<Reward>('/rewards', data),
+  updateReward: (id: string, data: { name: string; cost: number }) =>
+    api.put<Reward>(`/rewards/${id}`, data),
+  deleteReward: (id: string) => api.delete(`/rewards/${id}`),
+  redeemReward: (id: string, candidate_id: string) =>
+    api.post<PointEntry>(`/rewards/${id}/redeem`, { candidate_id }),
+  adjustPoints: (candidateId: string, amount: number, note?: string) =>
+    api.post<PointEntry>(`/candidates/${candidateId}/points`, { amount, note }),
+  getPointsLedger: (params?: { candidate_id?: string; kind?: string; limit?: number; cursor?: string }) =>
+    api.get<Page<PointEntry>>('/points/ledger', { params }),
+  getLeaderboard: () => api.get<LeaderboardEntry[]>('/points/leaderboard'),
+
+  // 徽章
+  getBadges: (candidate_id?: string) =>
+    api.get<{ earned: EarnedBadge[]; in_progress: BadgeProgress[] }>('/badges', { params: { candidate_id } }),
+  backfillBadges: () => api.post<{ awarded: number }>('/badges/backfill'),
+  getBadgeRules: () => api.get<BadgeRule[]>('/badges/rules'),
+  createBadgeRule: (data: { name: string; type: BadgeRuleType; threshold: number; project_id?: string }) =>
+    api.post<BadgeRule>('/badges/rules', data),
+  updateBadgeRule: (id: string, data: { name: string; type: BadgeRuleType; threshold: number; project_id?: string }) =>
+    api.put<BadgeRule>(`/badges/rules/${id}`, data),
+  deleteBadgeRule: (id: string) => api.delete(`/badges/rules/${id}`),
+
+  // 例程
+  getRoutines: () => api.get<Routine[]>('/routines'),
+  getRoutine: (id: string) => api.get<Routine>(`/routines/${id}`),
+  createRoutine: (data: { name: string; project_ids: string[]; avoid_repeats?: boolean }) =>
+    api.post<Routine>('/routines', data),
+  updateRoutine: (id: string, data: { name?: string; project_ids?: string[]; avoid_repeats?: boolean }) =>
+    api.put<Routine>(`/routines/${id}`, data),
+  deleteRoutine: (id: string) => api.delete(`/routines/${id}`),
+  runRoutine: (id: string) => api.post<RoutineRunDetail>(`/routines/${id}/runs`),
+  getRoutineRuns: (id: string) => api.get<RoutineRun[]>(`/routines/${id}/runs`),
+  getRoutineRun: (runId: string) => api.get<RoutineRunDetail>(`/routines/runs/${runId}`),
+  advanceRoutineRun: (runId: string, skip = false) =>
+    api.post<RoutineRunDetail>(`/routines/runs/${runId}/advance`, { skip }),
+
+  // 项目间约束
+  getConstraints: () => api.get<ProjectConstraint[]>('/constraints'),
+  createConstraint: (data: { source_project_id: string; target_project_id: string; mode: ConstraintMode; weight?: number }) =>
+    api.post<ProjectConstraint>('/constraints', data),
+  updateConstraint: (id: string, data: { source_project_id: string; target_project_id: string; mode: ConstraintMode; weight?: number }) =>
+    api.put<ProjectConstraint>(`/constraints/${id}`, data),
+  deleteConstraint: (id: string) => api.delete(`/constraints/${id}`),
 
-  // 随机选择
-  randomize: (project_id: string) =>
-    api.post<RandomizeResponse>('/randomize', { project_id }),
+  // 随机选择；重试时传入同一个 idempotencyKey，服务端返回第一次的结果而不会再选一次
+  randomize: (project_id: string, idempotencyKey?: string) =>
+    api.post<RandomizeResponse>('/randomize', { project_id }, idempotencyKey ? {
+      headers: { 'Idempotency-Key': idempotencyKey },
+    } : undefined),
+  splitTeams: (data: { project_id: string; group_count?: number; group_size?: number; keep_apart?: [string, string][] }) =>
+    api.post<TeamSplitResult>('/randomize/teams', data),
+  getTeamSplits: (params?: { project_id?: string; limit?: number; cursor?: string }) =>
+    api.get<Page<TeamSplit>>('/history/splits', { params }),
 };
 
 export default api;
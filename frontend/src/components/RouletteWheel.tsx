@@ -22,8 +22,11 @@ const getCoordinatesForPercent = (percent: number, radius: number) => {
   return [x, y];
 };
 
-// Get alternating colors for wheel segments - using arcade style colors
-const getSegmentColor = (index: number): string => {
+// Get the color for a wheel segment: the candidate's own color if set,
+// otherwise alternating arcade style colors
+const getSegmentColor = (candidate: Candidate, index: number): string => {
+  if (candidate.color) return candidate.color;
+
   const colors = [
     'var(--neon-pink)',    // #FF2E93
     'var(--electric-blue)', // #00D4FF
@@ -124,7 +127,7 @@ const RouletteWheel = forwardRef<RouletteHandle, RouletteWheelProps>(
       const avatarEmoji = ['😀', '😎', '🥳', '😊', '🤩', '😄'][index % 6];
 
       const isSelected = selectedIndex === index && spinning;
-      const segmentColor = getSegmentColor(index);
+      const segmentColor = getSegmentColor(candidate, index);
 
       return (
         <g key={candidate.id}>
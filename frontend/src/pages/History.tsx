@@ -5,6 +5,8 @@ import { apiClient, type History } from '../api';
 export default function HistoryPage() {
   const [histories, setHistories] = useState<History[]>([]);
   const [loading, setLoading] = useState(true);
+  const [nextCursor, setNextCursor] = useState<string | undefined>();
+  const [loadingMore, setLoadingMore] = useState(false);
 
   useEffect(() => {
     loadHistory();
@@ -13,7 +15,8 @@ export default function HistoryPage() {
   const loadHistory = async () => {
     try {
       const response = await apiClient.getHistory({ limit: 50 });
-      setHistories(response.data);
+      setHistories(response.data.items);
+      setNextCursor(response.data.next_cursor);
     } catch (error) {
       console.error('Failed to load history:', error);
     } finally {
@@ -21,6 +24,66 @@ export default function HistoryPage() {
     }
   };
 
+  const loadMore = async () => {
+    if (!nextCursor) return;
+    setLoadingMore(true);
+    try {
+      const response = await apiClient.getHistory({ limit: 50, cursor: nextCursor });
+      setHistories((prev) => [...prev, ...response.data.items]);
+      setNextCursor(response.data.next_cursor);
+    } catch (error) {
+      console.error('Failed to load more history:', error);
+    } finally {
+      setLoadingMore(false);
+    }
+  };
+
+  const replaceHistory = (updated: History) => {
+    setHistories((prev) => prev.map((h) => (h.id === updated.id ? updated : h)));
+  };
+
+  const changeTask = async (history: History, action: 'start' | 'complete' | 'skip' | 'reopen') => {
+    const actions = {
+      start: apiClient.startTask,
+      complete: apiClient.completeTask,
+      skip: apiClient.skipTask,
+      reopen: apiClient.reopenTask,
+    };
+    try {
+      const response = await actions[action](history.id);
+      replaceHistory(response.data);
+    } catch (error) {
+      console.error('Failed to update task:', error);
+    }
+  };
+
+  const editNote = async (history: History) => {
+    const note = prompt('备注', history.note);
+    if (note === null) return;
+    try {
+      const response = await apiClient.updateHistory(history.id, { note });
+      replaceHistory(response.data);
+    } catch (error) {
+      console.error('Failed to update history:', error);
+    }
+  };
+
+  const toggleVoided = async (history: History) => {
+    try {
+      if (history.voided_at) {
+        const response = await apiClient.unvoidHistory(history.id);
+        replaceHistory(response.data);
+        return;
+      }
+      const reason = prompt('作废原因（作废的记录不计入统计）');
+      if (!reason?.trim()) return;
+      const response = await apiClient.voidHistory(history.id, reason);
+      replaceHistory(response.data);
+    } catch (error) {
+      console.error('Failed to void history:', error);
+    }
+  };
+
   const formatDate = (dateString: string) => {
     const date = new Date(dateString);
     const now = new Date();
@@ -106,6 +169,7 @@ export default function HistoryPage() {
                 padding: 'clamp(16px, 4vw, 28px) clamp(16px, 4vw, 32px)',
                 animation: `slideInUp 0.5s ease-out forwards ${index * 0.08}s`,
                 background: index % 3 === 0 ? 'var(--soft-lilac)' : index % 3 === 1 ? 'var(--minty-fresh)' : 'var(--peachy)',
+                opacity: history.voided_at ? 0.6 : 1,
               }}
             >
               <div style={{ display: 'flex', alignItems: 'center', justifyContent: 'space-between', gap: 'clamp(12px, 3vw, 24px)', flexWrap: 'wrap' }}>
@@ -152,6 +216,48 @@ export default function HistoryPage() {
                       <span className="arcade-tag arcade-tag-green">
                         获胜者
                       </span>
+                      {history.status === 'started' && <span className="arcade-tag arcade-tag-pink">进行中</span>}
+                      {history.status === 'completed' && <span className="arcade-tag arcade-tag-blue">已完成</span>}
+                      {history.status === 'skipped' && <span className="arcade-tag arcade-tag-orange">已跳过</span>}
+                      {history.voided_at && <span className="arcade-tag arcade-tag-orange">已作废</span>}
+                    </div>
+                    {history.voided_at && (
+                      <p style={{ margin: 'clamp(4px, 1vw, 8px) 0 0', fontSize: 'clamp(0.75rem, 2vw, 0.875rem)', opacity: 0.8 }}>
+                        作废原因：{history.void_reason}
+                      </p>
+                    )}
+                    {history.note && (
+                      <p style={{ margin: 'clamp(4px, 1vw, 8px) 0 0', fontSize: 'clamp(0.75rem, 2vw, 0.875rem)' }}>
+                        📝 {history.note}
+                      </p>
+                    )}
+                    <div style={{ display: 'flex', gap: '8px', flexWrap: 'wrap', marginTop: 'clamp(8px, 2vw, 12px)' }}>
+                      {!history.voided_at && history.status === 'picked' && (
+                        <button className="arcade-btn" style={{ padding: '4px 12px', fontSize: '0.875rem' }} onClick={() => changeTask(history, 'start')}>
+                          ▶️ 开始
+                        </button>
+                      )}
+                      {!history.voided_at && (history.status === 'picked' || history.status === 'started') && (
+                        <>
+                          <button className="arcade-btn" style={{ padding: '4px 12px', fontSize: '0.875rem' }} onClick={() => changeTask(history, 'complete')}>
+                            ✅ 完成
+                          </button>
+                          <button className="arcade-btn" style={{ padding: '4px 12px', fontSize: '0.875rem' }} onClick={() => changeTask(history, 'skip')}>
+                            ⏭️ 跳过
+                          </button>
+                        </>
+                      )}
+                      {!history.voided_at && (history.status === 'completed' || history.status === 'skipped') && (
+                        <button className="arcade-btn" style={{ padding: '4px 12px', fontSize: '0.875rem' }} onClick={() => changeTask(history, 'reopen')}>
+                          ↩️ 撤销
+                        </button>
+                      )}
+                      <button className="arcade-btn" style={{ padding: '4px 12px', fontSize: '0.875rem' }} onClick={() => editNote(history)}>
+                        📝 备注
+                      </button>
+                      <button className="arcade-btn" style={{ padding: '4px 12px', fontSize: '0.875rem' }} onClick={() => toggleVoided(history)}>
+                        {history.voided_at ? '↩️ 恢复' : '🚫 作废'}
+                      </button>
                     </div>
                   </div>
                 </div>
@@ -176,6 +282,13 @@ export default function HistoryPage() {
               </div>
             </div>
           ))}
+          {nextCursor && (
+            <div style={{ textAlign: 'center' }}>
+              <button className="arcade-btn" onClick={loadMore} disabled={loadingMore}>
+                {loadingMore ? '加载中...' : '加载更多'}
+              </button>
+            </div>
+          )}
         </div>
       )}
     </div>